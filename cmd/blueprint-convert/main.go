@@ -0,0 +1,154 @@
+// Standalone executable that converts blueprints between TOML and JSON.
+//
+// The output is always in canonical form: sorted keys, no fields with
+// default values and deprecated fields migrated. With -check the files
+// are only compared against their canonical form in their own format,
+// which makes it usable as a formatter check in git hooks.
+//
+// Exits with 0 on success, 1 if -check found files that are not in
+// canonical form and 2 on errors.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/osbuild/images/pkg/blueprint"
+)
+
+type options struct {
+	from   blueprint.Format
+	to     blueprint.Format
+	output string
+	check  bool
+}
+
+func parseFormat(value string) (blueprint.Format, error) {
+	switch format := blueprint.Format(value); format {
+	case "", blueprint.FormatTOML, blueprint.FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, use %q or %q", value, blueprint.FormatTOML, blueprint.FormatJSON)
+	}
+}
+
+// readInput reads the blueprint from the file (or stdin for "-") and
+// detects its format unless it is set explicitly
+func readInput(path string, format blueprint.Format) ([]byte, blueprint.Format, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if format == "" {
+		if format, err = blueprint.FormatFromFilename(path); err != nil {
+			format = blueprint.DetectFormat(data)
+		}
+	}
+	return data, format, nil
+}
+
+// check reports whether the file is in canonical form
+func check(path string, opts options) (bool, error) {
+	data, format, err := readInput(path, opts.from)
+	if err != nil {
+		return false, err
+	}
+	canonical, err := blueprint.Convert(data, format, format)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	return bytes.Equal(data, canonical), nil
+}
+
+func convert(path string, opts options) error {
+	data, from, err := readInput(path, opts.from)
+	if err != nil {
+		return err
+	}
+	to := opts.to
+	if to == "" {
+		to = blueprint.FormatJSON
+		if from == blueprint.FormatJSON {
+			to = blueprint.FormatTOML
+		}
+	}
+
+	out, err := blueprint.Convert(data, from, to)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if opts.output == "" || opts.output == "-" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return os.WriteFile(opts.output, out, 0644)
+}
+
+func run() (bool, error) {
+	var opts options
+	var from, to string
+	flag.StringVar(&from, "from", "", "format of the input (toml or json), detected from the file extension or the content by default")
+	flag.StringVar(&to, "to", "", "format of the output (toml or json), defaults to the other format of the input")
+	flag.StringVar(&opts.output, "o", "", "write the output to this file instead of stdout")
+	flag.BoolVar(&opts.check, "check", false, "check that the files are in canonical form instead of converting them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-from FORMAT] [-to FORMAT] [-o OUTPUT] <blueprint>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s -check [-from FORMAT] <blueprint>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var err error
+	if opts.from, err = parseFormat(from); err != nil {
+		return false, err
+	}
+	if opts.to, err = parseFormat(to); err != nil {
+		return false, err
+	}
+
+	if opts.check {
+		if flag.NArg() == 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		clean := true
+		for _, path := range flag.Args() {
+			ok, err := check(path, opts)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				fmt.Printf("%s: not in canonical form\n", path)
+				clean = false
+			}
+		}
+		return !clean, nil
+	}
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	return false, convert(flag.Arg(0), opts)
+}
+
+func main() {
+	differs, err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	if differs {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	// we cannot use "maps" yet, as it needs go1.23
+	"golang.org/x/exp/maps"
+
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/repomd"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func formatAdvisoryFix(fix *dnfjson.AdvisoryFix) string {
+	details := []string{fix.Advisory.Type}
+	if fix.Advisory.Severity != "" {
+		details = append(details, fix.Advisory.Severity)
+	}
+	return fmt.Sprintf("%s (%s)", fix.Advisory.ID, strings.Join(details, ", "))
+}
+
+// printAdvisoryReport prints the advisories that are fixed, outstanding and
+// unfixed for the depsolved packages of each pipeline. The updateinfo of the
+// repositories is read (and downloaded for remote repositories) into the
+// metadata cache in cacheDir.
+func printAdvisoryReport(w io.Writer, depsolved map[string]dnfjson.DepsolveResult, cacheDir string) error {
+	reader := repomd.NewReader(cacheDir)
+
+	pipelines := maps.Keys(depsolved)
+	slices.Sort(pipelines)
+	for _, name := range pipelines {
+		result := depsolved[name]
+		fmt.Fprintf(w, "Advisories for pipeline %q:\n", name)
+
+		var repos []rpmmd.RepoConfig
+		for _, repo := range result.Repos {
+			// local packages have no updateinfo
+			if !repo.HasLocalPackages() {
+				repos = append(repos, repo)
+			}
+		}
+		md, err := reader.Read(repos)
+		if err != nil {
+			return fmt.Errorf("cannot read advisories: %w", err)
+		}
+
+		report := dnfjson.AdvisoryReport(&result, md)
+		if len(report) == 0 {
+			fmt.Fprintln(w, "  no advisories")
+			continue
+		}
+		for _, entry := range report {
+			fmt.Fprintf(w, "  %s\n", entry.Package.GetNEVRA())
+			for idx := range entry.Fixed {
+				fmt.Fprintf(w, "    fixed:       %s\n", formatAdvisoryFix(&entry.Fixed[idx]))
+			}
+			for idx := range entry.Outstanding {
+				fix := &entry.Outstanding[idx]
+				fmt.Fprintf(w, "    outstanding: %s, fixed in %s\n", formatAdvisoryFix(fix), fix.FixedIn.NEVRA())
+			}
+			for idx := range entry.Unfixed {
+				fix := &entry.Unfixed[idx]
+				fmt.Fprintf(w, "    unfixed:     %s, %s is not available\n", formatAdvisoryFix(fix), fix.FixedIn.NEVRA())
+			}
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -13,7 +14,10 @@ import (
 	"github.com/osbuild/images/internal/cmdutil"
 	"github.com/osbuild/images/pkg/arch"
 	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distrofactory"
+	"github.com/osbuild/images/pkg/dnfjson"
 	"github.com/osbuild/images/pkg/manifestgen"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/reporegistry"
@@ -33,6 +37,21 @@ func run() error {
 	flag.StringVar(&rpmCacheRoot, "rpmmd", "/tmp/rpmmd", "rpm metadata cache directory")
 	flag.StringVar(&repositories, "repositories", "test/data/repositories", "path to repository file or directory")
 
+	// layout report arg
+	var layoutFormat string
+	flag.StringVar(&layoutFormat, "layout", "", "write a report of the partition table layout to the output directory (text or json)")
+
+	// lockfile args
+	var lockfilePath string
+	var lockfileReplay, writeLockfile bool
+	flag.StringVar(&lockfilePath, "lockfile", "", "lockfile to pin the packages to; fails if the depsolved packages differ from it")
+	flag.BoolVar(&lockfileReplay, "lockfile-replay", false, "use the packages of the -lockfile without depsolving")
+	flag.BoolVar(&writeLockfile, "write-lockfile", false, "write a lockfile of the packages of the image to the output directory")
+
+	// advisory report arg
+	var advisories bool
+	flag.BoolVar(&advisories, "advisories", false, "print the advisories that are fixed, outstanding and unfixed for the packages of the image before building")
+
 	// osbuild checkpoint arg
 	var checkpoints cmdutil.MultiValue
 	flag.Var(&checkpoints, "checkpoints", "comma-separated list of pipeline names to checkpoint (passed to osbuild --checkpoint)")
@@ -49,6 +68,18 @@ func run() error {
 		flag.Usage()
 		os.Exit(1)
 	}
+	if lockfileReplay && lockfilePath == "" {
+		return fmt.Errorf("-lockfile-replay requires -lockfile")
+	}
+
+	var layoutExt string
+	if layoutFormat != "" {
+		var err error
+		layoutExt, err = cmdutil.LayoutExt(layoutFormat)
+		if err != nil {
+			return err
+		}
+	}
 
 	distroFac := distrofactory.NewDefault()
 	config, err := buildconfig.New(configFile)
@@ -112,6 +143,39 @@ func run() error {
 		OverrideRepos:  overrideRepos,
 		CustomSeed:     &seedArg,
 	}
+	if lockfilePath != "" {
+		lockfile, err := readLockfile(lockfilePath)
+		if err != nil {
+			return err
+		}
+		manifestOpts.Lockfile = lockfile
+		if lockfileReplay {
+			manifestOpts.LockMode = manifestgen.LockModeReplay
+		}
+	}
+	if writeLockfile {
+		manifestOpts.LockfileWriter = func(lockfile *manifestgen.Lockfile) error {
+			return writeLockfileTo(filepath.Join(buildDir, "lockfile.json"), lockfile)
+		}
+	}
+	// keep the depsolved packages for the advisory report
+	var depsolved map[string]dnfjson.DepsolveResult
+	if advisories {
+		manifestOpts.Depsolver = func(ctx context.Context, cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+			var err error
+			depsolved, err = manifestgen.DefaultDepsolver(ctx, cacheDir, packageSets, d, arch)
+			return depsolved, err
+		}
+		if manifestOpts.Lockfile != nil && manifestOpts.LockMode == manifestgen.LockModeReplay {
+			depsolved = manifestOpts.Lockfile.DepsolveResults()
+		}
+	}
+	if layoutFormat != "" {
+		manifestOpts.LayoutWriter = func(pipelineName string, layout *disk.Layout) error {
+			layoutPath := filepath.Join(buildDir, fmt.Sprintf("%s-layout.%s", pipelineName, layoutExt))
+			return cmdutil.WriteLayout(layoutPath, layoutFormat, layout)
+		}
+	}
 	// add RHSM fact to detect changes
 	config.Options.Facts = &facts.ImageOptions{
 		APIType: facts.TEST_APITYPE,
@@ -124,11 +188,17 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("[ERROR] manifest generator creation failed: %w", err)
 	}
-	if err := mg.Generate(config.Blueprint, distribution, imgType, arch, &config.Options); err != nil {
+	if err := mg.Generate(context.Background(), config.Blueprint, distribution, imgType, arch, &config.Options); err != nil {
 		return fmt.Errorf("[ERROR] manifest generation failed: %w", err)
 	}
 	fmt.Print("DONE\n")
 
+	if advisories {
+		if err := printAdvisoryReport(os.Stdout, depsolved, filepath.Join(rpmCacheRoot, "repomd")); err != nil {
+			return err
+		}
+	}
+
 	manifestPath := filepath.Join(buildDir, "manifest.json")
 	// nolint:gosec
 	if err := os.WriteFile(manifestPath, mf.Bytes(), 0644); err != nil {
@@ -147,6 +217,27 @@ func run() error {
 	return nil
 }
 
+func readLockfile(path string) (*manifestgen.Lockfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lockfile: %w", err)
+	}
+	defer f.Close()
+	return manifestgen.ReadLockfile(f)
+}
+
+func writeLockfileTo(path string, lockfile *manifestgen.Lockfile) error {
+	var buf bytes.Buffer
+	if err := lockfile.Write(&buf); err != nil {
+		return err
+	}
+	// nolint:gosec
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile %q: %w", path, err)
+	}
+	return nil
+}
+
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %s\n", err)
@@ -6,6 +6,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"flag"
@@ -23,11 +24,13 @@ import (
 	"github.com/osbuild/images/internal/cmdutil"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distrofactory"
 	"github.com/osbuild/images/pkg/dnfjson"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/manifestgen"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/rhsm/facts"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -174,6 +177,8 @@ func makeManifestJob(
 	path string,
 	content map[string]bool,
 	metadata bool,
+	validator *osbuild.SchemaValidator,
+	layoutFormat string,
 ) manifestJob {
 	name := bc.Name
 	distroName := distribution.Name()
@@ -216,7 +221,7 @@ func makeManifestJob(
 
 		var depsolvedSets map[string]dnfjson.DepsolveResult
 		if content["packages"] {
-			depsolvedSets, err = manifestgen.DefaultDepsolver(cacheDir, manifest.GetPackageSetChains(), distribution, archName)
+			depsolvedSets, err = manifestgen.DefaultDepsolver(context.Background(), cacheDir, manifest.GetPackageSetChains(), distribution, archName)
 			if err != nil {
 				err = fmt.Errorf("[%s] depsolve failed: %s", filename, err.Error())
 				return
@@ -233,7 +238,7 @@ func makeManifestJob(
 
 		var containerSpecs map[string][]container.Spec
 		if content["containers"] {
-			containerSpecs, err = manifestgen.DefaultContainerResolver(manifest.GetContainerSourceSpecs(), archName)
+			containerSpecs, err = manifestgen.DefaultContainerResolver(context.Background(), manifest.GetContainerSourceSpecs(), archName)
 			if err != nil {
 				return fmt.Errorf("[%s] container resolution failed: %s", filename, err.Error())
 			}
@@ -243,7 +248,7 @@ func makeManifestJob(
 
 		var commitSpecs map[string][]ostree.CommitSpec
 		if content["commits"] {
-			commitSpecs, err = manifestgen.DefaultCommitResolver(manifest.GetOSTreeSourceSpecs())
+			commitSpecs, err = manifestgen.DefaultCommitResolver(context.Background(), manifest.GetOSTreeSourceSpecs())
 			if err != nil {
 				return fmt.Errorf("[%s] ostree commit resolution failed: %s", filename, err.Error())
 			}
@@ -256,6 +261,21 @@ func makeManifestJob(
 			return fmt.Errorf("[%s] manifest serialization failed: %s", filename, err.Error())
 		}
 
+		if validator != nil {
+			var res *osbuild.Result
+			res, err = validator.ValidationResult(mf)
+			if err != nil {
+				return fmt.Errorf("[%s] manifest validation failed: %s", filename, err.Error())
+			}
+			if res != nil {
+				var msg strings.Builder
+				if err := res.Write(&msg); err != nil {
+					return err
+				}
+				return fmt.Errorf("[%s] manifest does not match the osbuild schemas:\n%s", filename, msg.String())
+			}
+		}
+
 		request := buildRequest{
 			Distro:       distribution.Name(),
 			Arch:         archName,
@@ -264,11 +284,39 @@ func makeManifestJob(
 			Config:       bc,
 		}
 		err = save(mf, depsolvedSets, containerSpecs, commitSpecs, request, path, filename, metadata)
+		if err != nil {
+			return
+		}
+
+		if layoutFormat != "" {
+			err = saveLayouts(manifest.GetPartitionTables(), layoutFormat, path, filename)
+		}
 		return
 	}
 	return job
 }
 
+// saveLayouts writes the layout report of each partition table next to the
+// manifest file, named after the manifest and the pipeline
+func saveLayouts(pts map[string]*disk.PartitionTable, format, path, filename string) error {
+	ext, err := cmdutil.LayoutExt(format)
+	if err != nil {
+		return err
+	}
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	for plName, pt := range pts {
+		layout, err := pt.Layout()
+		if err != nil {
+			return fmt.Errorf("[%s] failed to create layout for pipeline %q: %s", filename, plName, err.Error())
+		}
+		layoutPath := filepath.Join(path, fmt.Sprintf("%s.%s-layout.%s", base, plName, ext))
+		if err := cmdutil.WriteLayout(layoutPath, format, layout); err != nil {
+			return fmt.Errorf("[%s] %s", filename, err.Error())
+		}
+	}
+	return nil
+}
+
 func mockResolveContainers(containerSources map[string][]container.SourceSpec) map[string][]container.Spec {
 	containerSpecs := make(map[string][]container.Spec, len(containerSources))
 	for plName, sourceSpecs := range containerSources {
@@ -442,6 +490,7 @@ func main() {
 	var outputDir, cacheRoot, configPath, configMapPath string
 	var nWorkers int
 	var metadata, skipNoconfig, skipNorepos bool
+	var schemaDir, layoutFormat string
 	flag.StringVar(&outputDir, "output", "test/data/manifests/", "manifest store directory")
 	flag.IntVar(&nWorkers, "workers", 16, "number of workers to run concurrently")
 	flag.StringVar(&cacheRoot, "cache", "/tmp/rpmmd", "rpm metadata cache directory")
@@ -450,6 +499,8 @@ func main() {
 	flag.StringVar(&configMapPath, "config-map", "test/config-map.json", "configuration file mapping image types to configs")
 	flag.BoolVar(&skipNoconfig, "skip-noconfig", false, "skip distro-arch-image configurations that have no config (otherwise fail)")
 	flag.BoolVar(&skipNorepos, "skip-norepos", false, "skip distro-arch-image configurations that have no repositories (otherwise fail)")
+	flag.StringVar(&schemaDir, "validate-schemas", "", fmt.Sprintf("validate manifests against the module schemas of the osbuild library dir (e.g. %s)", osbuild.DefaultLibDir))
+	flag.StringVar(&layoutFormat, "layout", "", "write a report of the partition table layout next to each manifest (text or json)")
 
 	// content args
 	var packages, containers, commits bool
@@ -465,11 +516,28 @@ func main() {
 
 	flag.Parse()
 
+	if layoutFormat != "" {
+		if _, err := cmdutil.LayoutExt(layoutFormat); err != nil {
+			panic(err)
+		}
+	}
+
 	testedRepoRegistry, err := testrepos.New()
 	if err != nil {
 		panic(fmt.Sprintf("failed to create repo registry with tested distros: %v", err))
 	}
 
+	var validator *osbuild.SchemaValidator
+	if schemaDir != "" {
+		validator, err = osbuild.NewSchemaValidatorFromDir(schemaDir)
+		if err != nil {
+			panic(fmt.Sprintf("failed to load osbuild schemas: %v", err))
+		}
+		for _, warning := range validator.Warnings() {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", warning)
+		}
+	}
+
 	distroFac := distrofactory.NewDefault()
 	jobs := make([]manifestJob, 0)
 
@@ -550,7 +618,7 @@ func main() {
 				}
 
 				for _, itConfig := range imgTypeConfigs {
-					job := makeManifestJob(itConfig, imgType, distribution, repos, archName, cacheRoot, outputDir, contentResolve, metadata)
+					job := makeManifestJob(itConfig, imgType, distribution, repos, archName, cacheRoot, outputDir, contentResolve, metadata, validator, layoutFormat)
 					jobs = append(jobs, job)
 				}
 			}
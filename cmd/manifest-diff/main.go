@@ -0,0 +1,90 @@
+// Standalone executable that compares two osbuild manifests
+// semantically and reports the changes per pipeline and stage.
+//
+// The inputs can either be plain osbuild manifests or the files that
+// gen-manifests writes (with the manifest under the "manifest" key).
+//
+// Exits with 0 if the manifests are equivalent, 1 if they differ and
+// 2 on errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+// loadManifest reads an osbuild manifest from the given file, it also
+// accepts the gen-manifests output format
+func loadManifest(path string) (manifest.OSBuildManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var wrapped struct {
+		Manifest manifest.OSBuildManifest `json:"manifest"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	if len(wrapped.Manifest) > 0 {
+		return wrapped.Manifest, nil
+	}
+	return manifest.OSBuildManifest(data), nil
+}
+
+func run() (bool, error) {
+	var jsonOutput bool
+	flag.BoolVar(&jsonOutput, "json", false, "print the differences as json")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-json] <old-manifest> <new-manifest>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	oldMf, err := loadManifest(flag.Arg(0))
+	if err != nil {
+		return false, err
+	}
+	newMf, err := loadManifest(flag.Arg(1))
+	if err != nil {
+		return false, err
+	}
+
+	diff, err := manifest.DiffManifests(oldMf, newMf)
+	if err != nil {
+		return false, err
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			return false, err
+		}
+	} else if err := diff.WriteText(os.Stdout); err != nil {
+		return false, err
+	}
+
+	return !diff.Empty(), nil
+}
+
+func main() {
+	differ, err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	if differ {
+		os.Exit(1)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -70,7 +71,7 @@ func run(r io.Reader, w io.Writer) error {
 			Name:      bpSpec.Name,
 			TLSVerify: bpSpec.TLSVerify,
 		}
-		resolver.Add(srcSpec)
+		resolver.Add(context.Background(), srcSpec)
 	}
 
 	containerSpecs, err := resolver.Finish()
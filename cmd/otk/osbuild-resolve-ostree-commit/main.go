@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -76,7 +77,7 @@ func run(r io.Reader, w io.Writer) error {
 	var commitSpec ostree.CommitSpec
 	if !underTest() {
 		var err error
-		commitSpec, err = ostree.Resolve(sourceSpec)
+		commitSpec, err = ostree.Resolve(context.Background(), sourceSpec)
 		if err != nil {
 			return fmt.Errorf("failed to resolve ostree commit: %w", err)
 		}
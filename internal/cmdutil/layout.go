@@ -0,0 +1,51 @@
+package cmdutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/osbuild/images/pkg/disk"
+)
+
+// LayoutFormats are the valid values for the layout report format
+// command line arguments.
+var LayoutFormats = []string{"text", "json"}
+
+// LayoutExt returns the file extension for layout reports of the given
+// format.
+func LayoutExt(format string) (string, error) {
+	switch format {
+	case "text":
+		return "txt", nil
+	case "json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("unsupported layout format %q (valid formats: %v)", format, LayoutFormats)
+	}
+}
+
+// WriteLayout writes the layout report in the given format ("text" or
+// "json") to the file at path.
+func WriteLayout(path, format string, layout *disk.Layout) error {
+	var data []byte
+	switch format {
+	case "text":
+		data = []byte(layout.String())
+	case "json":
+		var err error
+		data, err = json.MarshalIndent(layout, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal layout: %w", err)
+		}
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("unsupported layout format %q (valid formats: %v)", format, LayoutFormats)
+	}
+
+	// nolint:gosec
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write layout file %q: %w", path, err)
+	}
+	return nil
+}
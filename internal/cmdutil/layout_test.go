@@ -0,0 +1,55 @@
+package cmdutil_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/cmdutil"
+	"github.com/osbuild/images/pkg/disk"
+)
+
+func TestLayoutExt(t *testing.T) {
+	ext, err := cmdutil.LayoutExt("text")
+	assert.NoError(t, err)
+	assert.Equal(t, "txt", ext)
+
+	ext, err = cmdutil.LayoutExt("json")
+	assert.NoError(t, err)
+	assert.Equal(t, "json", ext)
+
+	_, err = cmdutil.LayoutExt("yaml")
+	assert.EqualError(t, err, `unsupported layout format "yaml" (valid formats: [text json])`)
+}
+
+func TestWriteLayout(t *testing.T) {
+	layout := &disk.Layout{
+		Type:       "gpt",
+		Size:       1024 * 1024 * 1024,
+		SectorSize: 512,
+		Partitions: []disk.LayoutEntity{
+			{Kind: "partition", Number: 1, Start: 1024 * 1024, Size: 1024 * 1024},
+		},
+	}
+	tmpdir := t.TempDir()
+
+	textPath := filepath.Join(tmpdir, "layout.txt")
+	require.NoError(t, cmdutil.WriteLayout(textPath, "text", layout))
+	text, err := os.ReadFile(textPath)
+	require.NoError(t, err)
+	assert.Equal(t, layout.String(), string(text))
+
+	jsonPath := filepath.Join(tmpdir, "layout.json")
+	require.NoError(t, cmdutil.WriteLayout(jsonPath, "json", layout))
+	data, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	var decoded disk.Layout
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, *layout, decoded)
+
+	assert.Error(t, cmdutil.WriteLayout(filepath.Join(tmpdir, "layout"), "yaml", layout))
+}
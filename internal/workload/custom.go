@@ -3,6 +3,8 @@ package workload
 type Custom struct {
 	BaseWorkload
 	Packages         []string
+	ExcludePackages  []string
+	InstallWeakDeps  *bool
 	Services         []string
 	DisabledServices []string
 }
@@ -11,6 +13,14 @@ func (p *Custom) GetPackages() []string {
 	return p.Packages
 }
 
+func (p *Custom) GetExcludePackages() []string {
+	return p.ExcludePackages
+}
+
+func (p *Custom) GetInstallWeakDeps() *bool {
+	return p.InstallWeakDeps
+}
+
 func (p *Custom) GetServices() []string {
 	return p.Services
 }
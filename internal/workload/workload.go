@@ -4,6 +4,12 @@ import "github.com/osbuild/images/pkg/rpmmd"
 
 type Workload interface {
 	GetPackages() []string
+	// GetExcludePackages returns the packages to exclude from all package
+	// sets of the OS pipeline
+	GetExcludePackages() []string
+	// GetInstallWeakDeps overrides the installation of weak dependencies
+	// for all package sets of the OS pipeline, nil keeps the defaults
+	GetInstallWeakDeps() *bool
 	GetRepos() []rpmmd.RepoConfig
 	GetServices() []string
 	GetDisabledServices() []string
@@ -17,6 +23,14 @@ func (p BaseWorkload) GetPackages() []string {
 	return []string{}
 }
 
+func (p BaseWorkload) GetExcludePackages() []string {
+	return []string{}
+}
+
+func (p BaseWorkload) GetInstallWeakDeps() *bool {
+	return nil
+}
+
 func (p BaseWorkload) GetRepos() []rpmmd.RepoConfig {
 	return p.Repos
 }
@@ -4,6 +4,10 @@ type Artifact struct {
 	export   string
 	filename string
 	mimeType string
+
+	// files that are exported together with the main file, e.g. the image
+	// files of the additional disks of an image
+	extraFilenames []string
 }
 
 func New(export, filename string, mimeType *string) *Artifact {
@@ -18,6 +22,14 @@ func New(export, filename string, mimeType *string) *Artifact {
 	return artifact
 }
 
+// NewMultiFile creates an artifact that consists of several files of the same
+// type in the same export. The first file is the main file of the artifact.
+func NewMultiFile(export string, filenames []string, mimeType *string) *Artifact {
+	artifact := New(export, filenames[0], mimeType)
+	artifact.extraFilenames = filenames[1:]
+	return artifact
+}
+
 func (a *Artifact) Export() string {
 	return a.export
 }
@@ -26,6 +38,12 @@ func (a *Artifact) Filename() string {
 	return a.filename
 }
 
+// Filenames returns the names of all files of the artifact, starting with the
+// main file.
+func (a *Artifact) Filenames() []string {
+	return append([]string{a.filename}, a.extraFilenames...)
+}
+
 func (a *Artifact) MIMEType() string {
 	return a.mimeType
 }
@@ -1,6 +1,12 @@
 // Package blueprint contains primitives for representing weldr blueprints
 package blueprint
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // A Blueprint is a high-level description of an image.
 type Blueprint struct {
 	Name           string          `json:"name" toml:"name"`
@@ -15,14 +21,34 @@ type Blueprint struct {
 
 	// EXPERIMENTAL
 	Minimal bool `json:"minimal" toml:"minimal"`
+
+	// Packages that must not be installed, e.g. "firewalld" or
+	// "*-firmware". They are excluded from the whole package set of the
+	// image, including the packages the image type installs by default.
+	Exclude []string `json:"exclude,omitempty" toml:"exclude,omitempty"`
+
+	// Install the weak dependencies (Recommends) of the packages of the
+	// image (optional). By default, the image type decides for its own
+	// packages and the weak dependencies of the packages, modules and
+	// groups of the blueprint are not installed.
+	InstallWeakDeps *bool `json:"install_weak_deps,omitempty" toml:"install_weak_deps,omitempty"`
 }
 
 // A Package specifies an RPM package.
 type Package struct {
-	Name    string `json:"name" toml:"name"`
+	Name string `json:"name" toml:"name"`
+
+	// Version of the package (optional). Either a version, which can
+	// include the epoch and release and use globs, e.g. "2.4.*" or
+	// "1:2.4.62-1.el9", or a comparison with one of the operators >=, <=,
+	// >, < or =, e.g. ">= 2.4". Comparisons cannot use globs.
 	Version string `json:"version,omitempty" toml:"version,omitempty"`
 }
 
+// versionOperators are the comparison operators of package versions. The
+// two character operators must come first.
+var versionOperators = []string{">=", "<=", ">", "<", "="}
+
 // A group specifies an package group.
 type Group struct {
 	Name string `json:"name" toml:"name"`
@@ -63,11 +89,77 @@ func (b *Blueprint) GetPackagesEx(bootable bool) []string {
 	return packages
 }
 
+// ToNameVersion returns the package as a package specification for the
+// depsolver, e.g. "httpd-2.4.62" or "httpd >= 2.4" for a version comparison.
 func (p Package) ToNameVersion() string {
 	// Omit version to prevent all packages with prefix of name to be installed
 	if p.Version == "*" || p.Version == "" {
 		return p.Name
 	}
 
+	if op, version, ok := p.versionConstraint(); ok {
+		return p.Name + " " + op + " " + version
+	}
 	return p.Name + "-" + p.Version
 }
+
+// versionConstraint splits a version comparison into the operator and the
+// version. It returns false if the version is not a comparison.
+func (p Package) versionConstraint() (string, string, bool) {
+	version := strings.TrimSpace(p.Version)
+	for _, op := range versionOperators {
+		if strings.HasPrefix(version, op) {
+			return op, strings.TrimSpace(strings.TrimPrefix(version, op)), true
+		}
+	}
+	return "", "", false
+}
+
+// validate checks that the package has a name and a version that the
+// depsolver understands.
+func (p Package) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("package name is empty")
+	}
+	op, version, ok := p.versionConstraint()
+	if !ok {
+		version = p.Version
+	}
+	if ok && version == "" {
+		return fmt.Errorf("package %q: version comparison %q has no version", p.Name, op)
+	}
+	if strings.ContainsAny(version, " \t<>=") {
+		return fmt.Errorf("package %q: invalid version %q (expected a version, or an operator (%s) followed by a version)", p.Name, p.Version, strings.Join(versionOperators, ", "))
+	}
+	// the depsolver compares the version as is and never matches a glob
+	if ok && strings.ContainsAny(version, "*?[]") {
+		return fmt.Errorf("package %q: version comparison %q cannot use globs", p.Name, p.Version)
+	}
+	return nil
+}
+
+// ValidatePackages checks the versions of the packages and modules and the
+// package exclusions. Packages cannot be both included and excluded.
+func (b *Blueprint) ValidatePackages() error {
+	var errs []error
+	included := make(map[string]bool)
+	for _, pkgs := range [][]Package{b.Packages, b.Modules} {
+		for _, pkg := range pkgs {
+			if err := pkg.validate(); err != nil {
+				errs = append(errs, err)
+			}
+			included[pkg.Name] = true
+		}
+	}
+	for _, exclude := range b.Exclude {
+		if exclude == "" {
+			errs = append(errs, fmt.Errorf("excluded package name is empty"))
+		} else if included[exclude] {
+			errs = append(errs, fmt.Errorf("package %q cannot be both included and excluded", exclude))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid packages:\n%w", err)
+	}
+	return nil
+}
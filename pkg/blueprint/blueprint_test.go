@@ -89,6 +89,82 @@ func TestGetPackages(t *testing.T) {
 	assert.ElementsMatch(t, []string{"tmux-1.2", "openssh-server", "@anaconda-tools", "kernel"}, Received_packages)
 }
 
+func TestPackageToNameVersion(t *testing.T) {
+	testCases := map[string]struct {
+		version  string
+		expected string
+	}{
+		"none":          {"", "httpd"},
+		"any":           {"*", "httpd"},
+		"version":       {"2.4.62", "httpd-2.4.62"},
+		"glob":          {"2.4.*", "httpd-2.4.*"},
+		"evr":           {"1:2.4.62-1.el9", "httpd-1:2.4.62-1.el9"},
+		"greater-equal": {">= 2.4", "httpd >= 2.4"},
+		"less":          {"<2.5", "httpd < 2.5"},
+		"greater":       {"> 1:2.4.62-1.el9", "httpd > 1:2.4.62-1.el9"},
+		"less-equal":    {"<= 2.4.62", "httpd <= 2.4.62"},
+		"equal":         {" = 2.4.62-1.el9 ", "httpd = 2.4.62-1.el9"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			pkg := Package{Name: "httpd", Version: tc.version}
+			assert.Equal(t, tc.expected, pkg.ToNameVersion())
+			assert.NoError(t, pkg.validate())
+		})
+	}
+}
+
+func TestValidatePackages(t *testing.T) {
+	bp := Blueprint{
+		Packages: []Package{
+			{Name: "httpd", Version: ">= 2.4"},
+			{Name: "tmux", Version: ">="},
+			{Name: "vim", Version: "9.1 9.2"},
+			{Name: "firewalld"},
+			{Name: "bash", Version: ">= 5.2.*"},
+			{Name: "shadow-utils", Version: "= 2:4.15.?-3.fc40"},
+		},
+		Modules: []Package{
+			{Name: "nodejs", Version: "=< 20"},
+		},
+		Exclude: []string{"*-firmware", "firewalld", ""},
+	}
+	assert.EqualError(t, bp.ValidatePackages(), `invalid packages:
+package "tmux": version comparison ">=" has no version
+package "vim": invalid version "9.1 9.2" (expected a version, or an operator (>=, <=, >, <, =) followed by a version)
+package "bash": version comparison ">= 5.2.*" cannot use globs
+package "shadow-utils": version comparison "= 2:4.15.?-3.fc40" cannot use globs
+package "nodejs": invalid version "=< 20" (expected a version, or an operator (>=, <=, >, <, =) followed by a version)
+package "firewalld" cannot be both included and excluded
+excluded package name is empty`)
+
+	bp = Blueprint{
+		Packages: []Package{{Name: "httpd", Version: "< 2.5"}},
+		Exclude:  []string{"*-firmware"},
+	}
+	assert.NoError(t, bp.ValidatePackages())
+}
+
+func TestBlueprintParseExclude(t *testing.T) {
+	blueprintToml := `
+name = "test"
+exclude = ["firewalld", "*-firmware"]
+install_weak_deps = false
+
+[[packages]]
+name = "httpd"
+version = ">= 2.4"
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprintToml), &bp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"firewalld", "*-firmware"}, bp.Exclude)
+	require.NotNil(t, bp.InstallWeakDeps)
+	assert.False(t, *bp.InstallWeakDeps)
+	assert.Equal(t, []string{"httpd >= 2.4"}, bp.GetPackagesEx(false))
+}
+
 func TestKernelNameCustomization(t *testing.T) {
 	kernels := []string{"kernel", "kernel-debug", "kernel-rt"}
 
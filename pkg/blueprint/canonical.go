@@ -0,0 +1,396 @@
+package blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/osbuild/images/pkg/datasizes"
+)
+
+// Format is a serialization format of blueprints
+type Format string
+
+const (
+	FormatTOML Format = "toml"
+	FormatJSON Format = "json"
+)
+
+// FormatFromFilename returns the blueprint format based on the
+// extension of the filename
+func FormatFromFilename(filename string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".toml":
+		return FormatTOML, nil
+	case ".json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("cannot detect blueprint format of %q from extension %q", filename, ext)
+	}
+}
+
+// DetectFormat guesses the format of the blueprint data, JSON
+// blueprints are always objects while TOML ones never start with "{"
+func DetectFormat(data []byte) Format {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return FormatJSON
+	}
+	return FormatTOML
+}
+
+// Parse decodes a blueprint in the given format. Unlike decoding
+// directly into a Blueprint, unknown fields are an error and deprecated
+// fields are migrated to their replacements:
+//   - customizations.sshkey entries become the key of the matching
+//     customizations.user entry
+func Parse(data []byte, format Format) (*Blueprint, error) {
+	var raw map[string]any
+	switch format {
+	case FormatTOML:
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return nil, fmt.Errorf("cannot parse TOML blueprint: %w", err)
+		}
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("cannot parse JSON blueprint: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported blueprint format %q", format)
+	}
+
+	if err := migrateSSHKeys(raw); err != nil {
+		return nil, err
+	}
+
+	// both formats are decoded through json, the custom TOML
+	// unmarshalers of the blueprint types do the same anyway
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var bp Blueprint
+	dec := json.NewDecoder(bytes.NewReader(migrated))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&bp); err != nil {
+		return nil, fmt.Errorf("cannot decode blueprint: %w", err)
+	}
+	return &bp, nil
+}
+
+// migrateSSHKeys moves the deprecated customizations.sshkey entries
+// into customizations.user
+func migrateSSHKeys(raw map[string]any) error {
+	customizations, ok := raw["customizations"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	sshkeys, ok := customizations["sshkey"]
+	if !ok {
+		return nil
+	}
+	delete(customizations, "sshkey")
+
+	entries, ok := sshkeys.([]map[string]any)
+	if !ok {
+		list, ok := sshkeys.([]any)
+		if !ok {
+			return fmt.Errorf("customizations.sshkey must be a list")
+		}
+		for _, item := range list {
+			entry, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("customizations.sshkey entries must be objects")
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	var users []any
+	switch u := customizations["user"].(type) {
+	case nil:
+	case []any:
+		users = u
+	case []map[string]any:
+		for _, user := range u {
+			users = append(users, user)
+		}
+	default:
+		return fmt.Errorf("customizations.user must be a list")
+	}
+
+	for idx, entry := range entries {
+		name, _ := entry["user"].(string)
+		key, _ := entry["key"].(string)
+		if name == "" || key == "" {
+			return fmt.Errorf("customizations.sshkey[%d] requires a user and a key", idx)
+		}
+
+		var found map[string]any
+		for _, u := range users {
+			if user, ok := u.(map[string]any); ok && user["name"] == name {
+				found = user
+				break
+			}
+		}
+		if found == nil {
+			users = append(users, map[string]any{"name": name, "key": key})
+			continue
+		}
+		if existing, ok := found["key"]; ok && existing != key {
+			return fmt.Errorf("cannot migrate customizations.sshkey[%d]: user %q already has a different key", idx, name)
+		}
+		found["key"] = key
+	}
+	customizations["user"] = users
+
+	return nil
+}
+
+// Canonical encodes the blueprint in its canonical form: keys are
+// sorted, fields with default values are dropped and sizes are written
+// with units where possible. Canonical(Parse(Canonical(bp))) yields the
+// same output so it can be used to check the formatting of blueprints.
+func Canonical(bp *Blueprint, format Format) ([]byte, error) {
+	if bp == nil {
+		bp = &Blueprint{}
+	}
+	value, _, err := canonicalValue(reflect.ValueOf(*bp), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatTOML:
+		enc := toml.NewEncoder(&buf)
+		enc.Indent = ""
+		if err := enc.Encode(value); err != nil {
+			return nil, err
+		}
+		return separateTOMLTables(buf.Bytes()), nil
+	case FormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(value); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported blueprint format %q", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// Convert parses the blueprint data and returns it in canonical form in
+// the requested format, the formats can be the same
+func Convert(data []byte, from, to Format) ([]byte, error) {
+	bp, err := Parse(data, from)
+	if err != nil {
+		return nil, err
+	}
+	return Canonical(bp, to)
+}
+
+// separateTOMLTables puts an empty line before every table header, the
+// TOML encoder omits it after arrays of tables. String values never
+// contain raw newlines, they are escaped by the encoder.
+func separateTOMLTables(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	out := make([][]byte, 0, len(lines))
+	for idx, line := range lines {
+		if idx > 0 && bytes.HasPrefix(line, []byte("[")) && len(lines[idx-1]) > 0 {
+			out = append(out, nil)
+		}
+		out = append(out, line)
+	}
+	return bytes.Join(out, []byte("\n"))
+}
+
+// requiredFields lists the fields that are kept even if they have their
+// zero value because decoding fails without them
+var requiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(PartitionCustomization{}): {"minsize"},
+	reflect.TypeOf(LVCustomization{}):        {"minsize"},
+}
+
+// canonicalValue converts the value into plain maps, slices and scalars
+// and reports whether it differs from its default
+func canonicalValue(v reflect.Value, name string) (any, bool, error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		value, keep, err := canonicalValue(v.Elem(), name)
+		if err != nil {
+			return nil, false, err
+		}
+		// explicitly set scalars (e.g. "uid = 0") are kept, empty
+		// sections are not
+		if v.Elem().Kind() != reflect.Struct {
+			keep = true
+		}
+		return value, keep, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		if f, ok := v.Interface().(float64); ok && f == math.Trunc(f) {
+			return int64(f), true, nil
+		}
+		value, _, err := canonicalValue(v.Elem(), name)
+		return value, true, err
+	case reflect.Struct:
+		m, err := canonicalStruct(v)
+		if err != nil {
+			return nil, false, err
+		}
+		return m, len(m) > 0, nil
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil, false, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Struct {
+			list := make([]map[string]any, 0, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				m, err := canonicalStruct(v.Index(i))
+				if err != nil {
+					return nil, false, err
+				}
+				list = append(list, m)
+			}
+			return list, true, nil
+		}
+		list := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, _, err := canonicalValue(v.Index(i), name)
+			if err != nil {
+				return nil, false, err
+			}
+			list = append(list, item)
+		}
+		return list, true, nil
+	case reflect.String:
+		return v.String(), v.String() != "", nil
+	case reflect.Bool:
+		return v.Bool(), v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), v.Int() != 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if name == "minsize" || name == "start" || name == "maxsize" {
+			return canonicalSize(v.Uint()), v.Uint() != 0, nil
+		}
+		return v.Uint(), v.Uint() != 0, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), v.Float() != 0, nil
+	default:
+		return nil, false, fmt.Errorf("cannot encode %s value of %q", v.Kind(), name)
+	}
+}
+
+func canonicalStruct(v reflect.Value) (map[string]any, error) {
+	m := make(map[string]any)
+	if err := addStructFields(m, v); err != nil {
+		return nil, err
+	}
+	normalizeFields(v.Type(), m)
+
+	for _, required := range requiredFields[v.Type()] {
+		if _, ok := m[required]; !ok {
+			value, _, err := canonicalValue(v.FieldByIndex(jsonFieldIndex(v.Type(), required)), required)
+			if err != nil {
+				return nil, err
+			}
+			m[required] = value
+		}
+	}
+	return m, nil
+}
+
+// addStructFields adds the non-default fields of the struct to m using
+// their json names, embedded structs are flattened
+func addStructFields(m map[string]any, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			if err := addStructFields(m, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			return fmt.Errorf("field %s.%s has no json name", t.Name(), field.Name)
+		}
+		value, keep, err := canonicalValue(v.Field(i), name)
+		if err != nil {
+			return err
+		}
+		if keep {
+			m[name] = value
+		}
+	}
+	return nil
+}
+
+// jsonFieldIndex returns the index of the (possibly embedded) field with
+// the given json name
+func jsonFieldIndex(t reflect.Type, name string) []int {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tagName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tagName == name {
+			return field.Index
+		}
+		if field.Anonymous && tagName == "" && field.Type.Kind() == reflect.Struct {
+			if idx := jsonFieldIndex(field.Type, name); idx != nil {
+				return append([]int{i}, idx...)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeFields drops values that are equivalent to the defaults
+func normalizeFields(t reflect.Type, m map[string]any) {
+	switch t {
+	case reflect.TypeOf(Package{}):
+		// "*" is the same as no version, see Package.ToNameVersion()
+		if m["version"] == "*" {
+			delete(m, "version")
+		}
+	case reflect.TypeOf(PartitionCustomization{}):
+		if m["type"] == "plain" {
+			delete(m, "type")
+		}
+	}
+}
+
+// canonicalSize returns the size as a string with the largest unit that
+// represents it exactly, e.g. "20 GiB"
+func canonicalSize(size uint64) any {
+	if size == 0 {
+		return size
+	}
+	formatted := datasizes.Format(size)
+	if strings.HasSuffix(formatted, " bytes") {
+		return size
+	}
+	return formatted
+}
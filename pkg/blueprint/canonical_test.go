@@ -0,0 +1,188 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/datasizes"
+)
+
+const canonicalTestTOML = `description = "A test blueprint"
+distro = "centos-9"
+name = "test"
+version = "0.0.1"
+
+[[containers]]
+source = "quay.io/fedora/fedora:latest"
+tls-verify = false
+
+[customizations]
+hostname = "example"
+
+[[customizations.directories]]
+ensure_parents = true
+group = 0
+path = "/etc/foo"
+user = 1000
+
+[customizations.disk]
+minsize = "20 GiB"
+
+[[customizations.disk.partitions]]
+fs_type = "xfs"
+minsize = "1 GiB"
+mountpoint = "/boot"
+position = 1
+start = "1 MiB"
+
+[[customizations.disk.partitions]]
+fs_type = "xfs"
+grow_percent = 40
+maxsize = "8 GiB"
+minsize = "1 GiB"
+mountpoint = "/data"
+
+[[customizations.disk.partitions]]
+grow = true
+minsize = 0
+name = "vg0"
+type = "lvm"
+
+[[customizations.disk.partitions.logical_volumes]]
+fs_type = "xfs"
+grow = true
+maxsize = "15 GiB"
+minsize = "10 GiB"
+mountpoint = "/"
+
+[[customizations.filesystem]]
+minsize = 1234
+mountpoint = "/var"
+
+[[customizations.user]]
+key = "ssh-ed25519 AAAA"
+name = "alice"
+uid = 0
+
+[[packages]]
+name = "tmux"
+version = "3.2"
+`
+
+func TestCanonicalRoundTrip(t *testing.T) {
+	bp, err := Parse([]byte(canonicalTestTOML), FormatTOML)
+	require.NoError(t, err)
+
+	canonical, err := Canonical(bp, FormatTOML)
+	require.NoError(t, err)
+	assert.Equal(t, canonicalTestTOML, string(canonical))
+
+	js, err := Canonical(bp, FormatJSON)
+	require.NoError(t, err)
+	bp2, err := Parse(js, FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, bp, bp2)
+
+	back, err := Convert(js, FormatJSON, FormatTOML)
+	require.NoError(t, err)
+	assert.Equal(t, canonicalTestTOML, string(back))
+}
+
+func TestCanonicalDropsDefaults(t *testing.T) {
+	bp := &Blueprint{
+		Name:     "test",
+		Packages: []Package{{Name: "tmux", Version: "*"}},
+		Modules:  []Package{},
+		Customizations: &Customizations{
+			Kernel: &KernelCustomization{},
+			FIPS:   common.ToPtr(false),
+			Disk: &DiskCustomization{
+				Partitions: []PartitionCustomization{
+					{
+						Type:    "plain",
+						MinSize: 2 * datasizes.GiB,
+						FilesystemTypedCustomization: FilesystemTypedCustomization{
+							Mountpoint: "/",
+							FSType:     "ext4",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	out, err := Canonical(bp, FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "customizations": {
+    "disk": {
+      "partitions": [
+        {
+          "fs_type": "ext4",
+          "minsize": "2 GiB",
+          "mountpoint": "/"
+        }
+      ]
+    },
+    "fips": false
+  },
+  "name": "test",
+  "packages": [
+    {
+      "name": "tmux"
+    }
+  ]
+}
+`, string(out))
+}
+
+func TestParseMigratesSSHKeys(t *testing.T) {
+	bp, err := Parse([]byte(`{
+  "name": "test",
+  "customizations": {
+    "sshkey": [
+      {"user": "root", "key": "ssh-rsa ROOT"},
+      {"user": "alice", "key": "ssh-rsa ALICE"}
+    ],
+    "user": [{"name": "alice", "groups": ["wheel"]}]
+  }
+}`), FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, []UserCustomization{
+		{Name: "alice", Key: common.ToPtr("ssh-rsa ALICE"), Groups: []string{"wheel"}},
+		{Name: "root", Key: common.ToPtr("ssh-rsa ROOT")},
+	}, bp.Customizations.User)
+
+	_, err = Parse([]byte(`
+[[customizations.sshkey]]
+user = "alice"
+key = "ssh-rsa NEW"
+
+[[customizations.user]]
+name = "alice"
+key = "ssh-rsa OLD"
+`), FormatTOML)
+	assert.EqualError(t, err, `cannot migrate customizations.sshkey[0]: user "alice" already has a different key`)
+}
+
+func TestParseUnknownField(t *testing.T) {
+	_, err := Parse([]byte("name = \"test\"\npackage = []\n"), FormatTOML)
+	assert.EqualError(t, err, `cannot decode blueprint: json: unknown field "package"`)
+}
+
+func TestFormatDetection(t *testing.T) {
+	format, err := FormatFromFilename("dir/bp.JSON")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+	format, err = FormatFromFilename("bp.toml")
+	require.NoError(t, err)
+	assert.Equal(t, FormatTOML, format)
+	_, err = FormatFromFilename("bp.yaml")
+	assert.EqualError(t, err, `cannot detect blueprint format of "bp.yaml" from extension ".yaml"`)
+
+	assert.Equal(t, FormatJSON, DetectFormat([]byte("\n  {}")))
+	assert.Equal(t, FormatTOML, DetectFormat([]byte(`name = "x"`)))
+}
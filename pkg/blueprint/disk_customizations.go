@@ -6,8 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
 
 	"github.com/osbuild/images/pkg/datasizes"
 	"github.com/osbuild/images/pkg/pathpolicy"
@@ -16,15 +21,28 @@ import (
 type DiskCustomization struct {
 	// Type of the partition table: gpt or dos.
 	// Optional, the default depends on the distro and image type.
-	Type       string
-	MinSize    uint64
-	Partitions []PartitionCustomization
+	Type       string                   `json:"type,omitempty" toml:"type,omitempty"`
+	MinSize    uint64                   `json:"minsize,omitempty" toml:"minsize,omitempty"`
+	Partitions []PartitionCustomization `json:"partitions,omitempty" toml:"partitions,omitempty"`
+
+	// Additional disks of the image, e.g. a data or swap disk (optional).
+	// Each disk has its own partition table and is written to a separate
+	// image file. The filesystem root and the boot partitions are always on
+	// the first disk. Additional disks cannot have additional disks
+	// themselves.
+	//
+	// LVM volume groups cannot span disks: a volume group is created on a
+	// single partition, so using the name of a volume group of another disk
+	// is an error. Not all image types can export more than one disk, see
+	// [DiskCustomization.MultiDisk].
+	Disks []DiskCustomization `json:"disks,omitempty" toml:"disks,omitempty"`
 }
 
 type diskCustomizationMarshaler struct {
 	Type       string                   `json:"type,omitempty" toml:"type,omitempty"`
 	MinSize    datasizes.Size           `json:"minsize,omitempty" toml:"minsize,omitempty"`
 	Partitions []PartitionCustomization `json:"partitions,omitempty" toml:"partitions,omitempty"`
+	Disks      []DiskCustomization      `json:"disks,omitempty" toml:"disks,omitempty"`
 }
 
 func (dc *DiskCustomization) UnmarshalJSON(data []byte) error {
@@ -35,6 +53,7 @@ func (dc *DiskCustomization) UnmarshalJSON(data []byte) error {
 	dc.Type = dcm.Type
 	dc.MinSize = dcm.MinSize.Uint64()
 	dc.Partitions = dcm.Partitions
+	dc.Disks = dcm.Disks
 
 	return nil
 }
@@ -44,13 +63,16 @@ func (dc *DiskCustomization) UnmarshalTOML(data any) error {
 }
 
 // PartitionCustomization defines a single partition on a disk. The Type
-// defines the kind of "payload" for the partition: plain, lvm, or btrfs.
+// defines the kind of "payload" for the partition: plain, lvm, btrfs, or raw.
 //   - plain: the payload will be a filesystem on a partition (e.g. xfs, ext4).
 //     See [FilesystemTypedCustomization] for extra fields.
 //   - lvm: the payload will be an LVM volume group. See [VGCustomization] for
 //     extra fields
 //   - btrfs: the payload will be a btrfs volume. See
 //     [BtrfsVolumeCustomization] for extra fields.
+//   - raw (or none): the partition has no payload and is left unformatted,
+//     e.g. for the second slot of an A/B update scheme. Only the size and
+//     the partition entry ([PartitionEntryCustomization]) can be set.
 type PartitionCustomization struct {
 	// The type of payload for the partition (optional, defaults to "plain").
 	Type string `json:"type" toml:"type"`
@@ -64,6 +86,17 @@ type PartitionCustomization struct {
 	// (optional, defaults depend on payload and mountpoints).
 	MinSize uint64 `json:"minsize" toml:"minsize"`
 
+	// LUKS2 encryption of the partition payload (optional). Partitions
+	// for mountpoints that the bootloader reads (/boot, /boot/efi)
+	// cannot be encrypted.
+	Encryption *EncryptionCustomization `json:"encryption,omitempty" toml:"encryption,omitempty"`
+
+	PartitionEntryCustomization
+
+	PartitionLayoutCustomization
+
+	GrowthCustomization
+
 	BtrfsVolumeCustomization
 
 	VGCustomization
@@ -101,6 +134,13 @@ type LVCustomization struct {
 	// Minimum size of the logical volume
 	MinSize uint64 `json:"minsize,omitempty" toml:"minsize,omitempty"`
 
+	// Grow the logical volume to fill the free space of the volume group
+	// that is left after the other logical volumes have grown. At most one
+	// logical volume per volume group can grow.
+	Grow bool `json:"grow,omitempty" toml:"grow,omitempty"`
+
+	GrowthCustomization
+
 	FilesystemTypedCustomization
 }
 
@@ -110,6 +150,9 @@ func (lv *LVCustomization) UnmarshalJSON(data []byte) error {
 	var lvAnySize struct {
 		Name    string `json:"name,omitempty" toml:"name,omitempty"`
 		MinSize any    `json:"minsize,omitempty" toml:"minsize,omitempty"`
+		MaxSize any    `json:"maxsize,omitempty" toml:"maxsize,omitempty"`
+		Grow    bool   `json:"grow,omitempty" toml:"grow,omitempty"`
+		GrowthCustomization
 		FilesystemTypedCustomization
 	}
 	if err := json.Unmarshal(data, &lvAnySize); err != nil {
@@ -117,6 +160,8 @@ func (lv *LVCustomization) UnmarshalJSON(data []byte) error {
 	}
 
 	lv.Name = lvAnySize.Name
+	lv.Grow = lvAnySize.Grow
+	lv.GrowthCustomization = lvAnySize.GrowthCustomization
 	lv.FilesystemTypedCustomization = lvAnySize.FilesystemTypedCustomization
 
 	if lvAnySize.MinSize == nil {
@@ -128,12 +173,198 @@ func (lv *LVCustomization) UnmarshalJSON(data []byte) error {
 	}
 	lv.MinSize = size
 
+	if lvAnySize.MaxSize != nil {
+		maxsize, err := decodeSize(lvAnySize.MaxSize)
+		if err != nil {
+			return err
+		}
+		lv.MaxSize = maxsize
+	}
+
+	return nil
+}
+
+// The entry of the partition in the partition table. All fields are
+// optional, the defaults depend on the payload and mountpoint of the
+// partition.
+type PartitionEntryCustomization struct {
+	// Partition type: a GUID or a name of a discoverable partition type
+	// like "root-x86-64", "usr-verity" or "home" (gpt), or a two-digit hex
+	// ID like "83" (dos). The names without an architecture refer to the
+	// architecture of the image.
+	PartType string `json:"part_type,omitempty" toml:"part_type,omitempty"`
+
+	// Partition name, also known as the partition label (gpt only). At
+	// most 36 characters.
+	PartLabel string `json:"part_label,omitempty" toml:"part_label,omitempty"`
+
+	// Attribute bits to set (gpt only): the names "required",
+	// "no-block-io", "legacy-bios-bootable", "grow-fs", "read-only" and
+	// "no-auto", or bit numbers between 0 and 63, e.g. "48".
+	PartAttrs []string `json:"part_attrs,omitempty" toml:"part_attrs,omitempty"`
+}
+
+// gptAttributeBits maps the names of GPT partition attributes to their bit
+// numbers. The discoverable partitions specification defines the bits 59
+// and higher for the partition types it describes.
+var gptAttributeBits = map[string]uint{
+	"required":             0,
+	"no-block-io":          1,
+	"legacy-bios-bootable": 2,
+	"grow-fs":              59,
+	"read-only":            60,
+	"no-auto":              63,
+}
+
+// ParseGPTAttribute returns the bit number of a GPT partition attribute
+// given by name or number.
+func ParseGPTAttribute(attr string) (uint, error) {
+	if bit, ok := gptAttributeBits[attr]; ok {
+		return bit, nil
+	}
+	bit, err := strconv.ParseUint(attr, 10, 8)
+	if err != nil || bit > 63 {
+		return 0, fmt.Errorf("unknown GPT partition attribute %q", attr)
+	}
+	return uint(bit), nil
+}
+
+// GPTAttributeBits returns the bit numbers of the attributes in PartAttrs.
+func (p *PartitionEntryCustomization) GPTAttributeBits() ([]uint, error) {
+	if len(p.PartAttrs) == 0 {
+		return nil, nil
+	}
+	bits := make([]uint, 0, len(p.PartAttrs))
+	for _, attr := range p.PartAttrs {
+		bit, err := ParseGPTAttribute(attr)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(bits, bit) {
+			bits = append(bits, bit)
+		}
+	}
+	slices.Sort(bits)
+	return bits, nil
+}
+
+var (
+	partTypeNameRegex  = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	partTypeDOSIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{2}$`)
+)
+
+// validate checks the partition entry for a partition table of the given
+// type ("" for the distro default).
+func (p *PartitionEntryCustomization) validate(ptType string) error {
+	if ptType == "dos" {
+		if p.PartType != "" && !partTypeDOSIDRegex.MatchString(p.PartType) {
+			return fmt.Errorf("invalid partition type %q for dos partition table: must be a two-digit hex ID", p.PartType)
+		}
+		if p.PartLabel != "" {
+			return fmt.Errorf("partition label %q is not supported on dos partition tables", p.PartLabel)
+		}
+		if len(p.PartAttrs) > 0 {
+			return fmt.Errorf("partition attributes are not supported on dos partition tables")
+		}
+		return nil
+	}
+
+	if p.PartType != "" {
+		_, err := uuid.Parse(p.PartType)
+		if err != nil && !partTypeNameRegex.MatchString(p.PartType) {
+			return fmt.Errorf("invalid partition type %q: must be a GUID or a partition type name", p.PartType)
+		}
+	}
+	// GPT partition names are stored as 36 UTF-16 code units
+	if len(utf16.Encode([]rune(p.PartLabel))) > 36 {
+		return fmt.Errorf("partition label %q is longer than 36 characters", p.PartLabel)
+	}
+	if _, err := p.GPTAttributeBits(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// The placement of the partition on the disk. All fields are optional. By
+// default, the partitions are laid out in the order they are defined, after
+// the partitions that are added automatically for booting (BIOS boot, ESP,
+// /boot), and the partition with the root filesystem is placed at the end of
+// the disk and grows to fill it.
+type PartitionLayoutCustomization struct {
+	// Start offset of the partition on the disk. Must be aligned to 1 MiB
+	// and not overlap with the other partitions.
+	Start uint64 `json:"start,omitempty" toml:"start,omitempty"`
+
+	// Number of the partition in the partition table, starting at 1.
+	// Partitions without a position fill the remaining numbers in order.
+	Position uint `json:"position,omitempty" toml:"position,omitempty"`
+
+	// Grow the partition to fill the free space on the disk instead of the
+	// root partition. The partitions that follow it are moved to the end
+	// of the disk. At most one partition can grow.
+	Grow bool `json:"grow,omitempty" toml:"grow,omitempty"`
+}
+
+// validate checks the placement for a partition table of the given type
+// ("" for the distro default). The positions of the previously validated
+// partitions are tracked in positions.
+func (p *PartitionLayoutCustomization) validate(ptType string, positions map[uint]bool) error {
+	if p.Start%datasizes.MiB != 0 {
+		return fmt.Errorf("partition start %d is not aligned to 1 MiB", p.Start)
+	}
+
+	maxPosition := uint(128)
+	if ptType == "dos" {
+		maxPosition = 4
+	}
+	if p.Position > maxPosition {
+		return fmt.Errorf("partition position %d is out of range (1-%d)", p.Position, maxPosition)
+	}
+	if p.Position != 0 {
+		if positions[p.Position] {
+			return fmt.Errorf("duplicate partition position %d in partitioning customizations", p.Position)
+		}
+		positions[p.Position] = true
+	}
+	return nil
+}
+
+// The growth of a partition or logical volume beyond its minimum size. The
+// free space of a disk (or volume group) is the space that is left when all
+// of its partitions (or logical volumes) have their minimum size. By default,
+// the free space of the disk goes to the growing partition (see
+// [PartitionLayoutCustomization]) and the free space of a volume group is
+// left unallocated.
+type GrowthCustomization struct {
+	// Percentage of the free space to add to the minimum size (optional,
+	// 1-100). The percentages of all partitions on the disk, or of all
+	// logical volumes in a volume group, can add up to at most 100. The
+	// growing partition or logical volume gets what is left.
+	GrowPercent uint `json:"grow_percent,omitempty" toml:"grow_percent,omitempty"`
+
+	// Maximum size to grow to (optional). It does not shrink a partition or
+	// logical volume below its minimum size.
+	MaxSize uint64 `json:"maxsize,omitempty" toml:"maxsize,omitempty"`
+}
+
+// validate checks the growth of a partition or logical volume with the given
+// minimum size that can also fill the free space (grow)
+func (g *GrowthCustomization) validate(minsize uint64, grow bool) error {
+	if g.GrowPercent > 100 {
+		return fmt.Errorf("grow_percent %d is out of range (1-100)", g.GrowPercent)
+	}
+	if grow && g.GrowPercent != 0 {
+		return fmt.Errorf("grow and grow_percent cannot be combined")
+	}
+	if g.MaxSize != 0 && g.MaxSize < minsize {
+		return fmt.Errorf("maxsize %d is smaller than minsize %d", g.MaxSize, minsize)
+	}
 	return nil
 }
 
 // A btrfs volume consisting of one or more subvolumes.
 type BtrfsVolumeCustomization struct {
-	Subvolumes []BtrfsSubvolumeCustomization
+	Subvolumes []BtrfsSubvolumeCustomization `json:"subvolumes,omitempty" toml:"subvolumes,omitempty"`
 }
 
 type BtrfsSubvolumeCustomization struct {
@@ -156,6 +387,8 @@ func (v *PartitionCustomization) UnmarshalJSON(data []byte) error {
 	var typeSniffer struct {
 		Type    string `json:"type"`
 		MinSize any    `json:"minsize"`
+		Start   any    `json:"start"`
+		MaxSize any    `json:"maxsize"`
 	}
 	if err := json.Unmarshal(data, &typeSniffer); err != nil {
 		return fmt.Errorf("%s %w", errPrefix, err)
@@ -179,6 +412,10 @@ func (v *PartitionCustomization) UnmarshalJSON(data []byte) error {
 		if err := decodeLVM(v, data); err != nil {
 			return fmt.Errorf("%s %w", errPrefix, err)
 		}
+	case "raw", "none":
+		if err := decodeRaw(v, data); err != nil {
+			return fmt.Errorf("%s %w", errPrefix, err)
+		}
 	default:
 		return fmt.Errorf("%s unknown partition type: %s", errPrefix, partType)
 	}
@@ -195,6 +432,22 @@ func (v *PartitionCustomization) UnmarshalJSON(data []byte) error {
 	}
 	v.MinSize = minsize
 
+	if typeSniffer.Start != nil {
+		start, err := decodeSize(typeSniffer.Start)
+		if err != nil {
+			return fmt.Errorf("%s error decoding start for partition: %w", errPrefix, err)
+		}
+		v.Start = start
+	}
+
+	if typeSniffer.MaxSize != nil {
+		maxsize, err := decodeSize(typeSniffer.MaxSize)
+		if err != nil {
+			return fmt.Errorf("%s error decoding maxsize for partition: %w", errPrefix, err)
+		}
+		v.MaxSize = maxsize
+	}
+
 	return nil
 }
 
@@ -203,10 +456,18 @@ func (v *PartitionCustomization) UnmarshalJSON(data []byte) error {
 // the type is "plain", none of the fields for btrfs or lvm are used.
 func decodePlain(v *PartitionCustomization, data []byte) error {
 	var plain struct {
-		// Type and minsize are handled by the caller. These are added here to
-		// satisfy "DisallowUnknownFields" when decoding.
-		Type    string `json:"type"`
-		MinSize any    `json:"minsize"`
+		// Type, minsize, start and maxsize are handled by the caller. These
+		// are added here to satisfy "DisallowUnknownFields" when decoding.
+		// Encryption, the partition entry, the layout and the growth are
+		// valid for all partition types.
+		Type       string                   `json:"type"`
+		MinSize    any                      `json:"minsize"`
+		Start      any                      `json:"start"`
+		MaxSize    any                      `json:"maxsize"`
+		Encryption *EncryptionCustomization `json:"encryption"`
+		PartitionEntryCustomization
+		PartitionLayoutCustomization
+		GrowthCustomization
 		FilesystemTypedCustomization
 	}
 
@@ -218,6 +479,10 @@ func decodePlain(v *PartitionCustomization, data []byte) error {
 	}
 
 	v.FilesystemTypedCustomization = plain.FilesystemTypedCustomization
+	v.Encryption = plain.Encryption
+	v.PartitionEntryCustomization = plain.PartitionEntryCustomization
+	v.PartitionLayoutCustomization = plain.PartitionLayoutCustomization
+	v.GrowthCustomization = plain.GrowthCustomization
 	return nil
 }
 
@@ -226,10 +491,18 @@ func decodePlain(v *PartitionCustomization, data []byte) error {
 // the type is btrfs, none of the fields for plain or lvm are used.
 func decodeBtrfs(v *PartitionCustomization, data []byte) error {
 	var btrfs struct {
-		// Type and minsize are handled by the caller. These are added here to
-		// satisfy "DisallowUnknownFields" when decoding.
-		Type    string `json:"type"`
-		MinSize any    `json:"minsize"`
+		// Type, minsize, start and maxsize are handled by the caller. These
+		// are added here to satisfy "DisallowUnknownFields" when decoding.
+		// Encryption, the partition entry, the layout and the growth are
+		// valid for all partition types.
+		Type       string                   `json:"type"`
+		MinSize    any                      `json:"minsize"`
+		Start      any                      `json:"start"`
+		MaxSize    any                      `json:"maxsize"`
+		Encryption *EncryptionCustomization `json:"encryption"`
+		PartitionEntryCustomization
+		PartitionLayoutCustomization
+		GrowthCustomization
 		BtrfsVolumeCustomization
 	}
 
@@ -241,6 +514,10 @@ func decodeBtrfs(v *PartitionCustomization, data []byte) error {
 	}
 
 	v.BtrfsVolumeCustomization = btrfs.BtrfsVolumeCustomization
+	v.Encryption = btrfs.Encryption
+	v.PartitionEntryCustomization = btrfs.PartitionEntryCustomization
+	v.PartitionLayoutCustomization = btrfs.PartitionLayoutCustomization
+	v.GrowthCustomization = btrfs.GrowthCustomization
 	return nil
 }
 
@@ -249,10 +526,18 @@ func decodeBtrfs(v *PartitionCustomization, data []byte) error {
 // is lvm, none of the fields for plain or btrfs are used.
 func decodeLVM(v *PartitionCustomization, data []byte) error {
 	var vg struct {
-		// Type and minsize are handled by the caller. These are added here to
-		// satisfy "DisallowUnknownFields" when decoding.
-		Type    string `json:"type"`
-		MinSize any    `json:"minsize"`
+		// Type, minsize, start and maxsize are handled by the caller. These
+		// are added here to satisfy "DisallowUnknownFields" when decoding.
+		// Encryption, the partition entry, the layout and the growth are
+		// valid for all partition types.
+		Type       string                   `json:"type"`
+		MinSize    any                      `json:"minsize"`
+		Start      any                      `json:"start"`
+		MaxSize    any                      `json:"maxsize"`
+		Encryption *EncryptionCustomization `json:"encryption"`
+		PartitionEntryCustomization
+		PartitionLayoutCustomization
+		GrowthCustomization
 		VGCustomization
 	}
 
@@ -263,6 +548,38 @@ func decodeLVM(v *PartitionCustomization, data []byte) error {
 	}
 
 	v.VGCustomization = vg.VGCustomization
+	v.Encryption = vg.Encryption
+	v.PartitionEntryCustomization = vg.PartitionEntryCustomization
+	v.PartitionLayoutCustomization = vg.PartitionLayoutCustomization
+	v.GrowthCustomization = vg.GrowthCustomization
+	return nil
+}
+
+// decodeRaw decodes the data into a struct that only contains the partition
+// entry with DisallowUnknownFields. This ensures that when the type is raw (or
+// none), no payload fields are used.
+func decodeRaw(v *PartitionCustomization, data []byte) error {
+	var raw struct {
+		// Type, minsize, start and maxsize are handled by the caller. These
+		// are added here to satisfy "DisallowUnknownFields" when decoding.
+		Type    string `json:"type"`
+		MinSize any    `json:"minsize"`
+		Start   any    `json:"start"`
+		MaxSize any    `json:"maxsize"`
+		PartitionEntryCustomization
+		PartitionLayoutCustomization
+		GrowthCustomization
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return fmt.Errorf("error decoding partition with type \"raw\": %w", err)
+	}
+
+	v.PartitionEntryCustomization = raw.PartitionEntryCustomization
+	v.PartitionLayoutCustomization = raw.PartitionLayoutCustomization
+	v.GrowthCustomization = raw.GrowthCustomization
 	return nil
 }
 
@@ -305,6 +622,10 @@ func (v *PartitionCustomization) UnmarshalTOML(data any) error {
 		if err := decodeLVM(v, dataJSON); err != nil {
 			return fmt.Errorf("%s %w", errPrefix, err)
 		}
+	case "raw", "none":
+		if err := decodeRaw(v, dataJSON); err != nil {
+			return fmt.Errorf("%s %w", errPrefix, err)
+		}
 	default:
 		return fmt.Errorf("%s unknown partition type: %s", errPrefix, partType)
 	}
@@ -321,6 +642,22 @@ func (v *PartitionCustomization) UnmarshalTOML(data any) error {
 	}
 	v.MinSize = minsize
 
+	if startField, ok := d["start"]; ok {
+		start, err := decodeSize(startField)
+		if err != nil {
+			return fmt.Errorf("%s error decoding start for partition: %w", errPrefix, err)
+		}
+		v.Start = start
+	}
+
+	if maxsizeField, ok := d["maxsize"]; ok {
+		maxsize, err := decodeSize(maxsizeField)
+		if err != nil {
+			return fmt.Errorf("%s error decoding maxsize for partition: %w", errPrefix, err)
+		}
+		v.MaxSize = maxsize
+	}
+
 	return nil
 }
 
@@ -339,6 +676,18 @@ func (v *PartitionCustomization) UnmarshalTOML(data any) error {
 //   - All non-empty properties are valid for the partition type (e.g.
 //     LogicalVolumes is empty when the type is "plain" or "btrfs")
 //   - Filesystems with FSType set to "swap" do not specify a mountpoint.
+//   - Encryption customizations are valid and not used for /boot or /boot/efi.
+//   - Partition types, labels and attributes are well-formed and labels and
+//     attributes are only used on gpt partition tables.
+//   - Raw partitions have a size and no payload properties.
+//   - Partition start offsets are aligned, positions are unique and in range
+//     for the partition table type, and at most one partition grows.
+//   - Growth percentages are in range and add up to at most 100 per disk and
+//     volume group, maximum sizes are not smaller than minimum sizes, and at
+//     most one logical volume per volume group grows.
+//   - Additional disks have partitions, share the mountpoint and volume group
+//     namespaces with the first disk, and do not hold the filesystem root,
+//     /boot, /boot/efi, or additional disks.
 //
 // Note that in *addition* consumers should also call
 // ValidateLayoutConstraints() to validate that the policy for disk
@@ -348,6 +697,23 @@ func (p *DiskCustomization) Validate() error {
 		return nil
 	}
 
+	mountpoints := make(map[string]bool)
+	vgnames := make(map[string]bool)
+	if err := p.validateDisk(mountpoints, vgnames); err != nil {
+		return err
+	}
+	for idx := range p.Disks {
+		if err := p.Disks[idx].validateAdditionalDisk(mountpoints, vgnames); err != nil {
+			return fmt.Errorf("invalid additional disk %d: %w", idx+1, err)
+		}
+	}
+	return nil
+}
+
+// validateDisk validates the partition table type and the partitions of a
+// single disk. The mountpoints and volume group names are shared between all
+// disks.
+func (p *DiskCustomization) validateDisk(mountpoints, vgnames map[string]bool) error {
 	switch p.Type {
 	case "gpt", "":
 	case "dos":
@@ -363,8 +729,8 @@ func (p *DiskCustomization) Validate() error {
 		return fmt.Errorf("unknown partition table type: %s (valid: gpt, dos)", p.Type)
 	}
 
-	mountpoints := make(map[string]bool)
-	vgnames := make(map[string]bool)
+	positions := make(map[uint]bool)
+	var growing, growPercent uint
 	var errs []error
 	for _, part := range p.Partitions {
 		switch part.Type {
@@ -374,9 +740,25 @@ func (p *DiskCustomization) Validate() error {
 			errs = append(errs, part.validateLVM(mountpoints, vgnames))
 		case "btrfs":
 			errs = append(errs, part.validateBtrfs(mountpoints))
+		case "raw", "none":
+			errs = append(errs, part.validateRaw())
 		default:
 			errs = append(errs, fmt.Errorf("unknown partition type: %s", part.Type))
 		}
+		errs = append(errs, part.validateEncryption())
+		errs = append(errs, part.PartitionEntryCustomization.validate(p.Type))
+		errs = append(errs, part.PartitionLayoutCustomization.validate(p.Type, positions))
+		errs = append(errs, part.GrowthCustomization.validate(part.MinSize, part.Grow))
+		if part.Grow {
+			growing++
+		}
+		growPercent += part.GrowPercent
+	}
+	if growing > 1 {
+		errs = append(errs, fmt.Errorf("only one partition can grow, got %d", growing))
+	}
+	if growPercent > 100 {
+		errs = append(errs, fmt.Errorf("grow_percent of all partitions adds up to %d (maximum 100)", growPercent))
 	}
 
 	// will discard all nil errors
@@ -386,6 +768,31 @@ func (p *DiskCustomization) Validate() error {
 	return nil
 }
 
+// validateAdditionalDisk validates a disk in [DiskCustomization.Disks].
+func (p *DiskCustomization) validateAdditionalDisk(mountpoints, vgnames map[string]bool) error {
+	if len(p.Disks) > 0 {
+		return fmt.Errorf("additional disks cannot have additional disks")
+	}
+	if len(p.Partitions) == 0 {
+		return fmt.Errorf("no partitions defined")
+	}
+	for _, part := range p.Partitions {
+		// the lvm2 stages create a volume group on a single device
+		if part.Type == "lvm" && part.Name != "" && vgnames[part.Name] {
+			return fmt.Errorf("LVM volume group %q cannot span disks: each disk needs its own volume group", part.Name)
+		}
+	}
+	if err := p.validateDisk(mountpoints, vgnames); err != nil {
+		return err
+	}
+	for _, mp := range p.mountpoints() {
+		if mp == "/" || slices.Contains(plainOnlyMountpoints, mp) {
+			return fmt.Errorf("mountpoint %q must be on the first disk", mp)
+		}
+	}
+	return nil
+}
+
 func validateMountpoint(path string) error {
 	if path == "" {
 		return fmt.Errorf("mountpoint is empty")
@@ -403,8 +810,8 @@ func validateMountpoint(path string) error {
 }
 
 // ValidateLayoutConstraints checks that at most one LVM Volume Group or btrfs
-// volume is defined. Returns an error if both LVM and btrfs are set and if
-// either has more than one element.
+// volume is defined on each disk. Returns an error if both LVM and btrfs are
+// set on a disk and if either has more than one element.
 //
 // Note that this is a *policy* validation, in theory the "disk" code
 // does support the constraints but we choose not to allow them for
@@ -415,6 +822,18 @@ func (p *DiskCustomization) ValidateLayoutConstraints() error {
 		return nil
 	}
 
+	if err := p.validateDiskLayoutConstraints(); err != nil {
+		return err
+	}
+	for idx := range p.Disks {
+		if err := p.Disks[idx].validateDiskLayoutConstraints(); err != nil {
+			return fmt.Errorf("additional disk %d: %w", idx+1, err)
+		}
+	}
+	return nil
+}
+
+func (p *DiskCustomization) validateDiskLayoutConstraints() error {
 	var btrfsVols, lvmVGs uint
 	for _, part := range p.Partitions {
 		switch part.Type {
@@ -498,6 +917,36 @@ func (p *PartitionCustomization) validatePlain(mountpoints map[string]bool) erro
 	return nil
 }
 
+func (p *PartitionCustomization) validateRaw() error {
+	if p.MinSize == 0 {
+		return fmt.Errorf("raw partition requires a size (minsize)")
+	}
+	// check for invalid property usage
+	if p.FilesystemTypedCustomization != (FilesystemTypedCustomization{}) {
+		return fmt.Errorf("filesystem properties defined for raw partition (partition type %q)", p.Type)
+	}
+	if len(p.LogicalVolumes) > 0 || p.Name != "" {
+		return fmt.Errorf("LVM volume group defined for raw partition (partition type %q)", p.Type)
+	}
+	if len(p.Subvolumes) > 0 {
+		return fmt.Errorf("subvolumes defined for raw partition (partition type %q)", p.Type)
+	}
+	if p.Encryption != nil {
+		return fmt.Errorf("raw partition cannot be encrypted (partition type %q)", p.Type)
+	}
+	return nil
+}
+
+func (p *PartitionCustomization) validateEncryption() error {
+	if p.Encryption == nil {
+		return nil
+	}
+	if slices.Contains(plainOnlyMountpoints, p.Mountpoint) {
+		return fmt.Errorf("partition with mountpoint %q cannot be encrypted", p.Mountpoint)
+	}
+	return p.Encryption.Validate()
+}
+
 func (p *PartitionCustomization) validateLVM(mountpoints, vgnames map[string]bool) error {
 	if p.Name != "" && vgnames[p.Name] { // VGs with no name get autogenerated names
 		return fmt.Errorf("duplicate LVM volume group name %q in partitioning customizations", p.Name)
@@ -514,12 +963,27 @@ func (p *PartitionCustomization) validateLVM(mountpoints, vgnames map[string]boo
 
 	vgnames[p.Name] = true
 	lvnames := make(map[string]bool)
+	var growing, growPercent uint
 	for _, lv := range p.LogicalVolumes {
 		if lv.Name != "" && lvnames[lv.Name] { // LVs with no name get autogenerated names
 			return fmt.Errorf("duplicate LVM logical volume name %q in volume group %q in partitioning customizations", lv.Name, p.Name)
 		}
 		lvnames[lv.Name] = true
 
+		if err := lv.GrowthCustomization.validate(lv.MinSize, lv.Grow); err != nil {
+			return fmt.Errorf("invalid logical volume customization: %w", err)
+		}
+		if lv.Grow {
+			growing++
+		}
+		if growing > 1 {
+			return fmt.Errorf("only one logical volume in volume group %q can grow", p.Name)
+		}
+		growPercent += lv.GrowPercent
+		if growPercent > 100 {
+			return fmt.Errorf("grow_percent of the logical volumes in volume group %q adds up to more than 100", p.Name)
+		}
+
 		if lv.FSType == "swap" {
 			// make sure the mountpoint is empty and return
 			if lv.Mountpoint != "" {
@@ -591,19 +1055,9 @@ func CheckDiskMountpointsPolicy(partitioning *DiskCustomization, mountpointAllow
 	}
 
 	// collect all mountpoints
-	var mountpoints []string
-	for _, part := range partitioning.Partitions {
-		if part.Mountpoint != "" {
-			mountpoints = append(mountpoints, part.Mountpoint)
-		}
-		for _, lv := range part.LogicalVolumes {
-			if lv.Mountpoint != "" {
-				mountpoints = append(mountpoints, lv.Mountpoint)
-			}
-		}
-		for _, subvol := range part.Subvolumes {
-			mountpoints = append(mountpoints, subvol.Mountpoint)
-		}
+	mountpoints := partitioning.mountpoints()
+	for idx := range partitioning.Disks {
+		mountpoints = append(mountpoints, partitioning.Disks[idx].mountpoints()...)
 	}
 
 	var errs []error
@@ -619,3 +1073,30 @@ func CheckDiskMountpointsPolicy(partitioning *DiskCustomization, mountpointAllow
 
 	return nil
 }
+
+// MultiDisk returns true if the customizations describe more than one disk,
+// i.e. additional disks. Image types that export a single disk image cannot
+// build them.
+func (p *DiskCustomization) MultiDisk() bool {
+	return p != nil && len(p.Disks) > 0
+}
+
+// mountpoints returns the mountpoints of the partitions, logical volumes and
+// subvolumes on the disk (without its additional disks).
+func (p *DiskCustomization) mountpoints() []string {
+	var mountpoints []string
+	for _, part := range p.Partitions {
+		if part.Mountpoint != "" {
+			mountpoints = append(mountpoints, part.Mountpoint)
+		}
+		for _, lv := range part.LogicalVolumes {
+			if lv.Mountpoint != "" {
+				mountpoints = append(mountpoints, lv.Mountpoint)
+			}
+		}
+		for _, subvol := range part.Subvolumes {
+			mountpoints = append(mountpoints, subvol.Mountpoint)
+		}
+	}
+	return mountpoints
+}
@@ -970,6 +970,842 @@ func TestPartitioningValidation(t *testing.T) {
 			},
 			expectedMsg: `invalid partitioning customizations: "dos" partition table type only supports up to 4 partitions: got 6`,
 		},
+		"happy-encrypted": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type: "lvm",
+						Encryption: &blueprint.EncryptionCustomization{
+							Passphrase: "secret",
+							PBKDF:      &blueprint.PBKDFCustomization{Iterations: 4, Memory: 32, Parallelism: 1},
+							Clevis: &blueprint.ClevisCustomization{
+								TPM2: &blueprint.ClevisTPM2Customization{PCRIDs: "0,7"},
+								Tang: []blueprint.ClevisTangCustomization{
+									{URL: "https://tang.example.com"},
+								},
+								Threshold:        2,
+								RemovePassphrase: true,
+							},
+						},
+						VGCustomization: blueprint.VGCustomization{
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										FSType:     "xfs",
+										Mountpoint: "/",
+									},
+								},
+							},
+						},
+					},
+					{
+						Type:       "btrfs",
+						Encryption: &blueprint.EncryptionCustomization{Passphrase: "secret"},
+						BtrfsVolumeCustomization: blueprint.BtrfsVolumeCustomization{
+							Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+								{Name: "home", Mountpoint: "/home"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"unhappy-encrypted-boot": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Encryption: &blueprint.EncryptionCustomization{Passphrase: "secret"},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/boot",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\npartition with mountpoint \"/boot\" cannot be encrypted",
+		},
+		"unhappy-encryption": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Encryption: &blueprint.EncryptionCustomization{
+							PBKDF: &blueprint.PBKDFCustomization{Iterations: 1, Memory: 16, Parallelism: 8},
+							Clevis: &blueprint.ClevisCustomization{
+								TPM2: &blueprint.ClevisTPM2Customization{PCRIDs: "0,24"},
+								Tang: []blueprint.ClevisTangCustomization{
+									{URL: "tang.example.com"},
+								},
+								Threshold: 3,
+							},
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: `invalid partitioning customizations:
+invalid encryption customization: passphrase is required
+pbkdf iterations must be at least 4 (got 1)
+pbkdf memory must be between 32 and 4194304 KiB (got 16)
+pbkdf parallelism must be between 1 and 4 (got 8)
+clevis threshold 3 is larger than the number of pins (2)
+invalid tpm2 pcr_ids "0,24": "24" is not a PCR index (0-23)
+invalid tang url "tang.example.com": must be an http or https URL`,
+		},
+		"unhappy-clevis-nopins": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Encryption: &blueprint.EncryptionCustomization{
+							Passphrase: "secret",
+							Clevis:     &blueprint.ClevisCustomization{RemovePassphrase: true},
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\ninvalid encryption customization: clevis requires a tpm2 or tang pin",
+		},
+		"happy-raw": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type:    "raw",
+						MinSize: 2 * datasizes.GiB,
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartType:  "root-x86-64",
+							PartLabel: "root-b",
+						},
+					},
+					{
+						Type:    "none",
+						MinSize: 1 * datasizes.GiB,
+					},
+				},
+			},
+			expectedMsg: "",
+		},
+		"unhappy-raw-nosize": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type: "raw",
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\nraw partition requires a size (minsize)",
+		},
+		"unhappy-raw-filesystem": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type:    "raw",
+						MinSize: 1 * datasizes.GiB,
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\nfilesystem properties defined for raw partition (partition type \"raw\")",
+		},
+		"unhappy-raw-vg": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type:    "none",
+						MinSize: 1 * datasizes.GiB,
+						VGCustomization: blueprint.VGCustomization{
+							Name: "vg0",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\nLVM volume group defined for raw partition (partition type \"none\")",
+		},
+		"happy-layout": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Start:    4 * datasizes.MiB,
+							Position: 1,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Position: 2,
+							Grow:     true,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/var",
+						},
+					},
+				},
+			},
+			expectedMsg: "",
+		},
+		"unhappy-layout-unaligned-start": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Start: 4*datasizes.MiB + 512,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\npartition start 4194816 is not aligned to 1 MiB",
+		},
+		"unhappy-layout-duplicate-position": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Position: 2,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Position: 2,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/var",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\nduplicate partition position 2 in partitioning customizations",
+		},
+		"unhappy-layout-dos-position": {
+			partitioning: &blueprint.DiskCustomization{
+				Type: "dos",
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Position: 5,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\npartition position 5 is out of range (1-4)",
+		},
+		"unhappy-layout-multiple-grow": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Grow: true,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Grow: true,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/var",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\nonly one partition can grow, got 2",
+		},
+		"happy-growth": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						MinSize: 1 * datasizes.GiB,
+						GrowthCustomization: blueprint.GrowthCustomization{
+							GrowPercent: 40,
+							MaxSize:     20 * datasizes.GiB,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/var",
+						},
+					},
+					{
+						Type:    "lvm",
+						MinSize: 5 * datasizes.GiB,
+						GrowthCustomization: blueprint.GrowthCustomization{
+							GrowPercent: 60,
+						},
+						VGCustomization: blueprint.VGCustomization{
+							Name: "vg",
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									Name:    "rootlv",
+									MinSize: 2 * datasizes.GiB,
+									Grow:    true,
+									GrowthCustomization: blueprint.GrowthCustomization{
+										MaxSize: 10 * datasizes.GiB,
+									},
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										Mountpoint: "/",
+										FSType:     "xfs",
+									},
+								},
+								{
+									Name:    "homelv",
+									MinSize: 1 * datasizes.GiB,
+									GrowthCustomization: blueprint.GrowthCustomization{
+										GrowPercent: 50,
+									},
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										Mountpoint: "/home",
+										FSType:     "xfs",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "",
+		},
+		"unhappy-growth-percent-range": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						GrowthCustomization: blueprint.GrowthCustomization{
+							GrowPercent: 120,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/var",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\ngrow_percent 120 is out of range (1-100)\ngrow_percent of all partitions adds up to 120 (maximum 100)",
+		},
+		"unhappy-growth-percent-sum": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						GrowthCustomization: blueprint.GrowthCustomization{
+							GrowPercent: 60,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/var",
+						},
+					},
+					{
+						GrowthCustomization: blueprint.GrowthCustomization{
+							GrowPercent: 50,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/home",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\ngrow_percent of all partitions adds up to 110 (maximum 100)",
+		},
+		"unhappy-growth-grow-and-percent": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Grow: true,
+						},
+						GrowthCustomization: blueprint.GrowthCustomization{
+							GrowPercent: 50,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/var",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\ngrow and grow_percent cannot be combined",
+		},
+		"unhappy-growth-maxsize": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						MinSize: 2 * datasizes.GiB,
+						GrowthCustomization: blueprint.GrowthCustomization{
+							MaxSize: 1 * datasizes.GiB,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/var",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\nmaxsize 1073741824 is smaller than minsize 2147483648",
+		},
+		"unhappy-growth-lv-multiple-grow": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type: "lvm",
+						VGCustomization: blueprint.VGCustomization{
+							Name: "vg",
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									Grow: true,
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										Mountpoint: "/",
+										FSType:     "xfs",
+									},
+								},
+								{
+									Grow: true,
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										Mountpoint: "/home",
+										FSType:     "xfs",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\nonly one logical volume in volume group \"vg\" can grow",
+		},
+		"unhappy-growth-lv-percent-sum": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type: "lvm",
+						VGCustomization: blueprint.VGCustomization{
+							Name: "vg",
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									GrowthCustomization: blueprint.GrowthCustomization{
+										GrowPercent: 70,
+									},
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										Mountpoint: "/",
+										FSType:     "xfs",
+									},
+								},
+								{
+									GrowthCustomization: blueprint.GrowthCustomization{
+										GrowPercent: 70,
+									},
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										Mountpoint: "/home",
+										FSType:     "xfs",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\ngrow_percent of the logical volumes in volume group \"vg\" adds up to more than 100",
+		},
+		"unhappy-growth-lv-maxsize": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type: "lvm",
+						VGCustomization: blueprint.VGCustomization{
+							Name: "vg",
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									MinSize: 2 * datasizes.GiB,
+									GrowthCustomization: blueprint.GrowthCustomization{
+										MaxSize: 1 * datasizes.GiB,
+									},
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										Mountpoint: "/",
+										FSType:     "xfs",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\ninvalid logical volume customization: maxsize 1073741824 is smaller than minsize 2147483648",
+		},
+		"happy-gpt-entry": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartType:  "home",
+							PartLabel: "data",
+							PartAttrs: []string{"no-auto", "60"},
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "",
+		},
+		"happy-gpt-entry-guid": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartType: "0fc63daf-8483-4772-8e79-3d69d8477de4",
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "",
+		},
+		"happy-dos-entry": {
+			partitioning: &blueprint.DiskCustomization{
+				Type: "dos",
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartType: "83",
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "",
+		},
+		"unhappy-dos-part-type": {
+			partitioning: &blueprint.DiskCustomization{
+				Type: "dos",
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartType: "linux-generic",
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\ninvalid partition type \"linux-generic\" for dos partition table: must be a two-digit hex ID",
+		},
+		"unhappy-dos-part-label": {
+			partitioning: &blueprint.DiskCustomization{
+				Type: "dos",
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartLabel: "data",
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\npartition label \"data\" is not supported on dos partition tables",
+		},
+		"unhappy-dos-part-attrs": {
+			partitioning: &blueprint.DiskCustomization{
+				Type: "dos",
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartAttrs: []string{"read-only"},
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\npartition attributes are not supported on dos partition tables",
+		},
+		"unhappy-gpt-part-type": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartType: "Linux Data",
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\ninvalid partition type \"Linux Data\": must be a GUID or a partition type name",
+		},
+		"unhappy-gpt-part-label": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartLabel: "this-label-is-way-too-long-for-a-gpt-entry",
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\npartition label \"this-label-is-way-too-long-for-a-gpt-entry\" is longer than 36 characters",
+		},
+		"unhappy-gpt-part-attrs": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+							PartAttrs: []string{"hidden"},
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid partitioning customizations:\nunknown GPT partition attribute \"hidden\"",
+		},
+		"happy-additional-disks": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type: "lvm",
+						VGCustomization: blueprint.VGCustomization{
+							Name: "rootvg",
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										FSType:     "xfs",
+										Mountpoint: "/",
+									},
+								},
+							},
+						},
+					},
+				},
+				Disks: []blueprint.DiskCustomization{
+					{
+						Type: "dos",
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								Type: "lvm",
+								VGCustomization: blueprint.VGCustomization{
+									Name: "datavg",
+									LogicalVolumes: []blueprint.LVCustomization{
+										{
+											FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+												FSType:     "xfs",
+												Mountpoint: "/data",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+									FSType: "swap",
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "",
+		},
+		"unhappy-additional-disk-partition": {
+			partitioning: &blueprint.DiskCustomization{
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+									FSType:     "xfs",
+									Mountpoint: "data",
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid additional disk 1: invalid partitioning customizations:\nmountpoint \"data\" is not an absolute path",
+		},
+		"unhappy-additional-disk-dupe-mountpoint": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType:     "xfs",
+							Mountpoint: "/data",
+						},
+					},
+				},
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+									FSType:     "ext4",
+									Mountpoint: "/data",
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid additional disk 1: invalid partitioning customizations:\nduplicate mountpoint \"/data\" in partitioning customizations",
+		},
+		"unhappy-additional-disk-vg-span": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type: "lvm",
+						VGCustomization: blueprint.VGCustomization{
+							Name: "vg",
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										FSType:     "xfs",
+										Mountpoint: "/",
+									},
+								},
+							},
+						},
+					},
+				},
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								Type: "lvm",
+								VGCustomization: blueprint.VGCustomization{
+									Name: "vg",
+									LogicalVolumes: []blueprint.LVCustomization{
+										{
+											FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+												FSType:     "xfs",
+												Mountpoint: "/data",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid additional disk 1: LVM volume group \"vg\" cannot span disks: each disk needs its own volume group",
+		},
+		"unhappy-additional-disk-root": {
+			partitioning: &blueprint.DiskCustomization{
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								Type: "btrfs",
+								BtrfsVolumeCustomization: blueprint.BtrfsVolumeCustomization{
+									Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+										{
+											Name:       "root",
+											Mountpoint: "/",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid additional disk 1: mountpoint \"/\" must be on the first disk",
+		},
+		"unhappy-additional-disk-boot": {
+			partitioning: &blueprint.DiskCustomization{
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+									FSType:     "xfs",
+									Mountpoint: "/boot",
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: "invalid additional disk 1: mountpoint \"/boot\" must be on the first disk",
+		},
+		"unhappy-additional-disk-nested": {
+			partitioning: &blueprint.DiskCustomization{
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+									FSType:     "xfs",
+									Mountpoint: "/data",
+								},
+							},
+						},
+						Disks: []blueprint.DiskCustomization{{}},
+					},
+				},
+			},
+			expectedMsg: "invalid additional disk 1: additional disks cannot have additional disks",
+		},
+		"unhappy-additional-disk-empty": {
+			partitioning: &blueprint.DiskCustomization{
+				Disks: []blueprint.DiskCustomization{
+					{
+						Type: "gpt",
+					},
+				},
+			},
+			expectedMsg: "invalid additional disk 1: no partitions defined",
+		},
 	}
 
 	for name := range testCases {
@@ -985,6 +1821,43 @@ func TestPartitioningValidation(t *testing.T) {
 	}
 }
 
+func TestParseGPTAttribute(t *testing.T) {
+	testCases := map[string]struct {
+		attr        string
+		expected    uint
+		expectedErr string
+	}{
+		"required":             {attr: "required", expected: 0},
+		"legacy-bios-bootable": {attr: "legacy-bios-bootable", expected: 2},
+		"grow-fs":              {attr: "grow-fs", expected: 59},
+		"no-auto":              {attr: "no-auto", expected: 63},
+		"numeric":              {attr: "48", expected: 48},
+		"numeric-too-large":    {attr: "64", expectedErr: `unknown GPT partition attribute "64"`},
+		"unknown":              {attr: "hidden", expectedErr: `unknown GPT partition attribute "hidden"`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			bit, err := blueprint.ParseGPTAttribute(tc.attr)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, bit)
+		})
+	}
+}
+
+func TestPartitionEntryCustomizationGPTAttributeBits(t *testing.T) {
+	entry := blueprint.PartitionEntryCustomization{
+		PartAttrs: []string{"no-auto", "read-only", "60", "required"},
+	}
+	bits, err := entry.GPTAttributeBits()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{0, 60, 63}, bits)
+}
+
 func TestPartitioningLayoutConstraints(t *testing.T) {
 	type testCase struct {
 		partitioning *blueprint.DiskCustomization
@@ -1085,7 +1958,71 @@ func TestPartitioningLayoutConstraints(t *testing.T) {
 					},
 				},
 			},
-			expectedMsg: `multiple LVM volume groups are not yet supported`,
+			expectedMsg: `multiple LVM volume groups are not yet supported`,
+		},
+		"happy-lvm-per-disk": {
+			partitioning: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type: "lvm",
+						VGCustomization: blueprint.VGCustomization{
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{Mountpoint: "/"},
+								},
+							},
+						},
+					},
+				},
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								Type: "lvm",
+								VGCustomization: blueprint.VGCustomization{
+									LogicalVolumes: []blueprint.LVCustomization{
+										{
+											FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{Mountpoint: "/data"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"unhappy-btrfs+lvm-additional-disk": {
+			partitioning: &blueprint.DiskCustomization{
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								Type: "btrfs",
+								BtrfsVolumeCustomization: blueprint.BtrfsVolumeCustomization{
+									Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+										{
+											Name:       "data",
+											Mountpoint: "/data",
+										},
+									},
+								},
+							},
+							{
+								Type: "lvm",
+								VGCustomization: blueprint.VGCustomization{
+									LogicalVolumes: []blueprint.LVCustomization{
+										{
+											FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{Mountpoint: "/srv"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMsg: `additional disk 1: btrfs and lvm partitioning cannot be combined`,
 		},
 	}
 
@@ -1169,6 +2106,24 @@ path "/data/" must be canonical
 path "/etc" is not allowed`
 	err = blueprint.CheckDiskMountpointsPolicy(&disk, noEtc)
 	assert.EqualError(t, err, noEtcErr)
+
+	disk.Disks = []blueprint.DiskCustomization{
+		{
+			Partitions: []blueprint.PartitionCustomization{
+				{
+					FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+						Mountpoint: "/etc/data",
+					},
+				},
+			},
+		},
+	}
+	noEtcErr = `The following errors occurred while setting up custom mountpoints:
+path "/data/" must be canonical
+path "/etc" is not allowed
+path "/etc/data" is not allowed`
+	err = blueprint.CheckDiskMountpointsPolicy(&disk, noEtc)
+	assert.EqualError(t, err, noEtcErr)
 }
 
 func TestPartitionCustomizationUnmarshalJSON(t *testing.T) {
@@ -1455,6 +2410,276 @@ func TestPartitionCustomizationUnmarshalJSON(t *testing.T) {
 			}`,
 			errorMsg: `JSON unmarshal: error decoding partition with type "plain": json: unknown field "subvolumes"`,
 		},
+		"lvm-encrypted": {
+			input: `{
+				"type": "lvm",
+				"minsize": "10 GiB",
+				"encryption": {
+					"passphrase": "secret",
+					"cipher": "aes-xts-plain64",
+					"pbkdf": {"memory": 32},
+					"clevis": {
+						"tang": [{"url": "http://tang.example.com", "thumbprint": "abc"}],
+						"remove_passphrase": true
+					}
+				},
+				"logical_volumes": [
+					{
+						"minsize": "2 GiB",
+						"mountpoint": "/",
+						"fs_type": "xfs"
+					}
+				]
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "lvm",
+				MinSize: 10 * datasizes.GiB,
+				Encryption: &blueprint.EncryptionCustomization{
+					Passphrase: "secret",
+					Cipher:     "aes-xts-plain64",
+					PBKDF:      &blueprint.PBKDFCustomization{Memory: 32},
+					Clevis: &blueprint.ClevisCustomization{
+						Tang:             []blueprint.ClevisTangCustomization{{URL: "http://tang.example.com", Thumbprint: "abc"}},
+						RemovePassphrase: true,
+					},
+				},
+				VGCustomization: blueprint.VGCustomization{
+					LogicalVolumes: []blueprint.LVCustomization{
+						{
+							MinSize: 2 * datasizes.GiB,
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/",
+								FSType:     "xfs",
+							},
+						},
+					},
+				},
+			},
+		},
+		"encryption-unknown-field": {
+			input: `{
+				"minsize": "1 GiB",
+				"mountpoint": "/data",
+				"encryption": {"password": "secret"}
+			}`,
+			errorMsg: `JSON unmarshal: error decoding partition with type "plain": json: unknown field "password"`,
+		},
+		"plain-gpt-entry": {
+			input: `{
+				"type": "plain",
+				"minsize": "1 GiB",
+				"mountpoint": "/home",
+				"fs_type": "xfs",
+				"part_type": "home",
+				"part_label": "home",
+				"part_attrs": ["no-auto", "60"]
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "plain",
+				MinSize: 1 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartType:  "home",
+					PartLabel: "home",
+					PartAttrs: []string{"no-auto", "60"},
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/home",
+					FSType:     "xfs",
+				},
+			},
+		},
+		"raw": {
+			input: `{
+				"type": "raw",
+				"minsize": "2 GiB",
+				"part_type": "root-x86-64",
+				"part_label": "root-b"
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "raw",
+				MinSize: 2 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartType:  "root-x86-64",
+					PartLabel: "root-b",
+				},
+			},
+		},
+		"none": {
+			input: `{
+				"type": "none",
+				"minsize": "1 GiB"
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "none",
+				MinSize: 1 * datasizes.GiB,
+			},
+		},
+		"raw-with-mountpoint": {
+			input: `{
+				"type": "raw",
+				"minsize": "1 GiB",
+				"mountpoint": "/data"
+			}`,
+			errorMsg: `JSON unmarshal: error decoding partition with type "raw": json: unknown field "mountpoint"`,
+		},
+		"plain-layout": {
+			input: `{
+				"minsize": "1 GiB",
+				"start": "4 MiB",
+				"position": 2,
+				"grow": true,
+				"mountpoint": "/var",
+				"fs_type": "xfs"
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "plain",
+				MinSize: 1 * datasizes.GiB,
+				PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+					Start:    4 * datasizes.MiB,
+					Position: 2,
+					Grow:     true,
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/var",
+					FSType:     "xfs",
+				},
+			},
+		},
+		"raw-layout-int-start": {
+			input: `{
+				"type": "raw",
+				"minsize": "8 MiB",
+				"start": 1048576
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "raw",
+				MinSize: 8 * datasizes.MiB,
+				PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+					Start: 1 * datasizes.MiB,
+				},
+			},
+		},
+		"plain-growth": {
+			input: `{
+				"minsize": "1 GiB",
+				"grow_percent": 40,
+				"maxsize": "20 GiB",
+				"mountpoint": "/var",
+				"fs_type": "xfs"
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "plain",
+				MinSize: 1 * datasizes.GiB,
+				GrowthCustomization: blueprint.GrowthCustomization{
+					GrowPercent: 40,
+					MaxSize:     20 * datasizes.GiB,
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/var",
+					FSType:     "xfs",
+				},
+			},
+		},
+		"lvm-lv-growth": {
+			input: `{
+				"type": "lvm",
+				"minsize": "10 GiB",
+				"logical_volumes": [
+					{
+						"minsize": "2 GiB",
+						"grow": true,
+						"maxsize": 21474836480,
+						"mountpoint": "/",
+						"fs_type": "xfs"
+					},
+					{
+						"minsize": "1 GiB",
+						"grow_percent": 25,
+						"mountpoint": "/home",
+						"fs_type": "xfs"
+					}
+				]
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "lvm",
+				MinSize: 10 * datasizes.GiB,
+				VGCustomization: blueprint.VGCustomization{
+					LogicalVolumes: []blueprint.LVCustomization{
+						{
+							MinSize: 2 * datasizes.GiB,
+							Grow:    true,
+							GrowthCustomization: blueprint.GrowthCustomization{
+								MaxSize: 20 * datasizes.GiB,
+							},
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/",
+								FSType:     "xfs",
+							},
+						},
+						{
+							MinSize: 1 * datasizes.GiB,
+							GrowthCustomization: blueprint.GrowthCustomization{
+								GrowPercent: 25,
+							},
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/home",
+								FSType:     "xfs",
+							},
+						},
+					},
+				},
+			},
+		},
+		"growth-bad-maxsize": {
+			input: `{
+				"minsize": "1 GiB",
+				"maxsize": "lots",
+				"mountpoint": "/var",
+				"fs_type": "xfs"
+			}`,
+			errorMsg: `JSON unmarshal: error decoding maxsize for partition: the size string doesn't contain any number: lots`,
+		},
+		"layout-bad-start": {
+			input: `{
+				"minsize": "1 GiB",
+				"start": "four",
+				"mountpoint": "/var",
+				"fs_type": "xfs"
+			}`,
+			errorMsg: `JSON unmarshal: error decoding start for partition: the size string doesn't contain any number: four`,
+		},
+		"lvm-gpt-entry": {
+			input: `{
+				"type": "lvm",
+				"minsize": "10 GiB",
+				"part_label": "system",
+				"logical_volumes": [
+					{
+						"minsize": "2 GiB",
+						"mountpoint": "/",
+						"fs_type": "xfs"
+					}
+				]
+			}`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "lvm",
+				MinSize: 10 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartLabel: "system",
+				},
+				VGCustomization: blueprint.VGCustomization{
+					LogicalVolumes: []blueprint.LVCustomization{
+						{
+							MinSize: 2 * datasizes.GiB,
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/",
+								FSType:     "xfs",
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for name := range testCases {
@@ -1749,6 +2974,108 @@ func TestPartitionCustomizationUnmarshalTOML(t *testing.T) {
 					`,
 			errorMsg: `toml: line 0: TOML unmarshal: error decoding partition with type "plain": json: unknown field "subvolumes"`,
 		},
+		"plain-encrypted": {
+			input: `type = "plain"
+					minsize = "1 GiB"
+					mountpoint = "/"
+					fs_type = "xfs"
+
+					[encryption]
+					passphrase = "secret"
+
+					[encryption.clevis.tpm2]
+					pcr_ids = "7"
+					`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "plain",
+				MinSize: 1 * datasizes.GiB,
+				Encryption: &blueprint.EncryptionCustomization{
+					Passphrase: "secret",
+					Clevis: &blueprint.ClevisCustomization{
+						TPM2: &blueprint.ClevisTPM2Customization{PCRIDs: "7"},
+					},
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/",
+					FSType:     "xfs",
+				},
+			},
+		},
+		"raw": {
+			input: `type = "raw"
+					minsize = "2 GiB"
+					part_label = "root-b"
+					`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "raw",
+				MinSize: 2 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartLabel: "root-b",
+				},
+			},
+		},
+		"raw-with-fs_type": {
+			input: `type = "raw"
+					minsize = "2 GiB"
+					fs_type = "xfs"
+					`,
+			errorMsg: `toml: line 0: TOML unmarshal: error decoding partition with type "raw": json: unknown field "fs_type"`,
+		},
+		"lvm-layout": {
+			input: `type = "lvm"
+					minsize = "10 GiB"
+					start = "100 MiB"
+					position = 3
+					grow = true
+					`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "lvm",
+				MinSize: 10 * datasizes.GiB,
+				PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+					Start:    100 * datasizes.MiB,
+					Position: 3,
+					Grow:     true,
+				},
+			},
+		},
+		"raw-growth": {
+			input: `type = "raw"
+					minsize = "1 GiB"
+					grow_percent = 10
+					maxsize = "4 GiB"
+					`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "raw",
+				MinSize: 1 * datasizes.GiB,
+				GrowthCustomization: blueprint.GrowthCustomization{
+					GrowPercent: 10,
+					MaxSize:     4 * datasizes.GiB,
+				},
+			},
+		},
+		"plain-gpt-entry": {
+			input: `type = "plain"
+					minsize = "1 GiB"
+					mountpoint = "/home"
+					fs_type = "xfs"
+					part_type = "933ac7e1-2eb4-4f13-b844-0e14e2aef915"
+					part_label = "home"
+					part_attrs = ["read-only"]
+					`,
+			expected: &blueprint.PartitionCustomization{
+				Type:    "plain",
+				MinSize: 1 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartType:  "933ac7e1-2eb4-4f13-b844-0e14e2aef915",
+					PartLabel: "home",
+					PartAttrs: []string{"read-only"},
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/home",
+					FSType:     "xfs",
+				},
+			},
+		},
 	}
 
 	for name := range testCases {
@@ -1812,6 +3139,50 @@ func TestDiskCustomizationUnmarshalJSON(t *testing.T) {
 				Type: "gpt",
 			},
 		},
+		"disks": {
+			inputJSON: `{
+				"disks": [
+					{
+						"type": "dos",
+						"minsize": "10 GiB",
+						"partitions": [
+							{
+								"minsize": "1 GiB",
+								"mountpoint": "/data",
+								"fs_type": "ext4"
+							}
+						]
+					}
+				]
+			}`,
+			inputTOML: `
+			[[disks]]
+			type = "dos"
+			minsize = "10 GiB"
+			[[disks.partitions]]
+			minsize = "1 GiB"
+			mountpoint = "/data"
+			fs_type = "ext4"
+			`,
+			expected: &blueprint.DiskCustomization{
+				Disks: []blueprint.DiskCustomization{
+					{
+						Type:    "dos",
+						MinSize: 10 * datasizes.GiB,
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								Type:    "plain",
+								MinSize: 1 * datasizes.GiB,
+								FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+									Mountpoint: "/data",
+									FSType:     "ext4",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for name := range testCases {
@@ -1828,3 +3199,20 @@ func TestDiskCustomizationUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestDiskCustomizationMultiDisk(t *testing.T) {
+	var nilDisk *blueprint.DiskCustomization
+	assert.False(t, nilDisk.MultiDisk())
+
+	single := &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{Type: "plain", FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{Mountpoint: "/"}},
+		},
+	}
+	assert.False(t, single.MultiDisk())
+
+	extra := &blueprint.DiskCustomization{
+		Disks: []blueprint.DiskCustomization{*single},
+	}
+	assert.True(t, extra.MultiDisk())
+}
@@ -0,0 +1,153 @@
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// EncryptionCustomization defines the LUKS2 encryption of a partition. The
+// payload of the partition (filesystem, LVM volume group, or btrfs volume) is
+// created inside the encrypted volume.
+type EncryptionCustomization struct {
+	// Passphrase for formatting and unlocking the volume (required). When
+	// the volume is bound with Clevis, the passphrase can be removed at
+	// the end of the build, see [ClevisCustomization].
+	Passphrase string `json:"passphrase" toml:"passphrase"`
+
+	// Label of the LUKS2 header (optional).
+	Label string `json:"label,omitempty" toml:"label,omitempty"`
+
+	// Cipher specification, e.g. "aes-xts-plain64" (optional, defaults to
+	// the cryptsetup default).
+	Cipher string `json:"cipher,omitempty" toml:"cipher,omitempty"`
+
+	// Parameters of the argon2id password-based key derivation function
+	// (optional, unset values use the defaults).
+	PBKDF *PBKDFCustomization `json:"pbkdf,omitempty" toml:"pbkdf,omitempty"`
+
+	// Automatic unlocking with Clevis (optional).
+	Clevis *ClevisCustomization `json:"clevis,omitempty" toml:"clevis,omitempty"`
+}
+
+// PBKDFCustomization defines the parameters of the argon2id key derivation
+// function. The defaults are 4 iterations, 1 GiB of memory and 4 threads,
+// capped to half of the memory and the CPUs of the build host. Volumes that
+// remove the passphrase for Clevis default to 32 KiB of memory and 1 thread
+// instead, like the encrypted partition tables of the image definitions.
+type PBKDFCustomization struct {
+	// Number of iterations (at least 4).
+	Iterations uint `json:"iterations,omitempty" toml:"iterations,omitempty"`
+
+	// Amount of memory in KiB (between 32 and 4194304).
+	Memory uint `json:"memory,omitempty" toml:"memory,omitempty"`
+
+	// Number of threads (between 1 and 4).
+	Parallelism uint `json:"parallelism,omitempty" toml:"parallelism,omitempty"`
+}
+
+// ClevisCustomization binds an encrypted volume to one or more Clevis pins.
+// When more than one pin is defined, they are combined with Shamir's Secret
+// Sharing (the "sss" pin) and Threshold of them are needed for unlocking.
+type ClevisCustomization struct {
+	// Bind to the TPM2 chip of the machine.
+	TPM2 *ClevisTPM2Customization `json:"tpm2,omitempty" toml:"tpm2,omitempty"`
+
+	// Bind to Tang servers. The servers must be reachable during the build.
+	Tang []ClevisTangCustomization `json:"tang,omitempty" toml:"tang,omitempty"`
+
+	// Number of pins required for unlocking when multiple pins are defined
+	// (optional, defaults to 1).
+	Threshold uint `json:"threshold,omitempty" toml:"threshold,omitempty"`
+
+	// Remove the passphrase at the end of the build so that the volume can
+	// only be unlocked with Clevis.
+	RemovePassphrase bool `json:"remove_passphrase,omitempty" toml:"remove_passphrase,omitempty"`
+}
+
+// ClevisTPM2Customization configures the Clevis "tpm2" pin.
+type ClevisTPM2Customization struct {
+	// PCR bank to use, e.g. "sha256" (optional).
+	PCRBank string `json:"pcr_bank,omitempty" toml:"pcr_bank,omitempty"`
+
+	// Comma-separated list of the PCRs the key is sealed against, e.g.
+	// "0,7" (optional).
+	PCRIDs string `json:"pcr_ids,omitempty" toml:"pcr_ids,omitempty"`
+}
+
+// ClevisTangCustomization configures a Clevis "tang" pin.
+type ClevisTangCustomization struct {
+	// URL of the Tang server (required).
+	URL string `json:"url" toml:"url"`
+
+	// Thumbprint of the trusted signing key of the server (optional).
+	Thumbprint string `json:"thumbprint,omitempty" toml:"thumbprint,omitempty"`
+}
+
+// Pins returns the number of Clevis pins defined.
+func (c *ClevisCustomization) Pins() uint {
+	if c == nil {
+		return 0
+	}
+	pins := uint(len(c.Tang))
+	if c.TPM2 != nil {
+		pins++
+	}
+	return pins
+}
+
+// Validate checks the encryption customization. The PBKDF limits are the
+// ones enforced by the osbuild luks2.format stage.
+func (e *EncryptionCustomization) Validate() error {
+	if e == nil {
+		return nil
+	}
+
+	var errs []error
+	if e.Passphrase == "" {
+		errs = append(errs, fmt.Errorf("passphrase is required"))
+	}
+
+	if pbkdf := e.PBKDF; pbkdf != nil {
+		if pbkdf.Iterations != 0 && pbkdf.Iterations < 4 {
+			errs = append(errs, fmt.Errorf("pbkdf iterations must be at least 4 (got %d)", pbkdf.Iterations))
+		}
+		if pbkdf.Memory != 0 && (pbkdf.Memory < 32 || pbkdf.Memory > 4194304) {
+			errs = append(errs, fmt.Errorf("pbkdf memory must be between 32 and 4194304 KiB (got %d)", pbkdf.Memory))
+		}
+		if pbkdf.Parallelism > 4 {
+			errs = append(errs, fmt.Errorf("pbkdf parallelism must be between 1 and 4 (got %d)", pbkdf.Parallelism))
+		}
+	}
+
+	if clevis := e.Clevis; clevis != nil {
+		pins := clevis.Pins()
+		if pins == 0 {
+			errs = append(errs, fmt.Errorf("clevis requires a tpm2 or tang pin"))
+		}
+		if clevis.Threshold > pins {
+			errs = append(errs, fmt.Errorf("clevis threshold %d is larger than the number of pins (%d)", clevis.Threshold, pins))
+		}
+		if clevis.TPM2 != nil && clevis.TPM2.PCRIDs != "" {
+			for _, id := range strings.Split(clevis.TPM2.PCRIDs, ",") {
+				if n, err := strconv.ParseUint(id, 10, 8); err != nil || n > 23 {
+					errs = append(errs, fmt.Errorf("invalid tpm2 pcr_ids %q: %q is not a PCR index (0-23)", clevis.TPM2.PCRIDs, id))
+					break
+				}
+			}
+		}
+		for _, tang := range clevis.Tang {
+			u, err := url.Parse(tang.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("invalid tang url %q: must be an http or https URL", tang.URL))
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid encryption customization: %w", err)
+	}
+	return nil
+}
@@ -10,8 +10,8 @@ import (
 )
 
 type FilesystemCustomization struct {
-	Mountpoint string
-	MinSize    uint64
+	Mountpoint string `json:"mountpoint,omitempty" toml:"mountpoint,omitempty"`
+	MinSize    uint64 `json:"minsize,omitempty" toml:"minsize,omitempty"`
 }
 
 type filesystemCustomizationMarshaling struct {
@@ -0,0 +1,344 @@
+package blueprint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/pathpolicy"
+	"github.com/osbuild/images/pkg/policies"
+)
+
+// Severity of a lint Diagnostic
+type Severity string
+
+const (
+	// SeverityError marks problems that make the image build fail
+	SeverityError Severity = "error"
+	// SeverityWarning marks problems that do not stop the build but
+	// likely lead to unexpected results
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single finding of Lint
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	// Path is the location of the problem in the blueprint using
+	// the TOML/JSON field names, e.g.
+	// "customizations.filesystem[0].minsize". It is empty for
+	// problems that cannot be attributed to a single field.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+	// Fix is a suggestion on how to resolve the problem
+	Fix string `json:"fix,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	b.WriteString(string(d.Severity))
+	if d.Path != "" {
+		fmt.Fprintf(&b, ": %s", d.Path)
+	}
+	fmt.Fprintf(&b, ": %s", d.Message)
+	if d.Fix != "" {
+		fmt.Fprintf(&b, " (%s)", d.Fix)
+	}
+	return b.String()
+}
+
+// ImageTypeChecker provides the image type specific checks for Lint.
+// distro.NewBlueprintChecker() returns one for a distro.ImageType.
+type ImageTypeChecker interface {
+	// Name of the image type
+	Name() string
+
+	// CheckBlueprint runs the image type specific validation of
+	// the blueprint and returns its warnings.
+	CheckBlueprint(bp *Blueprint) (warnings []string, err error)
+
+	// RequiredDirectorySizes returns the minimum sizes of the
+	// directories of the image type, filesystems that are smaller
+	// are grown.
+	RequiredDirectorySizes() map[string]uint64
+}
+
+// Lint runs all blueprint checks at once and returns the problems
+// found as structured diagnostics. Unlike the validation during
+// manifest generation it does not stop at the first problem. If it is
+// nil only the checks that do not depend on the image type are run.
+func Lint(bp *Blueprint, it ImageTypeChecker) []Diagnostic {
+	l := &linter{}
+	if bp == nil {
+		bp = &Blueprint{}
+	}
+
+	l.lintPackages(bp)
+	if c := bp.Customizations; c != nil {
+		var requiredSizes map[string]uint64
+		if it != nil {
+			requiredSizes = it.RequiredDirectorySizes()
+		}
+		l.lintFilesystems(c, requiredSizes)
+		l.lintDisk(c, requiredSizes)
+		l.lintFSNodes(c)
+		l.lintUsers(c)
+		if _, err := c.GetRepositories(); err != nil {
+			l.add(SeverityError, "customizations.repositories", err.Error(), "")
+		}
+	}
+
+	if it != nil {
+		warnings, err := it.CheckBlueprint(bp)
+		for _, w := range warnings {
+			l.addUnique(SeverityWarning, "", strings.TrimSpace(w), "")
+		}
+		if err != nil {
+			l.addUnique(SeverityError, "", err.Error(), fmt.Sprintf("adjust the blueprint to the requirements of the %q image type", it.Name()))
+		}
+	}
+
+	return l.diags
+}
+
+type linter struct {
+	diags []Diagnostic
+}
+
+func (l *linter) add(sev Severity, path, msg, fix string) {
+	l.diags = append(l.diags, Diagnostic{Severity: sev, Path: path, Message: msg, Fix: fix})
+}
+
+// addUnique adds the diagnostic unless the problem was reported already,
+// the image type checks repeat some of the generic checks
+func (l *linter) addUnique(sev Severity, path, msg, fix string) {
+	if l.reported(msg) {
+		return
+	}
+	l.add(sev, path, msg, fix)
+}
+
+func (l *linter) hasMessage(msg string) bool {
+	for _, d := range l.diags {
+		if d.Message == msg {
+			return true
+		}
+	}
+	return false
+}
+
+// reported returns true if msg, or every problem listed in it, was
+// reported already. The image type errors list the problems one per line
+// below a header line that ends in a colon, e.g. "The following errors
+// occurred while setting up custom mountpoints:".
+func (l *linter) reported(msg string) bool {
+	if l.hasMessage(msg) {
+		return true
+	}
+	var problems int
+	for _, line := range strings.Split(msg, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasSuffix(line, ":") {
+			continue
+		}
+		if !l.hasMessage(line) {
+			return false
+		}
+		problems++
+	}
+	return problems > 0
+}
+
+func (l *linter) lintPackages(bp *Blueprint) {
+	for _, field := range []string{"packages", "modules"} {
+		pkgs := bp.Packages
+		if field == "modules" {
+			pkgs = bp.Modules
+		}
+		seen := make(map[string]bool)
+		for idx, pkg := range pkgs {
+			path := fmt.Sprintf("%s[%d].name", field, idx)
+			if pkg.Name == "" {
+				l.add(SeverityError, path, "package name is empty", "set the name or remove the entry")
+				continue
+			}
+			if seen[pkg.Name] {
+				l.add(SeverityWarning, path, fmt.Sprintf("%q is listed more than once", pkg.Name), "remove the duplicate entry")
+			}
+			seen[pkg.Name] = true
+			if err := pkg.validate(); err != nil {
+				l.add(SeverityError, fmt.Sprintf("%s[%d].version", field, idx), err.Error(), "use a version like \"2.4.*\" or \">= 2.4\"")
+			}
+		}
+	}
+
+	included := make(map[string]bool)
+	for _, pkgs := range [][]Package{bp.Packages, bp.Modules} {
+		for _, pkg := range pkgs {
+			included[pkg.Name] = true
+		}
+	}
+	for idx, exclude := range bp.Exclude {
+		path := fmt.Sprintf("exclude[%d]", idx)
+		if exclude == "" {
+			l.add(SeverityError, path, "excluded package name is empty", "set the name or remove the entry")
+		} else if included[exclude] {
+			l.add(SeverityError, path, fmt.Sprintf("%q is both included and excluded", exclude), "remove it from the packages or from the exclusions")
+		}
+	}
+}
+
+func (l *linter) lintFilesystems(c *Customizations, requiredSizes map[string]uint64) {
+	seen := make(map[string]bool)
+	for idx, fs := range c.Filesystem {
+		path := fmt.Sprintf("customizations.filesystem[%d]", idx)
+		if seen[fs.Mountpoint] {
+			l.add(SeverityError, path+".mountpoint", fmt.Sprintf("duplicate mountpoint %q", fs.Mountpoint), "remove one of the entries")
+		}
+		seen[fs.Mountpoint] = true
+
+		if err := policies.MountpointPolicies.Check(fs.Mountpoint); err != nil {
+			l.add(SeverityError, path+".mountpoint", err.Error(), "use a mountpoint that is allowed for custom filesystems")
+		}
+		l.lintMinSize(path+".minsize", fs.Mountpoint, fs.MinSize, requiredSizes)
+	}
+
+	if len(c.Filesystem) > 0 && c.Disk != nil {
+		l.add(SeverityError, "customizations.disk", "partitioning customizations cannot be used with custom filesystems (mountpoints)", "use either customizations.filesystem or customizations.disk")
+	}
+}
+
+func (l *linter) lintMinSize(path, mountpoint string, size uint64, requiredSizes map[string]uint64) {
+	required, ok := requiredSizes[mountpoint]
+	if !ok || size == 0 || size >= required {
+		return
+	}
+	l.add(SeverityWarning, path,
+		fmt.Sprintf("size of %q is smaller than the minimum of %s and will be increased", mountpoint, datasizes.Format(required)),
+		fmt.Sprintf("set minsize to at least %s", datasizes.Format(required)))
+}
+
+func (l *linter) lintDisk(c *Customizations, requiredSizes map[string]uint64) {
+	disk := c.Disk
+	if disk == nil {
+		return
+	}
+	if err := disk.Validate(); err != nil {
+		l.add(SeverityError, "customizations.disk", err.Error(), "")
+	}
+	if err := disk.ValidateLayoutConstraints(); err != nil {
+		l.add(SeverityError, "customizations.disk.partitions", err.Error(), "use at most one LVM volume group or btrfs volume")
+	}
+
+	l.lintDiskPartitions("customizations.disk", disk.Partitions, requiredSizes)
+	for idx := range disk.Disks {
+		l.lintDiskPartitions(fmt.Sprintf("customizations.disk.disks[%d]", idx), disk.Disks[idx].Partitions, requiredSizes)
+	}
+}
+
+func (l *linter) lintDiskPartitions(diskPath string, partitions []PartitionCustomization, requiredSizes map[string]uint64) {
+	for idx, part := range partitions {
+		path := fmt.Sprintf("%s.partitions[%d]", diskPath, idx)
+		l.lintMountpointPolicy(path+".mountpoint", part.Mountpoint)
+		l.lintMinSize(path+".minsize", part.Mountpoint, part.MinSize, requiredSizes)
+		for lvIdx, lv := range part.LogicalVolumes {
+			lvPath := fmt.Sprintf("%s.logical_volumes[%d]", path, lvIdx)
+			l.lintMountpointPolicy(lvPath+".mountpoint", lv.Mountpoint)
+			l.lintMinSize(lvPath+".minsize", lv.Mountpoint, lv.MinSize, requiredSizes)
+		}
+		for svIdx, sv := range part.Subvolumes {
+			l.lintMountpointPolicy(fmt.Sprintf("%s.subvolumes[%d].mountpoint", path, svIdx), sv.Mountpoint)
+		}
+	}
+}
+
+func (l *linter) lintMountpointPolicy(path, mountpoint string) {
+	if mountpoint == "" {
+		return
+	}
+	if err := policies.MountpointPolicies.Check(mountpoint); err != nil {
+		l.add(SeverityError, path, err.Error(), "use a mountpoint that is allowed for custom filesystems")
+	}
+}
+
+func (l *linter) lintFSNodes(c *Customizations) {
+	if err := ValidateDirFileCustomizations(c.Directories, c.Files); err != nil {
+		l.add(SeverityError, "customizations.directories", err.Error(), "")
+	}
+	for idx, dir := range c.Directories {
+		l.lintPathPolicy(fmt.Sprintf("customizations.directories[%d].path", idx), dir.Path, policies.CustomDirectoriesPolicies)
+	}
+	for idx, file := range c.Files {
+		l.lintPathPolicy(fmt.Sprintf("customizations.files[%d].path", idx), file.Path, policies.CustomFilesPolicies)
+	}
+}
+
+func (l *linter) lintPathPolicy(path, fsPath string, policy *pathpolicy.PathPolicies) {
+	if err := policy.Check(fsPath); err != nil {
+		l.add(SeverityError, path, err.Error(), "choose a path that is allowed by the policy")
+	}
+}
+
+func (l *linter) lintUsers(c *Customizations) {
+	var ksUsers, ksGroups map[string]bool
+	if c.Installer != nil && c.Installer.Kickstart != nil {
+		ksUsers, ksGroups = kickstartUsersAndGroups(c.Installer.Kickstart.Contents)
+	}
+
+	seen := make(map[string]bool)
+	for idx, user := range c.User {
+		path := fmt.Sprintf("customizations.user[%d].name", idx)
+		if seen[user.Name] {
+			l.add(SeverityError, path, fmt.Sprintf("user %q is defined more than once", user.Name), "merge the user definitions")
+		}
+		seen[user.Name] = true
+		if ksUsers[user.Name] {
+			l.add(SeverityError, path,
+				fmt.Sprintf("user %q is defined both in the blueprint and in the installer kickstart", user.Name),
+				"remove the user from either customizations.user or customizations.installer.kickstart")
+		}
+	}
+
+	seen = make(map[string]bool)
+	for idx, group := range c.Group {
+		path := fmt.Sprintf("customizations.group[%d].name", idx)
+		if seen[group.Name] {
+			l.add(SeverityError, path, fmt.Sprintf("group %q is defined more than once", group.Name), "merge the group definitions")
+		}
+		seen[group.Name] = true
+		if ksGroups[group.Name] {
+			l.add(SeverityError, path,
+				fmt.Sprintf("group %q is defined both in the blueprint and in the installer kickstart", group.Name),
+				"remove the group from either customizations.group or customizations.installer.kickstart")
+		}
+	}
+}
+
+// kickstartUsersAndGroups returns the names of the users and groups
+// created by the "user" and "group" commands of the kickstart
+func kickstartUsersAndGroups(contents string) (users, groups map[string]bool) {
+	users = make(map[string]bool)
+	groups = make(map[string]bool)
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var target map[string]bool
+		switch fields[0] {
+		case "user":
+			target = users
+		case "group":
+			target = groups
+		default:
+			continue
+		}
+		for i, f := range fields[1:] {
+			if name, ok := strings.CutPrefix(f, "--name="); ok {
+				target[name] = true
+			} else if f == "--name" && i+2 < len(fields) {
+				target[fields[i+2]] = true
+			}
+		}
+	}
+	return users, groups
+}
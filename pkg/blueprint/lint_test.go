@@ -0,0 +1,299 @@
+package blueprint
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/datasizes"
+)
+
+type fakeChecker struct {
+	warnings []string
+	err      error
+}
+
+func (c *fakeChecker) Name() string {
+	return "fake-image"
+}
+
+func (c *fakeChecker) CheckBlueprint(bp *Blueprint) ([]string, error) {
+	return c.warnings, c.err
+}
+
+func (c *fakeChecker) RequiredDirectorySizes() map[string]uint64 {
+	return map[string]uint64{
+		"/":    1 * datasizes.GiB,
+		"/usr": 2 * datasizes.GiB,
+	}
+}
+
+func TestLintClean(t *testing.T) {
+	bp := &Blueprint{
+		Packages: []Package{{Name: "tmux"}},
+		Customizations: &Customizations{
+			Filesystem: []FilesystemCustomization{
+				{Mountpoint: "/", MinSize: 5 * datasizes.GiB},
+			},
+			User: []UserCustomization{{Name: "alice"}},
+		},
+	}
+	assert.Empty(t, Lint(bp, &fakeChecker{}))
+	assert.Empty(t, Lint(nil, nil))
+}
+
+func TestLintDiagnostics(t *testing.T) {
+	bp := &Blueprint{
+		Packages: []Package{{Name: "tmux"}, {Name: ""}, {Name: "tmux"}},
+		Customizations: &Customizations{
+			Filesystem: []FilesystemCustomization{
+				{Mountpoint: "/", MinSize: 100 * datasizes.MiB},
+				{Mountpoint: "/etc", MinSize: 1 * datasizes.GiB},
+				{Mountpoint: "/", MinSize: 2 * datasizes.GiB},
+			},
+			Disk: &DiskCustomization{},
+			User: []UserCustomization{{Name: "alice"}, {Name: "bob"}},
+			Group: []GroupCustomization{
+				{Name: "wheel2"},
+			},
+			Installer: &InstallerCustomization{
+				Kickstart: &Kickstart{
+					Contents: "lang en_US.UTF-8\nuser --name=alice --groups=wheel\ngroup --name wheel2\n",
+				},
+			},
+			Directories: []DirectoryCustomization{
+				{Path: "/etc/foo", Mode: "0755"},
+				{Path: "/boot/foo"},
+			},
+		},
+	}
+
+	checker := &fakeChecker{
+		warnings: []string{"Please note that user customizations are deprecated\n"},
+		err:      errors.New("The following errors occurred while setting up custom mountpoints:\npath \"/etc\" is not allowed"),
+	}
+	assert.Equal(t, []Diagnostic{
+		{
+			Severity: SeverityError,
+			Path:     "packages[1].name",
+			Message:  "package name is empty",
+			Fix:      "set the name or remove the entry",
+		},
+		{
+			Severity: SeverityWarning,
+			Path:     "packages[2].name",
+			Message:  `"tmux" is listed more than once`,
+			Fix:      "remove the duplicate entry",
+		},
+		{
+			Severity: SeverityWarning,
+			Path:     "customizations.filesystem[0].minsize",
+			Message:  `size of "/" is smaller than the minimum of 1 GiB and will be increased`,
+			Fix:      "set minsize to at least 1 GiB",
+		},
+		{
+			Severity: SeverityError,
+			Path:     "customizations.filesystem[1].mountpoint",
+			Message:  `path "/etc" is not allowed`,
+			Fix:      "use a mountpoint that is allowed for custom filesystems",
+		},
+		{
+			Severity: SeverityError,
+			Path:     "customizations.filesystem[2].mountpoint",
+			Message:  `duplicate mountpoint "/"`,
+			Fix:      "remove one of the entries",
+		},
+		{
+			Severity: SeverityError,
+			Path:     "customizations.disk",
+			Message:  "partitioning customizations cannot be used with custom filesystems (mountpoints)",
+			Fix:      "use either customizations.filesystem or customizations.disk",
+		},
+		{
+			Severity: SeverityError,
+			Path:     "customizations.directories[1].path",
+			Message:  `path "/boot/foo" is not allowed`,
+			Fix:      "choose a path that is allowed by the policy",
+		},
+		{
+			Severity: SeverityError,
+			Path:     "customizations.user[0].name",
+			Message:  `user "alice" is defined both in the blueprint and in the installer kickstart`,
+			Fix:      "remove the user from either customizations.user or customizations.installer.kickstart",
+		},
+		{
+			Severity: SeverityError,
+			Path:     "customizations.group[0].name",
+			Message:  `group "wheel2" is defined both in the blueprint and in the installer kickstart`,
+			Fix:      "remove the group from either customizations.group or customizations.installer.kickstart",
+		},
+		{
+			Severity: SeverityWarning,
+			Message:  "Please note that user customizations are deprecated",
+		},
+	}, Lint(bp, checker))
+}
+
+func TestLintImageTypeDuplicates(t *testing.T) {
+	// only exact duplicates are dropped, a message that merely
+	// contains an earlier one is a different problem
+	diags := Lint(&Blueprint{}, &fakeChecker{
+		warnings: []string{"foo", "foo\n", "foo is deprecated"},
+	})
+	assert.Equal(t, []Diagnostic{
+		{Severity: SeverityWarning, Message: "foo"},
+		{Severity: SeverityWarning, Message: "foo is deprecated"},
+	}, diags)
+}
+
+func TestLintImageTypeReportedErrors(t *testing.T) {
+	bp := &Blueprint{
+		Customizations: &Customizations{
+			Filesystem: []FilesystemCustomization{
+				{Mountpoint: "/", MinSize: 5 * datasizes.GiB},
+				{Mountpoint: "/etc", MinSize: 1 * datasizes.GiB},
+			},
+		},
+	}
+	etcDiag := Diagnostic{
+		Severity: SeverityError,
+		Path:     "customizations.filesystem[1].mountpoint",
+		Message:  `path "/etc" is not allowed`,
+		Fix:      "use a mountpoint that is allowed for custom filesystems",
+	}
+
+	// the image type error only repeats a problem with a path
+	diags := Lint(bp, &fakeChecker{
+		err: errors.New("The following errors occurred while setting up custom mountpoints:\npath \"/etc\" is not allowed"),
+	})
+	assert.Equal(t, []Diagnostic{etcDiag}, diags)
+
+	// the image type error has a new problem as well
+	diags = Lint(bp, &fakeChecker{
+		err: errors.New("The following errors occurred while setting up custom mountpoints:\npath \"/etc\" is not allowed\npath \"/var/foo\" is not allowed"),
+	})
+	assert.Equal(t, []Diagnostic{
+		etcDiag,
+		{
+			Severity: SeverityError,
+			Message:  "The following errors occurred while setting up custom mountpoints:\npath \"/etc\" is not allowed\npath \"/var/foo\" is not allowed",
+			Fix:      `adjust the blueprint to the requirements of the "fake-image" image type`,
+		},
+	}, diags)
+}
+
+func TestLintPackages(t *testing.T) {
+	bp := &Blueprint{
+		Packages: []Package{{Name: "httpd", Version: ">= 2.4"}, {Name: "tmux", Version: "<"}},
+		Modules:  []Package{{Name: "nodejs"}},
+		Exclude:  []string{"*-firmware", "nodejs", ""},
+	}
+	assert.Equal(t, []Diagnostic{
+		{
+			Severity: SeverityError,
+			Path:     "packages[1].version",
+			Message:  `package "tmux": version comparison "<" has no version`,
+			Fix:      `use a version like "2.4.*" or ">= 2.4"`,
+		},
+		{
+			Severity: SeverityError,
+			Path:     "exclude[1]",
+			Message:  `"nodejs" is both included and excluded`,
+			Fix:      "remove it from the packages or from the exclusions",
+		},
+		{
+			Severity: SeverityError,
+			Path:     "exclude[2]",
+			Message:  "excluded package name is empty",
+			Fix:      "set the name or remove the entry",
+		},
+	}, Lint(bp, nil))
+}
+
+func TestLintDisk(t *testing.T) {
+	bp := &Blueprint{
+		Customizations: &Customizations{
+			Disk: &DiskCustomization{
+				Partitions: []PartitionCustomization{
+					{
+						Type:    "lvm",
+						MinSize: 10 * datasizes.GiB,
+						VGCustomization: VGCustomization{
+							LogicalVolumes: []LVCustomization{
+								{
+									MinSize: 512 * datasizes.MiB,
+									FilesystemTypedCustomization: FilesystemTypedCustomization{
+										Mountpoint: "/",
+										FSType:     "xfs",
+									},
+								},
+							},
+						},
+					},
+					{
+						Type:    "btrfs",
+						MinSize: 10 * datasizes.GiB,
+						BtrfsVolumeCustomization: BtrfsVolumeCustomization{
+							Subvolumes: []BtrfsSubvolumeCustomization{
+								{Name: "var", Mountpoint: "/var/run"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	diags := Lint(bp, &fakeChecker{})
+	assert.Equal(t, []Diagnostic{
+		{
+			Severity: SeverityError,
+			Path:     "customizations.disk.partitions",
+			Message:  "btrfs and lvm partitioning cannot be combined",
+			Fix:      "use at most one LVM volume group or btrfs volume",
+		},
+		{
+			Severity: SeverityWarning,
+			Path:     "customizations.disk.partitions[0].logical_volumes[0].minsize",
+			Message:  `size of "/" is smaller than the minimum of 1 GiB and will be increased`,
+			Fix:      "set minsize to at least 1 GiB",
+		},
+		{
+			Severity: SeverityError,
+			Path:     "customizations.disk.partitions[1].subvolumes[0].mountpoint",
+			Message:  `path "/var/run" is not allowed`,
+			Fix:      "use a mountpoint that is allowed for custom filesystems",
+		},
+	}, diags)
+}
+
+func TestLintImageTypeError(t *testing.T) {
+	bp := &Blueprint{
+		Customizations: &Customizations{
+			Hostname: common.ToPtr("example"),
+		},
+	}
+	diags := Lint(bp, &fakeChecker{err: errors.New("'Hostname' is not allowed")})
+	assert.Equal(t, []Diagnostic{
+		{
+			Severity: SeverityError,
+			Message:  "'Hostname' is not allowed",
+			Fix:      `adjust the blueprint to the requirements of the "fake-image" image type`,
+		},
+	}, diags)
+	assert.Equal(t, `error: 'Hostname' is not allowed (adjust the blueprint to the requirements of the "fake-image" image type)`, diags[0].String())
+}
+
+func TestKickstartUsersAndGroups(t *testing.T) {
+	users, groups := kickstartUsersAndGroups(`
+# user --name=commented
+user --name=alice --groups=wheel
+user --groups=wheel --name bob
+group --name=devs --gid=1234
+rootpw --lock
+`)
+	assert.Equal(t, map[string]bool{"alice": true, "bob": true}, users)
+	assert.Equal(t, map[string]bool{"devs": true}, groups)
+}
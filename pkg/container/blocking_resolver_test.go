@@ -1,6 +1,7 @@
 package container_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -38,7 +39,7 @@ func TestBlockingResolver(t *testing.T) {
 	resolver := container.NewBlockingResolver("amd64")
 
 	for _, r := range refs {
-		resolver.Add(container.SourceSpec{
+		resolver.Add(context.Background(), container.SourceSpec{
 			Source:    r,
 			Name:      "",
 			Digest:    common.ToPtr(""),
@@ -66,7 +67,7 @@ func TestBlockingResolver(t *testing.T) {
 func TestBlockingResolverFail(t *testing.T) {
 	resolver := container.NewBlockingResolver("amd64")
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    "invalid-reference@${IMAGE_DIGEST}",
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -80,7 +81,7 @@ func TestBlockingResolverFail(t *testing.T) {
 	registry := testregistry.New()
 	defer registry.Close()
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    registry.GetRef("repo"),
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -91,7 +92,7 @@ func TestBlockingResolverFail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Len(t, specs, 0)
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    registry.GetRef("repo"),
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -102,7 +103,7 @@ func TestBlockingResolverFail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Len(t, specs, 0)
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    registry.GetRef("repo"),
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -167,7 +168,7 @@ func TestBlockingResolverLocalManifest(t *testing.T) {
 		return container.NewClientWithTestStorage(target, tmpStorage)
 	})
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    "localhost/multi-arch",
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -185,7 +186,7 @@ func TestBlockingResolverLocalManifest(t *testing.T) {
 		return container.NewClientWithTestStorage(target, tmpStorage)
 	})
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    "localhost/multi-arch",
 		Name:      "",
 		Digest:    common.ToPtr(""),
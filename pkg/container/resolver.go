@@ -13,7 +13,7 @@ type resolveResult struct {
 }
 
 type Resolver interface {
-	Add(spec SourceSpec)
+	Add(ctx context.Context, spec SourceSpec)
 	Finish() ([]Spec, error)
 }
 
@@ -21,8 +21,6 @@ type asyncResolver struct {
 	jobs  int
 	queue chan resolveResult
 
-	ctx context.Context
-
 	Arch         string
 	AuthFilePath string
 
@@ -42,7 +40,6 @@ func NewResolver(arch string) *asyncResolver {
 	// NOTE: this should return the Resolver interface, but osbuild-composer
 	// sets the AuthFilePath and for now we don't want to break the API.
 	return &asyncResolver{
-		ctx:   context.Background(),
 		queue: make(chan resolveResult, 2),
 		Arch:  arch,
 
@@ -50,7 +47,7 @@ func NewResolver(arch string) *asyncResolver {
 	}
 }
 
-func (r *asyncResolver) Add(spec SourceSpec) {
+func (r *asyncResolver) Add(ctx context.Context, spec SourceSpec) {
 	client, err := r.newClient(spec.Source)
 	r.jobs += 1
 
@@ -66,7 +63,7 @@ func (r *asyncResolver) Add(spec SourceSpec) {
 	}
 
 	go func() {
-		spec, err := client.Resolve(r.ctx, spec.Name, spec.Local)
+		spec, err := client.Resolve(ctx, spec.Name, spec.Local)
 		if err != nil {
 			err = fmt.Errorf("'%s': %w", spec.Source, err)
 		}
@@ -119,7 +116,7 @@ func NewBlockingResolver(arch string) Resolver {
 	}
 }
 
-func (r *blockingResolver) Add(src SourceSpec) {
+func (r *blockingResolver) Add(ctx context.Context, src SourceSpec) {
 	client, err := r.newClient(src.Source)
 	if err != nil {
 		r.results = append(r.results, resolveResult{err: err})
@@ -132,7 +129,7 @@ func (r *blockingResolver) Add(src SourceSpec) {
 		client.SetAuthFilePath(r.AuthFilePath)
 	}
 
-	spec, err := client.Resolve(context.TODO(), src.Name, src.Local)
+	spec, err := client.Resolve(ctx, src.Name, src.Local)
 	if err != nil {
 		err = fmt.Errorf("'%s': %w", src.Source, err)
 	}
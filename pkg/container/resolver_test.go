@@ -1,6 +1,7 @@
 package container_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -46,7 +47,7 @@ func TestResolver(t *testing.T) {
 	resolver := container.NewResolver("amd64")
 
 	for _, r := range refs {
-		resolver.Add(container.SourceSpec{
+		resolver.Add(context.Background(), container.SourceSpec{
 			Source:    r,
 			Name:      "",
 			Digest:    common.ToPtr(""),
@@ -74,7 +75,7 @@ func TestResolver(t *testing.T) {
 func TestResolverFail(t *testing.T) {
 	resolver := container.NewResolver("amd64")
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    "invalid-reference@${IMAGE_DIGEST}",
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -88,7 +89,7 @@ func TestResolverFail(t *testing.T) {
 	registry := testregistry.New()
 	defer registry.Close()
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    registry.GetRef("repo"),
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -99,7 +100,7 @@ func TestResolverFail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Len(t, specs, 0)
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    registry.GetRef("repo"),
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -110,7 +111,7 @@ func TestResolverFail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Len(t, specs, 0)
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    registry.GetRef("repo"),
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -175,7 +176,7 @@ func TestResolverLocalManifest(t *testing.T) {
 		return container.NewClientWithTestStorage(target, tmpStorage)
 	})
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    "localhost/multi-arch",
 		Name:      "",
 		Digest:    common.ToPtr(""),
@@ -193,7 +194,7 @@ func TestResolverLocalManifest(t *testing.T) {
 		return container.NewClientWithTestStorage(target, tmpStorage)
 	})
 
-	resolver.Add(container.SourceSpec{
+	resolver.Add(context.Background(), container.SourceSpec{
 		Source:    "localhost/multi-arch",
 		Name:      "",
 		Digest:    common.ToPtr(""),
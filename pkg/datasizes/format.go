@@ -0,0 +1,21 @@
+package datasizes
+
+import "fmt"
+
+// Format returns the size as a string with the largest binary unit that
+// represents it exactly, e.g. "20 GiB", or in bytes if there is none.
+func Format(size uint64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   uint64
+	}{
+		{"GiB", GiB},
+		{"MiB", MiB},
+		{"KiB", KiB},
+	} {
+		if size >= unit.size && size%unit.size == 0 {
+			return fmt.Sprintf("%d %s", size/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
@@ -0,0 +1,25 @@
+package datasizes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/pkg/datasizes"
+)
+
+func TestFormat(t *testing.T) {
+	testCases := map[uint64]string{
+		0:                    "0 bytes",
+		512:                  "512 bytes",
+		datasizes.KiB:        "1 KiB",
+		1536:                 "1536 bytes",
+		2 * datasizes.MiB:    "2 MiB",
+		1025 * datasizes.MiB: "1025 MiB",
+		20 * datasizes.GiB:   "20 GiB",
+	}
+
+	for size, expected := range testCases {
+		assert.Equal(t, expected, datasizes.Format(size))
+	}
+}
@@ -65,6 +65,21 @@ const (
 	UsrPartitionPpc64leGUID = "15BB03AF-77E7-4D4A-B12B-C0D084F7491C" // SD_GPT_USR_PPC64_LE
 	UsrPartitionS390xGUID   = "8A4F5770-50AA-4ED3-874A-99B710DB6FEA" // SD_GPT_USR_S390X
 
+	RootVerityPartitionX86_64GUID  = "2C7357ED-EBD2-46D9-AEC1-23D437EC2BF5" // SD_GPT_ROOT_X86_64_VERITY
+	RootVerityPartitionAarch64GUID = "DF3300CE-D69F-4C92-978C-9BFB0F38D820" // SD_GPT_ROOT_ARM64_VERITY
+	RootVerityPartitionPpc64leGUID = "906BD944-4589-4AAE-A4E4-DD983917446A" // SD_GPT_ROOT_PPC64_LE_VERITY
+	RootVerityPartitionS390xGUID   = "B325BFBE-C7BE-4AB8-8357-139E652D2F6B" // SD_GPT_ROOT_S390X_VERITY
+
+	UsrVerityPartitionX86_64GUID  = "77FF5F63-E7B6-4633-ACF4-1565B864C0E6" // SD_GPT_USR_X86_64_VERITY
+	UsrVerityPartitionAarch64GUID = "6E11A4E7-FBCA-4DED-B9E9-E1A512BB664E" // SD_GPT_USR_ARM64_VERITY
+	UsrVerityPartitionPpc64leGUID = "EE2B9983-21E8-4153-86D9-B6901A54D1CE" // SD_GPT_USR_PPC64_LE_VERITY
+	UsrVerityPartitionS390xGUID   = "31741CC4-1A2A-4111-A581-E00B447D2D06" // SD_GPT_USR_S390X_VERITY
+
+	HomePartitionGUID = "933AC7E1-2EB4-4F13-B844-0E14E2AEF915" // SD_GPT_HOME
+	SrvPartitionGUID  = "3B8F8425-20E0-4F3B-907F-1A25A76F98E8" // SD_GPT_SRV
+	VarPartitionGUID  = "4D21B016-B534-45C2-A9FB-5C16E091FD2D" // SD_GPT_VAR
+	TmpPartitionGUID  = "7EC6F557-3BC5-4ACA-B293-16EF5DF639D1" // SD_GPT_TMP
+
 	// Partition type IDs for DOS disks
 
 	// Partition type ID for BIOS boot partition on dos.
@@ -166,7 +181,31 @@ func getPartitionTypeIDfor(ptType PartitionTableType, partTypeName string, archi
 			default:
 				return "", fmt.Errorf("unknown or unsupported architecture enum value: %d", architecture)
 			}
+		case "linux-generic":
+			return FilesystemDataGUID, nil
+		case "xbootldr":
+			return XBootLDRPartitionGUID, nil
+		case "home":
+			return HomePartitionGUID, nil
+		case "srv":
+			return SrvPartitionGUID, nil
+		case "var":
+			return VarPartitionGUID, nil
+		case "tmp":
+			return TmpPartitionGUID, nil
+		case "root-verity", "usr-verity":
+			if architecture == arch.ARCH_UNSET {
+				return "", fmt.Errorf("architecture must be specified for selecting GUID for %q partition", partTypeName)
+			}
+			guid, ok := archPartitionTypeGUIDs[partTypeName][architecture]
+			if !ok {
+				return "", fmt.Errorf("unknown or unsupported architecture enum value: %d", architecture)
+			}
+			return guid, nil
 		default:
+			if guid, ok := archPartitionTypeGUID(partTypeName); ok {
+				return guid, nil
+			}
 			return "", fmt.Errorf("unknown or unsupported partition type name: %s", partTypeName)
 		}
 	default:
@@ -174,6 +213,61 @@ func getPartitionTypeIDfor(ptType PartitionTableType, partTypeName string, archi
 	}
 }
 
+// archPartitionTypeGUIDs are the discoverable partition types that exist
+// for each architecture
+var archPartitionTypeGUIDs = map[string]map[arch.Arch]string{
+	"root": {
+		arch.ARCH_X86_64:  RootPartitionX86_64GUID,
+		arch.ARCH_AARCH64: RootPartitionAarch64GUID,
+		arch.ARCH_PPC64LE: RootPartitionPpc64leGUID,
+		arch.ARCH_S390X:   RootPartitionS390xGUID,
+	},
+	"usr": {
+		arch.ARCH_X86_64:  UsrPartitionX86_64GUID,
+		arch.ARCH_AARCH64: UsrPartitionAarch64GUID,
+		arch.ARCH_PPC64LE: UsrPartitionPpc64leGUID,
+		arch.ARCH_S390X:   UsrPartitionS390xGUID,
+	},
+	"root-verity": {
+		arch.ARCH_X86_64:  RootVerityPartitionX86_64GUID,
+		arch.ARCH_AARCH64: RootVerityPartitionAarch64GUID,
+		arch.ARCH_PPC64LE: RootVerityPartitionPpc64leGUID,
+		arch.ARCH_S390X:   RootVerityPartitionS390xGUID,
+	},
+	"usr-verity": {
+		arch.ARCH_X86_64:  UsrVerityPartitionX86_64GUID,
+		arch.ARCH_AARCH64: UsrVerityPartitionAarch64GUID,
+		arch.ARCH_PPC64LE: UsrVerityPartitionPpc64leGUID,
+		arch.ARCH_S390X:   UsrVerityPartitionS390xGUID,
+	},
+}
+
+// archPartitionTypeNames are the architecture names used in the partition
+// type names of systemd-repart, e.g. "root-x86-64" or "usr-arm64-verity"
+var archPartitionTypeNames = map[arch.Arch]string{
+	arch.ARCH_X86_64:  "x86-64",
+	arch.ARCH_AARCH64: "arm64",
+	arch.ARCH_PPC64LE: "ppc64-le",
+	arch.ARCH_S390X:   "s390x",
+}
+
+// archPartitionTypeGUID returns the GUID of the discoverable partition type
+// with a name that contains the architecture, e.g. "root-x86-64" or
+// "usr-arm64-verity"
+func archPartitionTypeGUID(name string) (string, bool) {
+	for a, archName := range archPartitionTypeNames {
+		for _, kind := range []string{"root", "usr"} {
+			switch name {
+			case kind + "-" + archName:
+				return archPartitionTypeGUIDs[kind][a], true
+			case kind + "-" + archName + "-verity":
+				return archPartitionTypeGUIDs[kind+"-verity"][a], true
+			}
+		}
+	}
+	return "", false
+}
+
 // FSType is the filesystem type enum.
 //
 // There should always be one value for each filesystem type supported by
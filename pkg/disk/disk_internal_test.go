@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/osbuild/images/pkg/arch"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -106,3 +107,42 @@ func TestGenUniqueStringManyCollisions(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPartitionTypeIDforGPTNames(t *testing.T) {
+	type testCase struct {
+		name   string
+		arch   arch.Arch
+		exp    string
+		errmsg string
+	}
+
+	testCases := []testCase{
+		{name: "linux-generic", exp: FilesystemDataGUID},
+		{name: "xbootldr", exp: XBootLDRPartitionGUID},
+		{name: "home", exp: HomePartitionGUID},
+		{name: "srv", exp: SrvPartitionGUID},
+		{name: "var", exp: VarPartitionGUID},
+		{name: "tmp", exp: TmpPartitionGUID},
+		{name: "root-verity", arch: arch.ARCH_AARCH64, exp: RootVerityPartitionAarch64GUID},
+		{name: "usr-verity", arch: arch.ARCH_S390X, exp: UsrVerityPartitionS390xGUID},
+		{name: "root-x86-64", exp: RootPartitionX86_64GUID},
+		{name: "usr-arm64", exp: UsrPartitionAarch64GUID},
+		{name: "root-ppc64-le-verity", exp: RootVerityPartitionPpc64leGUID},
+		{name: "usr-s390x-verity", arch: arch.ARCH_X86_64, exp: UsrVerityPartitionS390xGUID},
+		{name: "root-verity", errmsg: `architecture must be specified for selecting GUID for "root-verity" partition`},
+		{name: "root-riscv64", errmsg: "unknown or unsupported partition type name: root-riscv64"},
+		{name: "root-x86-64-signature", errmsg: "unknown or unsupported partition type name: root-x86-64-signature"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			guid, err := getPartitionTypeIDfor(PT_GPT, tc.name, tc.arch)
+			if tc.errmsg != "" {
+				assert.EqualError(t, err, tc.errmsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exp, guid)
+		})
+	}
+}
@@ -5,10 +5,19 @@ var (
 	EntityPath               = entityPath
 	AddBootPartition         = addBootPartition
 	AddPartitionsForBootMode = addPartitionsForBootMode
+	NewClevisBind            = newClevisBind
 )
 
 type PartitionTableFeatures = partitionTableFeatures
 
+func MockHostMemory(kib uint) (restore func()) {
+	saved := hostMemory
+	hostMemory = func() uint { return kib }
+	return func() {
+		hostMemory = saved
+	}
+}
+
 func FindDirectoryEntityPath(pt *PartitionTable, path string) []Entity {
 	return pt.findDirectoryEntityPath(path)
 }
@@ -0,0 +1,221 @@
+package disk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/osbuild/images/pkg/datasizes"
+)
+
+// Layout is a report of the final layout of a partition table: the tree of
+// its partitions and their payloads with offsets, sizes, identifiers and
+// mount information. It can be rendered as a text tree with String() or
+// serialized to JSON.
+type Layout struct {
+	Type       string         `json:"type"`
+	UUID       string         `json:"uuid,omitempty"`
+	Size       uint64         `json:"size"`
+	SectorSize uint64         `json:"sector_size"`
+	Partitions []LayoutEntity `json:"partitions"`
+
+	// Layouts of the additional disks
+	Disks []Layout `json:"disks,omitempty"`
+}
+
+// LayoutEntity is a single entity in a [Layout] tree.
+type LayoutEntity struct {
+	// Kind of the entity: partition, filesystem, swap, luks, lvm-vg,
+	// lvm-lv, btrfs, or btrfs-subvolume
+	Kind string `json:"kind"`
+
+	// Number of the partition in the partition table (partitions only)
+	Number int `json:"number,omitempty"`
+
+	// Offset of the partition on the disk (partitions only)
+	Start uint64 `json:"start,omitempty"`
+
+	Size uint64 `json:"size,omitempty"`
+
+	// Partition type ID or filesystem type
+	Type string `json:"type,omitempty"`
+
+	UUID string `json:"uuid,omitempty"`
+
+	// Partition name, volume group or logical volume name, or subvolume
+	// name
+	Name string `json:"name,omitempty"`
+
+	Label        string `json:"label,omitempty"`
+	Mountpoint   string `json:"mountpoint,omitempty"`
+	FSTabOptions string `json:"fstab_options,omitempty"`
+
+	// GPT attribute bits and legacy BIOS bootable flag (partitions only)
+	Attrs    []uint `json:"attrs,omitempty"`
+	Bootable bool   `json:"bootable,omitempty"`
+
+	Children []LayoutEntity `json:"children,omitempty"`
+}
+
+// Layout returns the layout report of the partition table.
+func (pt *PartitionTable) Layout() (*Layout, error) {
+	sectorSize := pt.SectorSize
+	if sectorSize == 0 {
+		sectorSize = DefaultSectorSize
+	}
+	layout := &Layout{
+		Type:       pt.Type.String(),
+		UUID:       pt.UUID,
+		Size:       pt.Size,
+		SectorSize: sectorSize,
+		Partitions: make([]LayoutEntity, 0, len(pt.Partitions)),
+	}
+
+	for idx := range pt.Partitions {
+		part := &pt.Partitions[idx]
+		entity := LayoutEntity{
+			Kind:     "partition",
+			Number:   idx + 1,
+			Start:    part.Start,
+			Size:     part.Size,
+			Type:     part.Type,
+			UUID:     part.UUID,
+			Name:     part.Name,
+			Attrs:    part.Attrs,
+			Bootable: part.Bootable,
+		}
+		if part.Payload != nil {
+			child, err := layoutEntity(part.Payload)
+			if err != nil {
+				return nil, fmt.Errorf("error creating layout of partition %d: %w", idx+1, err)
+			}
+			entity.Children = []LayoutEntity{child}
+		}
+		layout.Partitions = append(layout.Partitions, entity)
+	}
+
+	for idx, extra := range pt.ExtraDisks {
+		extraLayout, err := extra.Layout()
+		if err != nil {
+			return nil, fmt.Errorf("error creating layout of additional disk %d: %w", idx+1, err)
+		}
+		layout.Disks = append(layout.Disks, *extraLayout)
+	}
+	return layout, nil
+}
+
+func layoutEntity(ent Entity) (LayoutEntity, error) {
+	var entity LayoutEntity
+	switch e := ent.(type) {
+	case *Filesystem:
+		entity = LayoutEntity{Kind: "filesystem", Type: e.Type, UUID: e.UUID, Label: e.Label, Mountpoint: e.Mountpoint}
+	case *Swap:
+		entity = LayoutEntity{Kind: "swap", UUID: e.UUID, Label: e.Label}
+	case *LUKSContainer:
+		entity = LayoutEntity{Kind: "luks", UUID: e.UUID, Label: e.Label}
+	case *LVMVolumeGroup:
+		entity = LayoutEntity{Kind: "lvm-vg", Name: e.Name}
+	case *LVMLogicalVolume:
+		entity = LayoutEntity{Kind: "lvm-lv", Name: e.Name, Size: e.Size}
+	case *Btrfs:
+		entity = LayoutEntity{Kind: "btrfs", UUID: e.UUID, Label: e.Label, Mountpoint: e.Mountpoint}
+	case *BtrfsSubvolume:
+		entity = LayoutEntity{Kind: "btrfs-subvolume", Name: e.Name, Size: e.Size, Mountpoint: e.Mountpoint}
+	default:
+		return entity, fmt.Errorf("unsupported entity type %T", ent)
+	}
+
+	if fsTabEntity, ok := ent.(FSTabEntity); ok {
+		options, err := fsTabEntity.GetFSTabOptions()
+		if err != nil {
+			return entity, err
+		}
+		entity.FSTabOptions = options.MntOps
+	}
+
+	if container, ok := ent.(Container); ok {
+		for idx := uint(0); idx < container.GetItemCount(); idx++ {
+			child, err := layoutEntity(container.GetChild(idx))
+			if err != nil {
+				return entity, err
+			}
+			entity.Children = append(entity.Children, child)
+		}
+	}
+	return entity, nil
+}
+
+// String renders the layout as a text tree, one line per entity.
+func (l *Layout) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s partition table: size %s, sector size %d", l.Type, datasizes.Format(l.Size), l.SectorSize)
+	if l.UUID != "" {
+		fmt.Fprintf(&b, ", uuid %s", l.UUID)
+	}
+	b.WriteString("\n")
+	writeLayoutEntities(&b, l.Partitions, "")
+	for idx := range l.Disks {
+		fmt.Fprintf(&b, "additional disk %d: %s", idx+1, l.Disks[idx].String())
+	}
+	return b.String()
+}
+
+func writeLayoutEntities(b *strings.Builder, entities []LayoutEntity, prefix string) {
+	for idx := range entities {
+		branch, indent := "├── ", "│   "
+		if idx == len(entities)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(b, "%s%s%s\n", prefix, branch, entities[idx].describe())
+		writeLayoutEntities(b, entities[idx].Children, prefix+indent)
+	}
+}
+
+// describe returns the single line description of the entity for the text
+// tree
+func (e *LayoutEntity) describe() string {
+	head := e.Kind
+	var details []string
+	if e.Kind == "partition" {
+		head += " " + strconv.Itoa(e.Number)
+		details = append(details, "start "+datasizes.Format(e.Start))
+	} else if e.Type != "" {
+		head += " " + e.Type
+	}
+	if e.Size != 0 || e.Kind == "partition" {
+		details = append(details, "size "+datasizes.Format(e.Size))
+	}
+	if e.Kind == "partition" && e.Type != "" {
+		details = append(details, "type "+e.Type)
+	}
+	if e.Name != "" {
+		details = append(details, fmt.Sprintf("name %q", e.Name))
+	}
+	if e.Mountpoint != "" {
+		details = append(details, "mountpoint "+e.Mountpoint)
+	}
+	if e.UUID != "" {
+		details = append(details, "uuid "+e.UUID)
+	}
+	if e.Label != "" {
+		details = append(details, fmt.Sprintf("label %q", e.Label))
+	}
+	if e.FSTabOptions != "" {
+		details = append(details, fmt.Sprintf("fstab options %q", e.FSTabOptions))
+	}
+	if len(e.Attrs) > 0 {
+		attrs := make([]string, len(e.Attrs))
+		for idx, attr := range e.Attrs {
+			attrs[idx] = strconv.FormatUint(uint64(attr), 10)
+		}
+		details = append(details, "attrs "+strings.Join(attrs, ","))
+	}
+	if e.Bootable {
+		details = append(details, "bootable")
+	}
+
+	if len(details) == 0 {
+		return head
+	}
+	return head + ": " + strings.Join(details, ", ")
+}
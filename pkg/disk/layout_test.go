@@ -0,0 +1,181 @@
+package disk_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/testdisk"
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/disk"
+)
+
+func makeLayoutTestPT() *disk.PartitionTable {
+	return &disk.PartitionTable{
+		Size: 10 * datasizes.GiB,
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: disk.PT_GPT,
+		Partitions: []disk.Partition{
+			{
+				Start:    1 * datasizes.MiB,
+				Size:     1 * datasizes.MiB,
+				Bootable: true,
+				Type:     disk.BIOSBootPartitionGUID,
+				UUID:     disk.BIOSBootPartitionUUID,
+			},
+			{
+				Start: 2 * datasizes.MiB,
+				Size:  200 * datasizes.MiB,
+				Type:  disk.EFISystemPartitionGUID,
+				UUID:  disk.EFISystemPartitionUUID,
+				Name:  "ESP",
+				Payload: &disk.Filesystem{
+					Type:         "vfat",
+					UUID:         disk.EFIFilesystemUUID,
+					Mountpoint:   "/boot/efi",
+					Label:        "EFI-SYSTEM",
+					FSTabOptions: "defaults,uid=0,gid=0,umask=077,shortname=winnt",
+					FSTabPassNo:  2,
+				},
+			},
+			{
+				Start: 202 * datasizes.MiB,
+				Size:  10*datasizes.GiB - 202*datasizes.MiB - 1*datasizes.MiB,
+				Type:  disk.FilesystemDataGUID,
+				UUID:  "6264D520-3FB9-423F-8AB8-7A0A8E3D3562",
+				Attrs: []uint{59},
+				Payload: &disk.LUKSContainer{
+					UUID:  "fc4f6c34-6f44-4e30-a0da-1cd24c1b1f62",
+					Label: "crypt_root",
+					Payload: &disk.LVMVolumeGroup{
+						Name: "rootvg",
+						LogicalVolumes: []disk.LVMLogicalVolume{
+							{
+								Name: "rootlv",
+								Size: 5 * datasizes.GiB,
+								Payload: &disk.Filesystem{
+									Type:         "xfs",
+									UUID:         "6e4ff95f-f662-45ee-a82a-bdf44a2d0b75",
+									Mountpoint:   "/",
+									FSTabOptions: "defaults",
+								},
+							},
+							{
+								Name: "swaplv",
+								Size: 512 * datasizes.MiB,
+								Payload: &disk.Swap{
+									UUID:         "fb180daf-48a7-4ee0-b10d-394651850fd4",
+									FSTabOptions: "defaults",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestLayoutString(t *testing.T) {
+	layout, err := makeLayoutTestPT().Layout()
+	require.NoError(t, err)
+
+	expected := `gpt partition table: size 10 GiB, sector size 512, uuid D209C89E-EA5E-4FBD-B161-B461CCE297E0
+├── partition 1: start 1 MiB, size 1 MiB, type 21686148-6449-6E6F-744E-656564454649, uuid FAC7F1FB-3E8D-4137-A512-961DE09A5549, bootable
+├── partition 2: start 2 MiB, size 200 MiB, type C12A7328-F81F-11D2-BA4B-00A0C93EC93B, name "ESP", uuid 68B2905B-DF3E-4FB3-80FA-49D1E773AA33
+│   └── filesystem vfat: mountpoint /boot/efi, uuid 7B77-95E7, label "EFI-SYSTEM", fstab options "defaults,uid=0,gid=0,umask=077,shortname=winnt"
+└── partition 3: start 202 MiB, size 10037 MiB, type 0FC63DAF-8483-4772-8E79-3D69D8477DE4, uuid 6264D520-3FB9-423F-8AB8-7A0A8E3D3562, attrs 59
+    └── luks: uuid fc4f6c34-6f44-4e30-a0da-1cd24c1b1f62, label "crypt_root"
+        └── lvm-vg: name "rootvg"
+            ├── lvm-lv: size 5 GiB, name "rootlv"
+            │   └── filesystem xfs: mountpoint /, uuid 6e4ff95f-f662-45ee-a82a-bdf44a2d0b75, fstab options "defaults"
+            └── lvm-lv: size 512 MiB, name "swaplv"
+                └── swap: uuid fb180daf-48a7-4ee0-b10d-394651850fd4, fstab options "defaults"
+`
+	assert.Equal(t, expected, layout.String())
+}
+
+func TestLayoutStringAdditionalDisks(t *testing.T) {
+	pt := &disk.PartitionTable{
+		Size: 2 * datasizes.GiB,
+		Type: disk.PT_DOS,
+		Partitions: []disk.Partition{
+			{
+				Start: 1 * datasizes.MiB,
+				Size:  2*datasizes.GiB - 1*datasizes.MiB,
+				Payload: &disk.Filesystem{
+					Type:       "xfs",
+					Mountpoint: "/",
+				},
+			},
+		},
+		ExtraDisks: []*disk.PartitionTable{
+			{
+				Size: 1 * datasizes.GiB,
+				Type: disk.PT_DOS,
+				Partitions: []disk.Partition{
+					{
+						Start: 1 * datasizes.MiB,
+						Size:  1*datasizes.GiB - 1*datasizes.MiB,
+						Payload: &disk.Filesystem{
+							Type:       "ext4",
+							Mountpoint: "/data",
+						},
+					},
+				},
+			},
+		},
+	}
+	layout, err := pt.Layout()
+	require.NoError(t, err)
+	require.Len(t, layout.Disks, 1)
+
+	expected := `dos partition table: size 2 GiB, sector size 512
+└── partition 1: start 1 MiB, size 2047 MiB
+    └── filesystem xfs: mountpoint /
+additional disk 1: dos partition table: size 1 GiB, sector size 512
+└── partition 1: start 1 MiB, size 1023 MiB
+    └── filesystem ext4: mountpoint /data
+`
+	assert.Equal(t, expected, layout.String())
+}
+
+func TestLayoutJSON(t *testing.T) {
+	layout, err := makeLayoutTestPT().Layout()
+	require.NoError(t, err)
+
+	data, err := json.Marshal(layout)
+	require.NoError(t, err)
+
+	var decoded disk.Layout
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, layout, &decoded)
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, "gpt", raw["type"])
+	assert.Equal(t, float64(512), raw["sector_size"])
+
+	root := decoded.Partitions[2].Children[0].Children[0].Children[0].Children[0]
+	assert.Equal(t, disk.LayoutEntity{
+		Kind:         "filesystem",
+		Type:         "xfs",
+		UUID:         "6e4ff95f-f662-45ee-a82a-bdf44a2d0b75",
+		Mountpoint:   "/",
+		FSTabOptions: "defaults",
+	}, root)
+}
+
+func TestLayoutTestPartitionTables(t *testing.T) {
+	for name := range testdisk.TestPartitionTables {
+		pt := testdisk.TestPartitionTables[name]
+		t.Run(name, func(t *testing.T) {
+			layout, err := pt.Layout()
+			require.NoError(t, err)
+			assert.Len(t, layout.Partitions, len(pt.Partitions))
+			assert.NotEmpty(t, layout.String())
+		})
+	}
+}
@@ -1,12 +1,19 @@
 package disk
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"os"
 	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/datasizes"
 )
 
@@ -22,6 +29,65 @@ type Argon2id struct {
 	Parallelism uint
 }
 
+// ClevisArgon2id are the key derivation parameters of encryption
+// customizations that only unlock with Clevis, i.e. that remove the
+// passphrase at the end of the build. They are the same as the ones of the
+// encrypted partition tables of the image definitions: the passphrase is
+// never used on the deployed system, so the cheap parameters only keep the
+// memory usage of the build low.
+var ClevisArgon2id = Argon2id{
+	Iterations:  4,
+	Memory:      32,
+	Parallelism: 1,
+}
+
+// defaultArgon2idMemory is the memory cost (in KiB) of DefaultArgon2id(),
+// 1 GiB like the default of cryptsetup.
+const defaultArgon2idMemory = 1024 * 1024
+
+// hostMemory returns the total memory of the build host in KiB, or 0 if it
+// is unknown.
+var hostMemory = func() uint {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			mem, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return uint(mem)
+		}
+	}
+	return 0
+}
+
+// DefaultArgon2id returns the key derivation parameters used for encryption
+// customizations that do not define them and keep their passphrase. Like
+// cryptsetup, it uses 1 GiB of memory and 4 threads, capped to half of the
+// memory and to the number of CPUs of the build host, where the volume is
+// formatted. Blueprints can override them with the "pbkdf" encryption
+// customization.
+func DefaultArgon2id() Argon2id {
+	pbkdf := Argon2id{
+		Iterations:  4,
+		Memory:      defaultArgon2idMemory,
+		Parallelism: 4,
+	}
+	if limit := hostMemory() / 2; limit > 0 && pbkdf.Memory > limit {
+		pbkdf.Memory = max(limit, ClevisArgon2id.Memory)
+	}
+	if cpus := uint(runtime.NumCPU()); pbkdf.Parallelism > cpus {
+		pbkdf.Parallelism = cpus
+	}
+	return pbkdf
+}
+
 // ClevisBind defines parameters for binding a LUKS device with a given policy.
 type ClevisBind struct {
 	Pin    string
@@ -131,3 +197,104 @@ func (lc *LUKSContainer) minSize(size uint64) uint64 {
 	}
 	return minSize
 }
+
+// newLUKSContainer creates a LUKS container for the payload from the
+// blueprint encryption customization
+func newLUKSContainer(enc *blueprint.EncryptionCustomization, payload Entity) (*LUKSContainer, error) {
+	pbkdf := DefaultArgon2id()
+	if enc.Clevis != nil && enc.Clevis.RemovePassphrase {
+		pbkdf = ClevisArgon2id
+	}
+	if enc.PBKDF != nil {
+		if enc.PBKDF.Iterations != 0 {
+			pbkdf.Iterations = enc.PBKDF.Iterations
+		}
+		if enc.PBKDF.Memory != 0 {
+			pbkdf.Memory = enc.PBKDF.Memory
+		}
+		if enc.PBKDF.Parallelism != 0 {
+			pbkdf.Parallelism = enc.PBKDF.Parallelism
+		}
+	}
+
+	lc := &LUKSContainer{
+		Passphrase: enc.Passphrase,
+		Cipher:     enc.Cipher,
+		Label:      enc.Label,
+		PBKDF:      pbkdf,
+		Payload:    payload,
+	}
+	if enc.Clevis != nil {
+		clevis, err := newClevisBind(enc.Clevis)
+		if err != nil {
+			return nil, err
+		}
+		lc.Clevis = clevis
+	}
+	return lc, nil
+}
+
+// newClevisBind returns the clevis pin and its policy for the
+// customization. Multiple pins are combined with the "sss" pin.
+func newClevisBind(c *blueprint.ClevisCustomization) (*ClevisBind, error) {
+	type tangPolicy struct {
+		URL        string `json:"url"`
+		Thumbprint string `json:"thp,omitempty"`
+	}
+	type tpm2Policy struct {
+		PCRBank string `json:"pcr_bank,omitempty"`
+		PCRIDs  string `json:"pcr_ids,omitempty"`
+	}
+
+	pins := make(map[string]any)
+	if c.TPM2 != nil {
+		pins["tpm2"] = tpm2Policy{
+			PCRBank: c.TPM2.PCRBank,
+			PCRIDs:  c.TPM2.PCRIDs,
+		}
+	}
+	var tang []tangPolicy
+	for _, server := range c.Tang {
+		tang = append(tang, tangPolicy{
+			URL:        server.URL,
+			Thumbprint: server.Thumbprint,
+		})
+	}
+
+	var pin string
+	var policy any
+	switch {
+	case c.Pins() == 0:
+		return nil, fmt.Errorf("clevis customization without pins")
+	case c.Pins() == 1 && c.TPM2 != nil:
+		pin, policy = "tpm2", pins["tpm2"]
+	case c.Pins() == 1:
+		pin, policy = "tang", tang[0]
+	default:
+		if len(tang) > 0 {
+			pins["tang"] = tang
+		}
+		threshold := c.Threshold
+		if threshold == 0 {
+			threshold = 1
+		}
+		pin = "sss"
+		policy = struct {
+			Threshold uint           `json:"t"`
+			Pins      map[string]any `json:"pins"`
+		}{
+			Threshold: threshold,
+			Pins:      pins,
+		}
+	}
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &ClevisBind{
+		Pin:              pin,
+		Policy:           string(policyJSON),
+		RemovePassphrase: c.RemovePassphrase,
+	}, nil
+}
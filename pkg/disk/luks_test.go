@@ -1,11 +1,278 @@
 package disk_test
 
 import (
+	"math/rand"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/arch"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/datasizes"
 	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/platform"
 )
 
 func TestImplementsInterfacesCompileTimeCheckLUKS(t *testing.T) {
 	var _ = disk.Container(&disk.LUKSContainer{})
 }
+
+func TestNewClevisBind(t *testing.T) {
+	testCases := map[string]struct {
+		clevis   blueprint.ClevisCustomization
+		expected disk.ClevisBind
+	}{
+		"tpm2": {
+			clevis: blueprint.ClevisCustomization{
+				TPM2:             &blueprint.ClevisTPM2Customization{PCRBank: "sha256", PCRIDs: "0,7"},
+				RemovePassphrase: true,
+			},
+			expected: disk.ClevisBind{
+				Pin:              "tpm2",
+				Policy:           `{"pcr_bank":"sha256","pcr_ids":"0,7"}`,
+				RemovePassphrase: true,
+			},
+		},
+		"tpm2-defaults": {
+			clevis: blueprint.ClevisCustomization{
+				TPM2: &blueprint.ClevisTPM2Customization{},
+			},
+			expected: disk.ClevisBind{
+				Pin:    "tpm2",
+				Policy: `{}`,
+			},
+		},
+		"tang": {
+			clevis: blueprint.ClevisCustomization{
+				Tang: []blueprint.ClevisTangCustomization{{URL: "http://tang.example.com", Thumbprint: "abc"}},
+			},
+			expected: disk.ClevisBind{
+				Pin:    "tang",
+				Policy: `{"url":"http://tang.example.com","thp":"abc"}`,
+			},
+		},
+		"sss": {
+			clevis: blueprint.ClevisCustomization{
+				TPM2: &blueprint.ClevisTPM2Customization{PCRIDs: "7"},
+				Tang: []blueprint.ClevisTangCustomization{
+					{URL: "http://tang1.example.com"},
+					{URL: "http://tang2.example.com"},
+				},
+				Threshold: 2,
+			},
+			expected: disk.ClevisBind{
+				Pin:    "sss",
+				Policy: `{"t":2,"pins":{"tang":[{"url":"http://tang1.example.com"},{"url":"http://tang2.example.com"}],"tpm2":{"pcr_ids":"7"}}}`,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			bind, err := disk.NewClevisBind(&tc.clevis)
+			require.NoError(t, err)
+			assert.Equal(t, &tc.expected, bind)
+		})
+	}
+
+	_, err := disk.NewClevisBind(&blueprint.ClevisCustomization{})
+	assert.EqualError(t, err, "clevis customization without pins")
+}
+
+func TestNewCustomPartitionTableEncrypted(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				MinSize: 1 * datasizes.GiB,
+				Encryption: &blueprint.EncryptionCustomization{
+					Passphrase: "secret",
+					Cipher:     "aes-xts-plain64",
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/data",
+					FSType:     "ext4",
+				},
+			},
+			{
+				Type:    "lvm",
+				MinSize: 10 * datasizes.GiB,
+				Encryption: &blueprint.EncryptionCustomization{
+					Passphrase: "secret",
+					Label:      "crypt_root",
+					PBKDF:      &blueprint.PBKDFCustomization{Memory: 65536},
+					Clevis: &blueprint.ClevisCustomization{
+						TPM2:             &blueprint.ClevisTPM2Customization{},
+						RemovePassphrase: true,
+					},
+				},
+				VGCustomization: blueprint.VGCustomization{
+					Name: "rootvg",
+					LogicalVolumes: []blueprint.LVCustomization{
+						{
+							Name:    "rootlv",
+							MinSize: 5 * datasizes.GiB,
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/",
+								FSType:     "xfs",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType: disk.FS_XFS,
+		BootMode:      platform.BOOT_UEFI,
+		Architecture:  arch.ARCH_X86_64,
+	}
+
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(0))
+	pt, err := disk.NewCustomPartitionTable(customizations, options, rng)
+	require.NoError(t, err)
+
+	// ESP, the /boot partition that is required for the encrypted root,
+	// /data and the volume group
+	require.Len(t, pt.Partitions, 4)
+	assert.Equal(t, "/boot", pt.Partitions[1].Payload.(*disk.Filesystem).Mountpoint)
+
+	data, ok := pt.Partitions[2].Payload.(*disk.LUKSContainer)
+	require.True(t, ok)
+	assert.Equal(t, "secret", data.Passphrase)
+	assert.Equal(t, "aes-xts-plain64", data.Cipher)
+	assert.Equal(t, disk.DefaultArgon2id(), data.PBKDF)
+	assert.Nil(t, data.Clevis)
+	assert.NotEmpty(t, data.UUID)
+	assert.Equal(t, "/data", data.Payload.(*disk.Filesystem).Mountpoint)
+
+	root, ok := pt.Partitions[3].Payload.(*disk.LUKSContainer)
+	require.True(t, ok)
+	assert.Equal(t, "crypt_root", root.Label)
+	// the passphrase is removed, so the clevis parameters are the base
+	assert.Equal(t, disk.Argon2id{Iterations: 4, Memory: 65536, Parallelism: 1}, root.PBKDF)
+	assert.Equal(t, &disk.ClevisBind{Pin: "tpm2", Policy: "{}", RemovePassphrase: true}, root.Clevis)
+	vg, ok := root.Payload.(*disk.LVMVolumeGroup)
+	require.True(t, ok)
+	assert.Equal(t, "rootvg", vg.Name)
+	// the partition must fit the LUKS header in addition to the volume group
+	assert.GreaterOrEqual(t, pt.Partitions[3].Size, vg.LogicalVolumes[0].Size+vg.MetadataSize()+root.MetadataSize())
+
+	features := disk.GetPartitionTableFeatures(*pt)
+	assert.True(t, features.LUKS)
+	assert.Contains(t, pt.GetBuildPackages(), "cryptsetup")
+}
+
+func TestDefaultArgon2id(t *testing.T) {
+	restore := disk.MockHostMemory(16 * 1024 * 1024)
+	defer restore()
+	pbkdf := disk.DefaultArgon2id()
+	assert.Equal(t, uint(4), pbkdf.Iterations)
+	assert.Equal(t, uint(1024*1024), pbkdf.Memory)
+	assert.GreaterOrEqual(t, pbkdf.Parallelism, uint(1))
+	assert.LessOrEqual(t, pbkdf.Parallelism, uint(4))
+
+	// capped to half of the memory of the build host
+	disk.MockHostMemory(1024 * 1024)
+	assert.Equal(t, uint(512*1024), disk.DefaultArgon2id().Memory)
+
+	// but never below the minimum of the luks2.format stage
+	disk.MockHostMemory(16)
+	assert.Equal(t, uint(32), disk.DefaultArgon2id().Memory)
+
+	// unknown host memory
+	disk.MockHostMemory(0)
+	assert.Equal(t, uint(1024*1024), disk.DefaultArgon2id().Memory)
+}
+
+func TestNewCustomPartitionTableEncryptedClevis(t *testing.T) {
+	restore := disk.MockHostMemory(16 * 1024 * 1024)
+	defer restore()
+
+	testCases := map[string]struct {
+		removePassphrase bool
+		expected         disk.Argon2id
+	}{
+		"keep-passphrase":   {false, disk.DefaultArgon2id()},
+		"remove-passphrase": {true, disk.ClevisArgon2id},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			customizations := &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type:    "plain",
+						MinSize: 1 * datasizes.GiB,
+						Encryption: &blueprint.EncryptionCustomization{
+							Passphrase: "secret",
+							Clevis: &blueprint.ClevisCustomization{
+								TPM2:             &blueprint.ClevisTPM2Customization{},
+								RemovePassphrase: tc.removePassphrase,
+							},
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							Mountpoint: "/data",
+							FSType:     "ext4",
+						},
+					},
+				},
+			}
+			options := &disk.CustomPartitionTableOptions{
+				DefaultFSType: disk.FS_XFS,
+				BootMode:      platform.BOOT_UEFI,
+				Architecture:  arch.ARCH_X86_64,
+			}
+
+			/* #nosec G404 */
+			rng := rand.New(rand.NewSource(0))
+			pt, err := disk.NewCustomPartitionTable(customizations, options, rng)
+			require.NoError(t, err)
+			var luks *disk.LUKSContainer
+			for idx := range pt.Partitions {
+				if lc, ok := pt.Partitions[idx].Payload.(*disk.LUKSContainer); ok {
+					luks = lc
+				}
+			}
+			require.NotNil(t, luks)
+			assert.Equal(t, tc.expected, luks.PBKDF)
+		})
+	}
+}
+
+func TestNewCustomPartitionTableEncryptedAutoRoot(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				Type:    "btrfs",
+				MinSize: 10 * datasizes.GiB,
+				Encryption: &blueprint.EncryptionCustomization{
+					Passphrase: "secret",
+				},
+				BtrfsVolumeCustomization: blueprint.BtrfsVolumeCustomization{
+					Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+						{Name: "home", Mountpoint: "/home"},
+					},
+				},
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType: disk.FS_XFS,
+		BootMode:      platform.BOOT_UEFI,
+		Architecture:  arch.ARCH_X86_64,
+	}
+
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(0))
+	pt, err := disk.NewCustomPartitionTable(customizations, options, rng)
+	require.NoError(t, err)
+
+	// the root subvolume is created in the encrypted btrfs volume instead
+	// of a new plain partition
+	require.Len(t, pt.Partitions, 3)
+	luks := pt.Partitions[2].Payload.(*disk.LUKSContainer)
+	btrfs := luks.Payload.(*disk.Btrfs)
+	assert.Equal(t, []string{"/home", "/"}, []string{btrfs.Subvolumes[0].Mountpoint, btrfs.Subvolumes[1].Mountpoint})
+	assert.Equal(t, "/boot", pt.Partitions[1].Payload.(*disk.Filesystem).Mountpoint)
+}
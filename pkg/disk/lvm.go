@@ -148,6 +148,46 @@ func (vg *LVMVolumeGroup) AlignUp(size uint64) uint64 {
 	return alignUp(size)
 }
 
+// growLogicalVolumes distributes the free space of the volume group, given
+// the size available to it, among its logical volumes: each one gets its
+// percentage of the free space and the growing one gets what is left, all
+// limited by their maximum sizes. The free space is the space that is not
+// used by the logical volumes before they grow.
+func (vg *LVMVolumeGroup) growLogicalVolumes(size uint64) {
+	if size <= vg.MetadataSize() {
+		return
+	}
+	available := alignDown(size-vg.MetadataSize(), LVMDefaultExtentSize)
+	var used uint64
+	for _, lv := range vg.LogicalVolumes {
+		used += lv.Size
+	}
+	if used >= available {
+		return
+	}
+	free := available - used
+
+	for idx := range vg.LogicalVolumes {
+		lv := &vg.LogicalVolumes[idx]
+		if lv.GrowPercent == 0 {
+			continue
+		}
+		share := alignDown(free*uint64(lv.GrowPercent)/100, LVMDefaultExtentSize)
+		used -= lv.Size
+		lv.Size = growSize(lv.Size, share, lv.MaxSize, LVMDefaultExtentSize)
+		used += lv.Size
+	}
+
+	for idx := range vg.LogicalVolumes {
+		lv := &vg.LogicalVolumes[idx]
+		if !lv.Grow {
+			continue
+		}
+		lv.Size = growSize(lv.Size, available-used, lv.MaxSize, LVMDefaultExtentSize)
+		break
+	}
+}
+
 func (vg *LVMVolumeGroup) MetadataSize() uint64 {
 	if vg == nil {
 		return 0
@@ -175,8 +215,20 @@ func (vg *LVMVolumeGroup) minSize(size uint64) uint64 {
 }
 
 type LVMLogicalVolume struct {
-	Name    string
-	Size    uint64
+	Name string
+	Size uint64
+
+	// Grow the logical volume to fill the free space of the volume group
+	// that is left after the other logical volumes have grown
+	Grow bool `json:",omitempty"`
+
+	// Percentage of the free space of the volume group to add to the size
+	// of the logical volume
+	GrowPercent uint `json:",omitempty"`
+
+	// Maximum size that the logical volume grows to, 0 for no limit
+	MaxSize uint64 `json:",omitempty"`
+
 	Payload Entity
 }
 
@@ -185,9 +237,12 @@ func (lv *LVMLogicalVolume) Clone() Entity {
 		return nil
 	}
 	return &LVMLogicalVolume{
-		Name:    lv.Name,
-		Size:    lv.Size,
-		Payload: lv.Payload.Clone(),
+		Name:        lv.Name,
+		Size:        lv.Size,
+		Grow:        lv.Grow,
+		GrowPercent: lv.GrowPercent,
+		MaxSize:     lv.MaxSize,
+		Payload:     lv.Payload.Clone(),
 	}
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 )
 
 type Partition struct {
@@ -17,6 +18,28 @@ type Partition struct {
 	// is just a string.
 	UUID string
 
+	// Name of the partition, also known as the partition label (GPT)
+	Name string `json:",omitempty"`
+
+	// Attribute bits (0-63) to set on the partition (GPT), e.g. 60 for
+	// read-only partitions of the discoverable partitions specification
+	Attrs []uint `json:",omitempty"`
+
+	// Keep the partition at the offset in Start when the partition table
+	// is laid out
+	FixedStart bool `json:",omitempty"`
+
+	// Grow the partition to fill the free space of the partition table
+	// instead of the partition with the root filesystem
+	Grow bool `json:",omitempty"`
+
+	// Percentage of the free space of the partition table to add to the
+	// size of the partition when it is laid out
+	GrowPercent uint `json:",omitempty"`
+
+	// Maximum size that the partition grows to, 0 for no limit
+	MaxSize uint64 `json:",omitempty"`
+
 	// If nil, the partition is raw; It doesn't contain a payload.
 	Payload PayloadEntity
 }
@@ -32,6 +55,13 @@ func (p *Partition) Clone() Entity {
 		Type:     p.Type,
 		Bootable: p.Bootable,
 		UUID:     p.UUID,
+		Name:     p.Name,
+		Attrs:    slices.Clone(p.Attrs),
+
+		FixedStart:  p.FixedStart,
+		Grow:        p.Grow,
+		GrowPercent: p.GrowPercent,
+		MaxSize:     p.MaxSize,
 	}
 
 	if p.Payload != nil {
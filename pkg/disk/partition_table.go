@@ -1,9 +1,13 @@
 package disk
 
 import (
+	"cmp"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 
@@ -22,6 +26,12 @@ type PartitionTable struct {
 	SectorSize   uint64 // Sector size in bytes
 	ExtraPadding uint64 // Extra space at the end of the partition table (sectors)
 	StartOffset  uint64 // Starting offset of the first partition in the table (Mb)
+
+	// Additional disks of the image, each with its own partition table.
+	// They are children of the partition table after its partitions, so
+	// their filesystems are part of the same tree, and each of them is
+	// written to a separate image file.
+	ExtraDisks []*PartitionTable `json:",omitempty"`
 }
 
 type PartitioningMode string
@@ -45,6 +55,13 @@ const (
 	DefaultPartitioningMode PartitioningMode = ""
 )
 
+// DefaultRequiredDirectorySizes are the minimum sizes of directories
+// that are used by NewPartitionTable if no sizes are given.
+var DefaultRequiredDirectorySizes = map[string]uint64{
+	"/":    1073741824,
+	"/usr": 2147483648,
+}
+
 // NewPartitionTable takes an existing base partition table and some parameters
 // and returns a new version of the base table modified to satisfy the
 // parameters.
@@ -142,10 +159,7 @@ func NewPartitionTable(basePT *PartitionTable, mountpoints []blueprint.Filesyste
 
 	// If no separate requiredSizes are given then we use our defaults
 	if requiredSizes == nil {
-		requiredSizes = map[string]uint64{
-			"/":    1073741824,
-			"/usr": 2147483648,
-		}
+		requiredSizes = DefaultRequiredDirectorySizes
 	}
 
 	if len(requiredSizes) != 0 {
@@ -191,6 +205,9 @@ func (pt *PartitionTable) Clone() Entity {
 
 		clone.Partitions[idx] = *part
 	}
+	for _, extra := range pt.ExtraDisks {
+		clone.ExtraDisks = append(clone.ExtraDisks, extra.Clone().(*PartitionTable))
+	}
 	return clone
 }
 
@@ -242,6 +259,10 @@ func (pt *PartitionTable) GenerateUUIDs(rng *rand.Rand) {
 	}
 	_ = pt.ForEachEntity(setuuid)
 
+	for _, extra := range pt.ExtraDisks {
+		extra.GenerateUUIDs(rng)
+	}
+
 	// if this is a MBR partition table, there is no need to generate
 	// uuids for the partitions themselves
 	if pt.Type != PT_GPT {
@@ -255,11 +276,20 @@ func (pt *PartitionTable) GenerateUUIDs(rng *rand.Rand) {
 	}
 }
 
+// DiskCount returns the number of disks, and image files, of the partition
+// table: the first disk and its additional disks.
+func (pt *PartitionTable) DiskCount() uint {
+	return 1 + uint(len(pt.ExtraDisks))
+}
+
 func (pt *PartitionTable) GetItemCount() uint {
-	return uint(len(pt.Partitions))
+	return uint(len(pt.Partitions) + len(pt.ExtraDisks))
 }
 
 func (pt *PartitionTable) GetChild(n uint) Entity {
+	if n >= uint(len(pt.Partitions)) {
+		return pt.ExtraDisks[n-uint(len(pt.Partitions))]
+	}
 	return &pt.Partitions[n]
 }
 
@@ -419,6 +449,16 @@ func (pt *PartitionTable) HeaderSize() uint64 {
 	return header
 }
 
+// footerSize returns the size of the space at the end of the partition table
+// that is reserved for the secondary GPT header.
+func (pt *PartitionTable) footerSize() uint64 {
+	// The GPT header is also at the end of the partition table
+	if pt.Type == PT_GPT {
+		return pt.HeaderSize()
+	}
+	return 0
+}
+
 // Apply filesystem customization to the partition table. If create is false,
 // the function will only apply customizations to existing partitions and
 // return a list of left-over mountpoints (i.e. mountpoints in the input that
@@ -447,51 +487,58 @@ func (pt *PartitionTable) applyCustomization(mountpoints []blueprint.FilesystemC
 
 // Dynamically calculate and update the start point for each of the existing
 // partitions. Adjusts the overall size of image to either the supplied value
-// in `size` or to the sum of all partitions if that is larger. Will grow the
-// root partition if there is any empty space. Returns the updated start point.
+// in `size` or to the sum of all partitions if that is larger. Partitions with
+// a growth percentage get their share of the free space and the root
+// partition, or the partition that is marked to grow, fills the rest. On
+// partition tables without a root filesystem, like those of additional disks,
+// the last partition fills the rest. Returns the updated start point.
 func (pt *PartitionTable) relayout(size uint64) uint64 {
 	// always reserve one extra sector for the GPT header
 	header := pt.HeaderSize()
-	footer := uint64(0)
-
-	// The GPT header is also at the end of the partition table
-	if pt.Type == PT_GPT {
-		footer = header
-	}
+	footer := pt.footerSize()
 
-	start := pt.AlignUp(header)
-	start += pt.StartOffset
+	first := pt.AlignUp(header)
+	first += pt.StartOffset
 	size = pt.AlignUp(size)
 
-	var rootIdx = -1
-	for idx := range pt.Partitions {
-		partition := &pt.Partitions[idx]
-		if len(entityPath(partition, "/")) != 0 {
-			// keep the root partition index to handle after all the other
-			// partitions have been moved and resized
-			rootIdx = idx
-			continue
+	// The partition that grows to fill the partition table is either the
+	// one that is explicitly marked, or the root partition which is then
+	// moved to the end of the partition table
+	growIdx := slices.IndexFunc(pt.Partitions, func(p Partition) bool { return p.Grow })
+	order := make([]int, 0, len(pt.Partitions))
+	if growIdx < 0 {
+		for idx := range pt.Partitions {
+			if len(entityPath(&pt.Partitions[idx], "/")) != 0 {
+				// keep the root partition index to handle after all the
+				// other partitions have been moved and resized
+				growIdx = idx
+				continue
+			}
+			order = append(order, idx)
+		}
+		if growIdx < 0 {
+			if len(order) == 0 {
+				panic("no partitions in the partition table; this is a programming error")
+			}
+			growIdx = order[len(order)-1]
+			order = order[:len(order)-1]
+		}
+		order = append(order, growIdx)
+	} else {
+		for idx := range pt.Partitions {
+			order = append(order, idx)
 		}
-		partition.Start = start
-		partition.fitTo(partition.Size)
-		partition.Size = pt.AlignUp(partition.Size)
-		start += partition.Size
-	}
-
-	if rootIdx < 0 {
-		panic("no root filesystem found; this is a programming error")
 	}
 
-	root := &pt.Partitions[rootIdx]
-	root.Start = start
-	root.fitTo(root.Size)
+	// Lay out all partitions with their minimum sizes
+	end := pt.layoutPartitions(first, order, growIdx)
 
 	// add the extra padding specified in the partition table
 	footer += pt.ExtraPadding
 
 	// If the sum of all partitions is bigger then the specified size,
 	// we use that instead. Grow the partition table size if needed.
-	end := pt.AlignUp(root.Start + footer + root.Size)
+	end = pt.AlignUp(end + footer)
 	if end > size {
 		size = end
 	}
@@ -500,14 +547,166 @@ func (pt *PartitionTable) relayout(size uint64) uint64 {
 		pt.Size = size
 	}
 
-	// If there is space left in the partition table, grow root
-	root.Size = pt.Size - root.Start
+	// Give the partitions with a growth percentage their share of the free
+	// space and lay them out again
+	if pt.growPartitions(pt.Size - end) {
+		pt.layoutPartitions(first, order, growIdx)
+	}
+
+	// Move the partitions after the growing one to the end of the
+	// partition table, leaving space for the footer, e.g. the secondary GPT
+	// header.
+	tail := pt.Size - footer
+	for pos := len(order) - 1; order[pos] != growIdx; pos-- {
+		partition := &pt.Partitions[order[pos]]
+		if !partition.FixedStart {
+			partition.Start = alignDown(tail-partition.Size, DefaultGrainBytes)
+		}
+		tail = partition.Start
+	}
+
+	// If there is space left in the partition table, grow the partition,
+	// unless it only grows by its percentage
+	grow := &pt.Partitions[growIdx]
+	if grow.GrowPercent == 0 && tail > grow.Start+grow.Size {
+		grow.Size = growSize(grow.Size, tail-grow.Start-grow.Size, grow.MaxSize, DefaultGrainBytes)
+	}
+
+	return grow.Start
+}
+
+// layoutPartitions places the partitions in the given order one after the
+// other from the first usable offset with their current sizes and returns the
+// end of the last one. The sizes of all but the growing partition are aligned.
+func (pt *PartitionTable) layoutPartitions(first uint64, order []int, growIdx int) uint64 {
+	start := first
+	var end uint64
+	for _, idx := range order {
+		partition := &pt.Partitions[idx]
+		if partition.FixedStart {
+			start = partition.Start
+		}
+		partition.Start = start
+		partition.fitTo(partition.Size)
+		if idx == growIdx {
+			// the growing partition is resized at the end, the
+			// partitions after it start at the next aligned offset
+			end = max(end, partition.Start+partition.Size)
+			start = pt.AlignUp(partition.Start + partition.Size)
+			continue
+		}
+		partition.Size = pt.AlignUp(partition.Size)
+		start += partition.Size
+		end = max(end, start)
+	}
+	return end
+}
+
+// growPartitions adds the percentage of the free space to each partition that
+// has a growth percentage, limited by its maximum size. Returns true if any
+// partition grew.
+func (pt *PartitionTable) growPartitions(free uint64) bool {
+	var grown bool
+	for idx := range pt.Partitions {
+		partition := &pt.Partitions[idx]
+		if partition.GrowPercent == 0 {
+			continue
+		}
+		share := alignDown(free*uint64(partition.GrowPercent)/100, DefaultGrainBytes)
+		newSize := growSize(partition.Size, share, partition.MaxSize, DefaultGrainBytes)
+		if newSize != partition.Size {
+			partition.Size = newSize
+			grown = true
+		}
+	}
+	return grown
+}
+
+// growLogicalVolumes distributes the free space of each LVM volume group, in
+// a LUKS container or directly on the partition, among its
+// logical volumes. It must be called after the partitions are laid out.
+func (pt *PartitionTable) growLogicalVolumes() {
+	for idx := range pt.Partitions {
+		size := pt.Partitions[idx].Size
+		payload := Entity(pt.Partitions[idx].Payload)
+		for payload != nil {
+			switch ent := payload.(type) {
+			case *LUKSContainer:
+				size -= min(size, ent.MetadataSize())
+				payload = ent.Payload
+			case *LVMVolumeGroup:
+				ent.growLogicalVolumes(size)
+				payload = nil
+			default:
+				payload = nil
+			}
+		}
+	}
+}
+
+// checkLayout returns an error if a partition starts before the first usable
+// offset, ends after the last usable offset, i.e. in the secondary GPT
+// header, or if partitions overlap. Partitions with a fixed start offset can
+// violate any of these after relayout().
+func (pt *PartitionTable) checkLayout() error {
+	first := pt.AlignUp(pt.HeaderSize()) + pt.StartOffset
+	last := pt.Size - min(pt.Size, pt.footerSize())
+	partitions := make([]*Partition, len(pt.Partitions))
+	for idx := range pt.Partitions {
+		partitions[idx] = &pt.Partitions[idx]
+	}
+	slices.SortStableFunc(partitions, func(a, b *Partition) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+
+	for idx, partition := range partitions {
+		if partition.Start < first {
+			return fmt.Errorf("partition at offset %d starts before the first usable offset %d", partition.Start, first)
+		}
+		if partition.Start+partition.Size > last {
+			return fmt.Errorf("partition at offset %d ends after the last usable offset %d", partition.Start, last)
+		}
+		if idx > 0 {
+			prev := partitions[idx-1]
+			if prev.Start+prev.Size > partition.Start {
+				return fmt.Errorf("partition at offset %d overlaps with partition at offset %d", partition.Start, prev.Start)
+			}
+		}
+	}
+	return nil
+}
+
+// reorder moves the partitions at the indices in positions to the given
+// partition numbers (starting at 1). The other partitions keep their order
+// and fill the remaining numbers.
+func (pt *PartitionTable) reorder(positions map[int]uint) error {
+	if len(positions) == 0 {
+		return nil
+	}
 
-	// Finally we shrink the last partition, i.e. the root partition,
-	// to leave space for the footer, e.g. the secondary GPT header.
-	root.Size -= footer
+	partitions := make([]Partition, len(pt.Partitions))
+	placed := make([]bool, len(pt.Partitions))
+	for idx, pos := range positions {
+		if pos > uint(len(pt.Partitions)) {
+			return fmt.Errorf("partition position %d is out of range: the partition table has %d partitions", pos, len(pt.Partitions))
+		}
+		partitions[pos-1] = pt.Partitions[idx]
+		placed[pos-1] = true
+	}
 
-	return start
+	next := 0
+	for idx := range pt.Partitions {
+		if _, ok := positions[idx]; ok {
+			continue
+		}
+		for placed[next] {
+			next++
+		}
+		partitions[next] = pt.Partitions[idx]
+		placed[next] = true
+	}
+	pt.Partitions = partitions
+	return nil
 }
 
 func (pt *PartitionTable) createFilesystem(mountpoint string, size uint64) error {
@@ -660,7 +859,8 @@ func alignEntityBranch(path []Entity, size uint64) uint64 {
 
 // resizeEntityBranch resizes the first entity in the specified path to be at
 // least the specified size and then grows every entity up the path to the
-// PartitionTable accordingly.
+// PartitionTable accordingly. The partition table of an additional disk does
+// not change the size of its parent.
 func resizeEntityBranch(path []Entity, size uint64) {
 	if len(path) == 0 {
 		return
@@ -671,7 +871,12 @@ func resizeEntityBranch(path []Entity, size uint64) {
 	if c, ok := element.(Container); ok {
 		containerSize := uint64(0)
 		for idx := uint(0); idx < c.GetItemCount(); idx++ {
-			if s, ok := c.GetChild(idx).(Sizeable); ok {
+			child := c.GetChild(idx)
+			if _, ok := child.(*PartitionTable); ok {
+				// additional disks are not part of the size
+				continue
+			}
+			if s, ok := child.(Sizeable); ok {
 				containerSize += s.GetSize()
 			} else {
 				break
@@ -696,9 +901,29 @@ func resizeEntityBranch(path []Entity, size uint64) {
 			return
 		}
 	}
+	if _, ok := element.(*PartitionTable); ok {
+		return
+	}
 	resizeEntityBranch(path[1:], size)
 }
 
+// growSize returns the size of an entity that grows by the given amount but
+// not beyond its maximum size (0 for no limit). The maximum size is aligned
+// down to the given alignment and never shrinks the entity below its current
+// size.
+func growSize(size, growth, maxSize, alignment uint64) uint64 {
+	size += growth
+	if maxSize != 0 && size > maxSize {
+		size = max(size-growth, alignDown(maxSize, alignment))
+	}
+	return size
+}
+
+// alignDown aligns the size down to a multiple of the alignment.
+func alignDown(size, alignment uint64) uint64 {
+	return size / alignment * alignment
+}
+
 // GenUUID generates and sets UUIDs for all Partitions in the PartitionTable if
 // the layout is GPT.
 func (pt *PartitionTable) GenUUID(rng *rand.Rand) {
@@ -942,6 +1167,9 @@ func (pt *PartitionTable) GetMountpointSize(mountpoint string) (uint64, error) {
 //   - The first Btrfs volume if one exists, otherwise
 //   - At the end of the plain partitions.
 //
+// Volume groups and btrfs volumes inside of LUKS containers are taken into
+// account as well.
+//
 // For LVM and Plain, the fsType argument must be a valid filesystem type.
 func EnsureRootFilesystem(pt *PartitionTable, defaultFsType FSType, architecture arch.Arch) error {
 	// collect all labels and subvolume names to avoid conflicts
@@ -967,7 +1195,12 @@ func EnsureRootFilesystem(pt *PartitionTable, defaultFsType FSType, architecture
 	}
 
 	for _, part := range pt.Partitions {
-		switch payload := part.Payload.(type) {
+		var payload Entity = part.Payload
+		// look into encrypted volumes as well
+		if luks, ok := payload.(*LUKSContainer); ok {
+			payload = luks.Payload
+		}
+		switch payload := payload.(type) {
 		case *LVMVolumeGroup:
 			if defaultFsType == FS_NONE {
 				return fmt.Errorf("error creating root logical volume: no default filesystem type")
@@ -1271,35 +1504,100 @@ func NewCustomPartitionTable(customizations *blueprint.DiskCustomization, option
 		return nil, fmt.Errorf("%s %w", errPrefix, err)
 	}
 	// add user customized partitions
-	for _, part := range customizations.Partitions {
+	positions, err := addCustomPartitions(pt, customizations.Partitions, options)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", errPrefix, err)
+	}
+
+	// add the additional disks with their partitions, they are laid out
+	// after the directory sizes are applied to all disks
+	extraPositions := make([]map[int]uint, len(customizations.Disks))
+	for idx, diskCustomization := range customizations.Disks {
+		extra := &PartitionTable{Type: pt.Type}
+		switch diskCustomization.Type {
+		case "dos":
+			extra.Type = PT_DOS
+		case "gpt":
+			extra.Type = PT_GPT
+		}
+		extraPositions[idx], err = addCustomPartitions(extra, diskCustomization.Partitions, options)
+		if err != nil {
+			return nil, fmt.Errorf("%s additional disk %d: %w", errPrefix, idx+1, err)
+		}
+		pt.ExtraDisks = append(pt.ExtraDisks, extra)
+	}
+
+	if err := EnsureRootFilesystem(pt, options.DefaultFSType, options.Architecture); err != nil {
+		return nil, fmt.Errorf("%s %w", errPrefix, err)
+	}
+
+	if len(options.RequiredMinSizes) != 0 {
+		pt.EnsureDirectorySizes(options.RequiredMinSizes)
+	}
+
+	if err := pt.layoutCustomPartitions(positions, customizations.MinSize); err != nil {
+		return nil, fmt.Errorf("%s %w", errPrefix, err)
+	}
+	for idx, extra := range pt.ExtraDisks {
+		if err := extra.layoutCustomPartitions(extraPositions[idx], customizations.Disks[idx].MinSize); err != nil {
+			return nil, fmt.Errorf("%s additional disk %d: %w", errPrefix, idx+1, err)
+		}
+	}
+	pt.GenerateUUIDs(rng)
+
+	return pt, nil
+}
+
+// addCustomPartitions adds a partition for each of the partition
+// customizations to the partition table. Returns the requested partition
+// positions by index of the new partitions.
+func addCustomPartitions(pt *PartitionTable, partitions []blueprint.PartitionCustomization, options *CustomPartitionTableOptions) (map[int]uint, error) {
+	positions := make(map[int]uint)
+	for _, part := range partitions {
 		switch part.Type {
 		case "plain", "":
 			if err := addPlainPartition(pt, part, options); err != nil {
-				return nil, fmt.Errorf("%s %w", errPrefix, err)
+				return nil, err
 			}
 		case "lvm":
 			if err := addLVMPartition(pt, part, options); err != nil {
-				return nil, fmt.Errorf("%s %w", errPrefix, err)
+				return nil, err
 			}
 		case "btrfs":
-			if err := addBtrfsPartition(pt, part); err != nil {
-				return nil, fmt.Errorf("%s %w", errPrefix, err)
+			if err := addBtrfsPartition(pt, part, options); err != nil {
+				return nil, err
+			}
+		case "raw", "none":
+			if err := addRawPartition(pt, part, options); err != nil {
+				return nil, err
 			}
 		default:
-			return nil, fmt.Errorf("%s invalid partition type: %s", errPrefix, part.Type)
+			return nil, fmt.Errorf("invalid partition type: %s", part.Type)
 		}
-	}
 
-	if err := EnsureRootFilesystem(pt, options.DefaultFSType, options.Architecture); err != nil {
-		return nil, fmt.Errorf("%s %w", errPrefix, err)
+		// each customization adds exactly one partition
+		newIdx := len(pt.Partitions) - 1
+		setPartitionLayout(&pt.Partitions[newIdx], part.PartitionLayoutCustomization, part.GrowthCustomization)
+		if part.Position != 0 {
+			positions[newIdx] = part.Position
+		}
 	}
+	return positions, nil
+}
 
-	if len(options.RequiredMinSizes) != 0 {
-		pt.EnsureDirectorySizes(options.RequiredMinSizes)
+// layoutCustomPartitions moves the partitions to their requested positions,
+// lays them out on a disk of at least the given size and grows the logical
+// volumes.
+func (pt *PartitionTable) layoutCustomPartitions(positions map[int]uint, minSize uint64) error {
+	if err := pt.reorder(positions); err != nil {
+		return err
 	}
 
-	pt.relayout(customizations.MinSize)
-	pt.GenerateUUIDs(rng)
+	pt.relayout(minSize)
+	if err := pt.checkLayout(); err != nil {
+		return fmt.Errorf("invalid partition table: %w", err)
+	}
+	pt.growLogicalVolumes()
 
 	// One thing not caught by the customization validation is if a final "dos"
 	// partition table has more than 4 partitions. This is not possible to
@@ -1308,10 +1606,9 @@ func NewCustomPartitionTable(customizations *blueprint.DiskCustomization, option
 	// creation. We should therefore always check the final partition table for
 	// this rule.
 	if pt.Type == PT_DOS && len(pt.Partitions) > 4 {
-		return nil, fmt.Errorf("%s invalid partition table: \"dos\" partition table type only supports up to 4 partitions: got %d after creating the partition table with all necessary partitions", errPrefix, len(pt.Partitions))
+		return fmt.Errorf("invalid partition table: \"dos\" partition table type only supports up to 4 partitions: got %d after creating the partition table with all necessary partitions", len(pt.Partitions))
 	}
-
-	return pt, nil
+	return nil
 }
 
 func addPlainPartition(pt *PartitionTable, partition blueprint.PartitionCustomization, options *CustomPartitionTableOptions) error {
@@ -1356,15 +1653,106 @@ func addPlainPartition(pt *PartitionTable, partition blueprint.PartitionCustomiz
 		}
 	}
 
+	payload, err = maybeEncrypt(partition, payload)
+	if err != nil {
+		return fmt.Errorf("error creating partition with mountpoint %q: %w", partition.Mountpoint, err)
+	}
+
 	newpart := Partition{
 		Type:    partType,
 		Size:    partition.MinSize,
 		Payload: payload,
 	}
+	if err := setPartitionEntry(&newpart, pt.Type, partition.PartitionEntryCustomization, options.Architecture); err != nil {
+		return fmt.Errorf("error creating partition with mountpoint %q: %w", partition.Mountpoint, err)
+	}
+	pt.Partitions = append(pt.Partitions, newpart)
+	return nil
+}
+
+// addRawPartition adds a partition without a payload. The partition is left
+// unformatted and is not mounted or added to the fstab. Unless a type is set
+// in the customization, it gets the generic linux data type.
+func addRawPartition(pt *PartitionTable, partition blueprint.PartitionCustomization, options *CustomPartitionTableOptions) error {
+	partType, err := getPartitionTypeIDfor(pt.Type, "data", options.Architecture)
+	if err != nil {
+		return fmt.Errorf("error getting partition type ID for raw partition: %w", err)
+	}
+
+	newpart := Partition{
+		Type: partType,
+		Size: partition.MinSize,
+	}
+	if err := setPartitionEntry(&newpart, pt.Type, partition.PartitionEntryCustomization, options.Architecture); err != nil {
+		return fmt.Errorf("error creating raw partition: %w", err)
+	}
 	pt.Partitions = append(pt.Partitions, newpart)
 	return nil
 }
 
+// setPartitionLayout applies the start offset and growth of the customizations
+// to the partition. The position is applied by reorder() after all partitions
+// are added.
+func setPartitionLayout(part *Partition, layout blueprint.PartitionLayoutCustomization, growth blueprint.GrowthCustomization) {
+	if layout.Start != 0 {
+		part.Start = layout.Start
+		part.FixedStart = true
+	}
+	part.Grow = layout.Grow
+	part.GrowPercent = growth.GrowPercent
+	part.MaxSize = growth.MaxSize
+}
+
+// setPartitionEntry applies the partition type, name and attributes of the
+// customization to the partition
+func setPartitionEntry(part *Partition, ptType PartitionTableType, entry blueprint.PartitionEntryCustomization, architecture arch.Arch) error {
+	if entry.PartType != "" {
+		partType, err := partitionTypeFromCustomization(ptType, entry.PartType, architecture)
+		if err != nil {
+			return err
+		}
+		part.Type = partType
+	}
+
+	if ptType != PT_GPT && (entry.PartLabel != "" || len(entry.PartAttrs) > 0) {
+		return fmt.Errorf("partition labels and attributes are only supported on gpt partition tables")
+	}
+	attrs, err := entry.GPTAttributeBits()
+	if err != nil {
+		return err
+	}
+	part.Name = entry.PartLabel
+	part.Attrs = attrs
+	return nil
+}
+
+// partitionTypeFromCustomization returns the partition type ID for a type
+// given as a GUID or hex ID or by name, see getPartitionTypeIDfor()
+func partitionTypeFromCustomization(ptType PartitionTableType, partType string, architecture arch.Arch) (string, error) {
+	switch ptType {
+	case PT_GPT:
+		if guid, err := uuid.Parse(partType); err == nil {
+			return strings.ToUpper(guid.String()), nil
+		}
+	case PT_DOS:
+		if len(partType) == 2 {
+			if _, err := hex.DecodeString(partType); err == nil {
+				return strings.ToLower(partType), nil
+			}
+		}
+	}
+	return getPartitionTypeIDfor(ptType, partType, architecture)
+}
+
+// maybeEncrypt wraps the payload in a LUKS container if the partition
+// customization requests encryption
+func maybeEncrypt(partition blueprint.PartitionCustomization, payload PayloadEntity) (PayloadEntity, error) {
+	if partition.Encryption == nil {
+		return payload, nil
+	}
+	return newLUKSContainer(partition.Encryption, payload)
+}
+
 func addLVMPartition(pt *PartitionTable, partition blueprint.PartitionCustomization, options *CustomPartitionTableOptions) error {
 	vgname := partition.Name
 	if vgname == "" {
@@ -1413,9 +1801,13 @@ func addLVMPartition(pt *PartitionTable, partition blueprint.PartitionCustomizat
 				FSTabOptions: "defaults", // TODO: add customization
 			}
 		}
-		if _, err := newvg.CreateLogicalVolume(lv.Name, lv.MinSize, newfs); err != nil {
+		newlv, err := newvg.CreateLogicalVolume(lv.Name, lv.MinSize, newfs)
+		if err != nil {
 			return fmt.Errorf("error creating logical volume %q (%s): %w", lv.Name, lv.Mountpoint, err)
 		}
+		newlv.Grow = lv.Grow
+		newlv.GrowPercent = lv.GrowPercent
+		newlv.MaxSize = lv.MaxSize
 	}
 
 	// create partition for volume group
@@ -1423,17 +1815,24 @@ func addLVMPartition(pt *PartitionTable, partition blueprint.PartitionCustomizat
 	if err != nil {
 		return fmt.Errorf("error creating lvm partition %q: %w", vgname, err)
 	}
+	payload, err := maybeEncrypt(partition, newvg)
+	if err != nil {
+		return fmt.Errorf("error creating lvm partition %q: %w", vgname, err)
+	}
 	newpart := Partition{
 		Type:     partType,
 		Size:     partition.MinSize,
 		Bootable: false,
-		Payload:  newvg,
+		Payload:  payload,
+	}
+	if err := setPartitionEntry(&newpart, pt.Type, partition.PartitionEntryCustomization, options.Architecture); err != nil {
+		return fmt.Errorf("error creating lvm partition %q: %w", vgname, err)
 	}
 	pt.Partitions = append(pt.Partitions, newpart)
 	return nil
 }
 
-func addBtrfsPartition(pt *PartitionTable, partition blueprint.PartitionCustomization) error {
+func addBtrfsPartition(pt *PartitionTable, partition blueprint.PartitionCustomization, options *CustomPartitionTableOptions) error {
 	subvols := make([]BtrfsSubvolume, len(partition.Subvolumes))
 	for idx, subvol := range partition.Subvolumes {
 		newsubvol := BtrfsSubvolume{
@@ -1452,12 +1851,19 @@ func addBtrfsPartition(pt *PartitionTable, partition blueprint.PartitionCustomiz
 	if err != nil {
 		return fmt.Errorf("error creating btrfs partition: %w", err)
 	}
+	payload, err := maybeEncrypt(partition, newvol)
+	if err != nil {
+		return fmt.Errorf("error creating btrfs partition: %w", err)
+	}
 	newpart := Partition{
 		Type:     partType,
 		Bootable: false,
-		Payload:  newvol,
+		Payload:  payload,
 		Size:     partition.MinSize,
 	}
+	if err := setPartitionEntry(&newpart, pt.Type, partition.PartitionEntryCustomization, options.Architecture); err != nil {
+		return fmt.Errorf("error creating btrfs partition: %w", err)
+	}
 
 	pt.Partitions = append(pt.Partitions, newpart)
 	return nil
@@ -1466,6 +1872,7 @@ func addBtrfsPartition(pt *PartitionTable, partition blueprint.PartitionCustomiz
 // Determine if a boot partition is needed based on the customizations. A boot
 // partition is needed if any of the following conditions apply:
 //   - / is on LVM or btrfs and /boot is not defined.
+//   - / is on an encrypted plain partition and /boot is not defined.
 //   - / is not defined and btrfs or lvm volumes are defined.
 //
 // In the second case, a root partition will be created automatically on either
@@ -1475,12 +1882,16 @@ func needsBoot(disk *blueprint.DiskCustomization) bool {
 		return false
 	}
 
-	var foundBtrfsOrLVM bool
+	var foundBtrfsOrLVM, encryptedRoot bool
 	for _, part := range disk.Partitions {
 		switch part.Type {
 		case "plain", "":
 			if part.Mountpoint == "/" {
-				return false
+				if part.Encryption == nil {
+					return false
+				}
+				// the bootloader cannot read an encrypted root
+				encryptedRoot = true
 			}
 			if part.Mountpoint == "/boot" {
 				return false
@@ -1505,5 +1916,5 @@ func needsBoot(disk *blueprint.DiskCustomization) bool {
 			// NOTE: invalid types should be validated elsewhere
 		}
 	}
-	return foundBtrfsOrLVM
+	return foundBtrfsOrLVM || encryptedRoot
 }
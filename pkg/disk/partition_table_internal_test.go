@@ -273,6 +273,271 @@ func TestRelayout(t *testing.T) {
 				},
 			},
 		},
+		"dos-fixed-start": {
+			pt: &PartitionTable{
+				Type: PT_DOS,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Start:      4 * MiB,
+						FixedStart: true,
+						Size:       10 * MiB,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Size: 20 * MiB,
+					},
+				},
+			},
+			size: 100 * MiB,
+			expected: &PartitionTable{
+				Type: PT_DOS,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Start:      4 * MiB, // kept at the fixed offset
+						FixedStart: true,
+						Size:       10 * MiB,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Start: 14 * MiB,
+						Size:  86 * MiB,
+					},
+				},
+			},
+		},
+		"dos-grow-middle": {
+			pt: &PartitionTable{
+				Type: PT_DOS,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Size: 10 * MiB,
+					},
+					{
+						Size: 20 * MiB,
+						Grow: true,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Size: 20 * MiB,
+					},
+				},
+			},
+			size: 100 * MiB,
+			expected: &PartitionTable{
+				Type: PT_DOS,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Start: 1 * MiB,
+						Size:  10 * MiB,
+					},
+					{
+						Start: 11 * MiB,
+						Size:  69 * MiB, // grows to fill the space up to the root partition
+						Grow:  true,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Start: 80 * MiB, // moved to the end of the partition table
+						Size:  20 * MiB,
+					},
+				},
+			},
+		},
+		"gpt-grow-middle-fixed-tail": {
+			pt: &PartitionTable{
+				Type: PT_GPT,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Size: 10 * MiB,
+					},
+					{
+						Size: 20 * MiB,
+						Grow: true,
+					},
+					{
+						Start:      60 * MiB,
+						FixedStart: true,
+						Size:       10 * MiB,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Size: 20 * MiB,
+					},
+				},
+			},
+			size: 100 * MiB,
+			expected: &PartitionTable{
+				Type: PT_GPT,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Start: 1 * MiB,
+						Size:  10 * MiB,
+					},
+					{
+						Start: 11 * MiB,
+						Size:  49 * MiB, // grows up to the partition with the fixed start
+						Grow:  true,
+					},
+					{
+						Start:      60 * MiB,
+						FixedStart: true,
+						Size:       10 * MiB,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Start: 79 * MiB, // moved to the end, aligned down to leave space for the secondary GPT header
+						Size:  20 * MiB,
+					},
+				},
+			},
+		},
+		"gpt-grow-percent": {
+			pt: &PartitionTable{
+				Type: PT_GPT,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Size: 10 * MiB,
+					},
+					{
+						Size:        10 * MiB,
+						GrowPercent: 40,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Size: 20 * MiB,
+					},
+				},
+			},
+			size: 100 * MiB,
+			expected: &PartitionTable{
+				Type: PT_GPT,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Start: 1 * MiB,
+						Size:  10 * MiB,
+					},
+					{
+						Start:       11 * MiB,
+						Size:        33 * MiB, // 40% of the free 58 MiB (100 MiB - 42 MiB), aligned down
+						GrowPercent: 40,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Start: 44 * MiB,
+						Size:  56*MiB - (DefaultSectorSize + (128 * 128)), // fills the rest
+					},
+				},
+			},
+		},
+		"gpt-grow-percent-max-size": {
+			pt: &PartitionTable{
+				Type: PT_GPT,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Size: 10 * MiB,
+					},
+					{
+						Size:        10 * MiB,
+						GrowPercent: 40,
+						MaxSize:     15*MiB + 512, // aligned down
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Size:    20 * MiB,
+						MaxSize: 50 * MiB,
+					},
+				},
+			},
+			size: 100 * MiB,
+			expected: &PartitionTable{
+				Type: PT_GPT,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Start: 1 * MiB,
+						Size:  10 * MiB,
+					},
+					{
+						Start:       11 * MiB,
+						Size:        15 * MiB,
+						GrowPercent: 40,
+						MaxSize:     15*MiB + 512,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Start:   26 * MiB,
+						Size:    50 * MiB, // does not fill the partition table
+						MaxSize: 50 * MiB,
+					},
+				},
+			},
+		},
+		"gpt-grow-percent-root": {
+			pt: &PartitionTable{
+				Type: PT_GPT,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Size: 10 * MiB,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Size:        20 * MiB,
+						GrowPercent: 50,
+					},
+				},
+			},
+			size: 100 * MiB,
+			expected: &PartitionTable{
+				Type: PT_GPT,
+				Size: 100 * MiB,
+				Partitions: []Partition{
+					{
+						Start: 1 * MiB,
+						Size:  10 * MiB,
+					},
+					{
+						Payload: &Filesystem{
+							Mountpoint: "/",
+						},
+						Start:       11 * MiB,
+						Size:        54 * MiB, // only grows by 50% of the free 68 MiB (100 MiB - 32 MiB)
+						GrowPercent: 50,
+					},
+				},
+			},
+		},
 		"simple-gpt": {
 			pt: &PartitionTable{
 				Type: PT_GPT,
@@ -800,3 +1065,168 @@ func TestRelayout(t *testing.T) {
 		})
 	}
 }
+
+func TestGrowLogicalVolumes(t *testing.T) {
+	newVG := func() *LVMVolumeGroup {
+		return &LVMVolumeGroup{
+			Name: "vg",
+			LogicalVolumes: []LVMLogicalVolume{
+				{
+					Name: "rootlv",
+					Size: 200 * MiB,
+					Grow: true,
+				},
+				{
+					Name:        "homelv",
+					Size:        100 * MiB,
+					GrowPercent: 50,
+				},
+				{
+					Name:        "varlv",
+					Size:        100 * MiB,
+					GrowPercent: 25,
+					MaxSize:     202 * MiB, // aligned down to the extent size
+				},
+				{
+					Name: "swaplv",
+					Size: 24 * MiB,
+				},
+			},
+		}
+	}
+
+	// the volume group has 1024 MiB for logical volumes, 600 MiB of which
+	// are free
+	expected := []uint64{400 * MiB, 400 * MiB, 200 * MiB, 24 * MiB}
+
+	sizes := func(vg *LVMVolumeGroup) []uint64 {
+		var sizes []uint64
+		for _, lv := range vg.LogicalVolumes {
+			sizes = append(sizes, lv.Size)
+		}
+		return sizes
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		vg := newVG()
+		pt := &PartitionTable{
+			Partitions: []Partition{
+				{Size: 1025 * MiB, Payload: vg},
+			},
+		}
+		pt.growLogicalVolumes()
+		assert.Equal(t, expected, sizes(vg))
+	})
+
+	t.Run("luks", func(t *testing.T) {
+		vg := newVG()
+		pt := &PartitionTable{
+			Partitions: []Partition{
+				{Size: 1041 * MiB, Payload: &LUKSContainer{Payload: vg}},
+			},
+		}
+		pt.growLogicalVolumes()
+		assert.Equal(t, expected, sizes(vg))
+	})
+
+	t.Run("full", func(t *testing.T) {
+		vg := newVG()
+		pt := &PartitionTable{
+			Partitions: []Partition{
+				{Size: 400 * MiB, Payload: vg},
+			},
+		}
+		pt.growLogicalVolumes()
+		assert.Equal(t, []uint64{200 * MiB, 100 * MiB, 100 * MiB, 24 * MiB}, sizes(vg))
+	})
+}
+
+func TestGrowSize(t *testing.T) {
+	assert.Equal(t, uint64(30*MiB), growSize(10*MiB, 20*MiB, 0, MiB))
+	assert.Equal(t, uint64(25*MiB), growSize(10*MiB, 20*MiB, 25*MiB+1, MiB))
+	// the maximum size does not shrink the entity
+	assert.Equal(t, uint64(10*MiB), growSize(10*MiB, 20*MiB, 5*MiB, MiB))
+}
+
+func TestCheckLayout(t *testing.T) {
+	type testCase struct {
+		partitions []Partition
+		errmsg     string
+	}
+
+	testCases := map[string]testCase{
+		"ok": {
+			partitions: []Partition{
+				{Start: 1 * MiB, Size: 10 * MiB},
+				{Start: 20 * MiB, Size: 10 * MiB},
+				{Start: 11 * MiB, Size: 9 * MiB},
+			},
+		},
+		"overlap": {
+			partitions: []Partition{
+				{Start: 1 * MiB, Size: 10 * MiB},
+				{Start: 10 * MiB, Size: 10 * MiB},
+			},
+			errmsg: "partition at offset 10485760 overlaps with partition at offset 1048576",
+		},
+		"before-header": {
+			partitions: []Partition{
+				{Start: 0, Size: 10 * MiB},
+			},
+			errmsg: "partition at offset 0 starts before the first usable offset 1048576",
+		},
+		"end-of-disk": {
+			partitions: []Partition{
+				{Start: 1 * MiB, Size: 10 * MiB},
+				{Start: 90 * MiB, Size: 10 * MiB},
+			},
+			// the secondary GPT header takes the last 33 sectors
+			errmsg: "partition at offset 94371840 ends after the last usable offset 104840704",
+		},
+		"fills-disk": {
+			partitions: []Partition{
+				{Start: 1 * MiB, Size: 10 * MiB},
+				{Start: 90 * MiB, Size: 10*MiB - 33*512},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			pt := &PartitionTable{
+				Type:       PT_GPT,
+				Size:       100 * MiB,
+				Partitions: tc.partitions,
+			}
+			err := pt.checkLayout()
+			if tc.errmsg != "" {
+				assert.EqualError(t, err, tc.errmsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestReorder(t *testing.T) {
+	pt := &PartitionTable{
+		Partitions: []Partition{
+			{UUID: "a"},
+			{UUID: "b"},
+			{UUID: "c"},
+			{UUID: "d"},
+		},
+	}
+
+	err := pt.reorder(map[int]uint{3: 1, 1: 4})
+	assert.NoError(t, err)
+
+	var uuids []string
+	for _, part := range pt.Partitions {
+		uuids = append(uuids, part.UUID)
+	}
+	assert.Equal(t, []string{"d", "a", "c", "b"}, uuids)
+
+	err = pt.reorder(map[int]uint{0: 5})
+	assert.EqualError(t, err, "partition position 5 is out of range: the partition table has 4 partitions")
+}
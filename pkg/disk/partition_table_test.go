@@ -2651,6 +2651,54 @@ func TestNewCustomPartitionTableErrors(t *testing.T) {
 			},
 			errmsg: `error generating partition table: invalid partition table: "dos" partition table type only supports up to 4 partitions: got 5 after creating the partition table with all necessary partitions`,
 		},
+		"overlapping-start": {
+			customizations: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type:    "raw",
+						MinSize: 20 * datasizes.MiB,
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Start: 10 * datasizes.MiB,
+						},
+					},
+					{
+						Type:    "raw",
+						MinSize: 20 * datasizes.MiB,
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Start: 20 * datasizes.MiB,
+						},
+					},
+				},
+			},
+			options: &disk.CustomPartitionTableOptions{
+				DefaultFSType: disk.FS_XFS,
+				BootMode:      platform.BOOT_LEGACY,
+				Architecture:  arch.ARCH_X86_64,
+			},
+			errmsg: "error generating partition table: invalid partition table: partition at offset 20971520 overlaps with partition at offset 10485760",
+		},
+		"position-out-of-range": {
+			customizations: &blueprint.DiskCustomization{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						MinSize: 1 * datasizes.GiB,
+						PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+							Position: 10,
+						},
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							Mountpoint: "/",
+							FSType:     "xfs",
+						},
+					},
+				},
+			},
+			options: &disk.CustomPartitionTableOptions{
+				DefaultFSType: disk.FS_XFS,
+				BootMode:      platform.BOOT_LEGACY,
+				Architecture:  arch.ARCH_X86_64,
+			},
+			errmsg: "error generating partition table: partition position 10 is out of range: the partition table has 2 partitions",
+		},
 	}
 
 	// we don't care about the rng for error tests
@@ -2668,6 +2716,454 @@ func TestNewCustomPartitionTableErrors(t *testing.T) {
 	}
 }
 
+func TestNewCustomPartitionTablePartitionEntries(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				MinSize: 2 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartType:  "home",
+					PartLabel: "home",
+					PartAttrs: []string{"no-auto", "60"},
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/home",
+					FSType:     "xfs",
+				},
+			},
+			{
+				MinSize: 1 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartType: "3b8f8425-20e0-4f3b-907f-1a25a76f98e8",
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/srv",
+					FSType:     "xfs",
+				},
+			},
+			{
+				MinSize: 5 * datasizes.GiB,
+				Type:    "lvm",
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartType:  "linux-generic",
+					PartLabel: "system",
+				},
+				VGCustomization: blueprint.VGCustomization{
+					LogicalVolumes: []blueprint.LVCustomization{
+						{
+							MinSize: 2 * datasizes.GiB,
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/",
+								FSType:     "xfs",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType:      disk.FS_XFS,
+		BootMode:           platform.BOOT_HYBRID,
+		PartitionTableType: disk.PT_GPT,
+		Architecture:       arch.ARCH_X86_64,
+	}
+
+	/* #nosec G404 */
+	rnd := rand.New(rand.NewSource(0))
+	pt, err := disk.NewCustomPartitionTable(customizations, options, rnd)
+	require.NoError(t, err)
+
+	partitions := make(map[string]disk.Partition)
+	for _, part := range pt.Partitions {
+		switch payload := part.Payload.(type) {
+		case *disk.Filesystem:
+			partitions[payload.Mountpoint] = part
+		case *disk.LVMVolumeGroup:
+			partitions["lvm"] = part
+		}
+	}
+
+	assert.Equal(t, disk.HomePartitionGUID, partitions["/home"].Type)
+	assert.Equal(t, "home", partitions["/home"].Name)
+	assert.Equal(t, []uint{60, 63}, partitions["/home"].Attrs)
+
+	assert.Equal(t, disk.SrvPartitionGUID, partitions["/srv"].Type)
+	assert.Empty(t, partitions["/srv"].Name)
+	assert.Empty(t, partitions["/srv"].Attrs)
+
+	assert.Equal(t, disk.FilesystemDataGUID, partitions["lvm"].Type)
+	assert.Equal(t, "system", partitions["lvm"].Name)
+
+	// partitions without entry customizations keep their defaults
+	assert.Equal(t, disk.XBootLDRPartitionGUID, partitions["/boot"].Type)
+	assert.Empty(t, partitions["/boot"].Name)
+}
+
+func TestNewCustomPartitionTablePartitionEntriesDOS(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		Type: "dos",
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				MinSize: 2 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartType: "8E",
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/data",
+					FSType:     "xfs",
+				},
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType: disk.FS_XFS,
+		BootMode:      platform.BOOT_LEGACY,
+		Architecture:  arch.ARCH_X86_64,
+	}
+
+	/* #nosec G404 */
+	rnd := rand.New(rand.NewSource(0))
+	pt, err := disk.NewCustomPartitionTable(customizations, options, rnd)
+	require.NoError(t, err)
+
+	found := false
+	for _, part := range pt.Partitions {
+		if fs, ok := part.Payload.(*disk.Filesystem); ok && fs.Mountpoint == "/data" {
+			assert.Equal(t, "8e", part.Type)
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestNewCustomPartitionTableRaw(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				MinSize: 2 * datasizes.GiB,
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/",
+					FSType:     "ext4",
+				},
+			},
+			{
+				Type:    "raw",
+				MinSize: 2 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartType:  "root",
+					PartLabel: "root-b",
+				},
+			},
+			{
+				Type:    "none",
+				MinSize: 500 * datasizes.MiB,
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType:      disk.FS_XFS,
+		BootMode:           platform.BOOT_UEFI,
+		PartitionTableType: disk.PT_GPT,
+		Architecture:       arch.ARCH_X86_64,
+	}
+
+	/* #nosec G404 */
+	rnd := rand.New(rand.NewSource(0))
+	pt, err := disk.NewCustomPartitionTable(customizations, options, rnd)
+	require.NoError(t, err)
+
+	var raw []disk.Partition
+	for _, part := range pt.Partitions {
+		if part.Payload == nil {
+			raw = append(raw, part)
+		}
+	}
+	require.Len(t, raw, 2)
+
+	assert.Equal(t, disk.RootPartitionX86_64GUID, raw[0].Type)
+	assert.Equal(t, "root-b", raw[0].Name)
+	assert.Equal(t, uint64(2*datasizes.GiB), raw[0].Size)
+	assert.NotEmpty(t, raw[0].UUID)
+
+	assert.Equal(t, disk.FilesystemDataGUID, raw[1].Type)
+	assert.Equal(t, uint64(500*datasizes.MiB), raw[1].Size)
+
+	// raw partitions are not part of the fstab
+	var mountpoints []string
+	err = pt.ForEachFSTabEntity(func(ent disk.FSTabEntity, path []disk.Entity) error {
+		mountpoints = append(mountpoints, ent.GetFSFile())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/", "/boot/efi"}, mountpoints)
+}
+
+func TestNewCustomPartitionTableLayout(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		MinSize: 10 * datasizes.GiB,
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				Type:    "raw",
+				MinSize: 8 * datasizes.MiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartLabel: "firmware",
+				},
+				PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+					Start:    1 * datasizes.MiB,
+					Position: 1,
+				},
+			},
+			{
+				MinSize: 2 * datasizes.GiB,
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/",
+					FSType:     "ext4",
+				},
+			},
+			{
+				MinSize: 1 * datasizes.GiB,
+				PartitionLayoutCustomization: blueprint.PartitionLayoutCustomization{
+					Grow: true,
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/var",
+					FSType:     "xfs",
+				},
+			},
+			{
+				Type:    "raw",
+				MinSize: 1 * datasizes.GiB,
+				PartitionEntryCustomization: blueprint.PartitionEntryCustomization{
+					PartLabel: "slot-b",
+				},
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType:      disk.FS_XFS,
+		BootMode:           platform.BOOT_UEFI,
+		PartitionTableType: disk.PT_GPT,
+		Architecture:       arch.ARCH_X86_64,
+	}
+
+	/* #nosec G404 */
+	rnd := rand.New(rand.NewSource(0))
+	pt, err := disk.NewCustomPartitionTable(customizations, options, rnd)
+	require.NoError(t, err)
+	require.Len(t, pt.Partitions, 5)
+
+	// the positioned partition comes first, the others keep their order
+	firmware := pt.Partitions[0]
+	assert.Equal(t, "firmware", firmware.Name)
+	assert.Equal(t, uint64(1*datasizes.MiB), firmware.Start)
+	assert.Equal(t, uint64(8*datasizes.MiB), firmware.Size)
+
+	esp := pt.Partitions[1]
+	assert.Equal(t, disk.EFISystemPartitionGUID, esp.Type)
+	assert.Equal(t, firmware.Start+firmware.Size, esp.Start)
+
+	root := pt.Partitions[2]
+	assert.Equal(t, "/", root.Payload.(*disk.Filesystem).Mountpoint)
+	assert.Equal(t, esp.Start+esp.Size, root.Start)
+	assert.Equal(t, uint64(2*datasizes.GiB), root.Size)
+
+	// /var grows instead of the root partition and the partition after it
+	// is moved to the end of the disk
+	varPart := pt.Partitions[3]
+	slotB := pt.Partitions[4]
+	assert.Equal(t, "/var", varPart.Payload.(*disk.Filesystem).Mountpoint)
+	assert.Equal(t, root.Start+root.Size, varPart.Start)
+	assert.Greater(t, varPart.Size, uint64(1*datasizes.GiB))
+	assert.Equal(t, slotB.Start, varPart.Start+varPart.Size)
+	assert.Equal(t, "slot-b", slotB.Name)
+	assert.Equal(t, uint64(1*datasizes.GiB), slotB.Size)
+	assert.LessOrEqual(t, slotB.Start+slotB.Size, pt.Size-pt.HeaderSize())
+	assert.Greater(t, slotB.Start+slotB.Size+datasizes.MiB, pt.Size-pt.HeaderSize())
+}
+
+func TestNewCustomPartitionTableGrowth(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		MinSize: 20 * datasizes.GiB,
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				MinSize: 1 * datasizes.GiB,
+				GrowthCustomization: blueprint.GrowthCustomization{
+					GrowPercent: 40,
+				},
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/var",
+					FSType:     "xfs",
+				},
+			},
+			{
+				Type:    "lvm",
+				MinSize: 4 * datasizes.GiB,
+				VGCustomization: blueprint.VGCustomization{
+					Name: "vg",
+					LogicalVolumes: []blueprint.LVCustomization{
+						{
+							Name:    "rootlv",
+							MinSize: 2 * datasizes.GiB,
+							Grow:    true,
+							GrowthCustomization: blueprint.GrowthCustomization{
+								MaxSize: 4 * datasizes.GiB,
+							},
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/",
+								FSType:     "xfs",
+							},
+						},
+						{
+							Name:    "homelv",
+							MinSize: 1 * datasizes.GiB,
+							GrowthCustomization: blueprint.GrowthCustomization{
+								GrowPercent: 50,
+							},
+							FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+								Mountpoint: "/home",
+								FSType:     "xfs",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType:      disk.FS_XFS,
+		BootMode:           platform.BOOT_UEFI,
+		PartitionTableType: disk.PT_GPT,
+		Architecture:       arch.ARCH_X86_64,
+	}
+
+	for _, seed := range []int64{0, 42} {
+		/* #nosec G404 */
+		rnd := rand.New(rand.NewSource(seed))
+		pt, err := disk.NewCustomPartitionTable(customizations, options, rnd)
+		require.NoError(t, err)
+		require.Len(t, pt.Partitions, 4)
+		assert.Equal(t, uint64(20*datasizes.GiB), pt.Size)
+
+		// with their minimum sizes, the partitions (ESP, /boot, /var and
+		// the volume group) end at 5834 MiB (including the GPT footer),
+		// which leaves 14646 MiB of free space; /var gets 40% of it
+		varPart := pt.Partitions[2]
+		assert.Equal(t, "/var", varPart.Payload.(*disk.Filesystem).Mountpoint)
+		assert.Equal(t, uint64((1024+5858)*datasizes.MiB), varPart.Size)
+
+		// the volume group partition fills the rest of the disk
+		vgPart := pt.Partitions[3]
+		assert.Equal(t, varPart.Start+varPart.Size, vgPart.Start)
+		assert.Equal(t, pt.Size-pt.HeaderSize(), vgPart.Start+vgPart.Size)
+
+		// the volume group has 12880 MiB for logical volumes, 9808 MiB of
+		// which are free; /home gets 50% of it and / grows up to its
+		// maximum size
+		vg := vgPart.Payload.(*disk.LVMVolumeGroup)
+		assert.Equal(t, uint64(4*datasizes.GiB), vg.LogicalVolumes[0].Size)
+		assert.Equal(t, uint64((1024+4904)*datasizes.MiB), vg.LogicalVolumes[1].Size)
+	}
+}
+
+func TestNewCustomPartitionTableAdditionalDisks(t *testing.T) {
+	customizations := &blueprint.DiskCustomization{
+		Partitions: []blueprint.PartitionCustomization{
+			{
+				MinSize: 5 * datasizes.GiB,
+				FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+					Mountpoint: "/",
+					FSType:     "xfs",
+				},
+			},
+		},
+		Disks: []blueprint.DiskCustomization{
+			{
+				Type:    "dos",
+				MinSize: 10 * datasizes.GiB,
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						Type:    "lvm",
+						MinSize: 2 * datasizes.GiB,
+						VGCustomization: blueprint.VGCustomization{
+							Name: "datavg",
+							LogicalVolumes: []blueprint.LVCustomization{
+								{
+									Name:    "datalv",
+									MinSize: 1 * datasizes.GiB,
+									Grow:    true,
+									FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+										Mountpoint: "/data",
+										FSType:     "ext4",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				Partitions: []blueprint.PartitionCustomization{
+					{
+						MinSize: 1 * datasizes.GiB,
+						FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+							FSType: "swap",
+						},
+					},
+				},
+			},
+		},
+	}
+	options := &disk.CustomPartitionTableOptions{
+		DefaultFSType:      disk.FS_XFS,
+		BootMode:           platform.BOOT_HYBRID,
+		PartitionTableType: disk.PT_GPT,
+		Architecture:       arch.ARCH_X86_64,
+		RequiredMinSizes:   map[string]uint64{"/": 1 * datasizes.GiB, "/data/db": 3 * datasizes.GiB},
+	}
+
+	/* #nosec G404 */
+	rnd := rand.New(rand.NewSource(0))
+	pt, err := disk.NewCustomPartitionTable(customizations, options, rnd)
+	require.NoError(t, err)
+
+	// the boot partitions and the root filesystem are on the first disk,
+	// which is not affected by the sizes of the additional disks
+	require.Len(t, pt.Partitions, 3)
+	assert.Equal(t, "/", pt.Partitions[2].Payload.(*disk.Filesystem).Mountpoint)
+	assert.Less(t, pt.Size, uint64(6*datasizes.GiB))
+	require.Len(t, pt.ExtraDisks, 2)
+
+	// the volume group fills the data disk and the logical volume, which
+	// is at least as large as the required size of /data/db, the group
+	data := pt.ExtraDisks[0]
+	assert.Equal(t, disk.PT_DOS, data.Type)
+	assert.Equal(t, uint64(10*datasizes.GiB), data.Size)
+	require.Len(t, data.Partitions, 1)
+	assert.Equal(t, data.Size, data.Partitions[0].Start+data.Partitions[0].Size)
+	vg := data.Partitions[0].Payload.(*disk.LVMVolumeGroup)
+	assert.Equal(t, "datavg", vg.Name)
+	assert.Equal(t, (data.Partitions[0].Size-vg.MetadataSize())/disk.LVMDefaultExtentSize*disk.LVMDefaultExtentSize, vg.LogicalVolumes[0].Size)
+	assert.GreaterOrEqual(t, vg.LogicalVolumes[0].Size, uint64(3*datasizes.GiB))
+
+	// the swap disk uses the default partition table type and gets UUIDs
+	swap := pt.ExtraDisks[1]
+	assert.Equal(t, disk.PT_GPT, swap.Type)
+	assert.NotEmpty(t, swap.UUID)
+	require.Len(t, swap.Partitions, 1)
+	assert.NotEmpty(t, swap.Partitions[0].UUID)
+	assert.IsType(t, &disk.Swap{}, swap.Partitions[0].Payload)
+
+	// mountpoints of all disks are part of the same tree
+	assert.True(t, pt.ContainsMountpoint("/data"))
+	var mountpoints []string
+	require.NoError(t, pt.ForEachMountable(func(mnt disk.Mountable, path []disk.Entity) error {
+		mountpoints = append(mountpoints, mnt.GetMountpoint())
+		return nil
+	}))
+	assert.Equal(t, []string{"/boot/efi", "/", "/data"}, mountpoints)
+}
+
 func TestPartitionTableFeatures(t *testing.T) {
 	require := require.New(t)
 
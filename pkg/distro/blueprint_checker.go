@@ -0,0 +1,37 @@
+package distro
+
+import (
+	"github.com/osbuild/images/pkg/blueprint"
+)
+
+type blueprintChecker struct {
+	imgType ImageType
+	options ImageOptions
+}
+
+// NewBlueprintChecker returns a blueprint.ImageTypeChecker for the
+// given image type so that it can be used with blueprint.Lint(). The
+// options are passed on to the image type when checking blueprints,
+// e.g. the ostree options are required by some image types.
+func NewBlueprintChecker(imgType ImageType, options ImageOptions) blueprint.ImageTypeChecker {
+	return &blueprintChecker{
+		imgType: imgType,
+		options: options,
+	}
+}
+
+func (c *blueprintChecker) Name() string {
+	return c.imgType.Name()
+}
+
+// CheckBlueprint generates a manifest without repositories to run
+// the validation of the image type
+func (c *blueprintChecker) CheckBlueprint(bp *blueprint.Blueprint) ([]string, error) {
+	var seed int64
+	_, warnings, err := c.imgType.Manifest(bp, c.options, nil, &seed)
+	return warnings, err
+}
+
+func (c *blueprintChecker) RequiredDirectorySizes() map[string]uint64 {
+	return c.imgType.RequiredDirectorySizes()
+}
@@ -0,0 +1,110 @@
+package distro_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/datasizes"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distrofactory"
+)
+
+func getImageType(t *testing.T, distroName, archName, imgTypeName string) distro.ImageType {
+	d := distrofactory.NewDefault().GetDistro(distroName)
+	require.NotNil(t, d)
+	a, err := d.GetArch(archName)
+	require.NoError(t, err)
+	it, err := a.GetImageType(imgTypeName)
+	require.NoError(t, err)
+	return it
+}
+
+func TestBlueprintCheckerLint(t *testing.T) {
+	qcow2 := getImageType(t, "centos-9", "x86_64", "qcow2")
+	checker := distro.NewBlueprintChecker(qcow2, distro.ImageOptions{})
+	assert.Equal(t, "qcow2", checker.Name())
+
+	assert.Empty(t, blueprint.Lint(&blueprint.Blueprint{}, checker))
+
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Filesystem: []blueprint.FilesystemCustomization{
+				{Mountpoint: "/", MinSize: 500 * datasizes.MiB},
+				{Mountpoint: "/etc", MinSize: 1 * datasizes.GiB},
+			},
+		},
+	}
+	diags := blueprint.Lint(bp, checker)
+	// the image type reports the /etc mountpoint as well, it is
+	// dropped as it was already reported with a path
+	assert.Equal(t, []blueprint.Diagnostic{
+		{
+			Severity: blueprint.SeverityWarning,
+			Path:     "customizations.filesystem[0].minsize",
+			Message:  `size of "/" is smaller than the minimum of 1 GiB and will be increased`,
+			Fix:      "set minsize to at least 1 GiB",
+		},
+		{
+			Severity: blueprint.SeverityError,
+			Path:     "customizations.filesystem[1].mountpoint",
+			Message:  `path "/etc" is not allowed`,
+			Fix:      "use a mountpoint that is allowed for custom filesystems",
+		},
+	}, diags)
+}
+
+func TestBlueprintCheckerRequiredDirectorySizes(t *testing.T) {
+	qcow2 := getImageType(t, "centos-9", "x86_64", "qcow2")
+	assert.Equal(t, map[string]uint64{
+		"/":    1 * datasizes.GiB,
+		"/usr": 2 * datasizes.GiB,
+	}, distro.NewBlueprintChecker(qcow2, distro.ImageOptions{}).RequiredDirectorySizes())
+
+	// the iot raw image disables the minimum sizes
+	iotRaw := getImageType(t, "fedora-41", "x86_64", "iot-raw-image")
+	checker := distro.NewBlueprintChecker(iotRaw, distro.ImageOptions{})
+	assert.Equal(t, map[string]uint64{}, checker.RequiredDirectorySizes())
+
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Filesystem: []blueprint.FilesystemCustomization{
+				{Mountpoint: "/", MinSize: 500 * datasizes.MiB},
+			},
+		},
+	}
+	for _, diag := range blueprint.Lint(bp, checker) {
+		assert.NotEqual(t, "customizations.filesystem[0].minsize", diag.Path)
+	}
+}
+
+func TestBlueprintCheckerRequiredDirectorySizesIsACopy(t *testing.T) {
+	qcow2 := getImageType(t, "centos-9", "x86_64", "qcow2")
+	checker := distro.NewBlueprintChecker(qcow2, distro.ImageOptions{})
+
+	sizes := checker.RequiredDirectorySizes()
+	require.NotEmpty(t, sizes)
+	sizes["/"] = 1
+	assert.NotEqual(t, uint64(1), checker.RequiredDirectorySizes()["/"])
+}
+
+func TestBlueprintCheckerImageTypeError(t *testing.T) {
+	commit := getImageType(t, "centos-9", "x86_64", "edge-commit")
+	checker := distro.NewBlueprintChecker(commit, distro.ImageOptions{})
+	assert.Nil(t, checker.RequiredDirectorySizes())
+
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Kernel: &blueprint.KernelCustomization{Append: "debug"},
+		},
+	}
+	_, err := checker.CheckBlueprint(bp)
+	require.EqualError(t, err, "kernel boot parameter customizations are not supported for ostree types")
+
+	diags := blueprint.Lint(bp, checker)
+	require.Len(t, diags, 1)
+	assert.Equal(t, blueprint.SeverityError, diags[0].Severity)
+	assert.Equal(t, err.Error(), diags[0].Message)
+}
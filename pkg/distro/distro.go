@@ -102,6 +102,11 @@ type ImageType interface {
 	// has no partition table. Only support for RHEL 8.5+
 	PartitionType() disk.PartitionTableType
 
+	// Returns the minimum sizes of directories of the image type, filesystems
+	// that are smaller are grown. Returns nil if the image type has no
+	// partition table.
+	RequiredDirectorySizes() map[string]uint64
+
 	// Returns the corresponding boot mode ("legacy", "uefi", "hybrid") or "none"
 	BootMode() platform.BootMode
 
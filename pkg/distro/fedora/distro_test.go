@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/datasizes"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distro/distro_test_common"
 	"github.com/osbuild/images/pkg/distro/fedora"
@@ -975,6 +976,46 @@ func TestDistro_PartitioningConflict(t *testing.T) {
 
 }
 
+func TestDistro_MultiDiskExport(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Disk: &blueprint.DiskCustomization{
+				Disks: []blueprint.DiskCustomization{
+					{
+						Partitions: []blueprint.PartitionCustomization{
+							{
+								MinSize: 1 * datasizes.GiB,
+								FilesystemTypedCustomization: blueprint.FilesystemTypedCustomization{
+									FSType:     "xfs",
+									Mountpoint: "/data",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	arch, err := fedora.DistroFactory("fedora-41").GetArch("x86_64")
+	require.NoError(t, err)
+
+	// qcow2 and vmdk export every disk
+	for _, imgTypeName := range []string{"qcow2", "vmdk"} {
+		imgType, err := arch.GetImageType(imgTypeName)
+		require.NoError(t, err)
+		_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+		assert.NoError(t, err, imgTypeName)
+	}
+
+	// the ova only packages the first disk, the raw image is compressed
+	for _, imgTypeName := range []string{"ova", "minimal-raw"} {
+		imgType, err := arch.GetImageType(imgTypeName)
+		require.NoError(t, err)
+		_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, nil)
+		assert.EqualError(t, err, fmt.Sprintf("partitioning customizations with additional disks are not supported for %q: it exports a single disk", imgTypeName))
+	}
+}
+
 func TestDistroFactory(t *testing.T) {
 	type testCase struct {
 		strID    string
@@ -7,6 +7,9 @@ import (
 
 	"slices"
 
+	// we cannot use "maps" yet, as it needs go1.23
+	"golang.org/x/exp/maps"
+
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/environment"
 	"github.com/osbuild/images/internal/workload"
@@ -138,6 +141,13 @@ func (t *imageType) BootMode() platform.BootMode {
 	return platform.BOOT_NONE
 }
 
+// exportsAllDisks returns true if the image type exports an image file for
+// every disk of its partition table, see image.ExportsAllDisks. ostree disk
+// images only have one disk.
+func (t *imageType) exportsAllDisks() bool {
+	return !t.rpmOstree && image.ExportsAllDisks(t.platform.GetImageFormat(), t.compression)
+}
+
 func (t *imageType) getPartitionTable(
 	customizations *blueprint.Customizations,
 	options distro.ImageOptions,
@@ -205,6 +215,16 @@ func (t *imageType) getDefaultInstallerConfig() (*distro.InstallerConfig, error)
 	return t.defaultInstallerConfig, nil
 }
 
+func (t *imageType) RequiredDirectorySizes() map[string]uint64 {
+	if t.PartitionType() == disk.PT_NONE {
+		return nil
+	}
+	if t.requiredPartitionSizes == nil {
+		return maps.Clone(disk.DefaultRequiredDirectorySizes)
+	}
+	return maps.Clone(t.requiredPartitionSizes)
+}
+
 func (t *imageType) PartitionType() disk.PartitionTableType {
 	basePartitionTable, exists := t.basePartitionTables[t.arch.Name()]
 	if !exists {
@@ -258,7 +278,9 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 			BaseWorkload: workload.BaseWorkload{
 				Repos: payloadRepos,
 			},
-			Packages: bp.GetPackagesEx(false),
+			Packages:        bp.GetPackagesEx(false),
+			ExcludePackages: bp.Exclude,
+			InstallWeakDeps: bp.InstallWeakDeps,
 		}
 		if services := bp.Customizations.GetServices(); services != nil {
 			cw.Services = services.Enabled
@@ -307,6 +329,10 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("OSTree is not supported for %q", t.Name())
 	}
 
+	if err := bp.ValidatePackages(); err != nil {
+		return warnings, err
+	}
+
 	// we do not support embedding containers on ostree-derived images, only on commits themselves
 	if len(bp.Containers) > 0 && t.rpmOstree && (t.name != "iot-commit" && t.name != "iot-container") {
 		return warnings, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
@@ -403,6 +429,9 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 	if len(mountpoints) > 0 && partitioning != nil {
 		return warnings, fmt.Errorf("partitioning customizations cannot be used with custom filesystems (mountpoints)")
 	}
+	if partitioning.MultiDisk() && !t.exportsAllDisks() {
+		return warnings, fmt.Errorf("partitioning customizations with additional disks are not supported for %q: it exports a single disk", t.name)
+	}
 
 	if err := blueprint.CheckMountpointsPolicy(mountpoints, policies.MountpointPolicies); err != nil {
 		return warnings, err
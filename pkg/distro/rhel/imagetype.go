@@ -6,6 +6,9 @@ import (
 
 	"slices"
 
+	// we cannot use "maps" yet, as it needs go1.23
+	"golang.org/x/exp/maps"
+
 	"github.com/osbuild/images/internal/environment"
 	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/blueprint"
@@ -185,6 +188,13 @@ func (t *ImageType) BootMode() platform.BootMode {
 	return platform.BOOT_NONE
 }
 
+// ExportsAllDisks returns true if the image type exports an image file for
+// every disk of its partition table, see image.ExportsAllDisks. ostree disk
+// images only have one disk.
+func (t *ImageType) ExportsAllDisks() bool {
+	return !t.RPMOSTree && image.ExportsAllDisks(t.platform.GetImageFormat(), t.Compression)
+}
+
 func (t *ImageType) GetPartitionTable(
 	customizations *blueprint.Customizations,
 	options distro.ImageOptions,
@@ -243,6 +253,13 @@ func (t *ImageType) getDefaultInstallerConfig() (*distro.InstallerConfig, error)
 	return t.DefaultInstallerConfig, nil
 }
 
+func (t *ImageType) RequiredDirectorySizes() map[string]uint64 {
+	if t.PartitionType() == disk.PT_NONE {
+		return nil
+	}
+	return maps.Clone(requiredDirectorySizes)
+}
+
 func (t *ImageType) PartitionType() disk.PartitionTableType {
 	if t.BasePartitionTables == nil {
 		return disk.PT_NONE
@@ -308,7 +325,9 @@ func (t *ImageType) Manifest(bp *blueprint.Blueprint,
 			BaseWorkload: workload.BaseWorkload{
 				Repos: payloadRepos,
 			},
-			Packages: bp.GetPackagesEx(false),
+			Packages:        bp.GetPackagesEx(false),
+			ExcludePackages: bp.Exclude,
+			InstallWeakDeps: bp.InstallWeakDeps,
 		}
 		if services := bp.Customizations.GetServices(); services != nil {
 			cw.Services = services.Enabled
@@ -366,6 +385,10 @@ func (t *ImageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return nil, fmt.Errorf("OSTree is not supported for %q", t.Name())
 	}
 
+	if err := bp.ValidatePackages(); err != nil {
+		return nil, err
+	}
+
 	if t.arch.distro.CheckOptions != nil {
 		return t.arch.distro.CheckOptions(t, bp, options)
 	}
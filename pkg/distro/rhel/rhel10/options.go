@@ -39,6 +39,10 @@ func checkOptions(t *rhel.ImageType, bp *blueprint.Blueprint, options distro.Ima
 		return nil, fmt.Errorf("partitioning customizations cannot be used with custom filesystems (mountpoints)")
 	}
 
+	if partitioning.MultiDisk() && !t.ExportsAllDisks() {
+		return warnings, fmt.Errorf("partitioning customizations with additional disks are not supported for %q: it exports a single disk", t.Name())
+	}
+
 	if err := blueprint.CheckDiskMountpointsPolicy(partitioning, policies.MountpointPolicies); err != nil {
 		return warnings, err
 	}
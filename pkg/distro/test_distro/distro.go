@@ -212,6 +212,10 @@ func (t *TestImageType) PartitionType() disk.PartitionTableType {
 	return disk.PT_NONE
 }
 
+func (t *TestImageType) RequiredDirectorySizes() map[string]uint64 {
+	return nil
+}
+
 func (t *TestImageType) BootMode() platform.BootMode {
 	return platform.BOOT_HYBRID
 }
@@ -0,0 +1,102 @@
+package dnfjson
+
+import (
+	"sort"
+
+	"github.com/osbuild/images/pkg/repomd"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// AdvisoryFix is an advisory and the build of a package that fixes it.
+type AdvisoryFix struct {
+	Advisory *repomd.Advisory
+	FixedIn  repomd.AdvisoryPackage
+}
+
+// PackageAdvisories are the advisories of a depsolved package.
+type PackageAdvisories struct {
+	Package rpmmd.PackageSpec
+
+	// Advisories that are fixed by the depsolved build of the package
+	Fixed []AdvisoryFix
+
+	// Advisories that are fixed by a newer build of the package that is
+	// available in the repositories
+	Outstanding []AdvisoryFix
+
+	// Advisories that are only fixed by a newer build of the package that
+	// is not available in the repositories
+	Unfixed []AdvisoryFix
+}
+
+// hasBuild returns true if the repositories have a build of the package
+// that is at least as new as fix.
+func hasBuild(md *repomd.Metadata, fix *repomd.AdvisoryPackage) bool {
+	for _, repo := range md.Repositories {
+		for idx := range repo.Packages {
+			pkg := &repo.Packages[idx]
+			if pkg.Name == fix.Name && pkg.Arch == fix.Arch &&
+				rpmmd.CompareEVR(pkg.Epoch, pkg.Version, pkg.Release, fix.Epoch, fix.Version, fix.Release) >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AdvisoryReport returns the advisories (from the updateinfo metadata of the
+// repositories) of the packages of a depsolve result, sorted by package name.
+// Packages without advisories are omitted. An advisory is fixed if the
+// depsolved build is at least as new as the build of the advisory. An
+// advisory of a newer build is outstanding if the repositories have that
+// build, and unfixed if they do not.
+func AdvisoryReport(result *DepsolveResult, md *repomd.Metadata) []PackageAdvisories {
+	advisories := md.Advisories()
+
+	var report []PackageAdvisories
+	for _, pkg := range result.Packages {
+		entry := PackageAdvisories{Package: pkg}
+		seen := make(map[string]bool)
+		for aIdx := range advisories {
+			advisory := &advisories[aIdx]
+			// the same advisory can be part of multiple repositories
+			if seen[advisory.ID] {
+				continue
+			}
+			// an advisory can list several builds of the package, prefer
+			// the ones that fix it or are available
+			var unfixed *AdvisoryFix
+			for _, fix := range advisory.Packages {
+				if fix.Name != pkg.Name || fix.Arch != pkg.Arch {
+					continue
+				}
+				advisoryFix := AdvisoryFix{Advisory: advisory, FixedIn: fix}
+				if rpmmd.CompareEVR(pkg.Epoch, pkg.Version, pkg.Release, fix.Epoch, fix.Version, fix.Release) >= 0 {
+					entry.Fixed = append(entry.Fixed, advisoryFix)
+					seen[advisory.ID] = true
+					break
+				}
+				if hasBuild(md, &fix) {
+					entry.Outstanding = append(entry.Outstanding, advisoryFix)
+					seen[advisory.ID] = true
+					break
+				}
+				if unfixed == nil {
+					unfixed = &advisoryFix
+				}
+			}
+			if !seen[advisory.ID] && unfixed != nil {
+				entry.Unfixed = append(entry.Unfixed, *unfixed)
+				seen[advisory.ID] = true
+			}
+		}
+		if len(entry.Fixed) > 0 || len(entry.Outstanding) > 0 || len(entry.Unfixed) > 0 {
+			report = append(report, entry)
+		}
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].Package.GetNEVRA() < report[j].Package.GetNEVRA()
+	})
+	return report
+}
@@ -0,0 +1,72 @@
+package dnfjson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/repomd"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func TestAdvisoryReport(t *testing.T) {
+	opensslFix := repomd.AdvisoryPackage{Name: "openssl", Epoch: 1, Version: "3.0.7", Release: "27.el9", Arch: "x86_64"}
+	opensslUpdate := repomd.AdvisoryPackage{Name: "openssl", Epoch: 1, Version: "3.0.7", Release: "28.el9", Arch: "x86_64"}
+	bashFix := repomd.AdvisoryPackage{Name: "bash", Version: "5.1.8", Release: "10.el9", Arch: "x86_64"}
+	advisories := []repomd.Advisory{
+		{ID: "RHSA-2024:0001", Type: "security", Packages: []repomd.AdvisoryPackage{opensslFix}},
+		{ID: "RHSA-2024:0002", Type: "security", Packages: []repomd.AdvisoryPackage{opensslUpdate}},
+		// fixed by a build that is not available in the repositories
+		{ID: "RHBA-2024:0003", Type: "bugfix", Packages: []repomd.AdvisoryPackage{bashFix}},
+		// other architectures are ignored
+		{ID: "RHSA-2024:0004", Type: "security", Packages: []repomd.AdvisoryPackage{
+			{Name: "openssl", Epoch: 1, Version: "3.0.7", Release: "29.el9", Arch: "aarch64"},
+		}},
+	}
+	md := &repomd.Metadata{
+		Repositories: []*repomd.Repository{
+			{
+				Packages: []repomd.Package{
+					{Name: "openssl", Epoch: 1, Version: "3.0.7", Release: "27.el9", Arch: "x86_64"},
+					{Name: "openssl", Epoch: 1, Version: "3.0.7", Release: "28.el9", Arch: "x86_64"},
+					{Name: "openssl", Epoch: 1, Version: "3.0.7", Release: "29.el9", Arch: "aarch64"},
+					{Name: "bash", Version: "5.1.8", Release: "9.el9", Arch: "x86_64"},
+				},
+				Advisories: advisories,
+			},
+			// the same advisory in a second repository is reported once
+			{
+				Advisories: advisories[:1],
+			},
+		},
+	}
+
+	openssl := rpmmd.PackageSpec{Name: "openssl", Epoch: 1, Version: "3.0.7", Release: "27.el9", Arch: "x86_64"}
+	bash := rpmmd.PackageSpec{Name: "bash", Version: "5.1.8", Release: "9.el9", Arch: "x86_64"}
+	zlib := rpmmd.PackageSpec{Name: "zlib", Version: "1.2.11", Release: "40.el9", Arch: "x86_64"}
+	report := AdvisoryReport(&DepsolveResult{Packages: []rpmmd.PackageSpec{zlib, openssl, bash}}, md)
+	require.Len(t, report, 2)
+	assert.Equal(t, bash, report[0].Package)
+	assert.Empty(t, report[0].Fixed)
+	assert.Empty(t, report[0].Outstanding)
+	require.Len(t, report[0].Unfixed, 1)
+	assert.Equal(t, "RHBA-2024:0003", report[0].Unfixed[0].Advisory.ID)
+	assert.Equal(t, bashFix, report[0].Unfixed[0].FixedIn)
+
+	assert.Equal(t, openssl, report[1].Package)
+	require.Len(t, report[1].Fixed, 1)
+	assert.Equal(t, "RHSA-2024:0001", report[1].Fixed[0].Advisory.ID)
+	assert.Equal(t, opensslFix, report[1].Fixed[0].FixedIn)
+	require.Len(t, report[1].Outstanding, 1)
+	assert.Equal(t, "RHSA-2024:0002", report[1].Outstanding[0].Advisory.ID)
+	assert.Equal(t, opensslUpdate, report[1].Outstanding[0].FixedIn)
+	assert.Empty(t, report[1].Unfixed)
+
+	// the updated build fixes both advisories
+	openssl.Release = "28.el9"
+	report = AdvisoryReport(&DepsolveResult{Packages: []rpmmd.PackageSpec{openssl}}, md)
+	require.Len(t, report, 1)
+	assert.Len(t, report[0].Fixed, 2)
+	assert.Empty(t, report[0].Outstanding)
+}
@@ -15,6 +15,7 @@ package dnfjson
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -25,9 +26,11 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/repomd"
 	"github.com/osbuild/images/pkg/rhsm"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/sbom"
@@ -211,7 +214,9 @@ func (s *Solver) SetProxy(proxy string) error {
 // their associated repositories.  Each package set is depsolved as a separate
 // transactions in a chain.  It returns a list of all packages (with solved
 // dependencies) that will be installed into the system.
-func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet, sbomType sbom.StandardType) (*DepsolveResult, error) {
+//
+// Cancelling the context kills the depsolver.
+func (s *Solver) Depsolve(ctx context.Context, pkgSets []rpmmd.PackageSet, sbomType sbom.StandardType) (*DepsolveResult, error) {
 	req, rhsmMap, err := s.makeDepsolveRequest(pkgSets, sbomType)
 	if err != nil {
 		return nil, fmt.Errorf("makeDepsolveRequest failed: %w", err)
@@ -221,7 +226,7 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet, sbomType sbom.StandardType
 	s.cache.locker.RLock()
 	defer s.cache.locker.RUnlock()
 
-	output, err := run(s.dnfJsonCmd, req, s.Stderr)
+	output, err := run(ctx, s.dnfJsonCmd, req, s.Stderr)
 	if err != nil {
 		return nil, fmt.Errorf("running osbuild-depsolve-dnf failed:\n%w", err)
 	}
@@ -241,13 +246,21 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet, sbomType sbom.StandardType
 	}
 
 	packages, repos := result.toRPMMD(rhsmMap)
+	restoreLocalPackages(repos, req.Arguments.Repos)
 
 	var sbomDoc *sbom.Document
-	if sbomType != sbom.StandardTypeNone {
+	switch sbomType {
+	case sbom.StandardTypeNone:
+	case sbom.StandardTypeSpdx:
 		sbomDoc, err = sbom.NewDocument(sbomType, result.SBOM)
-		if err != nil {
-			return nil, fmt.Errorf("creating SBOM document failed: %w", err)
-		}
+	case sbom.StandardTypeCycloneDX:
+		// osbuild-depsolve-dnf only generates SPDX documents
+		sbomDoc, err = sbom.GenerateCycloneDX("", packages, repos, nil)
+	default:
+		err = fmt.Errorf("unsupported SBOM document type: %s", sbomType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating SBOM document failed: %w", err)
 	}
 
 	return &DepsolveResult{
@@ -260,7 +273,7 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet, sbomType sbom.StandardType
 
 // FetchMetadata returns the list of all the available packages in repos and
 // their info.
-func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, error) {
+func (s *Solver) FetchMetadata(ctx context.Context, repos []rpmmd.RepoConfig) (rpmmd.PackageList, error) {
 	req, err := s.makeDumpRequest(repos)
 	if err != nil {
 		return nil, err
@@ -275,7 +288,7 @@ func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, err
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req, s.Stderr)
+	result, err := run(ctx, s.dnfJsonCmd, req, s.Stderr)
 	if err != nil {
 		return nil, err
 	}
@@ -306,7 +319,7 @@ func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, err
 }
 
 // SearchMetadata searches for packages and returns a list of the info for matches.
-func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rpmmd.PackageList, error) {
+func (s *Solver) SearchMetadata(ctx context.Context, repos []rpmmd.RepoConfig, packages []string) (rpmmd.PackageList, error) {
 	req, err := s.makeSearchRequest(repos, packages)
 	if err != nil {
 		return nil, err
@@ -321,7 +334,7 @@ func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rp
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req, s.Stderr)
+	result, err := run(ctx, s.dnfJsonCmd, req, s.Stderr)
 	if err != nil {
 		return nil, err
 	}
@@ -351,6 +364,36 @@ func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rp
 	return pkgs, nil
 }
 
+// localPackagesRepo generates the metadata of a repository with local
+// packages in the cache directory and returns the baseurl to use for it.
+func (s *Solver) localPackagesRepo(rr rpmmd.RepoConfig) (string, error) {
+	if len(rr.BaseURLs) > 0 || rr.Metalink != "" || rr.MirrorList != "" {
+		return "", fmt.Errorf("repository %s: local packages cannot be combined with a baseurl, metalink or mirrorlist", rr.Name)
+	}
+	// the directory is prefixed by the repository hash so that it is
+	// part of the cache of the repository, see rpmCache
+	dest := filepath.Join(s.GetCacheDir(), rr.Hash()+"-packages")
+	if err := repomd.CreateRepo(dest, rr); err != nil {
+		return "", fmt.Errorf("cannot create metadata of local packages: %w", err)
+	}
+	return "file://" + dest, nil
+}
+
+// restoreLocalPackages replaces the generated baseurl of repositories with
+// local packages in the depsolve result with their original configuration.
+func restoreLocalPackages(repos []rpmmd.RepoConfig, dnfRepos []repoConfig) {
+	for idx := range repos {
+		repo := &repos[idx]
+		for _, dr := range dnfRepos {
+			if dr.ID == repo.Id && dr.localRepo != nil {
+				repo.BaseURLs = nil
+				repo.PackagesDir = dr.localRepo.PackagesDir
+				repo.PackageFiles = dr.localRepo.PackageFiles
+			}
+		}
+	}
+}
+
 func (s *Solver) reposFromRPMMD(rpmRepos []rpmmd.RepoConfig) ([]repoConfig, error) {
 	dnfRepos := make([]repoConfig, len(rpmRepos))
 	for idx, rr := range rpmRepos {
@@ -384,6 +427,17 @@ func (s *Solver) reposFromRPMMD(rpmRepos []rpmmd.RepoConfig) ([]repoConfig, erro
 			dr.SSLVerify = common.ToPtr(!*rr.IgnoreSSL)
 		}
 
+		if rr.HasLocalPackages() {
+			baseURL, err := s.localPackagesRepo(rr)
+			if err != nil {
+				return nil, err
+			}
+			dr.BaseURLs = []string{baseURL}
+			// the metadata is regenerated for every request
+			dr.MetadataExpire = "0"
+			dr.localRepo = &rr
+		}
+
 		if rr.RHSM {
 			if s.subscriptions == nil {
 				return nil, fmt.Errorf("This system does not have any valid subscriptions. Subscribe it before specifying rhsm: true in sources.")
@@ -422,6 +476,8 @@ type repoConfig struct {
 	// set the repo hass from `rpmmd.RepoConfig.Hash()` function
 	// rather than re-calculating it
 	repoHash string
+	// the original configuration of repositories with local packages
+	localRepo *rpmmd.RepoConfig
 }
 
 // use the hash calculated by the `rpmmd.RepoConfig.Hash()`
@@ -510,7 +566,9 @@ func (s *Solver) makeDepsolveRequest(pkgSets []rpmmd.PackageSet, sbomType sbom.S
 		Arguments:        args,
 	}
 
-	if sbomType != sbom.StandardTypeNone {
+	// other SBOM types are generated from the depsolved packages, see
+	// Depsolve()
+	if sbomType == sbom.StandardTypeSpdx {
 		req.Arguments.Sbom = &sbomRequest{Type: sbomType.String()}
 	}
 
@@ -844,7 +902,11 @@ func ParseError(data []byte) Error {
 	return e
 }
 
-func run(dnfJsonCmd []string, req *Request, stderr io.Writer) ([]byte, error) {
+// depsolverKillWaitDelay is the time to wait for the depsolver output
+// to be closed after it was killed because the context was cancelled
+var depsolverKillWaitDelay = 10 * time.Second
+
+func run(ctx context.Context, dnfJsonCmd []string, req *Request, stderr io.Writer) ([]byte, error) {
 	if len(dnfJsonCmd) == 0 {
 		dnfJsonCmd = []string{findDepsolveDnf()}
 	}
@@ -856,7 +918,14 @@ func run(dnfJsonCmd []string, req *Request, stderr io.Writer) ([]byte, error) {
 	if len(dnfJsonCmd) > 1 {
 		args = dnfJsonCmd[1:]
 	}
-	cmd := exec.Command(ex, args...)
+	cmd := exec.CommandContext(ctx, ex, args...)
+	// run the depsolver in its own process group so that all of
+	// its helpers are killed too when the context is cancelled
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = depsolverKillWaitDelay
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, fmt.Errorf("creating stdin pipe for %s failed: %w", ex, err)
@@ -882,6 +951,9 @@ func run(dnfJsonCmd []string, req *Request, stderr io.Writer) ([]byte, error) {
 	stdin.Close()
 
 	err = cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("running %s cancelled: %w", ex, ctxErr)
+	}
 	output := stdout.Bytes()
 	if runError, ok := err.(*exec.ExitError); ok && runError.ExitCode() != 0 {
 		return nil, parseError(output, req.Arguments.Repos)
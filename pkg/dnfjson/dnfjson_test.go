@@ -2,6 +2,7 @@ package dnfjson
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,9 +10,11 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/mocks/rpmrepo"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/sbom"
 	"github.com/stretchr/testify/assert"
@@ -125,7 +128,7 @@ func TestDepsolver(t *testing.T) {
 			}
 
 			solver.SetRootDir(tc.rootDir)
-			res, err := solver.Depsolve(pkgsets, tc.sbomType)
+			res, err := solver.Depsolve(context.Background(), pkgsets, tc.sbomType)
 			if tc.err {
 				assert.Error(err)
 				assert.Contains(err.Error(), tc.expMsg)
@@ -611,6 +614,59 @@ func TestMakeDepsolveRequest(t *testing.T) {
 	}
 }
 
+func TestMakeDepsolveRequestCycloneDX(t *testing.T) {
+	baseOS := rpmmd.RepoConfig{
+		Name:     "baseos",
+		BaseURLs: []string{"https://example.org/baseos"},
+	}
+	pkgSets := []rpmmd.PackageSet{
+		{
+			Include:      []string{"pkg1"},
+			Repositories: []rpmmd.RepoConfig{baseOS},
+		},
+	}
+
+	solver := NewSolver("", "", "", "", "")
+	// the depsolver only generates SPDX documents, CycloneDX documents
+	// are generated from the depsolved packages
+	req, _, err := solver.makeDepsolveRequest(pkgSets, sbom.StandardTypeCycloneDX)
+	require.NoError(t, err)
+	assert.Nil(t, req.Arguments.Sbom)
+}
+
+func TestMakeDepsolveRequestPackageVersions(t *testing.T) {
+	baseOS := rpmmd.RepoConfig{
+		Name:     "baseos",
+		BaseURLs: []string{"https://example.org/baseos"},
+	}
+	bp := blueprint.Blueprint{
+		Packages: []blueprint.Package{
+			{Name: "httpd", Version: ">= 2.4"},
+			{Name: "tmux", Version: "3.4"},
+			{Name: "vim-enhanced", Version: "2:9.1.*"},
+			{Name: "bash", Version: "*"},
+		},
+	}
+	require.NoError(t, bp.ValidatePackages())
+	pkgSets := []rpmmd.PackageSet{
+		{
+			Include:      bp.GetPackagesEx(false),
+			Repositories: []rpmmd.RepoConfig{baseOS},
+		},
+	}
+
+	solver := NewSolver("", "", "", "", "")
+	req, _, err := solver.makeDepsolveRequest(pkgSets, sbom.StandardTypeNone)
+	require.NoError(t, err)
+	require.Len(t, req.Arguments.Transactions, 1)
+
+	// the version comparisons are passed to dnf as provides, the other
+	// versions as NEVRA globs
+	data, err := json.Marshal(req.Arguments.Transactions[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"package-specs":["httpd \u003e= 2.4","tmux-3.4","vim-enhanced-2:9.1.*","bash"]`)
+}
+
 func expectedResult(repo rpmmd.RepoConfig) []rpmmd.PackageSpec {
 	// need to change the url for the RemoteLocation and the repo ID since the port is different each time and we don't want to have a fixed one
 	expectedTemplate := []rpmmd.PackageSpec{
@@ -784,7 +840,7 @@ func TestErrorRepoInfo(t *testing.T) {
 	solver := NewSolver("platform:f38", "38", "x86_64", "fedora-38", "/tmp/cache")
 	for idx, tc := range testCases {
 		t.Run(fmt.Sprintf("%d", idx), func(t *testing.T) {
-			_, err := solver.Depsolve([]rpmmd.PackageSet{
+			_, err := solver.Depsolve(context.Background(), []rpmmd.PackageSet{
 				{
 					Include:      []string{"osbuild"},
 					Exclude:      nil,
@@ -821,6 +877,45 @@ func TestRepoConfigHash(t *testing.T) {
 	assert.NotEqual(t, hash, rcs[1].Hash())
 }
 
+func TestReposFromRPMMDLocalPackages(t *testing.T) {
+	solver := NewSolver("platform:f38", "38", "x86_64", "fedora-38", t.TempDir())
+
+	_, err := solver.reposFromRPMMD([]rpmmd.RepoConfig{
+		{
+			Name:        "local",
+			BaseURLs:    []string{"https://arepourl/"},
+			PackagesDir: t.TempDir(),
+		},
+	})
+	assert.EqualError(t, err, "repository local: local packages cannot be combined with a baseurl, metalink or mirrorlist")
+
+	_, err = solver.reposFromRPMMD([]rpmmd.RepoConfig{
+		{
+			Name:        "local",
+			PackagesDir: t.TempDir(),
+		},
+	})
+	assert.ErrorContains(t, err, "cannot create metadata of local packages: repository ")
+	assert.ErrorContains(t, err, " has no packages")
+}
+
+func TestRestoreLocalPackages(t *testing.T) {
+	local := rpmmd.RepoConfig{Name: "local", PackageFiles: []string{"/tmp/hello.rpm"}}
+	dnfRepos := []repoConfig{
+		{ID: local.Hash(), BaseURLs: []string{"file:///cache/packages"}, localRepo: &local},
+		{ID: "remote", BaseURLs: []string{"https://arepourl/"}},
+	}
+	repos := []rpmmd.RepoConfig{
+		{Id: "remote", BaseURLs: []string{"https://arepourl/"}},
+		{Id: local.Hash(), Name: "local", BaseURLs: []string{"file:///cache/packages"}},
+	}
+	restoreLocalPackages(repos, dnfRepos)
+	assert.Equal(t, []rpmmd.RepoConfig{
+		{Id: "remote", BaseURLs: []string{"https://arepourl/"}},
+		{Id: local.Hash(), Name: "local", PackageFiles: []string{"/tmp/hello.rpm"}},
+	}, repos)
+}
+
 func TestRequestHash(t *testing.T) {
 	solver := NewSolver("platform:f38", "38", "x86_64", "fedora-38", "/tmp/cache")
 	repos := []rpmmd.RepoConfig{
@@ -858,10 +953,27 @@ exit 1
 	err := os.WriteFile(fakeDnfJsonPath, []byte(fakeDnfJsonNoOutput), 0o755)
 	assert.NoError(t, err)
 
-	_, err = run([]string{fakeDnfJsonPath}, &Request{}, nil)
+	_, err = run(context.Background(), []string{fakeDnfJsonPath}, &Request{}, nil)
 	assert.EqualError(t, err, `DNF error occurred: InternalError: dnf-json output was empty`)
 }
 
+func TestRunCancel(t *testing.T) {
+	fakeDnfJsonPath := filepath.Join(t.TempDir(), "dnfjson")
+	fakeDnfJsonSlow := `#!/bin/sh -e
+cat - > /dev/null
+sleep 60
+`
+	err := os.WriteFile(fakeDnfJsonPath, []byte(fakeDnfJsonSlow), 0o755) //nolint:gosec
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = run(ctx, []string{fakeDnfJsonPath}, &Request{}, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 30*time.Second)
+}
+
 func TestSolverRunWithSolverNoError(t *testing.T) {
 	tmpdir := t.TempDir()
 	fakeSolver := `#!/bin/sh -e
@@ -877,7 +989,7 @@ echo '{"solver": "zypper"}'
 	solver := NewSolver("platform:f38", "38", "x86_64", "fedora-38", "/tmp/cache")
 	solver.Stderr = &capturedStderr
 	solver.dnfJsonCmd = []string{fakeSolverPath}
-	res, err := solver.Depsolve(nil, sbom.StandardTypeNone)
+	res, err := solver.Depsolve(context.Background(), nil, sbom.StandardTypeNone)
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
 	assert.Equal(t, "output-on-stderr\n", capturedStderr.String())
@@ -893,6 +1005,42 @@ echo '{"solver": "zypper"}'
 	assert.Equal(t, 0, len(res.Repos))
 }
 
+func TestSolverDepsolveCycloneDX(t *testing.T) {
+	tmpdir := t.TempDir()
+	fakeSolver := `#!/bin/sh -e
+cat - > "$0".stdin
+echo '{"packages": [{"name": "bash", "epoch": 0, "version": "5.2.26", "release": "3.fc40", "arch": "x86_64", "repo_id": "fedora", "remote_location": "https://example.com/repo/Packages/bash-5.2.26-3.fc40.x86_64.rpm", "checksum": "sha256:bbbb"}], "repos": {"fedora": {"id": "fedora", "baseurl": ["https://example.com/repo"], "gpgcheck": false, "repo_gpgcheck": false}}}'
+`
+	fakeSolverPath := filepath.Join(tmpdir, "fake-solver")
+	err := os.WriteFile(fakeSolverPath, []byte(fakeSolver), 0755) //nolint:gosec
+	require.NoError(t, err)
+
+	solver := NewSolver("platform:f40", "40", "x86_64", "fedora-40", tmpdir)
+	solver.dnfJsonCmd = []string{fakeSolverPath}
+	res, err := solver.Depsolve(context.Background(), nil, sbom.StandardTypeCycloneDX)
+	require.NoError(t, err)
+	require.NotNil(t, res.SBOM)
+	assert.Equal(t, sbom.StandardTypeCycloneDX, res.SBOM.DocType)
+
+	var doc struct {
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			Name string `json:"name"`
+			PURL string `json:"purl"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(res.SBOM.Document, &doc))
+	assert.Equal(t, "CycloneDX", doc.BOMFormat)
+	require.Len(t, doc.Components, 1)
+	assert.Equal(t, "bash", doc.Components[0].Name)
+	assert.Equal(t, "pkg:rpm/bash@5.2.26-3.fc40?arch=x86_64&repository_id=fedora", doc.Components[0].PURL)
+
+	// no SBOM was requested from the depsolver
+	stdin, err := os.ReadFile(fakeSolverPath + ".stdin")
+	require.NoError(t, err)
+	assert.NotContains(t, string(stdin), `"sbom"`)
+}
+
 func TestDepsolveResultWithModulesKey(t *testing.T) {
 	// quick test that verifies that `depsolveResult` understands JSON that contains
 	// a `modules` key
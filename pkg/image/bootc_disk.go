@@ -48,6 +48,11 @@ func (img *BootcDiskImage) InstantiateManifestFromContainers(m *manifest.Manifes
 	runner runner.Runner,
 	rng *rand.Rand) error {
 
+	// the bootc install only writes the first disk
+	if img.PartitionTable != nil && img.PartitionTable.DiskCount() > 1 {
+		return fmt.Errorf("bootc disk images cannot have more than one disk")
+	}
+
 	buildPipeline := manifest.NewBuildFromContainer(m, runner, containers, &manifest.BuildOptions{ContainerBuildable: true})
 	buildPipeline.Checkpoint()
 
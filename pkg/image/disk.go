@@ -48,10 +48,30 @@ func NewDiskImage() *DiskImage {
 	}
 }
 
+// ExportsAllDisks returns true if a disk image with the given format and
+// compression exports an image file for every disk of its partition table,
+// i.e. also for the additional disks. The other formats only convert or
+// compress the first disk.
+func ExportsAllDisks(format platform.ImageFormat, compression string) bool {
+	if compression != "" {
+		return false
+	}
+	switch format {
+	case platform.FORMAT_RAW, platform.FORMAT_QCOW2, platform.FORMAT_VMDK:
+		return true
+	default:
+		return false
+	}
+}
+
 func (img *DiskImage) InstantiateManifest(m *manifest.Manifest,
 	repos []rpmmd.RepoConfig,
 	runner runner.Runner,
 	rng *rand.Rand) (*artifact.Artifact, error) {
+	if img.PartitionTable != nil && img.PartitionTable.DiskCount() > 1 && !ExportsAllDisks(img.Platform.GetImageFormat(), img.Compression) {
+		return nil, fmt.Errorf("disk image with format %q and compression %q cannot export more than one disk", img.Platform.GetImageFormat(), img.Compression)
+	}
+
 	buildPipeline := manifest.NewBuild(m, runner, repos, nil)
 	buildPipeline.Checkpoint()
 
@@ -94,6 +94,11 @@ func (img *OSTreeDiskImage) InstantiateManifest(m *manifest.Manifest,
 	repos []rpmmd.RepoConfig,
 	runner runner.Runner,
 	rng *rand.Rand) (*artifact.Artifact, error) {
+	// the ostree deployment is only written to the first disk
+	if img.PartitionTable != nil && img.PartitionTable.DiskCount() > 1 {
+		return nil, fmt.Errorf("ostree disk images cannot have more than one disk")
+	}
+
 	buildPipeline := manifestNewBuild(m, runner, repos, &manifest.BuildOptions{ContainerBuildable: img.ContainerBuildable})
 	buildPipeline.Checkpoint()
 
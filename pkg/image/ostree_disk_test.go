@@ -8,6 +8,7 @@ import (
 
 	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/image"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/platform"
@@ -59,3 +60,31 @@ func TestOSTreeDiskImageManifestSetsContainerBuildable(t *testing.T) {
 		require.Equal(t, buildOpts[0].ContainerBuildable, containerBuildable)
 	}
 }
+
+func TestOSTreeDiskImageManifestRejectsMultipleDisks(t *testing.T) {
+	rng := rand.New(rand.NewSource(0)) // nolint:gosec
+
+	mf := manifest.New()
+	img := image.NewOSTreeDiskImageFromContainer(container.SourceSpec{Source: "source-spec", Name: "name"}, "ostree/1/1/0")
+	img.Platform = &platform.X86{
+		BasePlatform: platform.BasePlatform{
+			ImageFormat: platform.FORMAT_QCOW2,
+		},
+		UEFIVendor: "fedora",
+	}
+	img.PartitionTable = &disk.PartitionTable{
+		Partitions: []disk.Partition{
+			{Payload: &disk.Filesystem{Type: "xfs", Mountpoint: "/"}},
+		},
+		ExtraDisks: []*disk.PartitionTable{
+			{
+				Partitions: []disk.Partition{
+					{Payload: &disk.Filesystem{Type: "xfs", Mountpoint: "/data"}},
+				},
+			},
+		},
+	}
+
+	_, err := img.InstantiateManifest(&mf, nil, &runner.Fedora{Version: 39}, rng)
+	require.EqualError(t, err, "ostree disk images cannot have more than one disk")
+}
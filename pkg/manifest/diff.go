@@ -0,0 +1,668 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// ChangeType describes how an element of a manifest changed
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+	ChangeMoved    ChangeType = "moved"
+)
+
+// ManifestDiff is the semantic difference between two osbuild
+// manifests, see DiffManifests()
+type ManifestDiff struct {
+	Pipelines []PipelineDiff `json:"pipelines"`
+	Sources   []SourceDiff   `json:"sources,omitempty"`
+}
+
+// SourceDiff describes the changes of the items of a single source
+// type, items are identified by their checksum or ID. The rpm sources
+// are not part of the sources diff, packages are compared by their
+// NEVRA in the rpm stages instead.
+type SourceDiff struct {
+	Type    string     `json:"type"`
+	Change  ChangeType `json:"change"`
+	Added   []string   `json:"added,omitempty"`
+	Removed []string   `json:"removed,omitempty"`
+	Changed []string   `json:"changed,omitempty"`
+}
+
+// PipelineDiff describes the changes of a single pipeline
+type PipelineDiff struct {
+	Name   string      `json:"name"`
+	Change ChangeType  `json:"change"`
+	Stages []StageDiff `json:"stages,omitempty"`
+}
+
+// StageDiff describes the changes of a single stage. OldIndex and
+// NewIndex are the positions of the stage in the old and new
+// pipeline, -1 if the stage does not exist on that side.
+type StageDiff struct {
+	Type     string        `json:"type"`
+	Change   ChangeType    `json:"change"`
+	OldIndex int           `json:"old-index"`
+	NewIndex int           `json:"new-index"`
+	Fields   []FieldChange `json:"fields,omitempty"`
+	Packages *PackageDiff  `json:"packages,omitempty"`
+}
+
+// FieldChange is a change of a single value in the stage options,
+// inputs, devices or mounts. Path uses a dotted notation, e.g.
+// "options.partitions[0].size".
+type FieldChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// PackageDiff is the difference of the package set installed by an
+// rpm stage. Packages are compared by name and architecture (as
+// "name.arch") and their version, so a package that only changed its
+// checksum or location is not reported.
+type PackageDiff struct {
+	Added   []string        `json:"added,omitempty"`
+	Removed []string        `json:"removed,omitempty"`
+	Changed []PackageChange `json:"changed,omitempty"`
+}
+
+// PackageChange is a package that is installed in both manifests
+// but with a different version. Name is "name.arch".
+type PackageChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+func (pd *PackageDiff) empty() bool {
+	return len(pd.Added) == 0 && len(pd.Removed) == 0 && len(pd.Changed) == 0
+}
+
+// Empty returns true if there are no differences
+func (d *ManifestDiff) Empty() bool {
+	return len(d.Pipelines) == 0 && len(d.Sources) == 0
+}
+
+// diffManifest is the subset of an osbuild manifest that is relevant
+// for diffing
+type diffManifest struct {
+	Pipelines []diffPipeline           `json:"pipelines"`
+	Sources   map[string]diffSourceSet `json:"sources"`
+}
+
+type diffPipeline struct {
+	Name   string           `json:"name"`
+	Stages []map[string]any `json:"stages"`
+}
+
+type diffSourceSet struct {
+	Items map[string]json.RawMessage `json:"items"`
+}
+
+// DiffManifests compares two serialized osbuild manifests
+// semantically and returns the changes per pipeline and stage.
+func DiffManifests(oldManifest, newManifest OSBuildManifest) (*ManifestDiff, error) {
+	var oldMf, newMf diffManifest
+	if err := json.Unmarshal(oldManifest, &oldMf); err != nil {
+		return nil, fmt.Errorf("cannot parse old manifest: %w", err)
+	}
+	if err := json.Unmarshal(newManifest, &newMf); err != nil {
+		return nil, fmt.Errorf("cannot parse new manifest: %w", err)
+	}
+	oldPkgs := oldMf.packageNames()
+	newPkgs := newMf.packageNames()
+
+	oldPipelines := make(map[string]diffPipeline, len(oldMf.Pipelines))
+	for _, pl := range oldMf.Pipelines {
+		oldPipelines[pl.Name] = pl
+	}
+	newPipelines := make(map[string]bool, len(newMf.Pipelines))
+
+	diff := &ManifestDiff{
+		Pipelines: []PipelineDiff{},
+	}
+	// report in the order of the new manifest, followed by the
+	// removed pipelines
+	for _, newPl := range newMf.Pipelines {
+		newPipelines[newPl.Name] = true
+		oldPl, ok := oldPipelines[newPl.Name]
+		if !ok {
+			diff.Pipelines = append(diff.Pipelines, PipelineDiff{Name: newPl.Name, Change: ChangeAdded})
+			continue
+		}
+		stages := diffStages(oldPl.Stages, newPl.Stages, oldPkgs, newPkgs)
+		if len(stages) > 0 {
+			diff.Pipelines = append(diff.Pipelines, PipelineDiff{
+				Name:   newPl.Name,
+				Change: ChangeModified,
+				Stages: stages,
+			})
+		}
+	}
+	for _, oldPl := range oldMf.Pipelines {
+		if !newPipelines[oldPl.Name] {
+			diff.Pipelines = append(diff.Pipelines, PipelineDiff{Name: oldPl.Name, Change: ChangeRemoved})
+		}
+	}
+	diff.Sources = diffSources(oldMf.Sources, newMf.Sources)
+
+	return diff, nil
+}
+
+// rpmSourceTypes are the source types that fetch the packages of the
+// rpm stages, they are diffed by the package NEVRAs and skipped by
+// diffSources()
+var rpmSourceTypes = map[string]bool{
+	osbuild.SourceNameCurl:    true,
+	osbuild.SourceNameLibrepo: true,
+}
+
+// diffSources compares the items of every source type, except for the
+// rpm sources, by their checksum or ID
+func diffSources(oldSources, newSources map[string]diffSourceSet) []SourceDiff {
+	types := make(map[string]bool, len(oldSources)+len(newSources))
+	for name := range oldSources {
+		if !rpmSourceTypes[name] {
+			types[name] = true
+		}
+	}
+	for name := range newSources {
+		if !rpmSourceTypes[name] {
+			types[name] = true
+		}
+	}
+	sortedTypes := make([]string, 0, len(types))
+	for name := range types {
+		sortedTypes = append(sortedTypes, name)
+	}
+	sort.Strings(sortedTypes)
+
+	var diffs []SourceDiff
+	for _, name := range sortedTypes {
+		oldSrc, inOld := oldSources[name]
+		newSrc, inNew := newSources[name]
+		sd := SourceDiff{Type: name, Change: ChangeModified}
+		switch {
+		case !inOld:
+			sd.Change = ChangeAdded
+		case !inNew:
+			sd.Change = ChangeRemoved
+		}
+		for id, newItem := range newSrc.Items {
+			oldItem, ok := oldSrc.Items[id]
+			switch {
+			case !ok:
+				sd.Added = append(sd.Added, id)
+			case !jsonEqual(oldItem, newItem):
+				sd.Changed = append(sd.Changed, id)
+			}
+		}
+		for id := range oldSrc.Items {
+			if _, ok := newSrc.Items[id]; !ok {
+				sd.Removed = append(sd.Removed, id)
+			}
+		}
+		if sd.Change == ChangeModified && len(sd.Added) == 0 && len(sd.Removed) == 0 && len(sd.Changed) == 0 {
+			continue
+		}
+		sort.Strings(sd.Added)
+		sort.Strings(sd.Removed)
+		sort.Strings(sd.Changed)
+		diffs = append(diffs, sd)
+	}
+	return diffs
+}
+
+// jsonEqual compares two JSON values semantically, i.e. ignoring the
+// formatting and the order of keys
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		return string(a) == string(b)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// packageNames returns a map of source checksums to rpm file names
+func (mf *diffManifest) packageNames() map[string]string {
+	names := make(map[string]string)
+	for _, src := range mf.Sources {
+		for checksum, raw := range src.Items {
+			var location string
+			var item struct {
+				URL  string `json:"url"`
+				Path string `json:"path"`
+			}
+			if err := json.Unmarshal(raw, &location); err != nil {
+				if err := json.Unmarshal(raw, &item); err != nil {
+					continue
+				}
+				location = item.URL
+				if location == "" {
+					location = item.Path
+				}
+			}
+			if strings.HasSuffix(location, ".rpm") {
+				names[checksum] = path.Base(location)
+			}
+		}
+	}
+	return names
+}
+
+func stageType(stage map[string]any) string {
+	t, _ := stage["type"].(string)
+	return t
+}
+
+// diffStages aligns the old and new stages by type using the longest
+// common subsequence and compares the matched stages. Stages of the
+// same type that are only found outside of the common subsequence
+// are reported as moved.
+func diffStages(oldStages, newStages []map[string]any, oldPkgs, newPkgs map[string]string) []StageDiff {
+	n, m := len(oldStages), len(newStages)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if stageType(oldStages[i]) == stageType(newStages[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type pair struct{ old, new int }
+	var matched []pair
+	var removed, added []int
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case stageType(oldStages[i]) == stageType(newStages[j]):
+			matched = append(matched, pair{i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, i)
+			i++
+		default:
+			added = append(added, j)
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		removed = append(removed, i)
+	}
+	for ; j < m; j++ {
+		added = append(added, j)
+	}
+
+	// pair up removed and added stages of the same type as moves
+	var moved []pair
+	var stillRemoved []int
+	for _, oldIdx := range removed {
+		found := -1
+		for k, newIdx := range added {
+			if stageType(oldStages[oldIdx]) == stageType(newStages[newIdx]) {
+				found = k
+				break
+			}
+		}
+		if found < 0 {
+			stillRemoved = append(stillRemoved, oldIdx)
+			continue
+		}
+		moved = append(moved, pair{oldIdx, added[found]})
+		added = append(added[:found], added[found+1:]...)
+	}
+
+	var diffs []StageDiff
+	compare := func(p pair, change ChangeType) {
+		sd := StageDiff{
+			Type:     stageType(newStages[p.new]),
+			Change:   change,
+			OldIndex: p.old,
+			NewIndex: p.new,
+			Fields:   diffStageFields(oldStages[p.old], newStages[p.new]),
+		}
+		if sd.Type == "org.osbuild.rpm" {
+			pd := diffPackages(stagePackages(oldStages[p.old], oldPkgs), stagePackages(newStages[p.new], newPkgs))
+			if !pd.empty() {
+				sd.Packages = pd
+			}
+		}
+		if change == ChangeModified && len(sd.Fields) == 0 && sd.Packages == nil {
+			return
+		}
+		diffs = append(diffs, sd)
+	}
+	for _, p := range matched {
+		compare(p, ChangeModified)
+	}
+	for _, p := range moved {
+		compare(p, ChangeMoved)
+	}
+	for _, newIdx := range added {
+		diffs = append(diffs, StageDiff{Type: stageType(newStages[newIdx]), Change: ChangeAdded, OldIndex: -1, NewIndex: newIdx})
+	}
+	for _, oldIdx := range stillRemoved {
+		diffs = append(diffs, StageDiff{Type: stageType(oldStages[oldIdx]), Change: ChangeRemoved, OldIndex: oldIdx, NewIndex: -1})
+	}
+
+	sort.SliceStable(diffs, func(a, b int) bool {
+		return diffs[a].sortKey() < diffs[b].sortKey()
+	})
+	return diffs
+}
+
+// sortKey orders stage diffs by their position in the new pipeline,
+// removed stages are ordered by their old position
+func (sd StageDiff) sortKey() int {
+	if sd.NewIndex >= 0 {
+		return sd.NewIndex
+	}
+	return sd.OldIndex
+}
+
+// diffStageFields compares everything but the type of two stages.
+// The references of inputs are compared by their checksum or ID,
+// independent of the notation (list or map) that is used. The
+// packages input of the rpm stage is skipped, it is compared via
+// diffPackages().
+func diffStageFields(oldStage, newStage map[string]any) []FieldChange {
+	var changes []FieldChange
+	for _, key := range []string{"options", "inputs", "devices", "mounts"} {
+		oldVal, newVal := oldStage[key], newStage[key]
+		if key == "inputs" {
+			oldVal = normalizeInputs(stageType(oldStage), oldVal)
+			newVal = normalizeInputs(stageType(newStage), newVal)
+		}
+		changes = append(changes, diffValues(key, oldVal, newVal)...)
+	}
+	return changes
+}
+
+func normalizeInputs(stageType string, inputs any) any {
+	m, ok := inputs.(map[string]any)
+	if !ok {
+		return inputs
+	}
+	res := make(map[string]any, len(m))
+	for name, input := range m {
+		if stageType == "org.osbuild.rpm" && name == "packages" {
+			continue
+		}
+		in, ok := input.(map[string]any)
+		if !ok {
+			res[name] = input
+			continue
+		}
+		normalized := make(map[string]any, len(in))
+		for k, v := range in {
+			normalized[k] = v
+		}
+		if refs, ok := in["references"]; ok {
+			normalized["references"] = normalizeReferences(refs)
+		}
+		res[name] = normalized
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// normalizeReferences converts the different notations of input
+// references into a map of the checksum or ID to the options of the
+// reference, so that they are compared by ID and not by position
+func normalizeReferences(refs any) any {
+	list, ok := refs.([]any)
+	if !ok {
+		return refs
+	}
+	res := make(map[string]any, len(list))
+	for _, ref := range list {
+		switch r := ref.(type) {
+		case string:
+			res[r] = map[string]any{}
+		case map[string]any:
+			id, ok := r["id"].(string)
+			if !ok {
+				return refs
+			}
+			opts, ok := r["options"]
+			if !ok {
+				opts = map[string]any{}
+			}
+			res[id] = opts
+		default:
+			return refs
+		}
+	}
+	return res
+}
+
+func diffValues(path string, oldVal, newVal any) []FieldChange {
+	switch o := oldVal.(type) {
+	case map[string]any:
+		n, ok := newVal.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool, len(o)+len(n))
+		for k := range o {
+			keys[k] = true
+		}
+		for k := range n {
+			keys[k] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for k := range keys {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+		var changes []FieldChange
+		for _, k := range sortedKeys {
+			changes = append(changes, diffValues(path+"."+k, o[k], n[k])...)
+		}
+		return changes
+	case []any:
+		n, ok := newVal.([]any)
+		if !ok {
+			break
+		}
+		var changes []FieldChange
+		for idx := 0; idx < max(len(o), len(n)); idx++ {
+			var ov, nv any
+			if idx < len(o) {
+				ov = o[idx]
+			}
+			if idx < len(n) {
+				nv = n[idx]
+			}
+			changes = append(changes, diffValues(fmt.Sprintf("%s[%d]", path, idx), ov, nv)...)
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(oldVal, newVal) {
+		return nil
+	}
+	return []FieldChange{{Path: path, Old: oldVal, New: newVal}}
+}
+
+// stagePackages returns the rpm file names referenced by the
+// packages input of an rpm stage
+func stagePackages(stage map[string]any, names map[string]string) []string {
+	inputs, _ := stage["inputs"].(map[string]any)
+	pkgInput, _ := inputs["packages"].(map[string]any)
+
+	var checksums []string
+	switch refs := pkgInput["references"].(type) {
+	case []any:
+		for _, ref := range refs {
+			switch r := ref.(type) {
+			case string:
+				checksums = append(checksums, r)
+			case map[string]any:
+				if id, ok := r["id"].(string); ok {
+					checksums = append(checksums, id)
+				}
+			}
+		}
+	case map[string]any:
+		for checksum := range refs {
+			checksums = append(checksums, checksum)
+		}
+	}
+
+	pkgs := make([]string, 0, len(checksums))
+	for _, checksum := range checksums {
+		if name, ok := names[checksum]; ok {
+			pkgs = append(pkgs, name)
+		} else {
+			pkgs = append(pkgs, checksum)
+		}
+	}
+	return pkgs
+}
+
+// splitRPMFilename splits "name-version-release.arch.rpm" into the
+// "name.arch" and the "version-release" parts
+func splitRPMFilename(filename string) (string, string) {
+	nevra := strings.TrimSuffix(filename, ".rpm")
+	archIdx := strings.LastIndex(nevra, ".")
+	if archIdx <= 0 {
+		return nevra, ""
+	}
+	nevr, arch := nevra[:archIdx], nevra[archIdx+1:]
+	relIdx := strings.LastIndex(nevr, "-")
+	if relIdx <= 0 {
+		return nevra, ""
+	}
+	verIdx := strings.LastIndex(nevr[:relIdx], "-")
+	if verIdx <= 0 {
+		return nevra, ""
+	}
+	return nevr[:verIdx] + "." + arch, nevr[verIdx+1:]
+}
+
+func diffPackages(oldPkgs, newPkgs []string) *PackageDiff {
+	oldVersions := make(map[string]string, len(oldPkgs))
+	for _, pkg := range oldPkgs {
+		nameArch, version := splitRPMFilename(pkg)
+		oldVersions[nameArch] = version
+	}
+	newVersions := make(map[string]string, len(newPkgs))
+	for _, pkg := range newPkgs {
+		nameArch, version := splitRPMFilename(pkg)
+		newVersions[nameArch] = version
+	}
+
+	pd := &PackageDiff{}
+	for name, newVersion := range newVersions {
+		oldVersion, ok := oldVersions[name]
+		switch {
+		case !ok:
+			pd.Added = append(pd.Added, name)
+		case oldVersion != newVersion:
+			pd.Changed = append(pd.Changed, PackageChange{Name: name, Old: oldVersion, New: newVersion})
+		}
+	}
+	for name := range oldVersions {
+		if _, ok := newVersions[name]; !ok {
+			pd.Removed = append(pd.Removed, name)
+		}
+	}
+	sort.Strings(pd.Added)
+	sort.Strings(pd.Removed)
+	sort.Slice(pd.Changed, func(i, j int) bool {
+		return pd.Changed[i].Name < pd.Changed[j].Name
+	})
+	return pd
+}
+
+func formatValue(v any) string {
+	if v == nil {
+		return "<unset>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func formatStageIndex(sd StageDiff) string {
+	switch {
+	case sd.OldIndex < 0:
+		return fmt.Sprintf("[%d]", sd.NewIndex)
+	case sd.NewIndex < 0, sd.OldIndex == sd.NewIndex:
+		return fmt.Sprintf("[%d]", sd.OldIndex)
+	default:
+		return fmt.Sprintf("[%d -> %d]", sd.OldIndex, sd.NewIndex)
+	}
+}
+
+// WriteText writes a human readable representation of the diff
+func (d *ManifestDiff) WriteText(w io.Writer) error {
+	if d.Empty() {
+		_, err := fmt.Fprintln(w, "no differences")
+		return err
+	}
+
+	var b strings.Builder
+	for _, pl := range d.Pipelines {
+		fmt.Fprintf(&b, "pipeline %s: %s\n", pl.Name, pl.Change)
+		for _, sd := range pl.Stages {
+			fmt.Fprintf(&b, "  stage %s %s: %s\n", sd.Type, formatStageIndex(sd), sd.Change)
+			for _, fc := range sd.Fields {
+				fmt.Fprintf(&b, "    %s: %s -> %s\n", fc.Path, formatValue(fc.Old), formatValue(fc.New))
+			}
+			if sd.Packages != nil {
+				for _, name := range sd.Packages.Added {
+					fmt.Fprintf(&b, "    + %s\n", name)
+				}
+				for _, name := range sd.Packages.Removed {
+					fmt.Fprintf(&b, "    - %s\n", name)
+				}
+				for _, pc := range sd.Packages.Changed {
+					fmt.Fprintf(&b, "    ~ %s: %s -> %s\n", pc.Name, pc.Old, pc.New)
+				}
+			}
+		}
+	}
+	for _, sd := range d.Sources {
+		fmt.Fprintf(&b, "source %s: %s\n", sd.Type, sd.Change)
+		for _, id := range sd.Added {
+			fmt.Fprintf(&b, "  + %s\n", id)
+		}
+		for _, id := range sd.Removed {
+			fmt.Fprintf(&b, "  - %s\n", id)
+		}
+		for _, id := range sd.Changed {
+			fmt.Fprintf(&b, "  ~ %s\n", id)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
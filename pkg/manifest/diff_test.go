@@ -0,0 +1,274 @@
+package manifest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+const diffOldManifest = `{
+  "version": "2",
+  "pipelines": [
+    {
+      "name": "build",
+      "stages": [
+        {"type": "org.osbuild.rpm", "inputs": {"packages": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": [{"id": "sha256:b1"}]}}}
+      ]
+    },
+    {
+      "name": "os",
+      "stages": [
+        {"type": "org.osbuild.rpm", "inputs": {"packages": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": [{"id": "sha256:o1"}, {"id": "sha256:o2"}, {"id": "sha256:o3"}]}}},
+        {"type": "org.osbuild.kernel-cmdline", "options": {"root_fs_uuid": "1234", "kernel_opts": "ro"}},
+        {"type": "org.osbuild.hostname", "options": {"hostname": "old"}},
+        {"type": "org.osbuild.locale", "options": {"language": "en_US.UTF-8"}},
+        {"type": "org.osbuild.selinux", "options": {"file_contexts": "etc/selinux/targeted/contexts/files/file_contexts"}}
+      ]
+    },
+    {
+      "name": "old-only",
+      "stages": []
+    }
+  ],
+  "sources": {
+    "org.osbuild.curl": {
+      "items": {
+        "sha256:b1": {"url": "https://example.com/Packages/bash-5.2.26-1.fc40.x86_64.rpm"},
+        "sha256:o1": {"url": "https://example.com/Packages/bash-5.2.26-1.fc40.x86_64.rpm"},
+        "sha256:o2": "https://example.com/Packages/vim-minimal-9.1.0-1.fc40.x86_64.rpm",
+        "sha256:o3": {"url": "https://example.com/Packages/nano-7.2-1.fc40.x86_64.rpm"}
+      }
+    }
+  }
+}`
+
+const diffNewManifest = `{
+  "version": "2",
+  "pipelines": [
+    {
+      "name": "build",
+      "stages": [
+        {"type": "org.osbuild.rpm", "inputs": {"packages": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": [{"id": "sha256:b1-new"}]}}}
+      ]
+    },
+    {
+      "name": "os",
+      "stages": [
+        {"type": "org.osbuild.rpm", "inputs": {"packages": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": {"sha256:n1": {}, "sha256:n2": {}, "sha256:n4": {}}}}},
+        {"type": "org.osbuild.kernel-cmdline", "options": {"root_fs_uuid": "1234", "kernel_opts": "ro quiet"}},
+        {"type": "org.osbuild.locale", "options": {"language": "en_US.UTF-8"}},
+        {"type": "org.osbuild.hostname", "options": {"hostname": "old"}},
+        {"type": "org.osbuild.timezone", "options": {"zone": "UTC"}}
+      ]
+    },
+    {
+      "name": "new-only",
+      "stages": []
+    }
+  ],
+  "sources": {
+    "org.osbuild.librepo": {
+      "items": {
+        "sha256:b1-new": {"path": "Packages/bash-5.2.26-1.fc40.x86_64.rpm", "mirror": "m1"},
+        "sha256:n1": {"path": "Packages/bash-5.2.26-2.fc40.x86_64.rpm", "mirror": "m1"},
+        "sha256:n2": {"path": "Packages/vim-minimal-9.1.0-1.fc40.x86_64.rpm", "mirror": "m1"},
+        "sha256:n4": {"path": "Packages/less-643-4.fc40.x86_64.rpm", "mirror": "m1"}
+      }
+    }
+  }
+}`
+
+func TestDiffManifests(t *testing.T) {
+	diff, err := manifest.DiffManifests(manifest.OSBuildManifest(diffOldManifest), manifest.OSBuildManifest(diffNewManifest))
+	require.NoError(t, err)
+
+	expected := &manifest.ManifestDiff{
+		Pipelines: []manifest.PipelineDiff{
+			{
+				Name:   "os",
+				Change: manifest.ChangeModified,
+				Stages: []manifest.StageDiff{
+					{
+						Type:     "org.osbuild.rpm",
+						Change:   manifest.ChangeModified,
+						OldIndex: 0,
+						NewIndex: 0,
+						Packages: &manifest.PackageDiff{
+							Added:   []string{"less.x86_64"},
+							Removed: []string{"nano.x86_64"},
+							Changed: []manifest.PackageChange{
+								{Name: "bash.x86_64", Old: "5.2.26-1.fc40", New: "5.2.26-2.fc40"},
+							},
+						},
+					},
+					{
+						Type:     "org.osbuild.kernel-cmdline",
+						Change:   manifest.ChangeModified,
+						OldIndex: 1,
+						NewIndex: 1,
+						Fields: []manifest.FieldChange{
+							{Path: "options.kernel_opts", Old: "ro", New: "ro quiet"},
+						},
+					},
+					{
+						Type:     "org.osbuild.hostname",
+						Change:   manifest.ChangeMoved,
+						OldIndex: 2,
+						NewIndex: 3,
+					},
+					{
+						Type:     "org.osbuild.timezone",
+						Change:   manifest.ChangeAdded,
+						OldIndex: -1,
+						NewIndex: 4,
+					},
+					{
+						Type:     "org.osbuild.selinux",
+						Change:   manifest.ChangeRemoved,
+						OldIndex: 4,
+						NewIndex: -1,
+					},
+				},
+			},
+			{Name: "new-only", Change: manifest.ChangeAdded},
+			{Name: "old-only", Change: manifest.ChangeRemoved},
+		},
+	}
+	assert.Equal(t, expected, diff)
+
+	var buf bytes.Buffer
+	require.NoError(t, diff.WriteText(&buf))
+	assert.Equal(t, `pipeline os: modified
+  stage org.osbuild.rpm [0]: modified
+    + less.x86_64
+    - nano.x86_64
+    ~ bash.x86_64: 5.2.26-1.fc40 -> 5.2.26-2.fc40
+  stage org.osbuild.kernel-cmdline [1]: modified
+    options.kernel_opts: "ro" -> "ro quiet"
+  stage org.osbuild.hostname [2 -> 3]: moved
+  stage org.osbuild.timezone [4]: added
+  stage org.osbuild.selinux [4]: removed
+pipeline new-only: added
+pipeline old-only: removed
+`, buf.String())
+
+	js, err := json.Marshal(diff)
+	require.NoError(t, err)
+	assert.Contains(t, string(js), `{"path":"options.kernel_opts","old":"ro","new":"ro quiet"}`)
+}
+
+func TestDiffManifestsIdentical(t *testing.T) {
+	diff, err := manifest.DiffManifests(manifest.OSBuildManifest(diffOldManifest), manifest.OSBuildManifest(diffOldManifest))
+	require.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	var buf bytes.Buffer
+	require.NoError(t, diff.WriteText(&buf))
+	assert.Equal(t, "no differences\n", buf.String())
+}
+
+func TestDiffManifestsPackageChecksum(t *testing.T) {
+	// same NEVRA with a new checksum and source type
+	oldMf := `{"pipelines": [{"name": "os", "stages": [{"type": "org.osbuild.rpm", "inputs": {"packages": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": [{"id": "sha256:aa"}]}}}]}],
+"sources": {"org.osbuild.curl": {"items": {"sha256:aa": {"url": "https://example.com/Packages/bash-5.2.26-1.fc40.x86_64.rpm"}}}}}`
+	newMf := `{"pipelines": [{"name": "os", "stages": [{"type": "org.osbuild.rpm", "inputs": {"packages": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": [{"id": "sha256:bb"}]}}}]}],
+"sources": {"org.osbuild.librepo": {"items": {"sha256:bb": {"path": "Packages/bash-5.2.26-1.fc40.x86_64.rpm", "mirror": "m1"}}}}}`
+
+	diff, err := manifest.DiffManifests(manifest.OSBuildManifest(oldMf), manifest.OSBuildManifest(newMf))
+	require.NoError(t, err)
+	assert.True(t, diff.Empty())
+}
+
+func TestDiffManifestsNestedOptions(t *testing.T) {
+	oldMf := `{"pipelines": [{"name": "image", "stages": [{"type": "org.osbuild.sfdisk", "options": {"partitions": [{"size": 100}, {"size": 200}]}, "devices": {"device": {"type": "org.osbuild.loopback", "options": {"filename": "disk.raw"}}}}]}]}`
+	newMf := `{"pipelines": [{"name": "image", "stages": [{"type": "org.osbuild.sfdisk", "options": {"partitions": [{"size": 100}, {"size": 300}, {"size": 10}]}, "devices": {"device": {"type": "org.osbuild.loopback", "options": {"filename": "disk.img"}}}}]}]}`
+
+	diff, err := manifest.DiffManifests(manifest.OSBuildManifest(oldMf), manifest.OSBuildManifest(newMf))
+	require.NoError(t, err)
+	require.Len(t, diff.Pipelines, 1)
+	require.Len(t, diff.Pipelines[0].Stages, 1)
+	assert.Equal(t, []manifest.FieldChange{
+		{Path: "options.partitions[1].size", Old: float64(200), New: float64(300)},
+		{Path: "options.partitions[2]", New: map[string]any{"size": float64(10)}},
+		{Path: "devices.device.options.filename", Old: "disk.raw", New: "disk.img"},
+	}, diff.Pipelines[0].Stages[0].Fields)
+}
+
+func TestDiffManifestsMultilibPackages(t *testing.T) {
+	mf := func(pkgs ...string) manifest.OSBuildManifest {
+		refs := []string{}
+		items := map[string]string{}
+		for _, pkg := range pkgs {
+			refs = append(refs, "sha256:"+pkg)
+			items["sha256:"+pkg] = "https://example.com/" + pkg
+		}
+		js, err := json.Marshal(map[string]any{
+			"pipelines": []any{
+				map[string]any{"name": "os", "stages": []any{
+					map[string]any{"type": "org.osbuild.rpm", "inputs": map[string]any{
+						"packages": map[string]any{"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": refs},
+					}},
+				}},
+			},
+			"sources": map[string]any{"org.osbuild.curl": map[string]any{"items": items}},
+		})
+		require.NoError(t, err)
+		return manifest.OSBuildManifest(js)
+	}
+
+	oldMf := mf("glibc-2.39-5.fc40.x86_64.rpm", "glibc-2.39-5.fc40.i686.rpm")
+	newMf := mf("glibc-2.39-6.fc40.x86_64.rpm")
+	diff, err := manifest.DiffManifests(oldMf, newMf)
+	require.NoError(t, err)
+	require.Len(t, diff.Pipelines, 1)
+	require.Len(t, diff.Pipelines[0].Stages, 1)
+	assert.Equal(t, &manifest.PackageDiff{
+		Removed: []string{"glibc.i686"},
+		Changed: []manifest.PackageChange{
+			{Name: "glibc.x86_64", Old: "2.39-5.fc40", New: "2.39-6.fc40"},
+		},
+	}, diff.Pipelines[0].Stages[0].Packages)
+}
+
+func TestDiffManifestsSourceInputs(t *testing.T) {
+	oldMf := `{"pipelines": [{"name": "os", "stages": [{"type": "org.osbuild.copy", "inputs": {"inlinefile": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": [{"id": "sha256:aa", "options": {"path": "a"}}, {"id": "sha256:bb"}]}}}]}],
+"sources": {"org.osbuild.inline": {"items": {"sha256:aa": {"encoding": "base64", "data": "YQ=="}, "sha256:bb": {"encoding": "base64", "data": "Yg=="}}}}}`
+	// same references in a different order and notation
+	sameMf := `{"pipelines": [{"name": "os", "stages": [{"type": "org.osbuild.copy", "inputs": {"inlinefile": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": {"sha256:bb": {}, "sha256:aa": {"path": "a"}}}}}]}],
+"sources": {"org.osbuild.inline": {"items": {"sha256:bb": {"data": "Yg==", "encoding": "base64"}, "sha256:aa": {"encoding": "base64", "data": "YQ=="}}}}}`
+	newMf := `{"pipelines": [{"name": "os", "stages": [{"type": "org.osbuild.copy", "inputs": {"inlinefile": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": [{"id": "sha256:aa", "options": {"path": "a"}}, {"id": "sha256:cc"}]}}}]}],
+"sources": {"org.osbuild.inline": {"items": {"sha256:aa": {"encoding": "base64", "data": "YQ=="}, "sha256:cc": {"encoding": "base64", "data": "Yw=="}}}}}`
+
+	diff, err := manifest.DiffManifests(manifest.OSBuildManifest(oldMf), manifest.OSBuildManifest(sameMf))
+	require.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	diff, err = manifest.DiffManifests(manifest.OSBuildManifest(oldMf), manifest.OSBuildManifest(newMf))
+	require.NoError(t, err)
+	require.Len(t, diff.Pipelines, 1)
+	require.Len(t, diff.Pipelines[0].Stages, 1)
+	assert.Equal(t, []manifest.FieldChange{
+		{Path: "inputs.inlinefile.references.sha256:bb", Old: map[string]any{}},
+		{Path: "inputs.inlinefile.references.sha256:cc", New: map[string]any{}},
+	}, diff.Pipelines[0].Stages[0].Fields)
+	assert.Equal(t, []manifest.SourceDiff{
+		{
+			Type:    "org.osbuild.inline",
+			Change:  manifest.ChangeModified,
+			Added:   []string{"sha256:cc"},
+			Removed: []string{"sha256:bb"},
+		},
+	}, diff.Sources)
+}
+
+func TestDiffManifestsBadInput(t *testing.T) {
+	_, err := manifest.DiffManifests(manifest.OSBuildManifest("{"), manifest.OSBuildManifest("{}"))
+	assert.ErrorContains(t, err, "cannot parse old manifest")
+	_, err = manifest.DiffManifests(manifest.OSBuildManifest("{}"), manifest.OSBuildManifest("[]"))
+	assert.ErrorContains(t, err, "cannot parse new manifest")
+}
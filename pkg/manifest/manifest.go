@@ -16,6 +16,7 @@ import (
 	"encoding/json"
 
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/dnfjson"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
@@ -141,6 +142,32 @@ func (m Manifest) GetOSTreeSourceSpecs() map[string][]ostree.SourceSpec {
 	return ostreeSpecs
 }
 
+// GetPartitionTables returns the partition tables of the pipelines that lay
+// out a disk, keyed by pipeline name. For installers, it is the partition
+// table of the installed system.
+func (m Manifest) GetPartitionTables() map[string]*disk.PartitionTable {
+	pts := make(map[string]*disk.PartitionTable)
+	for _, pipeline := range m.pipelines {
+		var pt *disk.PartitionTable
+		switch p := pipeline.(type) {
+		case *OS:
+			pt = p.PartitionTable
+		case *OSTreeDeployment:
+			pt = p.PartitionTable
+		case *RawBootcImage:
+			pt = p.PartitionTable
+		case *AnacondaInstallerISOTree:
+			pt = p.PartitionTable
+		case *CoreOSISOTree:
+			pt = p.PartitionTable
+		}
+		if pt != nil {
+			pts[pipeline.Name()] = pt
+		}
+	}
+	return pts
+}
+
 type SerializeOptions struct {
 	RpmDownloader osbuild.RpmDownloader
 }
@@ -3,6 +3,7 @@ package manifest
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
@@ -264,12 +265,26 @@ func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
 
 	osRepos := append(p.repos, p.ExtraBaseRepos...)
 
+	// The workload can exclude packages from and select the weak
+	// dependencies of the whole pipeline, since the package sets are
+	// installed together
+	exclude := p.ExcludeBasePackages
+	installWeakDeps := p.InstallWeakDeps
+	var workloadWeakDeps bool
+	if p.Workload != nil {
+		exclude = append(slices.Clone(exclude), p.Workload.GetExcludePackages()...)
+		if weakDeps := p.Workload.GetInstallWeakDeps(); weakDeps != nil {
+			installWeakDeps = *weakDeps
+			workloadWeakDeps = *weakDeps
+		}
+	}
+
 	chain := []rpmmd.PackageSet{
 		{
 			Include:         append(packages, p.ExtraBasePackages...),
-			Exclude:         p.ExcludeBasePackages,
+			Exclude:         exclude,
 			Repositories:    osRepos,
-			InstallWeakDeps: p.InstallWeakDeps,
+			InstallWeakDeps: installWeakDeps,
 		},
 	}
 
@@ -277,8 +292,10 @@ func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
 		workloadPackages := p.Workload.GetPackages()
 		if len(workloadPackages) > 0 {
 			chain = append(chain, rpmmd.PackageSet{
-				Include:      workloadPackages,
-				Repositories: append(osRepos, p.Workload.GetRepos()...),
+				Include:         workloadPackages,
+				Exclude:         p.Workload.GetExcludePackages(),
+				Repositories:    append(osRepos, p.Workload.GetRepos()...),
+				InstallWeakDeps: workloadWeakDeps,
 			})
 		}
 	}
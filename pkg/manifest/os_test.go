@@ -8,9 +8,12 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/testdisk"
+	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/customizations/bootc"
 	"github.com/osbuild/images/pkg/customizations/subscription"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/dnfjson"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
@@ -182,6 +185,36 @@ func TestRhcInsightsPackages(t *testing.T) {
 	CheckPkgSetInclude(t, os.getPackageSetChain(DISTRO_NULL), []string{"rhc", "subscription-manager", "insights-client"})
 }
 
+func TestWorkloadExcludeAndWeakDeps(t *testing.T) {
+	os := NewTestOS()
+	os.ExcludeBasePackages = []string{"dracut-config-rescue"}
+	os.Workload = &workload.Custom{
+		Packages:        []string{"httpd >= 2.4"},
+		ExcludePackages: []string{"firewalld"},
+	}
+
+	// the defaults are kept when weak dependencies are not set
+	chain := os.getPackageSetChain(DISTRO_NULL)
+	require.Len(t, chain, 2)
+	assert.Equal(t, []string{"dracut-config-rescue", "firewalld"}, chain[0].Exclude)
+	assert.True(t, chain[0].InstallWeakDeps)
+	assert.Equal(t, []string{"httpd >= 2.4"}, chain[1].Include)
+	assert.Equal(t, []string{"firewalld"}, chain[1].Exclude)
+	assert.False(t, chain[1].InstallWeakDeps)
+	assert.Equal(t, []string{"dracut-config-rescue"}, os.ExcludeBasePackages)
+
+	for _, weakDeps := range []bool{true, false} {
+		os.Workload = &workload.Custom{
+			Packages:        []string{"httpd"},
+			InstallWeakDeps: common.ToPtr(weakDeps),
+		}
+		chain := os.getPackageSetChain(DISTRO_NULL)
+		require.Len(t, chain, 2)
+		assert.Equal(t, weakDeps, chain[0].InstallWeakDeps)
+		assert.Equal(t, weakDeps, chain[1].InstallWeakDeps)
+	}
+}
+
 func TestBootupdStage(t *testing.T) {
 	os := NewTestOS()
 	os.OSTreeRef = "some/ref"
@@ -230,3 +263,12 @@ func testTomlPkgsFor(t *testing.T, os *OS) {
 		assert.Contains(t, buildPkgs, tc.expectedTomlPkg)
 	}
 }
+
+func TestGetPartitionTables(t *testing.T) {
+	os := NewTestOS()
+	assert.Empty(t, os.manifest.GetPartitionTables())
+
+	pt := testdisk.MakeFakePartitionTable("/", "/boot")
+	os.PartitionTable = pt
+	assert.Equal(t, map[string]*disk.PartitionTable{"os": pt}, os.manifest.GetPartitionTables())
+}
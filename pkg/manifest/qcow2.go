@@ -22,6 +22,17 @@ func (p *QCOW2) SetFilename(filename string) {
 	p.filename = filename
 }
 
+// Filenames returns the names of the qcow2 images of all disks, starting with
+// Filename(). They are named like the raw image files, see
+// osbuild.DiskFilename.
+func (p QCOW2) Filenames() []string {
+	filenames := make([]string, len(diskFilenames(p.imgPipeline)))
+	for idx := range filenames {
+		filenames[idx] = osbuild.DiskFilename(p.Filename(), uint(idx))
+	}
+	return filenames
+}
+
 // NewQCOW2 createsa new QCOW2 pipeline. imgPipeline is the pipeline producing the
 // raw image. The pipeline name is the name of the new pipeline. Filename is the name
 // of the produced qcow2 image.
@@ -43,14 +54,17 @@ func NewQCOW2(buildPipeline Build, imgPipeline FilePipeline) *QCOW2 {
 func (p *QCOW2) serialize() osbuild.Pipeline {
 	pipeline := p.Base.serialize()
 
-	pipeline.AddStage(osbuild.NewQEMUStage(
-		osbuild.NewQEMUStageOptions(p.Filename(),
-			osbuild.QEMUFormatQCOW2,
-			osbuild.QCOW2Options{
-				Compat: p.Compat,
-			}),
-		osbuild.NewQemuStagePipelineFilesInputs(p.imgPipeline.Name(), p.imgPipeline.Filename()),
-	))
+	filenames := p.Filenames()
+	for idx, imgFilename := range diskFilenames(p.imgPipeline) {
+		pipeline.AddStage(osbuild.NewQEMUStage(
+			osbuild.NewQEMUStageOptions(filenames[idx],
+				osbuild.QEMUFormatQCOW2,
+				osbuild.QCOW2Options{
+					Compat: p.Compat,
+				}),
+			osbuild.NewQemuStagePipelineFilesInputs(p.imgPipeline.Name(), imgFilename),
+		))
+	}
 
 	return pipeline
 }
@@ -62,5 +76,5 @@ func (p *QCOW2) getBuildPackages(Distro) []string {
 func (p *QCOW2) Export() *artifact.Artifact {
 	p.Base.export = true
 	mimeType := "application/x-qemu-disk"
-	return artifact.New(p.Name(), p.Filename(), &mimeType)
+	return artifact.NewMultiFile(p.Name(), p.Filenames(), &mimeType)
 }
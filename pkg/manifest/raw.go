@@ -25,6 +25,30 @@ func (p *RawImage) SetFilename(filename string) {
 	p.filename = filename
 }
 
+// Filenames returns the names of the image files of all disks, starting with
+// Filename(), see osbuild.DiskFilenames.
+func (p RawImage) Filenames() []string {
+	pt := p.treePipeline.PartitionTable
+	if pt == nil {
+		return []string{p.filename}
+	}
+	return osbuild.DiskFilenames(pt, p.filename)
+}
+
+// A multiDiskPipeline produces an image file for each disk of the image.
+type multiDiskPipeline interface {
+	Filenames() []string
+}
+
+// diskFilenames returns the names of the image files of all disks produced by
+// the pipeline, starting with its Filename().
+func diskFilenames(p FilePipeline) []string {
+	if mdp, ok := p.(multiDiskPipeline); ok {
+		return mdp.Filenames()
+	}
+	return []string{p.Filename()}
+}
+
 func NewRawImage(buildPipeline Build, treePipeline *OS) *RawImage {
 	p := &RawImage{
 		Base:         NewBase("image", buildPipeline),
@@ -112,5 +136,5 @@ func (p *RawImage) serialize() osbuild.Pipeline {
 
 func (p *RawImage) Export() *artifact.Artifact {
 	p.Base.export = true
-	return artifact.New(p.Name(), p.Filename(), nil)
+	return artifact.NewMultiFile(p.Name(), p.Filenames(), nil)
 }
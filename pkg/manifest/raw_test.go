@@ -0,0 +1,49 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/testdisk"
+	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+func TestRawImageExtraDisks(t *testing.T) {
+	os := NewTestOS()
+	pt := testdisk.MakeFakePartitionTable("/", "/boot")
+	pt.ExtraDisks = []*disk.PartitionTable{
+		testdisk.MakeFakePartitionTable("/data"),
+		testdisk.MakeFakePartitionTable("/srv"),
+	}
+	os.PartitionTable = pt
+
+	rawImage := NewRawImage(os.build, os)
+	rawImage.SetFilename("disk.raw")
+	assert.Equal(t, []string{"disk.raw", "disk-1.raw", "disk-2.raw"}, rawImage.Filenames())
+
+	qcow2 := NewQCOW2(os.build, rawImage)
+	qcow2.SetFilename("disk.qcow2")
+	vmdk := NewVMDK(os.build, rawImage)
+	vmdk.SetFilename("disk.vmdk")
+
+	assert.Equal(t, []string{"disk.raw", "disk-1.raw", "disk-2.raw"}, rawImage.Export().Filenames())
+	assert.Equal(t, []string{"disk.qcow2", "disk-1.qcow2", "disk-2.qcow2"}, qcow2.Export().Filenames())
+	assert.Equal(t, "disk.qcow2", qcow2.Export().Filename())
+	assert.Equal(t, []string{"disk.vmdk", "disk-1.vmdk", "disk-2.vmdk"}, vmdk.Export().Filenames())
+
+	// every disk is converted on its own
+	for _, pipeline := range []Pipeline{qcow2, vmdk} {
+		stages := pipeline.serialize().Stages
+		require.Len(t, stages, 3)
+		for idx, stage := range stages {
+			assert.Equal(t, "org.osbuild.qemu", stage.Type)
+			options := stage.Options.(*osbuild.QEMUStageOptions)
+			assert.Equal(t, pipeline.Export().Filenames()[idx], options.Filename)
+			refs := stage.Inputs.(*osbuild.QEMUStageInputs).Image.References.(*osbuild.FilesInputPipelineObjectRef)
+			assert.Equal(t, rawImage.Filenames()[idx], (*refs)["name:image"].File)
+		}
+	}
+}
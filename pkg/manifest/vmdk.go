@@ -10,7 +10,7 @@ type VMDK struct {
 	Base
 	filename string
 
-	imgPipeline Pipeline
+	imgPipeline FilePipeline
 }
 
 func (p VMDK) Filename() string {
@@ -21,6 +21,17 @@ func (p *VMDK) SetFilename(filename string) {
 	p.filename = filename
 }
 
+// Filenames returns the names of the vmdk images of all disks, starting with
+// Filename(). They are named like the raw image files, see
+// osbuild.DiskFilename.
+func (p VMDK) Filenames() []string {
+	filenames := make([]string, len(diskFilenames(p.imgPipeline)))
+	for idx := range filenames {
+		filenames[idx] = osbuild.DiskFilename(p.Filename(), uint(idx))
+	}
+	return filenames
+}
+
 // NewVMDK creates a new VMDK pipeline. imgPipeline is the pipeline producing the
 // raw image. imgOstreePipeline is the pipeline producing the raw ostree image.
 // Either imgPipeline or imgOStreePipeline are required, but not both at the same time.
@@ -43,12 +54,15 @@ func NewVMDK(buildPipeline Build, imgPipeline FilePipeline) *VMDK {
 func (p *VMDK) serialize() osbuild.Pipeline {
 	pipeline := p.Base.serialize()
 
-	pipeline.AddStage(osbuild.NewQEMUStage(
-		osbuild.NewQEMUStageOptions(p.Filename(), osbuild.QEMUFormatVMDK, osbuild.VMDKOptions{
-			Subformat: osbuild.VMDKSubformatStreamOptimized,
-		}),
-		osbuild.NewQemuStagePipelineFilesInputs(p.imgPipeline.Name(), p.imgPipeline.Export().Filename()),
-	))
+	filenames := p.Filenames()
+	for idx, imgFilename := range p.imgPipeline.Export().Filenames() {
+		pipeline.AddStage(osbuild.NewQEMUStage(
+			osbuild.NewQEMUStageOptions(filenames[idx], osbuild.QEMUFormatVMDK, osbuild.VMDKOptions{
+				Subformat: osbuild.VMDKSubformatStreamOptimized,
+			}),
+			osbuild.NewQemuStagePipelineFilesInputs(p.imgPipeline.Name(), imgFilename),
+		))
+	}
 
 	return pipeline
 }
@@ -60,5 +74,5 @@ func (p *VMDK) getBuildPackages(Distro) []string {
 func (p *VMDK) Export() *artifact.Artifact {
 	p.Base.export = true
 	mimeType := "application/x-vmdk"
-	return artifact.New(p.Name(), p.Filename(), &mimeType)
+	return artifact.NewMultiFile(p.Name(), p.Filenames(), &mimeType)
 }
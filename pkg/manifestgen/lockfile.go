@@ -0,0 +1,202 @@
+package manifestgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	// we cannot use "maps" yet, as it needs go1.23
+	"golang.org/x/exp/maps"
+
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// LockfileVersion is the version of the lockfile format that is written by
+// [Lockfile.Write]. Lockfiles with a different version are rejected.
+const LockfileVersion = 1
+
+// Lockfile pins the packages of each pipeline of a manifest to the exact
+// packages (NEVRAs and checksums) and repositories that a previous depsolve
+// resolved them to. It can be used to rebuild an image with the same content,
+// see [Options.Lockfile].
+type Lockfile struct {
+	Version int `json:"version"`
+
+	// Locked packages and repositories, keyed by pipeline name
+	Pipelines map[string]LockedPipeline `json:"pipelines"`
+}
+
+// LockedPipeline is the locked depsolve result of a single pipeline.
+type LockedPipeline struct {
+	Packages []rpmmd.PackageSpec `json:"packages"`
+	Repos    []rpmmd.RepoConfig  `json:"repos"`
+}
+
+// LockMode selects how the generator uses a lockfile.
+type LockMode int
+
+const (
+	// LockModeVerify depsolves the package sets and fails if the result
+	// differs from the lockfile.
+	LockModeVerify LockMode = iota
+
+	// LockModeReplay skips depsolving and uses the locked packages and
+	// repositories.
+	LockModeReplay
+)
+
+// NewLockfile creates a lockfile from the depsolve results of the pipelines
+// of a manifest.
+func NewLockfile(depsolved map[string]dnfjson.DepsolveResult) *Lockfile {
+	lf := &Lockfile{
+		Version:   LockfileVersion,
+		Pipelines: make(map[string]LockedPipeline, len(depsolved)),
+	}
+	for name, res := range depsolved {
+		lf.Pipelines[name] = LockedPipeline{
+			Packages: slices.Clone(res.Packages),
+			Repos:    slices.Clone(res.Repos),
+		}
+	}
+	return lf
+}
+
+// ReadLockfile reads a lockfile written by [Lockfile.Write].
+func ReadLockfile(r io.Reader) (*Lockfile, error) {
+	var lf Lockfile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&lf); err != nil {
+		return nil, fmt.Errorf("cannot read lockfile: %w", err)
+	}
+	if lf.Version != LockfileVersion {
+		return nil, fmt.Errorf("unsupported lockfile version %d (expected %d)", lf.Version, LockfileVersion)
+	}
+	if lf.Pipelines == nil {
+		return nil, fmt.Errorf("lockfile has no pipelines")
+	}
+	return &lf, nil
+}
+
+// Write writes the lockfile as indented JSON.
+func (lf *Lockfile) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(lf)
+}
+
+// DepsolveResults returns the locked packages and repositories as the
+// depsolve results that a manifest is serialized with.
+func (lf *Lockfile) DepsolveResults() map[string]dnfjson.DepsolveResult {
+	depsolved := make(map[string]dnfjson.DepsolveResult, len(lf.Pipelines))
+	for name, pipeline := range lf.Pipelines {
+		depsolved[name] = dnfjson.DepsolveResult{
+			Packages: slices.Clone(pipeline.Packages),
+			Repos:    slices.Clone(pipeline.Repos),
+		}
+	}
+	return depsolved
+}
+
+// checkPipelines returns an error if the lockfile does not lock exactly the
+// given pipelines, i.e. if the image definition changed since it was written.
+func (lf *Lockfile) checkPipelines(pipelines []string) error {
+	var errs []error
+	for _, name := range pipelines {
+		if _, ok := lf.Pipelines[name]; !ok {
+			errs = append(errs, fmt.Errorf("no packages locked for pipeline %q", name))
+		}
+	}
+	locked := maps.Keys(lf.Pipelines)
+	slices.Sort(locked)
+	for _, name := range locked {
+		if !slices.Contains(pipelines, name) {
+			errs = append(errs, fmt.Errorf("packages locked for unknown pipeline %q", name))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("lockfile does not match the manifest:\n%w", err)
+	}
+	return nil
+}
+
+// Verify returns an error that lists all differences between the locked
+// packages and the given depsolve results.
+func (lf *Lockfile) Verify(depsolved map[string]dnfjson.DepsolveResult) error {
+	names := maps.Keys(depsolved)
+	slices.Sort(names)
+	if err := lf.checkPipelines(names); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, verifyPackages(name, lf.Pipelines[name].Packages, depsolved[name].Packages)...)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("depsolved packages differ from the lockfile:\n%w", err)
+	}
+	return nil
+}
+
+// verifyPackages compares the locked and the depsolved packages of a
+// pipeline. Packages are matched by NEVRA, as installonly packages like the
+// kernel can be installed in more than one version. If a single version of a
+// package is locked and a single other version is depsolved, it is reported
+// as changed.
+func verifyPackages(pipeline string, locked, depsolved []rpmmd.PackageSpec) []error {
+	lockedByNEVRA := make(map[string]*rpmmd.PackageSpec, len(locked))
+	for idx := range locked {
+		lockedByNEVRA[locked[idx].GetNEVRA()] = &locked[idx]
+	}
+	depsolvedByNEVRA := make(map[string]*rpmmd.PackageSpec, len(depsolved))
+	for idx := range depsolved {
+		depsolvedByNEVRA[depsolved[idx].GetNEVRA()] = &depsolved[idx]
+	}
+
+	// the versions that are only locked or only depsolved, by name and
+	// architecture
+	key := func(ps *rpmmd.PackageSpec) string {
+		return ps.Name + "." + ps.Arch
+	}
+	onlyLocked := make(map[string][]*rpmmd.PackageSpec)
+	for idx := range locked {
+		if _, ok := depsolvedByNEVRA[locked[idx].GetNEVRA()]; !ok {
+			onlyLocked[key(&locked[idx])] = append(onlyLocked[key(&locked[idx])], &locked[idx])
+		}
+	}
+	onlyDepsolved := make(map[string][]*rpmmd.PackageSpec)
+	for idx := range depsolved {
+		if _, ok := lockedByNEVRA[depsolved[idx].GetNEVRA()]; !ok {
+			onlyDepsolved[key(&depsolved[idx])] = append(onlyDepsolved[key(&depsolved[idx])], &depsolved[idx])
+		}
+	}
+	changed := func(ps *rpmmd.PackageSpec) bool {
+		return len(onlyLocked[key(ps)]) == 1 && len(onlyDepsolved[key(ps)]) == 1
+	}
+
+	var errs []error
+	for idx := range depsolved {
+		pkg := &depsolved[idx]
+		lockedPkg, ok := lockedByNEVRA[pkg.GetNEVRA()]
+		switch {
+		case !ok && changed(pkg):
+			errs = append(errs, fmt.Errorf("pipeline %q: %s changed to %s", pipeline, onlyLocked[key(pkg)][0].GetNEVRA(), pkg.GetNEVRA()))
+		case !ok:
+			errs = append(errs, fmt.Errorf("pipeline %q: %s is not locked", pipeline, pkg.GetNEVRA()))
+		case lockedPkg.Checksum != pkg.Checksum:
+			errs = append(errs, fmt.Errorf("pipeline %q: checksum of %s changed from %s to %s", pipeline, pkg.GetNEVRA(), lockedPkg.Checksum, pkg.Checksum))
+		}
+	}
+	for idx := range locked {
+		pkg := &locked[idx]
+		if _, ok := depsolvedByNEVRA[pkg.GetNEVRA()]; ok || changed(pkg) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("pipeline %q: %s is no longer part of the depsolve result", pipeline, pkg.GetNEVRA()))
+	}
+	return errs
+}
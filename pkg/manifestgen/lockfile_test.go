@@ -0,0 +1,260 @@
+package manifestgen_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distrofactory"
+	"github.com/osbuild/images/pkg/dnfjson"
+	"github.com/osbuild/images/pkg/imagefilter"
+	"github.com/osbuild/images/pkg/manifestgen"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/sbom"
+	testrepos "github.com/osbuild/images/test/data/repositories"
+)
+
+func lockedResult(pkgs ...rpmmd.PackageSpec) dnfjson.DepsolveResult {
+	return dnfjson.DepsolveResult{
+		Packages: pkgs,
+		Repos:    []rpmmd.RepoConfig{{Id: "baseos", BaseURLs: []string{"https://example.com/baseos"}}},
+	}
+}
+
+func TestLockfileRoundTrip(t *testing.T) {
+	depsolved := map[string]dnfjson.DepsolveResult{
+		"os": lockedResult(
+			rpmmd.PackageSpec{Name: "bash", Version: "5.2.26", Release: "3.fc40", Arch: "x86_64", Checksum: sha256For("bash"), RepoID: "baseos"},
+			rpmmd.PackageSpec{Name: "shadow-utils", Epoch: 2, Version: "4.15.1", Release: "3.fc40", Arch: "x86_64", Checksum: sha256For("shadow-utils"), RepoID: "baseos"},
+		),
+		"build": lockedResult(
+			rpmmd.PackageSpec{Name: "rpm", Version: "4.19.1.1", Release: "1.fc40", Arch: "x86_64", Checksum: sha256For("rpm"), RepoID: "baseos"},
+		),
+	}
+	lf := manifestgen.NewLockfile(depsolved)
+	assert.Equal(t, manifestgen.LockfileVersion, lf.Version)
+
+	var buf bytes.Buffer
+	require.NoError(t, lf.Write(&buf))
+	read, err := manifestgen.ReadLockfile(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, lf, read)
+	assert.Equal(t, depsolved, read.DepsolveResults())
+	assert.NoError(t, read.Verify(depsolved))
+}
+
+func TestReadLockfileErrors(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected string
+	}{
+		"version": {
+			input:    `{"version": 2, "pipelines": {}}`,
+			expected: "unsupported lockfile version 2 (expected 1)",
+		},
+		"no-pipelines": {
+			input:    `{"version": 1}`,
+			expected: "lockfile has no pipelines",
+		},
+		"unknown-field": {
+			input:    `{"version": 1, "pipelines": {}, "packages": []}`,
+			expected: `cannot read lockfile: json: unknown field "packages"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := manifestgen.ReadLockfile(strings.NewReader(tc.input))
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestLockfileVerifyDrift(t *testing.T) {
+	bash := rpmmd.PackageSpec{Name: "bash", Version: "5.2.26", Release: "3.fc40", Arch: "x86_64", Checksum: sha256For("bash")}
+	newBash := bash
+	newBash.Release = "4.fc40"
+	rebuiltBash := bash
+	rebuiltBash.Checksum = sha256For("rebuilt-bash")
+	rpm := rpmmd.PackageSpec{Name: "rpm", Version: "4.19.1.1", Release: "1.fc40", Arch: "x86_64", Checksum: sha256For("rpm")}
+	zlib := rpmmd.PackageSpec{Name: "zlib-ng-compat", Version: "2.1.7", Release: "1.fc40", Arch: "x86_64", Checksum: sha256For("zlib")}
+
+	lf := manifestgen.NewLockfile(map[string]dnfjson.DepsolveResult{
+		"os": lockedResult(bash, rpm),
+	})
+
+	testCases := map[string]struct {
+		depsolved map[string]dnfjson.DepsolveResult
+		expected  string
+	}{
+		"same": {
+			depsolved: map[string]dnfjson.DepsolveResult{"os": lockedResult(rpm, bash)},
+		},
+		"update": {
+			depsolved: map[string]dnfjson.DepsolveResult{"os": lockedResult(newBash, rpm)},
+			expected:  "depsolved packages differ from the lockfile:\npipeline \"os\": bash-5.2.26-3.fc40.x86_64 changed to bash-5.2.26-4.fc40.x86_64",
+		},
+		"rebuild": {
+			depsolved: map[string]dnfjson.DepsolveResult{"os": lockedResult(rebuiltBash, rpm)},
+			expected:  "depsolved packages differ from the lockfile:\npipeline \"os\": checksum of bash-5.2.26-3.fc40.x86_64 changed from " + bash.Checksum + " to " + rebuiltBash.Checksum,
+		},
+		"added-removed": {
+			depsolved: map[string]dnfjson.DepsolveResult{"os": lockedResult(bash, zlib)},
+			expected:  "depsolved packages differ from the lockfile:\npipeline \"os\": zlib-ng-compat-2.1.7-1.fc40.x86_64 is not locked\npipeline \"os\": rpm-4.19.1.1-1.fc40.x86_64 is no longer part of the depsolve result",
+		},
+		"pipelines": {
+			depsolved: map[string]dnfjson.DepsolveResult{"build": lockedResult(rpm)},
+			expected:  "lockfile does not match the manifest:\nno packages locked for pipeline \"build\"\npackages locked for unknown pipeline \"os\"",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := lf.Verify(tc.depsolved)
+			if tc.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLockfileVerifyInstallonly(t *testing.T) {
+	kernel := rpmmd.PackageSpec{Name: "kernel", Version: "6.8.9", Release: "300.fc40", Arch: "x86_64", Checksum: sha256For("kernel")}
+	oldKernel := kernel
+	oldKernel.Version = "6.8.5"
+	oldKernel.Release = "301.fc40"
+	oldKernel.Checksum = sha256For("old-kernel")
+	newKernel := kernel
+	newKernel.Version = "6.9.7"
+	newKernel.Release = "200.fc40"
+	newKernel.Checksum = sha256For("new-kernel")
+
+	lf := manifestgen.NewLockfile(map[string]dnfjson.DepsolveResult{
+		"os": lockedResult(oldKernel, kernel),
+	})
+
+	testCases := map[string]struct {
+		depsolved map[string]dnfjson.DepsolveResult
+		expected  string
+	}{
+		"same": {
+			depsolved: map[string]dnfjson.DepsolveResult{"os": lockedResult(kernel, oldKernel)},
+		},
+		"added": {
+			depsolved: map[string]dnfjson.DepsolveResult{"os": lockedResult(oldKernel, kernel, newKernel)},
+			expected:  "depsolved packages differ from the lockfile:\npipeline \"os\": kernel-6.9.7-200.fc40.x86_64 is not locked",
+		},
+		"removed": {
+			depsolved: map[string]dnfjson.DepsolveResult{"os": lockedResult(kernel)},
+			expected:  "depsolved packages differ from the lockfile:\npipeline \"os\": kernel-6.8.5-301.fc40.x86_64 is no longer part of the depsolve result",
+		},
+		"update": {
+			depsolved: map[string]dnfjson.DepsolveResult{"os": lockedResult(kernel, newKernel)},
+			expected:  "depsolved packages differ from the lockfile:\npipeline \"os\": kernel-6.8.5-301.fc40.x86_64 changed to kernel-6.9.7-200.fc40.x86_64",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := lf.Verify(tc.depsolved)
+			if tc.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expected)
+			}
+		})
+	}
+}
+
+func panicDepsolve(ctx context.Context, cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+	panic("depsolve called")
+}
+
+func TestManifestGeneratorLockfile(t *testing.T) {
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	fac := distrofactory.NewDefault()
+
+	filter, err := imagefilter.New(fac, repos)
+	require.NoError(t, err)
+	res, err := filter.Filter("distro:centos-9", "type:qcow2", "arch:x86_64")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+
+	seed := int64(0)
+	generate := func(opts *manifestgen.Options) ([]byte, error) {
+		var osbuildManifest bytes.Buffer
+		opts.Output = &osbuildManifest
+		opts.CustomSeed = &seed
+		opts.CommitResolver = panicCommitResolver
+		opts.ContainerResolver = panicContainerResolver
+		mg, err := manifestgen.New(repos, opts)
+		if err != nil {
+			return nil, err
+		}
+		var bp blueprint.Blueprint
+		err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+		return osbuildManifest.Bytes(), err
+	}
+
+	// write the lockfile of a regular build
+	var lockfile *manifestgen.Lockfile
+	expected, err := generate(&manifestgen.Options{
+		Depsolver: fakeDepsolve,
+		LockfileWriter: func(lf *manifestgen.Lockfile) error {
+			lockfile = lf
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, lockfile)
+	assert.Contains(t, lockfile.Pipelines, "build")
+	assert.Contains(t, lockfile.Pipelines, "os")
+
+	// replaying the lockfile does not depsolve
+	replayed, err := generate(&manifestgen.Options{
+		Depsolver: panicDepsolve,
+		Lockfile:  lockfile,
+		LockMode:  manifestgen.LockModeReplay,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, replayed)
+
+	// verifying the lockfile depsolves and compares the result
+	verified, err := generate(&manifestgen.Options{
+		Depsolver: fakeDepsolve,
+		Lockfile:  lockfile,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, verified)
+
+	// a package that is no longer depsolved is drift
+	drifted := manifestgen.NewLockfile(lockfile.DepsolveResults())
+	osPipeline := drifted.Pipelines["os"]
+	osPipeline.Packages = append(osPipeline.Packages, rpmmd.PackageSpec{Name: "removed-pkg", Version: "1", Release: "1", Arch: "noarch", Checksum: sha256For("removed-pkg")})
+	drifted.Pipelines["os"] = osPipeline
+	_, err = generate(&manifestgen.Options{
+		Depsolver: fakeDepsolve,
+		Lockfile:  drifted,
+	})
+	assert.ErrorContains(t, err, "depsolved packages differ from the lockfile:\npipeline \"os\": removed-pkg-1-1.noarch is no longer part of the depsolve result")
+
+	// SBOMs are generated by the depsolver
+	_, err = generate(&manifestgen.Options{
+		Lockfile: lockfile,
+		LockMode: manifestgen.LockModeReplay,
+		SBOMWriter: func(filename string, content io.Reader, docType sbom.StandardType) error {
+			panic("SBOMWriter called")
+		},
+	})
+	assert.EqualError(t, err, "cannot write SBOMs when replaying a lockfile")
+}
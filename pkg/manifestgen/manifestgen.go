@@ -2,6 +2,7 @@ package manifestgen
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,8 +11,12 @@ import (
 	"slices"
 	"strings"
 
+	// we cannot use "maps" yet, as it needs go1.23
+	"golang.org/x/exp/maps"
+
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/dnfjson"
 	"github.com/osbuild/images/pkg/manifest"
@@ -24,11 +29,23 @@ import (
 
 const (
 	defaultDepsolverSBOMType = sbom.StandardTypeSpdx
-	defaultSBOMExt           = "spdx.json"
 
 	defaultDepsolveCacheDir = "osbuild-depsolve-dnf"
 )
 
+// sbomExtFor returns the conventional file extension for SBOM
+// documents of the given standard type
+func sbomExtFor(docType sbom.StandardType) (string, error) {
+	switch docType {
+	case sbom.StandardTypeSpdx:
+		return "spdx.json", nil
+	case sbom.StandardTypeCycloneDX:
+		return "cdx.json", nil
+	default:
+		return "", fmt.Errorf("unsupported SBOM type %v", docType)
+	}
+}
+
 // Options contains the optional settings for the manifest generation.
 // For unset values defaults will be used.
 type Options struct {
@@ -43,6 +60,24 @@ type Options struct {
 	// filename contains the suggest filename string and the
 	// content can be read
 	SBOMWriter SBOMWriterFunc
+	// SBOMType selects the standard of the SBOM documents that are
+	// passed to the SBOMWriter. If unset SPDX is used. SPDX documents
+	// are generated by the depsolver, CycloneDX documents are
+	// generated from the depsolved packages.
+	SBOMType sbom.StandardType
+
+	// LayoutWriter will be called with the layout report of each
+	// partition table in the manifest, keyed by the name of the
+	// pipeline that lays out the disk
+	LayoutWriter LayoutWriterFunc
+
+	// Lockfile pins the packages of the manifest to the ones of a
+	// previous build. How it is used is selected by LockMode.
+	Lockfile *Lockfile
+	LockMode LockMode
+	// LockfileWriter will be called with the lockfile of the
+	// packages that the manifest is generated with
+	LockfileWriter LockfileWriterFunc
 
 	// WarningsOutput will receive any warnings that are part of
 	// the manifest generation. If it is unset any warnings will
@@ -74,6 +109,11 @@ type Generator struct {
 	containerResolver ContainerResolverFunc
 	commitResolver    CommitResolverFunc
 	sbomWriter        SBOMWriterFunc
+	sbomType          sbom.StandardType
+	layoutWriter      LayoutWriterFunc
+	lockfile          *Lockfile
+	lockMode          LockMode
+	lockfileWriter    LockfileWriterFunc
 	warningsOutput    io.Writer
 
 	reporegistry *reporegistry.RepoRegistry
@@ -99,6 +139,11 @@ func New(reporegistry *reporegistry.RepoRegistry, opts *Options) (*Generator, er
 		commitResolver:    opts.CommitResolver,
 		rpmDownloader:     opts.RpmDownloader,
 		sbomWriter:        opts.SBOMWriter,
+		sbomType:          opts.SBOMType,
+		layoutWriter:      opts.LayoutWriter,
+		lockfile:          opts.Lockfile,
+		lockMode:          opts.LockMode,
+		lockfileWriter:    opts.LockfileWriter,
 		warningsOutput:    opts.WarningsOutput,
 		customSeed:        opts.CustomSeed,
 		overrideRepos:     opts.OverrideRepos,
@@ -109,19 +154,27 @@ func New(reporegistry *reporegistry.RepoRegistry, opts *Options) (*Generator, er
 	if mg.depsolver == nil {
 		mg.depsolver = DefaultDepsolver
 	}
+	if mg.sbomType == sbom.StandardTypeNone {
+		mg.sbomType = defaultDepsolverSBOMType
+	}
 	if mg.containerResolver == nil {
 		mg.containerResolver = DefaultContainerResolver
 	}
 	if mg.commitResolver == nil {
 		mg.commitResolver = DefaultCommitResolver
 	}
+	if mg.lockfile != nil && mg.lockMode == LockModeReplay && mg.sbomWriter != nil {
+		// the SBOM documents are generated by the depsolver
+		return nil, fmt.Errorf("cannot write SBOMs when replaying a lockfile")
+	}
 
 	return mg, nil
 }
 
 // Generate will generate a new manifest for the given distro/imageType/arch
-// combination.
-func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgType distro.ImageType, a distro.Arch, imgOpts *distro.ImageOptions) (err error) {
+// combination. The context is passed on to the depsolver and the
+// container and commit resolvers.
+func (mg *Generator) Generate(ctx context.Context, bp *blueprint.Blueprint, dist distro.Distro, imgType distro.ImageType, a distro.Arch, imgOpts *distro.ImageOptions) (err error) {
 	if imgOpts == nil {
 		imgOpts = &distro.ImageOptions{}
 	}
@@ -151,15 +204,20 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 			return fmt.Errorf("Warnings during manifest creation:\n%v", warn)
 		}
 	}
-	depsolved, err := mg.depsolver(mg.cacheDir, preManifest.GetPackageSetChains(), dist, a.Name())
+	depsolved, err := mg.depsolve(ctx, preManifest.GetPackageSetChains(), dist, a.Name())
 	if err != nil {
 		return err
 	}
-	containerSpecs, err := mg.containerResolver(preManifest.GetContainerSourceSpecs(), a.Name())
+	if mg.lockfileWriter != nil {
+		if err := mg.lockfileWriter(NewLockfile(depsolved)); err != nil {
+			return err
+		}
+	}
+	containerSpecs, err := mg.containerResolver(ctx, preManifest.GetContainerSourceSpecs(), a.Name())
 	if err != nil {
 		return err
 	}
-	commitSpecs, err := mg.commitResolver(preManifest.GetOSTreeSourceSpecs())
+	commitSpecs, err := mg.commitResolver(ctx, preManifest.GetOSTreeSourceSpecs())
 	if err != nil {
 		return err
 	}
@@ -176,6 +234,10 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 		// XXX: this is very similar to
 		// osbuild-composer:jobimpl-osbuild.go, see if code
 		// can be shared
+		sbomExt, err := sbomExtFor(mg.sbomType)
+		if err != nil {
+			return err
+		}
 		for plName, depsolvedPipeline := range depsolved {
 			pipelinePurpose := "unknown"
 			switch {
@@ -186,14 +248,33 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 			}
 			// XXX: sync with image-builder-cli:build.go name generation - can we have a shared helper?
 			imageName := fmt.Sprintf("%s-%s-%s", dist.Name(), imgType.Name(), a.Name())
-			sbomDocOutputFilename := fmt.Sprintf("%s.%s-%s.%s", imageName, pipelinePurpose, plName, defaultSBOMExt)
+			sbomDocOutputFilename := fmt.Sprintf("%s.%s-%s.%s", imageName, pipelinePurpose, plName, sbomExt)
 
+			sbomDoc, err := mg.sbomFor(fmt.Sprintf("%s.%s-%s", imageName, pipelinePurpose, plName), plName, &depsolvedPipeline)
+			if err != nil {
+				return err
+			}
 			var buf bytes.Buffer
 			enc := json.NewEncoder(&buf)
-			if err := enc.Encode(depsolvedPipeline.SBOM.Document); err != nil {
+			if err := enc.Encode(sbomDoc.Document); err != nil {
 				return err
 			}
-			if err := mg.sbomWriter(sbomDocOutputFilename, &buf, depsolvedPipeline.SBOM.DocType); err != nil {
+			if err := mg.sbomWriter(sbomDocOutputFilename, &buf, sbomDoc.DocType); err != nil {
+				return err
+			}
+		}
+	}
+
+	if mg.layoutWriter != nil {
+		pts := preManifest.GetPartitionTables()
+		plNames := maps.Keys(pts)
+		slices.Sort(plNames)
+		for _, plName := range plNames {
+			layout, err := pts[plName].Layout()
+			if err != nil {
+				return fmt.Errorf("cannot create layout for pipeline %q: %w", plName, err)
+			}
+			if err := mg.layoutWriter(plName, layout); err != nil {
 				return err
 			}
 		}
@@ -202,6 +283,46 @@ func (mg *Generator) Generate(bp *blueprint.Blueprint, dist distro.Distro, imgTy
 	return nil
 }
 
+// sbomFor returns the SBOM document of the depsolved packages of a
+// pipeline in the configured standard.
+func (mg *Generator) sbomFor(docName, plName string, depsolved *dnfjson.DepsolveResult) (*sbom.Document, error) {
+	switch mg.sbomType {
+	case sbom.StandardTypeCycloneDX:
+		// the depsolver only generates SPDX documents
+		return sbom.GenerateCycloneDX(docName, depsolved.Packages, depsolved.Repos, nil)
+	default:
+		if depsolved.SBOM == nil {
+			return nil, fmt.Errorf("no SBOM generated for pipeline %q", plName)
+		}
+		return depsolved.SBOM, nil
+	}
+}
+
+// depsolve depsolves the package sets with the depsolver, unless the
+// packages are replayed from the lockfile. Depsolve results that differ from
+// the lockfile are an error.
+func (mg *Generator) depsolve(ctx context.Context, packageSets map[string][]rpmmd.PackageSet, dist distro.Distro, archName string) (map[string]dnfjson.DepsolveResult, error) {
+	if mg.lockfile != nil && mg.lockMode == LockModeReplay {
+		pipelines := maps.Keys(packageSets)
+		slices.Sort(pipelines)
+		if err := mg.lockfile.checkPipelines(pipelines); err != nil {
+			return nil, err
+		}
+		return mg.lockfile.DepsolveResults(), nil
+	}
+
+	depsolved, err := mg.depsolver(ctx, mg.cacheDir, packageSets, dist, archName)
+	if err != nil {
+		return nil, err
+	}
+	if mg.lockfile != nil {
+		if err := mg.lockfile.Verify(depsolved); err != nil {
+			return nil, err
+		}
+	}
+	return depsolved, nil
+}
+
 func xdgCacheHome() (string, error) {
 	xdgCacheHome := os.Getenv("XDG_CACHE_HOME")
 	if xdgCacheHome != "" {
@@ -217,7 +338,7 @@ func xdgCacheHome() (string, error) {
 // DefaultDepsolver provides a default implementation for depsolving.
 // It should rarely be necessary to use it directly and will be used
 // by default by manifestgen (unless overriden)
-func DefaultDepsolver(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+func DefaultDepsolver(ctx context.Context, cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
 	if cacheDir == "" {
 		xdgCacheHomeDir, err := xdgCacheHome()
 		if err != nil {
@@ -229,12 +350,12 @@ func DefaultDepsolver(cacheDir string, packageSets map[string][]rpmmd.PackageSet
 	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), arch, d.Name(), cacheDir)
 	depsolvedSets := make(map[string]dnfjson.DepsolveResult)
 	for name, pkgSet := range packageSets {
-		// Always generate Spdx SBOMs for now, this makes the
-		// default depsolve slightly slower but it means we
-		// need no extra argument here to select the SBOM
-		// type. Once we have more types than Spdx of course
-		// we need to add a option to select the type.
-		res, err := solver.Depsolve(pkgSet, sbom.StandardTypeSpdx)
+		// Always generate Spdx SBOMs, this makes the default
+		// depsolve slightly slower but it means we need no
+		// extra argument here to select the SBOM type. Other
+		// SBOM types are generated from the depsolved packages
+		// by the Generator.
+		res, err := solver.Depsolve(ctx, pkgSet, sbom.StandardTypeSpdx)
 		if err != nil {
 			return nil, fmt.Errorf("error depsolving: %w", err)
 		}
@@ -243,11 +364,11 @@ func DefaultDepsolver(cacheDir string, packageSets map[string][]rpmmd.PackageSet
 	return depsolvedSets, nil
 }
 
-func resolveContainers(containers []container.SourceSpec, archName string) ([]container.Spec, error) {
+func resolveContainers(ctx context.Context, containers []container.SourceSpec, archName string) ([]container.Spec, error) {
 	resolver := container.NewBlockingResolver(archName)
 
 	for _, c := range containers {
-		resolver.Add(c)
+		resolver.Add(ctx, c)
 	}
 
 	return resolver.Finish()
@@ -257,10 +378,10 @@ func resolveContainers(containers []container.SourceSpec, archName string) ([]co
 // container resolving.
 // It should rarely be necessary to use it directly and will be used
 // by default by manifestgen (unless overriden)
-func DefaultContainerResolver(containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
+func DefaultContainerResolver(ctx context.Context, containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
 	containerSpecs := make(map[string][]container.Spec, len(containerSources))
 	for plName, sourceSpecs := range containerSources {
-		specs, err := resolveContainers(sourceSpecs, archName)
+		specs, err := resolveContainers(ctx, sourceSpecs, archName)
 		if err != nil {
 			return nil, fmt.Errorf("error container resolving: %w", err)
 		}
@@ -273,13 +394,13 @@ func DefaultContainerResolver(containerSources map[string][]container.SourceSpec
 // ostree commit resolving.
 // It should rarely be necessary to use it directly and will be used
 // by default by manifestgen (unless overriden)
-func DefaultCommitResolver(commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error) {
+func DefaultCommitResolver(ctx context.Context, commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error) {
 	commits := make(map[string][]ostree.CommitSpec, len(commitSources))
 	for name, commitSources := range commitSources {
 		commitSpecs := make([]ostree.CommitSpec, len(commitSources))
 		for idx, commitSource := range commitSources {
 			var err error
-			commitSpecs[idx], err = ostree.Resolve(commitSource)
+			commitSpecs[idx], err = ostree.Resolve(ctx, commitSource)
 			if err != nil {
 				return nil, fmt.Errorf("error ostree commit resolving: %w", err)
 			}
@@ -290,11 +411,15 @@ func DefaultCommitResolver(commitSources map[string][]ostree.SourceSpec) (map[st
 }
 
 type (
-	DepsolveFunc func(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error)
+	DepsolveFunc func(ctx context.Context, cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error)
 
-	ContainerResolverFunc func(containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error)
+	ContainerResolverFunc func(ctx context.Context, containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error)
 
-	CommitResolverFunc func(commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error)
+	CommitResolverFunc func(ctx context.Context, commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error)
 
 	SBOMWriterFunc func(filename string, content io.Reader, docType sbom.StandardType) error
+
+	LayoutWriterFunc func(pipelineName string, layout *disk.Layout) error
+
+	LockfileWriterFunc func(lockfile *Lockfile) error
 )
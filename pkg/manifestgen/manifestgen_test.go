@@ -2,10 +2,12 @@ package manifestgen_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 	"testing"
 
@@ -15,6 +17,7 @@ import (
 
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distrofactory"
 	"github.com/osbuild/images/pkg/dnfjson"
@@ -71,7 +74,7 @@ func TestManifestGeneratorDepsolve(t *testing.T) {
 			assert.NoError(t, err)
 			assert.NotNil(t, mg)
 			var bp blueprint.Blueprint
-			err = mg.Generate(&bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+			err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
 			require.NoError(t, err)
 
 			pipelineNames, err := manifesttest.PipelineNamesFrom(osbuildManifest.Bytes())
@@ -119,7 +122,7 @@ func TestManifestGeneratorWithOstreeCommit(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, mg)
 	var bp blueprint.Blueprint
-	err = mg.Generate(&bp, res[0].Distro, res[0].ImgType, res[0].Arch, imageOpts)
+	err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, imageOpts)
 	assert.NoError(t, err)
 
 	pipelineNames, err := manifesttest.PipelineNamesFrom(osbuildManifest.Bytes())
@@ -136,7 +139,7 @@ func TestManifestGeneratorWithOstreeCommit(t *testing.T) {
 	assert.Contains(t, osbuildManifest.String(), expectedSha256)
 }
 
-func fakeDepsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+func fakeDepsolve(ctx context.Context, cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
 	depsolvedSets := make(map[string]dnfjson.DepsolveResult)
 	for name, pkgSets := range packageSets {
 		repoId := fmt.Sprintf("repo_id_%s", name)
@@ -167,7 +170,7 @@ func fakeDepsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d
 	return depsolvedSets, nil
 }
 
-func fakeCommitResolver(commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error) {
+func fakeCommitResolver(ctx context.Context, commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error) {
 	commits := make(map[string][]ostree.CommitSpec, len(commitSources))
 	for name, commitSources := range commitSources {
 		commitSpecs := make([]ostree.CommitSpec, len(commitSources))
@@ -182,14 +185,14 @@ func fakeCommitResolver(commitSources map[string][]ostree.SourceSpec) (map[strin
 
 }
 
-func panicCommitResolver(commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error) {
+func panicCommitResolver(ctx context.Context, commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error) {
 	if len(commitSources) > 0 {
 		panic("panicCommitResolver")
 	}
 	return nil, nil
 }
 
-func fakeContainerResolver(containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
+func fakeContainerResolver(ctx context.Context, containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
 	containerSpecs := make(map[string][]container.Spec, len(containerSources))
 	for plName, sourceSpecs := range containerSources {
 		var containers []container.Spec
@@ -205,7 +208,7 @@ func fakeContainerResolver(containerSources map[string][]container.SourceSpec, a
 	return containerSpecs, nil
 }
 
-func panicContainerResolver(containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
+func panicContainerResolver(ctx context.Context, containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
 	if len(containerSources) > 0 {
 		panic("panicContainerResolver")
 	}
@@ -241,13 +244,45 @@ func TestManifestGeneratorContainers(t *testing.T) {
 			},
 		},
 	}
-	err = mg.Generate(&bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+	err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
 	assert.NoError(t, err)
 
 	// container is included
 	assert.Contains(t, osbuildManifest.String(), "resolved-cnt-"+fakeContainerSource)
 }
 
+func TestManifestGeneratorContextCancelled(t *testing.T) {
+	repos, err := testrepos.New()
+	assert.NoError(t, err)
+	fac := distrofactory.NewDefault()
+
+	filter, err := imagefilter.New(fac, repos)
+	assert.NoError(t, err)
+	res, err := filter.Filter("distro:centos-9", "type:qcow2", "arch:x86_64")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(res))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var osbuildManifest bytes.Buffer
+	opts := &manifestgen.Options{
+		Output: &osbuildManifest,
+		Depsolver: func(ctx context.Context, cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string]dnfjson.DepsolveResult, error) {
+			return nil, ctx.Err()
+		},
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+	}
+	mg, err := manifestgen.New(repos, opts)
+	assert.NoError(t, err)
+
+	var bp blueprint.Blueprint
+	err = mg.Generate(ctx, &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, osbuildManifest.String())
+}
+
 func TestManifestGeneratorDepsolveWithSbomWriter(t *testing.T) {
 	repos, err := testrepos.New()
 	assert.NoError(t, err)
@@ -280,7 +315,7 @@ func TestManifestGeneratorDepsolveWithSbomWriter(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, mg)
 	var bp blueprint.Blueprint
-	err = mg.Generate(&bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+	err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
 	require.NoError(t, err)
 
 	assert.Contains(t, generatedSboms, "centos-9-qcow2-x86_64.buildroot-build.spdx.json")
@@ -292,6 +327,113 @@ func TestManifestGeneratorDepsolveWithSbomWriter(t *testing.T) {
 	assert.Equal(t, expected, generatedSboms)
 }
 
+func TestManifestGeneratorLayoutWriter(t *testing.T) {
+	repos, err := testrepos.New()
+	assert.NoError(t, err)
+	fac := distrofactory.NewDefault()
+
+	filter, err := imagefilter.New(fac, repos)
+	assert.NoError(t, err)
+	res, err := filter.Filter("distro:centos-9", "type:qcow2", "arch:x86_64")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(res))
+
+	var osbuildManifest bytes.Buffer
+	layouts := map[string]*disk.Layout{}
+	opts := &manifestgen.Options{
+		Output:            &osbuildManifest,
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+
+		LayoutWriter: func(pipelineName string, layout *disk.Layout) error {
+			layouts[pipelineName] = layout
+			return nil
+		},
+	}
+	mg, err := manifestgen.New(repos, opts)
+	assert.NoError(t, err)
+	var bp blueprint.Blueprint
+	err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+	require.NoError(t, err)
+
+	require.Contains(t, layouts, "os")
+	assert.Len(t, layouts, 1)
+	layout := layouts["os"]
+	assert.Equal(t, "gpt", layout.Type)
+	var mountpoints []string
+	for _, part := range layout.Partitions {
+		for _, child := range part.Children {
+			if child.Mountpoint != "" {
+				mountpoints = append(mountpoints, child.Mountpoint)
+			}
+		}
+	}
+	assert.Contains(t, mountpoints, "/")
+	assert.Contains(t, mountpoints, "/boot/efi")
+}
+
+func TestManifestGeneratorDepsolveWithCycloneDXSbomWriter(t *testing.T) {
+	repos, err := testrepos.New()
+	assert.NoError(t, err)
+	fac := distrofactory.NewDefault()
+
+	filter, err := imagefilter.New(fac, repos)
+	assert.NoError(t, err)
+	res, err := filter.Filter("distro:centos-9", "type:qcow2", "arch:x86_64")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(res))
+
+	var osbuildManifest bytes.Buffer
+	generatedSboms := map[string]string{}
+	opts := &manifestgen.Options{
+		Output:            &osbuildManifest,
+		Depsolver:         fakeDepsolve,
+		CommitResolver:    panicCommitResolver,
+		ContainerResolver: panicContainerResolver,
+
+		SBOMType: sbom.StandardTypeCycloneDX,
+		SBOMWriter: func(filename string, content io.Reader, docType sbom.StandardType) error {
+			assert.Equal(t, sbom.StandardTypeCycloneDX, docType)
+
+			b, err := io.ReadAll(content)
+			assert.NoError(t, err)
+			generatedSboms[filename] = strings.TrimSpace(string(b))
+			return nil
+		},
+	}
+	mg, err := manifestgen.New(repos, opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, mg)
+	var bp blueprint.Blueprint
+	err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+	require.NoError(t, err)
+
+	// the CycloneDX documents are generated from the depsolved packages
+	assert.Len(t, generatedSboms, 2)
+	for filename, docName := range map[string]string{
+		"centos-9-qcow2-x86_64.buildroot-build.cdx.json": "centos-9-qcow2-x86_64.buildroot-build",
+		"centos-9-qcow2-x86_64.image-os.cdx.json":        "centos-9-qcow2-x86_64.image-os",
+	} {
+		require.Contains(t, generatedSboms, filename)
+		var doc struct {
+			BOMFormat string `json:"bomFormat"`
+			Metadata  struct {
+				Component struct {
+					Name string `json:"name"`
+				} `json:"component"`
+			} `json:"metadata"`
+			Components []struct {
+				Name string `json:"name"`
+			} `json:"components"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(generatedSboms[filename]), &doc))
+		assert.Equal(t, "CycloneDX", doc.BOMFormat)
+		assert.Equal(t, docName, doc.Metadata.Component.Name)
+		assert.NotEmpty(t, doc.Components)
+	}
+}
+
 func TestManifestGeneratorSeed(t *testing.T) {
 	repos, err := testrepos.New()
 	assert.NoError(t, err)
@@ -318,7 +460,7 @@ func TestManifestGeneratorSeed(t *testing.T) {
 		assert.NoError(t, err)
 
 		var bp blueprint.Blueprint
-		err = mg.Generate(&bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+		err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
 		assert.NoError(t, err)
 
 		// with the customSeed we always get a predicatable uuid for
@@ -363,7 +505,7 @@ func TestManifestGeneratorOverrideRepos(t *testing.T) {
 			assert.NoError(t, err)
 
 			var bp blueprint.Blueprint
-			err = mg.Generate(&bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+			err = mg.Generate(context.Background(), &bp, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
 			assert.NoError(t, err)
 			if withOverrideRepos {
 				assert.Contains(t, osbuildManifest.String(), "http://example.com/overriden-repo/kernel.rpm")
@@ -373,3 +515,42 @@ func TestManifestGeneratorOverrideRepos(t *testing.T) {
 		})
 	}
 }
+
+func TestManifestGeneratorValidatesAgainstOsbuildSchemas(t *testing.T) {
+	if _, err := os.Stat(osbuild.DefaultLibDir); err != nil {
+		t.Skip("Test needs an installed osbuild")
+	}
+	validator, err := osbuild.NewSchemaValidatorFromDir(osbuild.DefaultLibDir)
+	require.NoError(t, err)
+	for _, w := range validator.Warnings() {
+		t.Log(w)
+	}
+
+	repos, err := testrepos.New()
+	require.NoError(t, err)
+	filter, err := imagefilter.New(distrofactory.NewDefault(), repos)
+	require.NoError(t, err)
+
+	for _, distroName := range []string{"centos-9", "fedora-40"} {
+		t.Run(distroName, func(t *testing.T) {
+			res, err := filter.Filter("distro:"+distroName, "type:qcow2", "arch:x86_64")
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			var osbuildManifest bytes.Buffer
+			mg, err := manifestgen.New(repos, &manifestgen.Options{
+				Output:            &osbuildManifest,
+				Depsolver:         fakeDepsolve,
+				CommitResolver:    panicCommitResolver,
+				ContainerResolver: panicContainerResolver,
+			})
+			require.NoError(t, err)
+			err = mg.Generate(context.Background(), &blueprint.Blueprint{}, res[0].Distro, res[0].ImgType, res[0].Arch, nil)
+			require.NoError(t, err)
+
+			errs, err := validator.Validate(osbuildManifest.Bytes())
+			require.NoError(t, err)
+			assert.Empty(t, errs)
+		})
+	}
+}
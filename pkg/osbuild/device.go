@@ -188,6 +188,10 @@ func getDevices(path []disk.Entity, filename string, lockLoopback bool) (map[str
 	for _, elem := range path {
 		switch e := elem.(type) {
 		case *disk.PartitionTable:
+			if pt != nil {
+				// additional disks are in separate image files
+				filename = extraDiskFilename(pt, e, filename)
+			}
 			pt = e
 		case *disk.Partition:
 			if pt == nil {
@@ -2,6 +2,9 @@ package osbuild
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 
@@ -19,6 +22,8 @@ func sfdiskStageOptions(pt *disk.PartitionTable) *SfdiskStageOptions {
 			Size:     pt.BytesToSectors(p.Size),
 			Type:     p.Type,
 			UUID:     p.UUID,
+			Name:     p.Name,
+			Attrs:    p.Attrs,
 		}
 	}
 	stageOptions := &SfdiskStageOptions{
@@ -40,6 +45,8 @@ func sgdiskStageOptions(pt *disk.PartitionTable) *SgdiskStageOptions {
 			Start:    pt.BytesToSectors(p.Start),
 			Size:     pt.BytesToSectors(p.Size),
 			Type:     p.Type,
+			Name:     p.Name,
+			Attrs:    p.Attrs,
 		}
 
 		if p.UUID != "" {
@@ -63,7 +70,42 @@ const (
 	PTSgdisk PartTool = "sgdisk"
 )
 
-func GenImagePrepareStages(pt *disk.PartitionTable, filename string, partTool PartTool) []*Stage {
+// DiskFilename returns the name of the image file of the idx-th disk of an
+// image with additional disks. The first disk uses the filename itself, the
+// others get the index appended to the name: disk.raw, disk-1.raw, ...
+func DiskFilename(filename string, idx uint) string {
+	if idx == 0 {
+		return filename
+	}
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, ext), idx, ext)
+}
+
+// DiskFilenames returns the names of the image files of all disks of the
+// partition table: the first disk followed by its additional disks, see
+// DiskFilename.
+func DiskFilenames(pt *disk.PartitionTable, filename string) []string {
+	disks := pt.DiskCount()
+	filenames := make([]string, 0, disks)
+	for idx := uint(0); idx < disks; idx++ {
+		filenames = append(filenames, DiskFilename(filename, idx))
+	}
+	return filenames
+}
+
+// extraDiskFilename returns the name of the image file of the additional
+// disk extra of the partition table, see DiskFilenames.
+func extraDiskFilename(pt, extra *disk.PartitionTable, filename string) string {
+	idx := slices.Index(pt.ExtraDisks, extra)
+	if idx < 0 {
+		panic("partition table is not an additional disk of the image; this is a programming error")
+	}
+	return DiskFilename(filename, uint(idx)+1)
+}
+
+// genDiskPrepareStages creates the image file of a single disk and the
+// partition layout in it
+func genDiskPrepareStages(pt *disk.PartitionTable, filename string, partTool PartTool) []*Stage {
 	stages := make([]*Stage, 0)
 
 	// create an empty file of the given size via `org.osbuild.truncate`
@@ -94,6 +136,18 @@ func GenImagePrepareStages(pt *disk.PartitionTable, filename string, partTool Pa
 	} else {
 		panic("programming error: unknown PartTool: " + partTool)
 	}
+	return stages
+}
+
+func GenImagePrepareStages(pt *disk.PartitionTable, filename string, partTool PartTool) []*Stage {
+	stages := make([]*Stage, 0)
+
+	stages = append(stages, genDiskPrepareStages(pt, filename, partTool)...)
+
+	// additional disks have their own layout
+	for _, extra := range pt.ExtraDisks {
+		stages = append(stages, genDiskPrepareStages(extra, extraDiskFilename(pt, extra, filename), partTool)...)
+	}
 
 	// Generate all the needed "devices", like LUKS2 and LVM2
 	s := GenDeviceCreationStages(pt, filename)
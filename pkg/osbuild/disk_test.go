@@ -157,3 +157,114 @@ func TestGenImagePrepareStages(t *testing.T) {
 	}, actualStages)
 
 }
+
+func TestDiskFilename(t *testing.T) {
+	assert.Equal(t, "disk.raw", DiskFilename("disk.raw", 0))
+	assert.Equal(t, "disk-1.raw", DiskFilename("disk.raw", 1))
+	assert.Equal(t, "image-2", DiskFilename("image", 2))
+}
+
+func TestGenImagePrepareStagesExtraDisks(t *testing.T) {
+	data := &disk.PartitionTable{
+		Size: 4 * datasizes.GiB,
+		UUID: "0x14fc63d2",
+		Type: disk.PT_DOS,
+		Partitions: []disk.Partition{
+			{
+				Start: 1 * datasizes.MiB,
+				Size:  4*datasizes.GiB - 1*datasizes.MiB,
+				Type:  disk.FilesystemLinuxDOSID,
+				Payload: &disk.Filesystem{
+					Type:       "ext4",
+					UUID:       "fb180daf-48a7-4ee0-b10d-394651850fd4",
+					Mountpoint: "/data",
+				},
+			},
+		},
+	}
+	pt := &disk.PartitionTable{
+		Size: 2 * datasizes.GiB,
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: disk.PT_GPT,
+		Partitions: []disk.Partition{
+			{
+				Start: 1 * datasizes.MiB,
+				Size:  2*datasizes.GiB - 2*datasizes.MiB,
+				Type:  disk.FilesystemDataGUID,
+				UUID:  "CB07C243-BC44-4717-853E-28852021225B",
+				Payload: &disk.Filesystem{
+					Type:       "xfs",
+					UUID:       "0194fdc2-fa2f-4cc0-81d3-ff12045b73c8",
+					Mountpoint: "/",
+				},
+			},
+		},
+		ExtraDisks: []*disk.PartitionTable{data},
+	}
+
+	assert.Equal(t, []string{"disk.raw", "disk-1.raw"}, DiskFilenames(pt, "disk.raw"))
+
+	stages := GenImagePrepareStages(pt, "disk.raw", PTSfdisk)
+	types := make([]string, len(stages))
+	for idx, stage := range stages {
+		types[idx] = stage.Type
+	}
+	assert.Equal(t, []string{
+		"org.osbuild.truncate",
+		"org.osbuild.sfdisk",
+		"org.osbuild.truncate",
+		"org.osbuild.sfdisk",
+		"org.osbuild.mkfs.xfs",
+		"org.osbuild.mkfs.ext4",
+	}, types)
+
+	// the additional disk has its own file and layout
+	assert.Equal(t, &TruncateStageOptions{Filename: "disk-1.raw", Size: fmt.Sprintf("%d", 4*datasizes.GiB)}, stages[2].Options)
+	assert.Equal(t, "disk-1.raw", stages[3].Devices["device"].Options.(*LoopbackDeviceOptions).Filename)
+	assert.Equal(t, "dos", stages[3].Options.(*SfdiskStageOptions).Label)
+
+	// the filesystem on the additional disk is created in its file
+	assert.Equal(t, map[string]Device{
+		"device": {
+			Type: "org.osbuild.loopback",
+			Options: &LoopbackDeviceOptions{
+				Filename: "disk-1.raw",
+				Start:    1 * datasizes.MiB / 512,
+				Size:     (4*datasizes.GiB - 1*datasizes.MiB) / 512,
+				Lock:     true,
+			},
+		},
+	}, stages[5].Devices)
+
+	// and mounted from there
+	_, mounts, devices, err := GenMountsDevicesFromPT("disk.raw", pt)
+	assert.NoError(t, err)
+	assert.Len(t, mounts, 2)
+	assert.Equal(t, "disk-1.raw", devices["data"].Options.(*LoopbackDeviceOptions).Filename)
+	assert.Equal(t, "disk.raw", devices["-"].Options.(*LoopbackDeviceOptions).Filename)
+}
+
+func TestPartitionEntryStageOptions(t *testing.T) {
+	pt := &disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: disk.PT_GPT,
+		Partitions: []disk.Partition{
+			{
+				Start: 1 * datasizes.MiB,
+				Size:  1 * datasizes.GiB,
+				Type:  disk.HomePartitionGUID,
+				UUID:  "F83B8E88-3BBD-487D-A5B6-4E5C7E3A9BB4",
+				Name:  "home",
+				Attrs: []uint{60, 63},
+			},
+		},
+	}
+
+	sfdisk := sfdiskStageOptions(pt)
+	assert.Equal(t, "home", sfdisk.Partitions[0].Name)
+	assert.Equal(t, []uint{60, 63}, sfdisk.Partitions[0].Attrs)
+
+	sgdisk := sgdiskStageOptions(pt)
+	assert.Equal(t, "home", sgdisk.Partitions[0].Name)
+	assert.Equal(t, []uint{60, 63}, sgdisk.Partitions[0].Attrs)
+}
@@ -4,6 +4,10 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/osbuild/images/pkg/rpmmd"
 )
 
 const SourceNameInline = "org.osbuild.inline"
@@ -41,3 +45,25 @@ func (s *InlineSource) AddItem(data string) string {
 
 	return name
 }
+
+// AddPackage embeds a local package file, i.e. a package with a file:// remote
+// location, in the source. The package must not have changed since it was
+// depsolved.
+func (s *InlineSource) AddPackage(pkg rpmmd.PackageSpec) error {
+	u, err := url.Parse(pkg.RemoteLocation)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "file" {
+		return fmt.Errorf("package %s is not a local file: %s", pkg.Name, pkg.RemoteLocation)
+	}
+	data, err := os.ReadFile(u.Path)
+	if err != nil {
+		return fmt.Errorf("cannot read package %s: %w", pkg.Name, err)
+	}
+	if checksum := s.AddItem(string(data)); checksum != pkg.Checksum {
+		delete(s.Items, checksum)
+		return fmt.Errorf("package %s has changed since it was depsolved: expected checksum %s, got %s", u.Path, pkg.Checksum, checksum)
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"path"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -40,14 +42,14 @@ type Status struct {
 // Each progress can have an arbitrary number of sub-progress information
 //
 // Note while those can be nested arbitrarly deep in practise
-// we are at 2 levels currently:
+// we are at 3 levels currently:
 //  1. overall pipeline progress
 //  2. stages inside each pipeline
+//  3. stage progress (rpm install and curl download progress)
 //
-// we might get
-//  3. stage progress (e.g. rpm install progress)
-//
-// in the future
+// The third level is derived from the stage output. Its Total is
+// only known if the scanner was given the manifest via
+// SetManifest(), otherwise it is 0.
 type Progress struct {
 	// A human readable message about what is going on
 	Message string
@@ -73,6 +75,8 @@ func NewStatusScanner(r io.Reader) *StatusScanner {
 		scanner:         scanner,
 		contextMap:      make(map[string]*contextJSON),
 		stageContextMap: make(map[string]*stageContextJSON),
+		rpmProgress:     make(map[string]*packageProgress),
+		rpmStagesSeen:   make(map[string]int),
 	}
 }
 
@@ -81,6 +85,148 @@ type StatusScanner struct {
 	scanner         *bufio.Scanner
 	contextMap      map[string]*contextJSON
 	stageContextMap map[string]*stageContextJSON
+
+	// rpm install progress, keyed by stage id
+	rpmProgress map[string]*packageProgress
+	// number of rpm stages started, keyed by pipeline name
+	rpmStagesSeen map[string]int
+	// curl download progress
+	curlProgress packageProgress
+
+	// totals extracted from the manifest
+	rpmTotals  map[string][]int
+	curlTotals int
+}
+
+// packageProgress tracks the per-package progress of a single stage
+// or source
+type packageProgress struct {
+	// set once rpm started the installation transaction
+	installing bool
+	done       int
+	total      int
+}
+
+// SetManifest extracts the number of packages that each rpm stage
+// installs and the curl source downloads from the given osbuild
+// manifest. It is used to provide the totals for the per-package
+// progress.
+func (sr *StatusScanner) SetManifest(manifest []byte) error {
+	var mf struct {
+		Pipelines []struct {
+			Name   string `json:"name"`
+			Stages []struct {
+				Type   string `json:"type"`
+				Inputs struct {
+					Packages struct {
+						References json.RawMessage `json:"references"`
+					} `json:"packages"`
+				} `json:"inputs"`
+			} `json:"stages"`
+		} `json:"pipelines"`
+		Sources struct {
+			Curl struct {
+				Items map[string]json.RawMessage `json:"items"`
+			} `json:"org.osbuild.curl"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(manifest, &mf); err != nil {
+		return fmt.Errorf("cannot parse manifest for progress totals: %w", err)
+	}
+
+	sr.rpmTotals = make(map[string][]int)
+	for _, pl := range mf.Pipelines {
+		for _, stage := range pl.Stages {
+			if stage.Type != "org.osbuild.rpm" {
+				continue
+			}
+			n, err := countReferences(stage.Inputs.Packages.References)
+			if err != nil {
+				return fmt.Errorf("cannot count packages of rpm stage in pipeline %q: %w", pl.Name, err)
+			}
+			sr.rpmTotals[pl.Name] = append(sr.rpmTotals[pl.Name], n)
+		}
+	}
+	sr.curlTotals = len(mf.Sources.Curl.Items)
+	sr.curlProgress.total = sr.curlTotals
+
+	return nil
+}
+
+// countReferences counts input references, which are either a list
+// or a map
+func countReferences(refs json.RawMessage) (int, error) {
+	if len(refs) == 0 {
+		return 0, nil
+	}
+	var l []json.RawMessage
+	if err := json.Unmarshal(refs, &l); err == nil {
+		return len(l), nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(refs, &m); err != nil {
+		return 0, err
+	}
+	return len(m), nil
+}
+
+// rpmPackageLine matches the lines that "rpm --install --verbose"
+// prints for every package it installs, i.e. a NEVRA
+var rpmPackageLine = regexp.MustCompile(`^[^\s/:]+-[^\s/:-]+-[^\s/:-]+\.[A-Za-z0-9_]+$`)
+
+// curlDownloadLine matches the line the curl source prints for
+// every downloaded url
+var curlDownloadLine = regexp.MustCompile(`^source/org\.osbuild\.curl \(org\.osbuild\.curl\): Downloaded (\S+)$`)
+
+// packageSubProgress returns the per-package progress for the given
+// stage output or nil if the output contains no package progress
+func (sr *StatusScanner) packageSubProgress(context *contextJSON, stageID, output string) *Progress {
+	var sub *Progress
+
+	switch {
+	case context.Pipeline.Name == "source org.osbuild.curl":
+		for _, line := range strings.Split(output, "\n") {
+			m := curlDownloadLine.FindStringSubmatch(strings.TrimSpace(line))
+			if m == nil {
+				continue
+			}
+			sr.curlProgress.done++
+			sub = &Progress{
+				Message: fmt.Sprintf("Downloaded %s", path.Base(m[1])),
+				Done:    sr.curlProgress.done,
+				Total:   sr.curlProgress.total,
+			}
+		}
+	case context.Pipeline.Stage.Name == "org.osbuild.rpm" && stageID != "":
+		prog := sr.rpmProgress[stageID]
+		if prog == nil {
+			pipelineName := context.Pipeline.Name
+			prog = &packageProgress{}
+			if idx := sr.rpmStagesSeen[pipelineName]; idx < len(sr.rpmTotals[pipelineName]) {
+				prog.total = sr.rpmTotals[pipelineName][idx]
+			}
+			sr.rpmStagesSeen[pipelineName]++
+			sr.rpmProgress[stageID] = prog
+		}
+		for _, line := range strings.Split(output, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "Preparing packages..." {
+				prog.installing = true
+				continue
+			}
+			if !prog.installing || !rpmPackageLine.MatchString(line) {
+				continue
+			}
+			prog.done++
+			sub = &Progress{
+				Message: fmt.Sprintf("Installing %s", line),
+				Done:    prog.done,
+				Total:   prog.total,
+			}
+		}
+	}
+
+	return sub
 }
 
 // Status returns a single status struct from the scanner or nil
@@ -115,6 +261,7 @@ func (sr *StatusScanner) Status() (*Status, error) {
 	} else {
 		trace = strings.TrimSpace(status.Message)
 	}
+	packageProg := sr.packageSubProgress(context, context.Pipeline.Stage.ID, trace)
 
 	st := &Status{
 		Trace:   trace,
@@ -135,6 +282,9 @@ func (sr *StatusScanner) Status() (*Status, error) {
 		stageContext = &context.Pipeline.Stage
 	}
 	stageName := fmt.Sprintf("Stage %s", stageContext.Name)
+	if source, ok := strings.CutPrefix(pipelineName, "source "); ok && stageContext.Name == "" {
+		stageName = fmt.Sprintf("Source %s", source)
+	}
 	prog := st.Progress
 	for subProg := status.Progress.SubProgress; subProg != nil; subProg = subProg.SubProgress {
 		prog.SubProgress = &Progress{
@@ -144,6 +294,17 @@ func (sr *StatusScanner) Status() (*Status, error) {
 		}
 		prog = prog.SubProgress
 	}
+	// the package progress is always below a stage (or source) level,
+	// even if osbuild did not report the progress of the stage
+	if packageProg != nil {
+		if prog == st.Progress {
+			prog.SubProgress = &Progress{
+				Message: stageName,
+			}
+			prog = prog.SubProgress
+		}
+		prog.SubProgress = packageProg
+	}
 
 	return st, nil
 }
@@ -34,6 +34,13 @@ func TestScannerSimple(t *testing.T) {
 			Done:    0,
 			Total:   4,
 			Message: "Pipeline source org.osbuild.curl",
+			SubProgress: &osbuild.Progress{
+				Message: "Source org.osbuild.curl",
+				SubProgress: &osbuild.Progress{
+					Message: "Downloaded kpartx-0.9.5-2.fc39.x86_64.rpm",
+					Done:    1,
+				},
+			},
 		},
 		Timestamp: time.UnixMilli(int64(ts1)),
 	}, st)
@@ -46,6 +53,13 @@ func TestScannerSimple(t *testing.T) {
 			Done:    0,
 			Total:   4,
 			Message: "Pipeline source org.osbuild.curl",
+			SubProgress: &osbuild.Progress{
+				Message: "Source org.osbuild.curl",
+				SubProgress: &osbuild.Progress{
+					Message: "Downloaded langpacks-fonts-en-4.0-9.fc39.noarch.rpm",
+					Done:    2,
+				},
+			},
 		},
 		Timestamp: time.UnixMilli(int64(ts2)),
 	}, st)
@@ -103,6 +117,97 @@ func TestScannerSubprogress(t *testing.T) {
 	}, st)
 }
 
+const osbuildMonitorLines_rpm = `{"message": "Starting module org.osbuild.rpm", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "os", "id": "p-os", "stage": {"name": "org.osbuild.rpm", "id": "s-rpm"}}, "id": "c-mon"}, "progress": {"name": "pipelines", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 8, "done": 2}}, "timestamp": 1731600115.0}
+{"message": "imported gpg key\nVerifying packages...\nPreparing packages...\nbash-5.2.26-3.fc40.x86_64\n", "context": {"origin": "org.osbuild", "pipeline": {"name": "os", "id": "p-os", "stage": {"name": "org.osbuild.rpm", "id": "s-rpm"}}, "id": "c-rpm"}, "progress": {"name": "pipelines", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 8, "done": 2}}, "timestamp": 1731600116.0}
+{"message": "/usr/lib/tmpfiles.d/abrt.conf:2: Failed to resolve user 'abrt': No such process\nglibc-2.39-5.fc40.x86_64\nkernel-core-6.8.5-301.fc40.x86_64\n", "context": {"id": "c-rpm"}, "progress": {"name": "pipelines", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 8, "done": 2}}, "timestamp": 1731600117.0}
+`
+
+const osbuildManifest_rpm = `{
+  "version": "2",
+  "pipelines": [
+    {"name": "build", "stages": [{"type": "org.osbuild.rpm", "inputs": {"packages": {"references": [{"id": "sha256:1"}]}}}]},
+    {"name": "os", "stages": [{"type": "org.osbuild.kernel-cmdline"}, {"type": "org.osbuild.rpm", "inputs": {"packages": {"references": {"sha256:1": {}, "sha256:2": {}, "sha256:3": {}, "sha256:4": {}}}}}]}
+  ],
+  "sources": {"org.osbuild.curl": {"items": {"sha256:1": {}, "sha256:2": {}, "sha256:3": {}, "sha256:4": {}}}}
+}`
+
+func TestScannerRpmSubprogress(t *testing.T) {
+	r := bytes.NewBufferString(osbuildMonitorLines_rpm)
+	scanner := osbuild.NewStatusScanner(r)
+	require.NoError(t, scanner.SetManifest([]byte(osbuildManifest_rpm)))
+
+	// the "Starting module" message has no package progress
+	st, err := scanner.Status()
+	require.NoError(t, err)
+	assert.Nil(t, st.Progress.SubProgress.SubProgress)
+
+	st, err = scanner.Status()
+	require.NoError(t, err)
+	assert.Equal(t, &osbuild.Progress{
+		Message: "Installing bash-5.2.26-3.fc40.x86_64",
+		Done:    1,
+		Total:   4,
+	}, st.Progress.SubProgress.SubProgress)
+
+	st, err = scanner.Status()
+	require.NoError(t, err)
+	assert.Equal(t, &osbuild.Progress{
+		Message: "Installing kernel-core-6.8.5-301.fc40.x86_64",
+		Done:    3,
+		Total:   4,
+	}, st.Progress.SubProgress.SubProgress)
+}
+
+func TestScannerSetManifestCurlTotal(t *testing.T) {
+	r := bytes.NewBufferString(osbuildMonitorLines_curl)
+	scanner := osbuild.NewStatusScanner(r)
+	require.NoError(t, scanner.SetManifest([]byte(osbuildManifest_rpm)))
+
+	st, err := scanner.Status()
+	require.NoError(t, err)
+	// osbuild reports no progress for the source, the package progress
+	// is still one level below the pipeline progress
+	assert.Equal(t, &osbuild.Progress{
+		Message: "Source org.osbuild.curl",
+		SubProgress: &osbuild.Progress{
+			Message: "Downloaded kpartx-0.9.5-2.fc39.x86_64.rpm",
+			Done:    1,
+			Total:   4,
+		},
+	}, st.Progress.SubProgress)
+}
+
+const osbuildMonitorLines_rpmNoSubprogress = `{"message": "Preparing packages...\nbash-5.2.26-3.fc40.x86_64\n", "context": {"origin": "org.osbuild", "pipeline": {"name": "os", "id": "p-os", "stage": {"name": "org.osbuild.rpm", "id": "s-rpm"}}, "id": "c-rpm"}, "progress": {"name": "pipelines", "total": 2, "done": 1}, "timestamp": 1731600116.0}
+`
+
+func TestScannerRpmSubprogressWithoutStageProgress(t *testing.T) {
+	r := bytes.NewBufferString(osbuildMonitorLines_rpmNoSubprogress)
+	scanner := osbuild.NewStatusScanner(r)
+	require.NoError(t, scanner.SetManifest([]byte(osbuildManifest_rpm)))
+
+	st, err := scanner.Status()
+	require.NoError(t, err)
+	assert.Equal(t, &osbuild.Progress{
+		Message: "Pipeline os",
+		Done:    1,
+		Total:   2,
+		SubProgress: &osbuild.Progress{
+			Message: "Stage org.osbuild.rpm",
+			SubProgress: &osbuild.Progress{
+				Message: "Installing bash-5.2.26-3.fc40.x86_64",
+				Done:    1,
+				Total:   4,
+			},
+		},
+	}, st.Progress)
+}
+
+func TestScannerSetManifestBad(t *testing.T) {
+	scanner := osbuild.NewStatusScanner(bytes.NewBufferString(""))
+	err := scanner.SetManifest([]byte(`{"pipelines": [{"name": "os", "stages": [{"type": "org.osbuild.rpm", "inputs": {"packages": {"references": "bad"}}}]}]}`))
+	assert.ErrorContains(t, err, `cannot count packages of rpm stage in pipeline "os"`)
+}
+
 func TestScannerSmoke(t *testing.T) {
 	f, err := os.Open("../../test/data/osbuild-monitor-output.json")
 	require.NoError(t, err)
@@ -2,45 +2,56 @@ package osbuild
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/osbuild/images/pkg/datasizes"
 )
 
-// Run an instance of osbuild, returning a parsed osbuild.Result.
-//
-// Note that osbuild returns non-zero when the pipeline fails. This function
-// does not return an error in this case. Instead, the failure is communicated
-// with its corresponding logs through osbuild.Result.
-func RunOSBuild(manifest []byte, store, outputDirectory string, exports, checkpoints, extraEnv []string, result bool, errorWriter io.Writer) (*Result, error) {
-	var stdoutBuffer bytes.Buffer
-	var res Result
-
-	cmd := exec.Command(
-		"osbuild",
+// osbuildArgs returns the common osbuild arguments for building the
+// manifest that is passed on stdin
+func osbuildArgs(store, outputDirectory string, exports, checkpoints []string) []string {
+	args := []string{
 		"--store", store,
 		"--output-directory", outputDirectory,
 		"-",
-	)
+	}
 
 	for _, export := range exports {
-		cmd.Args = append(cmd.Args, "--export", export)
+		args = append(args, "--export", export)
 	}
 
 	for _, checkpoint := range checkpoints {
-		cmd.Args = append(cmd.Args, "--checkpoint", checkpoint)
+		args = append(args, "--checkpoint", checkpoint)
 	}
 
 	if len(checkpoints) > 0 {
 		// set the cache-max-size to a reasonable size that the checkpoints actually get stored
-		cmd.Args = append(cmd.Args, "--cache-max-size", fmt.Sprint(20*datasizes.GiB))
+		args = append(args, "--cache-max-size", fmt.Sprint(20*datasizes.GiB))
 	}
 
+	return args
+}
+
+// Run an instance of osbuild, returning a parsed osbuild.Result.
+//
+// Note that osbuild returns non-zero when the pipeline fails. This function
+// does not return an error in this case. Instead, the failure is communicated
+// with its corresponding logs through osbuild.Result.
+func RunOSBuild(manifest []byte, store, outputDirectory string, exports, checkpoints, extraEnv []string, result bool, errorWriter io.Writer) (*Result, error) {
+	var stdoutBuffer bytes.Buffer
+	var res Result
+
+	cmd := exec.Command("osbuild", osbuildArgs(store, outputDirectory, exports, checkpoints)...)
+
 	if result {
 		cmd.Args = append(cmd.Args, "--json")
 		cmd.Stdout = &stdoutBuffer
@@ -96,6 +107,157 @@ func RunOSBuild(manifest []byte, store, outputDirectory string, exports, checkpo
 	return &res, nil
 }
 
+// RunOSBuildOptions contains the settings for RunOSBuildWithProgress
+type RunOSBuildOptions struct {
+	Store           string
+	OutputDirectory string
+	Exports         []string
+	Checkpoints     []string
+	ExtraEnv        []string
+
+	// Stderr receives the stderr output of osbuild, if unset
+	// it is discarded. Monitor messages that cannot be parsed
+	// are reported here as warnings as well.
+	Stderr io.Writer
+
+	// StatusCallback is called for every status update that
+	// osbuild reports via its monitor. It is called from a
+	// separate goroutine, but never concurrently.
+	StatusCallback func(*Status)
+}
+
+// osbuildKillWaitDelay is the time to wait for osbuild's output to be
+// closed after it was killed because the context was cancelled
+var osbuildKillWaitDelay = 10 * time.Second
+
+// RunOSBuildWithProgress runs an instance of osbuild, streaming its
+// progress to opts.StatusCallback and returning the parsed
+// osbuild.Result once the build is finished.
+//
+// When the context is cancelled, osbuild and all processes in its
+// process group are killed and the context error is returned.
+//
+// Like RunOSBuild this function does not return an error when the
+// pipeline fails, the failure is communicated via osbuild.Result.
+// Progress is only informational, monitor messages that cannot be
+// parsed are skipped and never fail the build.
+func RunOSBuildWithProgress(ctx context.Context, manifest []byte, opts *RunOSBuildOptions) (*Result, error) {
+	if opts == nil {
+		opts = &RunOSBuildOptions{}
+	}
+
+	monitorR, monitorW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating monitor pipe for osbuild: %w", err)
+	}
+	defer monitorR.Close()
+
+	args := osbuildArgs(opts.Store, opts.OutputDirectory, opts.Exports, opts.Checkpoints)
+	// the first entry of ExtraFiles becomes fd 3 in the child
+	args = append(args, "--monitor=JSONSeqMonitor", "--monitor-fd=3", "--json")
+
+	var stdoutBuffer bytes.Buffer
+	cmd := exec.CommandContext(ctx, "osbuild", args...)
+	cmd.Stdout = &stdoutBuffer
+	cmd.Stderr = opts.Stderr
+	cmd.ExtraFiles = []*os.File{monitorW}
+	if len(opts.ExtraEnv) > 0 {
+		cmd.Env = append(os.Environ(), opts.ExtraEnv...)
+	}
+	// run osbuild in its own process group so that on cancel
+	// all its helpers (bubblewrap, stages, ...) are killed too
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = osbuildKillWaitDelay
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		monitorW.Close()
+		return nil, fmt.Errorf("error setting up stdin for osbuild: %w", err)
+	}
+
+	err = cmd.Start()
+	// the child has its own copy of the write end now
+	monitorW.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error starting osbuild: %w", err)
+	}
+
+	monitorDone := make(chan struct{})
+	go func() {
+		defer close(monitorDone)
+		// drain the pipe so that osbuild never blocks on it, even
+		// if the progress cannot be read anymore
+		defer func() { _, _ = io.Copy(io.Discard, monitorR) }()
+
+		scanner := NewStatusScanner(monitorR)
+		// without the totals the package progress is still
+		// reported, so a manifest that cannot be parsed here is
+		// not fatal (osbuild will reject it anyway)
+		_ = scanner.SetManifest(manifest)
+		for {
+			st, err := scanner.Status()
+			if err != nil {
+				if opts.Stderr != nil {
+					fmt.Fprintf(opts.Stderr, "WARNING: ignoring osbuild progress: %v\n", err)
+				}
+				// a single malformed message is skipped, but
+				// once reading fails no more lines can be scanned
+				if scanner.scanner.Err() != nil {
+					return
+				}
+				continue
+			}
+			if st == nil {
+				return
+			}
+			if opts.StatusCallback != nil {
+				opts.StatusCallback(st)
+			}
+		}
+	}()
+
+	_, err = stdin.Write(manifest)
+	if err == nil {
+		err = stdin.Close()
+	}
+	if err != nil && ctx.Err() == nil {
+		_ = cmd.Cancel()
+		_ = cmd.Wait()
+		<-monitorDone
+		return nil, fmt.Errorf("error writing osbuild manifest: %w", err)
+	}
+
+	err = cmd.Wait()
+	<-monitorDone
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("running osbuild cancelled: %w", ctxErr)
+	}
+
+	// try to decode the output even though the job could have failed
+	if stdoutBuffer.Len() == 0 {
+		if err != nil {
+			return nil, fmt.Errorf("running osbuild failed: %w", err)
+		}
+		return nil, fmt.Errorf("osbuild did not return any output")
+	}
+	var res Result
+	if decodeErr := json.Unmarshal(stdoutBuffer.Bytes(), &res); decodeErr != nil {
+		return nil, fmt.Errorf("error decoding osbuild output: %v\nthe raw output:\n%s", decodeErr, stdoutBuffer.String())
+	}
+
+	// ignore ExitError if output could be decoded correctly
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("running osbuild failed: %w", err)
+	}
+
+	return &res, nil
+}
+
 // OSBuildVersion returns the version of osbuild.
 func OSBuildVersion() (string, error) {
 	var stdoutBuffer bytes.Buffer
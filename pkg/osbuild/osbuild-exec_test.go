@@ -0,0 +1,120 @@
+package osbuild_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+func makeFakeOSBuild(t *testing.T, content string) {
+	tmpdir := t.TempDir()
+	t.Setenv("PATH", tmpdir+":"+os.Getenv("PATH"))
+
+	// nolint:gosec
+	err := os.WriteFile(filepath.Join(tmpdir, "osbuild"), []byte(content), 0755)
+	require.NoError(t, err)
+}
+
+var fakeOSBuildWithMonitor = `#!/bin/sh -e
+cat - > "$0".stdin
+echo "$@" > "$0".args
+printf '\036%s\n' '{"message": "Starting pipeline build", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "build", "id": "p1", "stage": {}}, "id": "c1"}, "progress": {"name": "pipelines", "total": 2, "done": 0}, "timestamp": 1731589407.0}' >&3
+printf '\036%s\n' '{"message": "Finished pipeline build", "context": {"id": "c1"}, "progress": {"name": "pipelines", "total": 2, "done": 1}, "timestamp": 1731589408.0}' >&3
+echo '{"success": true}'
+`
+
+func TestRunOSBuildWithProgress(t *testing.T) {
+	makeFakeOSBuild(t, fakeOSBuildWithMonitor)
+
+	var statuses []*osbuild.Status
+	res, err := osbuild.RunOSBuildWithProgress(context.Background(), []byte(`{"fake":"manifest"}`), &osbuild.RunOSBuildOptions{
+		Store:           "store",
+		OutputDirectory: "output",
+		Exports:         []string{"qcow2"},
+		StatusCallback: func(st *osbuild.Status) {
+			statuses = append(statuses, st)
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "Starting pipeline build", statuses[0].Message)
+	assert.Equal(t, "Pipeline build", statuses[0].Progress.Message)
+	assert.Equal(t, 0, statuses[0].Progress.Done)
+	assert.Equal(t, "Finished pipeline build", statuses[1].Message)
+	assert.Equal(t, 1, statuses[1].Progress.Done)
+	assert.Equal(t, 2, statuses[1].Progress.Total)
+
+	fakePath, err := exec.LookPath("osbuild")
+	require.NoError(t, err)
+	stdin, err := os.ReadFile(fakePath + ".stdin")
+	require.NoError(t, err)
+	assert.Equal(t, `{"fake":"manifest"}`, string(stdin))
+	args, err := os.ReadFile(fakePath + ".args")
+	require.NoError(t, err)
+	assert.Equal(t, "--store store --output-directory output - --export qcow2 --monitor=JSONSeqMonitor --monitor-fd=3 --json\n", string(args))
+}
+
+func TestRunOSBuildWithProgressMalformedMonitorLine(t *testing.T) {
+	makeFakeOSBuild(t, `#!/bin/sh -e
+cat - > /dev/null
+printf '\036%s\n' '{"message": "Starting pipeline build", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "build", "id": "p1", "stage": {}}, "id": "c1"}, "progress": {"name": "pipelines", "total": 2, "done": 0}, "timestamp": 1731589407.0}' >&3
+printf '\036%s\n' '{"message": "truncated' >&3
+printf '\036%s\n' '{"message": "Finished pipeline build", "context": {"id": "c1"}, "progress": {"name": "pipelines", "total": 2, "done": 1}, "timestamp": 1731589408.0}' >&3
+echo '{"success": true}'
+`)
+
+	var stderr bytes.Buffer
+	var statuses []*osbuild.Status
+	res, err := osbuild.RunOSBuildWithProgress(context.Background(), []byte(`{}`), &osbuild.RunOSBuildOptions{
+		Stderr: &stderr,
+		StatusCallback: func(st *osbuild.Status) {
+			statuses = append(statuses, st)
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, res.Success)
+	// the malformed line is skipped, the following ones are still reported
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "Finished pipeline build", statuses[1].Message)
+	assert.Contains(t, stderr.String(), "WARNING: ignoring osbuild progress: cannot scan line")
+}
+
+func TestRunOSBuildWithProgressCancel(t *testing.T) {
+	makeFakeOSBuild(t, `#!/bin/sh
+cat - > /dev/null
+# the child keeps the monitor fd open, it must be killed as well
+sleep 60 &
+sleep 60
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	res, err := osbuild.RunOSBuildWithProgress(ctx, []byte(`{}`), nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, res)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRunOSBuildWithProgressNoOutput(t *testing.T) {
+	makeFakeOSBuild(t, `#!/bin/sh
+cat - > /dev/null
+exit 1
+`)
+
+	res, err := osbuild.RunOSBuildWithProgress(context.Background(), []byte(`{}`), nil)
+	assert.EqualError(t, err, "running osbuild failed: exit status 1")
+	assert.Nil(t, res)
+}
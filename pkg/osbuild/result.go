@@ -78,20 +78,9 @@ func (md *PipelineMetadata) UnmarshalJSON(data []byte) error {
 	}
 	var pmd PipelineMetadata = make(map[string]StageMetadata)
 	for name, rawStageData := range rawPipelineMetadata {
-		var metadata StageMetadata
-		switch name {
-		case "org.osbuild.rpm":
-			metadata = new(RPMStageMetadata)
-			if err := json.Unmarshal(rawStageData, metadata); err != nil {
-				return err
-			}
-		case "org.osbuild.ostree.commit":
-			metadata = new(OSTreeCommitStageMetadata)
-			if err := json.Unmarshal(rawStageData, metadata); err != nil {
-				return err
-			}
-		default:
-			metadata = RawStageMetadata(rawStageData)
+		metadata, err := decodeStageMetadata(name, rawStageData)
+		if err != nil {
+			return err
 		}
 		pmd[name] = metadata
 	}
@@ -99,6 +88,25 @@ func (md *PipelineMetadata) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// decodeStageMetadata decodes the metadata of the given stage type
+// into its typed StageMetadata. Stages without a dedicated type are
+// returned as RawStageMetadata.
+func decodeStageMetadata(stageType string, data json.RawMessage) (StageMetadata, error) {
+	var metadata StageMetadata
+	switch stageType {
+	case "org.osbuild.rpm":
+		metadata = new(RPMStageMetadata)
+	case "org.osbuild.ostree.commit":
+		metadata = new(OSTreeCommitStageMetadata)
+	default:
+		return RawStageMetadata(data), nil
+	}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, fmt.Errorf("cannot decode metadata of stage %s: %w", stageType, err)
+	}
+	return metadata, nil
+}
+
 func (res *Result) UnmarshalJSON(data []byte) error {
 	// detect if the input is v1 result
 	if isV1, err := isV1Result(data); err != nil {
@@ -123,6 +131,134 @@ func (res *Result) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// InstalledPackages returns the packages that the rpm stage of the
+// given pipeline installed, or nil if the pipeline has no rpm stage
+// metadata.
+func (res *Result) InstalledPackages(pipeline string) []RPMPackageMetadata {
+	if res == nil {
+		return nil
+	}
+	md, ok := res.Metadata[pipeline]["org.osbuild.rpm"].(*RPMStageMetadata)
+	if !ok {
+		return nil
+	}
+	return md.Packages
+}
+
+// OSTreeCommit returns the metadata of the first ostree commit stage
+// found in the result (in pipeline name order) or nil if no commit
+// was created.
+func (res *Result) OSTreeCommit() *OSTreeCommitStageMetadataCompose {
+	if res == nil {
+		return nil
+	}
+	pipelineNames := make([]string, 0, len(res.Metadata))
+	for name := range res.Metadata {
+		pipelineNames = append(pipelineNames, name)
+	}
+	sort.Strings(pipelineNames)
+	for _, name := range pipelineNames {
+		if md, ok := res.Metadata[name]["org.osbuild.ostree.commit"].(*OSTreeCommitStageMetadata); ok {
+			return &md.Compose
+		}
+	}
+	return nil
+}
+
+// CommitID returns the id of the ostree commit that was created by
+// the build or an empty string if there is none.
+func (res *Result) CommitID() string {
+	if commit := res.OSTreeCommit(); commit != nil {
+		return commit.OSTreeCommit
+	}
+	return ""
+}
+
+// ContainerImage is a container image that a stage of a pipeline copied
+// into the image or deployed.
+type ContainerImage struct {
+	Pipeline string
+	Stage    string
+
+	// ImageID is the id of the image, Name its local name in the image
+	ImageID string
+	Name    string
+
+	// Source and Digest are the name and manifest digest that the image
+	// was pulled from, empty for images from the local containers storage
+	Source string
+	Digest string
+}
+
+// containerManifest is the subset of a manifest that references
+// container images
+type containerManifest struct {
+	Pipelines []struct {
+		Name   string `json:"name"`
+		Stages []struct {
+			Type   string `json:"type"`
+			Inputs map[string]struct {
+				Type       string                              `json:"type"`
+				References map[string]ContainersInputSourceRef `json:"references"`
+			} `json:"inputs"`
+		} `json:"stages"`
+	} `json:"pipelines"`
+	Sources struct {
+		Skopeo SkopeoSource `json:"org.osbuild.skopeo"`
+	} `json:"sources"`
+}
+
+// ContainerImages returns the container images of the manifest that was
+// built, in pipeline and stage order, with the digests of the
+// org.osbuild.skopeo source they were pulled from. The container stages do
+// not report metadata, so the images are taken from the container inputs of
+// the manifest. It returns nil if the build failed.
+func (res *Result) ContainerImages(manifest []byte) ([]ContainerImage, error) {
+	if res == nil || !res.Success {
+		return nil, nil
+	}
+	var mf containerManifest
+	if err := json.Unmarshal(manifest, &mf); err != nil {
+		return nil, fmt.Errorf("cannot parse manifest: %w", err)
+	}
+
+	var images []ContainerImage
+	for _, pl := range mf.Pipelines {
+		for _, stage := range pl.Stages {
+			inputNames := make([]string, 0, len(stage.Inputs))
+			for name := range stage.Inputs {
+				inputNames = append(inputNames, name)
+			}
+			sort.Strings(inputNames)
+			for _, inputName := range inputNames {
+				input := stage.Inputs[inputName]
+				if input.Type != "org.osbuild.containers" && input.Type != SourceNameContainersStorage {
+					continue
+				}
+				ids := make([]string, 0, len(input.References))
+				for id := range input.References {
+					ids = append(ids, id)
+				}
+				sort.Strings(ids)
+				for _, id := range ids {
+					image := ContainerImage{
+						Pipeline: pl.Name,
+						Stage:    stage.Type,
+						ImageID:  id,
+						Name:     input.References[id].Name,
+					}
+					if item, ok := mf.Sources.Skopeo.Items[id]; ok && input.Type == "org.osbuild.containers" {
+						image.Source = item.Image.Name
+						image.Digest = item.Image.Digest
+					}
+					images = append(images, image)
+				}
+			}
+		}
+	}
+	return images, nil
+}
+
 func (res *Result) Write(writer io.Writer) error {
 	// Error may be included, print them first
 	if res != nil && len(res.Errors) > 0 {
@@ -179,6 +179,116 @@ func TestUnmarshalV2Success(t *testing.T) {
 	}
 }
 
+func TestResultHelpersV2Success(t *testing.T) {
+	var result Result
+	err := json.Unmarshal([]byte(v2ResultSuccess), &result)
+	require.NoError(t, err)
+
+	assert.Equal(t, "f2b16f20de69edf932866662efbd00754c2d0decd878c60b4914372e34b5e629", result.CommitID())
+	commit := result.OSTreeCommit()
+	require.NotNil(t, commit)
+	assert.NotEmpty(t, commit.Ref)
+
+	pkgs := result.InstalledPackages("ostree-tree")
+	assert.NotEmpty(t, pkgs)
+	assert.Nil(t, result.InstalledPackages("assembler"))
+	assert.Nil(t, result.InstalledPackages("no-such-pipeline"))
+}
+
+func TestResultHelpersNil(t *testing.T) {
+	var result *Result
+	assert.Equal(t, "", result.CommitID())
+	assert.Nil(t, result.InstalledPackages("os"))
+}
+
+func TestResultContainerStageMetadataIsRaw(t *testing.T) {
+	// the container stages do not report structured metadata (yet),
+	// make sure that whatever they return is kept as is
+	raw := `{"type": "result", "success": true, "log": {}, "metadata": {"os": {"org.osbuild.container-deploy": {"foo": "bar"}}}}`
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(raw), &result))
+	assert.Equal(t, RawStageMetadata(`{"foo": "bar"}`), result.Metadata["os"]["org.osbuild.container-deploy"])
+}
+
+func TestResultContainerImages(t *testing.T) {
+	manifest := `{
+  "pipelines": [
+    {"name": "build", "stages": [{"type": "org.osbuild.rpm"}]},
+    {"name": "os", "stages": [
+      {"type": "org.osbuild.skopeo", "inputs": {
+        "images": {"type": "org.osbuild.containers", "origin": "org.osbuild.source", "references": {
+          "sha256:2222222222222222222222222222222222222222222222222222222222222222": {"name": "registry.example.com/app:latest"},
+          "sha256:1111111111111111111111111111111111111111111111111111111111111111": {"name": "registry.example.com/db:latest"}
+        }},
+        "manifest-lists": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": {}}
+      }},
+      {"type": "org.osbuild.skopeo", "inputs": {
+        "images": {"type": "org.osbuild.containers-storage", "origin": "org.osbuild.source", "references": {
+          "sha256:3333333333333333333333333333333333333333333333333333333333333333": {"name": "localhost/local:latest"}
+        }}
+      }}
+    ]}
+  ],
+  "sources": {
+    "org.osbuild.skopeo": {"items": {
+      "sha256:1111111111111111111111111111111111111111111111111111111111111111": {"image": {"name": "registry.example.com/db", "digest": "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}},
+      "sha256:2222222222222222222222222222222222222222222222222222222222222222": {"image": {"name": "registry.example.com/app", "digest": "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"}}
+    }}
+  }
+}`
+	result := &Result{Success: true}
+	images, err := result.ContainerImages([]byte(manifest))
+	require.NoError(t, err)
+	assert.Equal(t, []ContainerImage{
+		{
+			Pipeline: "os",
+			Stage:    "org.osbuild.skopeo",
+			ImageID:  "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+			Name:     "registry.example.com/db:latest",
+			Source:   "registry.example.com/db",
+			Digest:   "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		},
+		{
+			Pipeline: "os",
+			Stage:    "org.osbuild.skopeo",
+			ImageID:  "sha256:2222222222222222222222222222222222222222222222222222222222222222",
+			Name:     "registry.example.com/app:latest",
+			Source:   "registry.example.com/app",
+			Digest:   "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		},
+		{
+			Pipeline: "os",
+			Stage:    "org.osbuild.skopeo",
+			ImageID:  "sha256:3333333333333333333333333333333333333333333333333333333333333333",
+			Name:     "localhost/local:latest",
+		},
+	}, images)
+
+	// no images for a failed build
+	images, err = (&Result{Success: false}).ContainerImages([]byte(manifest))
+	require.NoError(t, err)
+	assert.Nil(t, images)
+
+	_, err = result.ContainerImages([]byte("{"))
+	assert.ErrorContains(t, err, "cannot parse manifest")
+}
+
+func TestResultBadTypedMetadata(t *testing.T) {
+	raw := `{"type": "result", "success": true, "log": {}, "metadata": {"os": {"org.osbuild.rpm": {"packages": "bad"}}}}`
+	var result Result
+	err := json.Unmarshal([]byte(raw), &result)
+	assert.ErrorContains(t, err, "cannot decode metadata of stage org.osbuild.rpm")
+}
+
+func TestRPMPackageMetadataNEVRA(t *testing.T) {
+	assert.Equal(t, "bash-5.2.26-3.fc40.x86_64", RPMPackageMetadata{
+		Name: "bash", Version: "5.2.26", Release: "3.fc40", Arch: "x86_64",
+	}.NEVRA())
+	assert.Equal(t, "glibc-1:2.39-5.fc40.x86_64", RPMPackageMetadata{
+		Name: "glibc", Epoch: common.ToPtr("1"), Version: "2.39", Release: "5.fc40", Arch: "x86_64",
+	}.NEVRA())
+}
+
 func TestUnmarshalV2Failure(t *testing.T) {
 	assert := assert.New(t)
 	var result Result
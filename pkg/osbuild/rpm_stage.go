@@ -1,6 +1,7 @@
 package osbuild
 
 import (
+	"fmt"
 	"slices"
 
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -87,6 +88,16 @@ type RPMPackageMetadata struct {
 
 func (RPMStageMetadata) isStageMetadata() {}
 
+// NEVRA returns the name-[epoch:]version-release.arch string of the
+// package
+func (pkg RPMPackageMetadata) NEVRA() string {
+	evr := pkg.Version + "-" + pkg.Release
+	if pkg.Epoch != nil && *pkg.Epoch != "" && *pkg.Epoch != "0" {
+		evr = *pkg.Epoch + ":" + evr
+	}
+	return fmt.Sprintf("%s-%s.%s", pkg.Name, evr, pkg.Arch)
+}
+
 func OSBuildMetadataToRPMs(stagesMetadata map[string]StageMetadata) []rpmmd.RPM {
 	rpms := make([]rpmmd.RPM, 0)
 	for _, md := range stagesMetadata {
@@ -0,0 +1,595 @@
+package osbuild
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// DefaultLibDir is the location of the osbuild modules of a system wide
+// osbuild installation
+const DefaultLibDir = "/usr/lib/osbuild"
+
+// The module kinds known to the validator, the values are the names of
+// the directories in the osbuild library dir
+const (
+	moduleStages  = "stages"
+	moduleSources = "sources"
+	moduleInputs  = "inputs"
+	moduleDevices = "devices"
+	moduleMounts  = "mounts"
+)
+
+var moduleKinds = []string{moduleStages, moduleSources, moduleInputs, moduleDevices, moduleMounts}
+
+// manifestSchemaV2 describes the structure of a version 2 manifest,
+// the module specific parts are checked against the module schemas
+var manifestSchemaV2 = mustCompileSchema(`{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["version"],
+  "properties": {
+    "version": {"enum": ["2"]},
+    "pipelines": {"type": "array", "items": {"$ref": "#/definitions/pipeline"}},
+    "sources": {"type": "object", "additionalProperties": {"type": "object"}},
+    "metadata": {"type": "object"}
+  },
+  "definitions": {
+    "pipeline": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "name": {"type": "string"},
+        "build": {"type": "string"},
+        "runner": {"type": "string"},
+        "source-epoch": {"type": "integer"},
+        "stages": {"type": "array", "items": {"$ref": "#/definitions/stage"}}
+      }
+    },
+    "stage": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["type"],
+      "properties": {
+        "type": {"type": "string"},
+        "id": {"type": "string"},
+        "options": {"type": "object"},
+        "inputs": {"type": "object", "additionalProperties": {"$ref": "#/definitions/input"}},
+        "devices": {"type": "object", "additionalProperties": {"$ref": "#/definitions/device"}},
+        "mounts": {"type": "array", "items": {"$ref": "#/definitions/mount"}}
+      }
+    },
+    "input": {
+      "type": "object",
+      "required": ["type", "origin", "references"],
+      "properties": {
+        "type": {"type": "string"},
+        "origin": {"enum": ["org.osbuild.source", "org.osbuild.pipeline"]},
+        "references": {"type": ["array", "object"]},
+        "options": {"type": "object"}
+      }
+    },
+    "device": {
+      "type": "object",
+      "required": ["type"],
+      "properties": {
+        "type": {"type": "string"},
+        "parent": {"type": "string"},
+        "options": {"type": "object"}
+      }
+    },
+    "mount": {
+      "type": "object",
+      "required": ["name", "type"],
+      "properties": {
+        "name": {"type": "string"},
+        "type": {"type": "string"},
+        "source": {"type": "string"},
+        "target": {"type": "string"},
+        "partition": {"type": "integer"},
+        "options": {"type": "object"}
+      }
+    }
+  }
+}`)
+
+func mustCompileSchema(data string) *gojsonschema.Schema {
+	schema, err := decodeJSON([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// decodeJSON decodes numbers as json.Number, so large integers keep
+// their exact value
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+	return v, nil
+}
+
+func compileSchema(schema any) (*gojsonschema.Schema, error) {
+	return gojsonschema.NewSchemaLoader().Compile(gojsonschema.NewGoLoader(schema))
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SchemaValidator checks manifests offline against the JSON schemas of
+// the osbuild stages, sources, inputs, devices and mounts. This allows
+// to catch invalid options without running osbuild.
+type SchemaValidator struct {
+	// kind -> module name -> schema
+	modules map[string]map[string]*gojsonschema.Schema
+
+	warnings []string
+}
+
+// NewSchemaValidator loads the module schemas from the given file
+// system. The layout must be the same as the one of the osbuild
+// library dir, i.e. the "<kind>/<name>.meta.json" files of osbuild.
+func NewSchemaValidator(fsys fs.FS) (*SchemaValidator, error) {
+	v := &SchemaValidator{
+		modules: make(map[string]map[string]*gojsonschema.Schema, len(moduleKinds)),
+	}
+	found := 0
+	for _, kind := range moduleKinds {
+		v.modules[kind] = make(map[string]*gojsonschema.Schema)
+
+		metaFiles, err := fs.Glob(fsys, path.Join(kind, "*.meta.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, metaFile := range metaFiles {
+			name := strings.TrimSuffix(path.Base(metaFile), ".meta.json")
+			data, err := fs.ReadFile(fsys, metaFile)
+			if err != nil {
+				return nil, err
+			}
+			if err := v.addModule(kind, name, data); err != nil {
+				return nil, fmt.Errorf("cannot load schema %q: %w", metaFile, err)
+			}
+			found++
+		}
+	}
+	if found == 0 {
+		return nil, fmt.Errorf("no osbuild module schemas found")
+	}
+	return v, nil
+}
+
+// Warnings returns the parts of the loaded schemas that the validator
+// cannot check, e.g. patterns that use regular expression features
+// that Go does not support. Manifests may be accepted even though
+// osbuild would reject them in these places.
+func (v *SchemaValidator) Warnings() []string {
+	return v.warnings
+}
+
+// NewSchemaValidatorFromDir loads the module schemas from an osbuild
+// library dir, e.g. DefaultLibDir.
+func NewSchemaValidatorFromDir(libdir string) (*SchemaValidator, error) {
+	st, err := os.Stat(libdir)
+	if err != nil {
+		return nil, err
+	}
+	if !st.IsDir() {
+		return nil, fmt.Errorf("%q is not a directory", libdir)
+	}
+	return NewSchemaValidator(os.DirFS(libdir))
+}
+
+func (v *SchemaValidator) addModule(kind, name string, data []byte) error {
+	raw, err := decodeJSON(data)
+	if err != nil {
+		return err
+	}
+	meta, ok := raw.(map[string]any)
+	if !ok {
+		return errors.New("module metadata is not an object")
+	}
+
+	var schema map[string]any
+	switch kind {
+	case moduleStages:
+		schema = stageSchema(name, meta)
+	default:
+		schema = entrySchema(kind, name, meta)
+	}
+	scopeDefinitionRefs(schema, "", nil)
+
+	var unsupported []string
+	prepareSchema(schema, &unsupported)
+	for _, p := range unsupported {
+		v.warnings = append(v.warnings, fmt.Sprintf("%s/%s: pattern %q is not supported and is not checked", kind, name, p))
+	}
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return err
+	}
+	v.modules[kind][name] = compiled
+	return nil
+}
+
+// prepareSchema adapts a module schema to the validator. Patterns are
+// Python regular expressions, the ones that Go cannot compile are
+// removed and collected in unsupported. Like osbuild, formats are
+// annotations only and are removed so they are not checked.
+func prepareSchema(fragment any, unsupported *[]string) {
+	switch f := fragment.(type) {
+	case map[string]any:
+		if p, ok := f["pattern"].(string); ok {
+			if _, err := regexp.Compile(p); err != nil {
+				*unsupported = append(*unsupported, p)
+				delete(f, "pattern")
+			}
+		}
+		if pp, ok := f["patternProperties"].(map[string]any); ok {
+			for _, p := range sortedKeys(pp) {
+				if _, err := regexp.Compile(p); err != nil {
+					*unsupported = append(*unsupported, p)
+					delete(pp, p)
+				}
+			}
+		}
+		if _, ok := f["format"].(string); ok {
+			delete(f, "format")
+		}
+		for _, v := range f {
+			prepareSchema(v, unsupported)
+		}
+	case []any:
+		for _, v := range f {
+			prepareSchema(v, unsupported)
+		}
+	}
+}
+
+// stageSchema builds the schema for a complete stage object from the
+// "schema_2" metadata, which contains separate schemas for the
+// options, inputs, devices and mounts of the stage. Modules without
+// version 2 metadata fall back to the version 1 "schema" for the
+// options.
+func stageSchema(name string, meta map[string]any) map[string]any {
+	var options any
+	props := map[string]any{
+		"type": map[string]any{"enum": []any{name}},
+	}
+	schema := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+	if s2, ok := meta["schema_2"].(map[string]any); ok {
+		options = s2["options"]
+		for _, member := range []string{"inputs", "devices", "mounts"} {
+			if s, ok := s2[member]; ok {
+				props[member] = s
+			}
+		}
+		// like osbuild, the top level definitions of the stage
+		// are available to all members
+		for _, key := range []string{"definitions", "$defs"} {
+			if defs, ok := s2[key]; ok {
+				schema[key] = defs
+			}
+		}
+	}
+	if options == nil {
+		options = meta["schema"]
+	}
+	optionsSchema := objectSchema(options)
+	props["options"] = optionsSchema
+
+	// like osbuild, require the options if they have required
+	// properties
+	if req, ok := optionsSchema["required"].([]any); ok && len(req) > 0 {
+		schema["required"] = []any{"options"}
+	}
+	return schema
+}
+
+// entrySchema builds the schema for a source, input, device or mount
+// entry, for these modules "schema_2" describes the whole entry
+func entrySchema(kind, name string, meta map[string]any) map[string]any {
+	s2, ok := meta["schema_2"].(map[string]any)
+	if !ok {
+		s2, _ = meta["schema"].(map[string]any)
+	}
+	if len(s2) == 0 {
+		// without a schema only the structure of the entry is
+		// checked
+		return map[string]any{"type": "object"}
+	}
+	schema := objectSchema(s2)
+
+	// sources are keyed by their name, all other entries carry their
+	// module name in the "type" property
+	if kind != moduleSources {
+		props := make(map[string]any)
+		if p, ok := schema["properties"].(map[string]any); ok {
+			for k, v := range p {
+				props[k] = v
+			}
+		}
+		if _, ok := props["type"]; !ok {
+			props["type"] = map[string]any{"enum": []any{name}}
+		}
+		schema["properties"] = props
+	}
+	return schema
+}
+
+// objectSchema returns a copy of the given schema fragment that only
+// accepts objects, osbuild module schemas frequently omit the type. An
+// empty schema is taken to mean that the module takes no options.
+func objectSchema(fragment any) map[string]any {
+	schema := map[string]any{
+		"type": "object",
+	}
+	f, ok := fragment.(map[string]any)
+	if !ok || len(f) == 0 {
+		schema["additionalProperties"] = false
+		return schema
+	}
+	for k, v := range f {
+		schema[k] = v
+	}
+	if _, ok := f["type"]; !ok {
+		schema["type"] = "object"
+	}
+	return schema
+}
+
+// definitionScope is a schema fragment that declares definitions
+type definitionScope struct {
+	// pointer is the JSON pointer of the fragment in the schema
+	pointer string
+	key     string
+	names   map[string]any
+}
+
+// scopeDefinitionRefs rewrites "#/definitions/<name>" references to
+// point to the definitions of the closest enclosing fragment that
+// declares <name>. The module schemas are written as if every part
+// (options, inputs, devices, mounts) was the root of its own schema,
+// so a definition must only be visible in the part that declares it
+// and definitions with the same name in different parts must not
+// clash. References to undeclared names are left as is and resolved
+// against the root.
+func scopeDefinitionRefs(fragment any, pointer string, scopes []definitionScope) {
+	switch f := fragment.(type) {
+	case map[string]any:
+		for _, key := range []string{"definitions", "$defs"} {
+			if defs, ok := f[key].(map[string]any); ok {
+				scopes = append(scopes, definitionScope{pointer: pointer, key: key, names: defs})
+			}
+		}
+		if ref, ok := f["$ref"].(string); ok {
+			f["$ref"] = scopedRef(ref, scopes)
+		}
+		for k, v := range f {
+			scopeDefinitionRefs(v, pointer+"/"+escapeJSONPointer(k), scopes)
+		}
+	case []any:
+		for idx, v := range f {
+			scopeDefinitionRefs(v, fmt.Sprintf("%s/%d", pointer, idx), scopes)
+		}
+	}
+}
+
+func scopedRef(ref string, scopes []definitionScope) string {
+	for _, key := range []string{"definitions", "$defs"} {
+		rest, ok := strings.CutPrefix(ref, "#/"+key+"/")
+		if !ok {
+			continue
+		}
+		name, tail, _ := strings.Cut(rest, "/")
+		name = strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~")
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if scopes[i].key != key {
+				continue
+			}
+			if _, ok := scopes[i].names[name]; !ok {
+				continue
+			}
+			scoped := "#" + scopes[i].pointer + "/" + key + "/" + escapeJSONPointer(name)
+			if tail != "" {
+				scoped += "/" + tail
+			}
+			return scoped
+		}
+	}
+	return ref
+}
+
+func escapeJSONPointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// Validate checks the serialized manifest against the loaded module
+// schemas. Schema violations are returned as ValidationErrors with the
+// same path layout that osbuild uses, an error is only returned if the
+// manifest cannot be parsed.
+func (v *SchemaValidator) Validate(manifest []byte) ([]ValidationError, error) {
+	raw, err := decodeJSON(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse manifest: %w", err)
+	}
+
+	var errs []ValidationError
+	validateEntry(manifestSchemaV2, raw, nil, &errs)
+	if len(errs) > 0 {
+		// the structure is broken, the module schemas cannot be
+		// applied reliably
+		return errs, nil
+	}
+
+	mf := raw.(map[string]any)
+	pipelines, _ := mf["pipelines"].([]any)
+	for pidx, p := range pipelines {
+		pipeline := p.(map[string]any)
+		stages, _ := pipeline["stages"].([]any)
+		for sidx, s := range stages {
+			stagePath := []string{"pipelines", fmt.Sprintf("[%d]", pidx), "stages", fmt.Sprintf("[%d]", sidx)}
+			v.validateStage(s.(map[string]any), stagePath, &errs)
+		}
+	}
+
+	sources, _ := mf["sources"].(map[string]any)
+	for _, name := range sortedKeys(sources) {
+		v.validateModule(moduleSources, name, sources[name], []string{"sources", name}, &errs)
+	}
+
+	return errs, nil
+}
+
+func (v *SchemaValidator) validateStage(stage map[string]any, stagePath []string, errs *[]ValidationError) {
+	stageType, _ := stage["type"].(string)
+	if !v.validateModule(moduleStages, stageType, stage, stagePath, errs) {
+		return
+	}
+
+	inputs, _ := stage["inputs"].(map[string]any)
+	for _, name := range sortedKeys(inputs) {
+		input := inputs[name].(map[string]any)
+		inputType, _ := input["type"].(string)
+		v.validateModule(moduleInputs, inputType, input, append(stagePath, "inputs", name), errs)
+	}
+
+	devices, _ := stage["devices"].(map[string]any)
+	for _, name := range sortedKeys(devices) {
+		device := devices[name].(map[string]any)
+		deviceType, _ := device["type"].(string)
+		v.validateModule(moduleDevices, deviceType, device, append(stagePath, "devices", name), errs)
+	}
+
+	mounts, _ := stage["mounts"].([]any)
+	for idx, m := range mounts {
+		mount := m.(map[string]any)
+		mountType, _ := mount["type"].(string)
+		v.validateModule(moduleMounts, mountType, mount, append(stagePath, "mounts", fmt.Sprintf("[%d]", idx)), errs)
+	}
+}
+
+// validateModule validates a single module entry, it returns false if
+// no schema for the module is known
+func (v *SchemaValidator) validateModule(kind, name string, entry any, entryPath []string, errs *[]ValidationError) bool {
+	schema, ok := v.modules[kind][name]
+	if !ok {
+		*errs = append(*errs, ValidationError{
+			Message: fmt.Sprintf("Could not find schema information for '%s'", name),
+			Path:    append([]string(nil), entryPath...),
+		})
+		return false
+	}
+	validateEntry(schema, entry, entryPath, errs)
+	return true
+}
+
+// validateEntry validates entry against the schema, the paths of the
+// errors are prefixed with entryPath
+func validateEntry(schema *gojsonschema.Schema, entry any, entryPath []string, errs *[]ValidationError) {
+	res, err := schema.Validate(gojsonschema.NewGoLoader(entry))
+	if err != nil {
+		*errs = append(*errs, ValidationError{
+			Message: err.Error(),
+			Path:    append([]string(nil), entryPath...),
+		})
+		return
+	}
+	found := make([]ValidationError, 0, len(res.Errors()))
+	for _, re := range res.Errors() {
+		found = append(found, ValidationError{
+			Message: re.Description(),
+			Path:    append(append([]string(nil), entryPath...), instancePath(entry, re.Context())...),
+		})
+	}
+	// the order of the errors depends on map iteration
+	sort.SliceStable(found, func(i, j int) bool {
+		pi, pj := strings.Join(found[i].Path, "/"), strings.Join(found[j].Path, "/")
+		if pi != pj {
+			return pi < pj
+		}
+		return found[i].Message < found[j].Message
+	})
+	*errs = append(*errs, found...)
+}
+
+// instancePath converts the context of a validation error to the path
+// layout of osbuild, where array indices are written as "[<idx>]"
+func instancePath(instance any, ctx *gojsonschema.JsonContext) []string {
+	if ctx == nil {
+		return nil
+	}
+	segments := strings.Split(ctx.String("\x00"), "\x00")
+	if len(segments) > 0 && segments[0] == gojsonschema.STRING_CONTEXT_ROOT {
+		segments = segments[1:]
+	}
+	var p []string
+	for _, seg := range segments {
+		switch node := instance.(type) {
+		case []any:
+			idx, err := strconv.Atoi(seg)
+			if err == nil && idx >= 0 && idx < len(node) {
+				p = append(p, fmt.Sprintf("[%d]", idx))
+				instance = node[idx]
+				continue
+			}
+			instance = nil
+		case map[string]any:
+			instance = node[seg]
+		default:
+			instance = nil
+		}
+		p = append(p, seg)
+	}
+	return p
+}
+
+// ValidationResult validates the manifest and wraps any schema
+// violations into a Result that looks like the one osbuild returns
+// for manifests that fail validation. It returns nil if the manifest
+// is valid.
+func (v *SchemaValidator) ValidationResult(manifest []byte) (*Result, error) {
+	errs, err := v.Validate(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	return &Result{
+		Type:    "https://osbuild.org/validation-error",
+		Success: false,
+		Title:   "JSON Schema validation failed",
+		Errors:  errs,
+	}, nil
+}
@@ -0,0 +1,340 @@
+package osbuild_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// a small subset of the osbuild module metadata
+var testSchemaFS = fstest.MapFS{
+	"stages/org.osbuild.hostname.meta.json": {Data: []byte(`{
+  "summary": "Set system hostname",
+  "schema": {
+    "additionalProperties": false,
+    "required": ["hostname"],
+    "properties": {
+      "hostname": {"type": "string", "pattern": "^[a-z0-9.-]+$"}
+    }
+  }
+}`)},
+	"stages/org.osbuild.rpm.meta.json": {Data: []byte(`{
+  "summary": "Verify, and install RPM packages",
+  "schema_2": {
+    "options": {
+      "additionalProperties": false,
+      "definitions": {
+        "gpgkey": {"type": "string", "minLength": 1}
+      },
+      "properties": {
+        "gpgkeys": {"type": "array", "items": {"$ref": "#/definitions/gpgkey"}},
+        "disable_dracut": {"type": "boolean"}
+      }
+    },
+    "inputs": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["packages"],
+      "properties": {
+        "packages": {"type": "object"}
+      }
+    }
+  }
+}`)},
+	"stages/org.osbuild.copy.meta.json": {Data: []byte(`{
+  "summary": "Copy items",
+  "schema_2": {
+    "options": {
+      "additionalProperties": false,
+      "required": ["paths"],
+      "properties": {
+        "paths": {"type": "array", "minItems": 1}
+      }
+    },
+    "devices": {"type": "object"},
+    "mounts": {"type": "array"}
+  }
+}`)},
+	"sources/org.osbuild.curl.meta.json": {Data: []byte(`{
+  "summary": "Download files using cURL",
+  "schema_2": {
+    "additionalProperties": false,
+    "required": ["items"],
+    "properties": {
+      "items": {
+        "type": "object",
+        "additionalProperties": {
+          "oneOf": [
+            {"type": "string"},
+            {"type": "object", "required": ["url"], "properties": {"url": {"type": "string"}}}
+          ]
+        }
+      }
+    }
+  }
+}`)},
+	"inputs/org.osbuild.files.meta.json": {Data: []byte(`{
+  "summary": "Inputs for individual files",
+  "schema_2": {
+    "additionalProperties": false,
+    "required": ["type", "origin", "references"],
+    "properties": {
+      "type": {"enum": ["org.osbuild.files"]},
+      "origin": {"enum": ["org.osbuild.source", "org.osbuild.pipeline"]},
+      "references": {"type": ["array", "object"]}
+    }
+  }
+}`)},
+	"devices/org.osbuild.loopback.meta.json": {Data: []byte(`{
+  "summary": "Loopback device",
+  "schema_2": {
+    "additionalProperties": false,
+    "required": ["options"],
+    "properties": {
+      "options": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["filename"],
+        "properties": {
+          "filename": {"type": "string"},
+          "start": {"type": "integer", "minimum": 0}
+        }
+      }
+    }
+  }
+}`)},
+	"mounts/org.osbuild.ext4.meta.json": {Data: []byte(`{
+  "summary": "Mount an ext4 filesystem",
+  "schema_2": {}
+}`)},
+}
+
+func TestSchemaValidatorValid(t *testing.T) {
+	v, err := osbuild.NewSchemaValidator(testSchemaFS)
+	require.NoError(t, err)
+
+	pipeline := osbuild.Pipeline{Name: "os"}
+	pipeline.AddStage(osbuild.NewHostnameStage(&osbuild.HostnameStageOptions{Hostname: "example"}))
+	mf, err := json.Marshal(osbuild.Manifest{
+		Version:   "2",
+		Pipelines: []osbuild.Pipeline{pipeline},
+		Sources:   osbuild.Sources{},
+	})
+	require.NoError(t, err)
+
+	errs, err := v.Validate(mf)
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	res, err := v.ValidationResult(mf)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+}
+
+func TestSchemaValidatorErrors(t *testing.T) {
+	v, err := osbuild.NewSchemaValidator(testSchemaFS)
+	require.NoError(t, err)
+
+	mf := []byte(`{
+  "version": "2",
+  "pipelines": [
+    {
+      "name": "build",
+      "stages": [
+        {"type": "org.osbuild.hostname", "options": {"hostname": "Not Valid", "homer": 1}}
+      ]
+    },
+    {
+      "name": "os",
+      "stages": [
+        {
+          "type": "org.osbuild.rpm",
+          "inputs": {
+            "packages": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": [], "extra": true}
+          },
+          "options": {"gpgkeys": [""]}
+        },
+        {
+          "type": "org.osbuild.copy",
+          "options": {"paths": []},
+          "devices": {"disk": {"type": "org.osbuild.loopback", "options": {"start": -1}}},
+          "mounts": [{"name": "root", "type": "org.osbuild.ext4", "source": "disk", "target": "/"}]
+        },
+        {"type": "org.osbuild.unknown"}
+      ]
+    }
+  ],
+  "sources": {
+    "org.osbuild.curl": {
+      "items": {
+        "sha256:1": "https://example.com/1.rpm",
+        "sha256:2": {"uri": "https://example.com/2.rpm"}
+      }
+    }
+  }
+}`)
+
+	errs, err := v.Validate(mf)
+	require.NoError(t, err)
+	assert.Equal(t, []osbuild.ValidationError{
+		{
+			Message: "Additional property homer is not allowed",
+			Path:    []string{"pipelines", "[0]", "stages", "[0]", "options"},
+		},
+		{
+			Message: "Does not match pattern '^[a-z0-9.-]+$'",
+			Path:    []string{"pipelines", "[0]", "stages", "[0]", "options", "hostname"},
+		},
+		{
+			Message: "String length must be greater than or equal to 1",
+			Path:    []string{"pipelines", "[1]", "stages", "[0]", "options", "gpgkeys", "[0]"},
+		},
+		{
+			Message: "Additional property extra is not allowed",
+			Path:    []string{"pipelines", "[1]", "stages", "[0]", "inputs", "packages"},
+		},
+		{
+			Message: "Array must have at least 1 items",
+			Path:    []string{"pipelines", "[1]", "stages", "[1]", "options", "paths"},
+		},
+		{
+			Message: "filename is required",
+			Path:    []string{"pipelines", "[1]", "stages", "[1]", "devices", "disk", "options"},
+		},
+		{
+			Message: "Must be greater than or equal to 0",
+			Path:    []string{"pipelines", "[1]", "stages", "[1]", "devices", "disk", "options", "start"},
+		},
+		{
+			Message: "Could not find schema information for 'org.osbuild.unknown'",
+			Path:    []string{"pipelines", "[1]", "stages", "[2]"},
+		},
+		{
+			Message: "Must validate one and only one schema (oneOf)",
+			Path:    []string{"sources", "org.osbuild.curl", "items", "sha256:2"},
+		},
+		{
+			Message: "url is required",
+			Path:    []string{"sources", "org.osbuild.curl", "items", "sha256:2"},
+		},
+	}, errs)
+
+	res, err := v.ValidationResult(mf)
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.False(t, res.Success)
+	assert.Equal(t, errs, res.Errors)
+}
+
+func TestSchemaValidatorStructure(t *testing.T) {
+	v, err := osbuild.NewSchemaValidator(testSchemaFS)
+	require.NoError(t, err)
+
+	errs, err := v.Validate([]byte(`{"version": "1", "pipelines": [{"stages": [{"options": {}}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []osbuild.ValidationError{
+		{Message: "type is required", Path: []string{"pipelines", "[0]", "stages", "[0]"}},
+		{Message: `version must be one of the following: "2"`, Path: []string{"version"}},
+	}, errs)
+
+	_, err = v.Validate([]byte(`{`))
+	assert.ErrorContains(t, err, "cannot parse manifest")
+}
+
+func TestSchemaValidatorDefinitionScopes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"stages/org.osbuild.test.meta.json": {Data: []byte(`{
+  "schema_2": {
+    "definitions": {
+      "shared": {"type": "integer"}
+    },
+    "options": {
+      "additionalProperties": false,
+      "definitions": {
+        "name": {"type": "string"}
+      },
+      "properties": {
+        "name": {"$ref": "#/definitions/name"},
+        "count": {"$ref": "#/definitions/shared"}
+      }
+    },
+    "inputs": {
+      "type": "object",
+      "definitions": {
+        "name": {"type": "object", "required": ["options"]}
+      },
+      "additionalProperties": {"$ref": "#/definitions/name"}
+    }
+  }
+}`)},
+	}
+	v, err := osbuild.NewSchemaValidator(fsys)
+	require.NoError(t, err)
+
+	// the options and the inputs both define "name", each part
+	// must use its own definition
+	errs, err := v.Validate([]byte(`{"version": "2", "pipelines": [{"stages": [{"type": "org.osbuild.test", "options": {"name": "foo", "count": 1}, "inputs": {"tree": {"type": "org.osbuild.tree", "origin": "org.osbuild.pipeline", "references": [], "options": {}}}}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []osbuild.ValidationError{
+		{Message: "Could not find schema information for 'org.osbuild.tree'", Path: []string{"pipelines", "[0]", "stages", "[0]", "inputs", "tree"}},
+	}, errs)
+
+	errs, err = v.Validate([]byte(`{"version": "2", "pipelines": [{"stages": [{"type": "org.osbuild.test", "options": {"name": 1, "count": "1"}, "inputs": {"tree": {"type": "org.osbuild.tree", "origin": "org.osbuild.pipeline", "references": []}}}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []osbuild.ValidationError{
+		{Message: "options is required", Path: []string{"pipelines", "[0]", "stages", "[0]", "inputs", "tree"}},
+		{Message: "Invalid type. Expected: integer, given: string", Path: []string{"pipelines", "[0]", "stages", "[0]", "options", "count"}},
+		{Message: "Invalid type. Expected: string, given: integer", Path: []string{"pipelines", "[0]", "stages", "[0]", "options", "name"}},
+		{Message: "Could not find schema information for 'org.osbuild.tree'", Path: []string{"pipelines", "[0]", "stages", "[0]", "inputs", "tree"}},
+	}, errs)
+}
+
+func TestSchemaValidatorUnsupportedPatternWarnings(t *testing.T) {
+	fsys := fstest.MapFS{
+		"stages/org.osbuild.test.meta.json": {Data: []byte(`{
+  "schema": {
+    "properties": {
+      "user": {"type": "string", "pattern": "^(?!root$)[a-z]+$"},
+      "group": {"type": "string", "pattern": "^[a-z]+$"}
+    }
+  }
+}`)},
+	}
+	v, err := osbuild.NewSchemaValidator(fsys)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`stages/org.osbuild.test: pattern "^(?!root$)[a-z]+$" is not supported and is not checked`,
+	}, v.Warnings())
+
+	// the unsupported pattern is skipped, the others are still checked
+	errs, err := v.Validate([]byte(`{"version": "2", "pipelines": [{"stages": [{"type": "org.osbuild.test", "options": {"user": "root", "group": "Root"}}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []osbuild.ValidationError{
+		{Message: "Does not match pattern '^[a-z]+$'", Path: []string{"pipelines", "[0]", "stages", "[0]", "options", "group"}},
+	}, errs)
+}
+
+func TestNewSchemaValidatorFromDir(t *testing.T) {
+	tmpdir := t.TempDir()
+	_, err := osbuild.NewSchemaValidatorFromDir(tmpdir)
+	assert.EqualError(t, err, "no osbuild module schemas found")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpdir, "stages"), 0755))
+	err = os.WriteFile(filepath.Join(tmpdir, "stages", "org.osbuild.hostname.meta.json"), testSchemaFS["stages/org.osbuild.hostname.meta.json"].Data, 0644)
+	require.NoError(t, err)
+	v, err := osbuild.NewSchemaValidatorFromDir(tmpdir)
+	require.NoError(t, err)
+
+	errs, err := v.Validate([]byte(`{"version": "2", "pipelines": [{"stages": [{"type": "org.osbuild.hostname"}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []osbuild.ValidationError{
+		{Message: "options is required", Path: []string{"pipelines", "[0]", "stages", "[0]"}},
+	}, errs)
+}
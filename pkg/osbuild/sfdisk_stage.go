@@ -40,12 +40,25 @@ type SfdiskPartition struct {
 
 	// UUID of the partition (GPT)
 	UUID string `json:"uuid,omitempty"`
+
+	// Attribute bits to set (GPT)
+	Attrs []uint `json:"attrs,omitempty"`
 }
 
 func (o SfdiskStageOptions) validate() error {
 	if o.Label == disk.PT_DOS.String() && len(o.Partitions) > 4 {
 		return fmt.Errorf("sfdisk stage creation failed: \"dos\" partition table only supports up to 4 partitions: got %d", len(o.Partitions))
 	}
+	for idx, part := range o.Partitions {
+		if o.Label == disk.PT_DOS.String() && len(part.Attrs) > 0 {
+			return fmt.Errorf("sfdisk stage creation failed: partition %d: attributes are not supported on \"dos\" partition tables", idx)
+		}
+		for _, attr := range part.Attrs {
+			if attr > 63 {
+				return fmt.Errorf("sfdisk stage creation failed: partition %d: invalid attribute bit %d", idx, attr)
+			}
+		}
+	}
 	return nil
 }
 
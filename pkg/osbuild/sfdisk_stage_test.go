@@ -60,3 +60,27 @@ func TestNewSfdiskStageInvalid(t *testing.T) {
 		NewSfdiskStage(&options, device)
 	})
 }
+
+func TestNewSfdiskStageInvalidAttrs(t *testing.T) {
+	device := NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "disk.raw"})
+
+	options := SfdiskStageOptions{
+		Label:      "gpt",
+		UUID:       "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Partitions: []SfdiskPartition{{Attrs: []uint{60, 63}}},
+	}
+	assert.NotPanics(t, func() {
+		NewSfdiskStage(&options, device)
+	})
+
+	options.Partitions[0].Attrs = []uint{64}
+	assert.PanicsWithError(t, "sfdisk stage creation failed: partition 0: invalid attribute bit 64", func() {
+		NewSfdiskStage(&options, device)
+	})
+
+	options.Label = "dos"
+	options.Partitions[0].Attrs = []uint{60}
+	assert.PanicsWithError(t, `sfdisk stage creation failed: partition 0: attributes are not supported on "dos" partition tables`, func() {
+		NewSfdiskStage(&options, device)
+	})
+}
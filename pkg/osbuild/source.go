@@ -101,19 +101,59 @@ func (sources Sources) addPackagesLibrepo(packages []rpmmd.PackageSpec, rpmRepos
 	return nil
 }
 
+// inlineSource returns the inline source, it is added if it does not exist.
+func (sources Sources) inlineSource() *InlineSource {
+	if ils, ok := sources[SourceNameInline].(*InlineSource); ok {
+		return ils
+	}
+	ils := NewInlineSource()
+	sources[SourceNameInline] = ils
+	return ils
+}
+
+// addPackagesInline embeds the packages of repositories with local packages,
+// see rpmmd.RepoConfig.PackagesDir, in the inline source. The other packages
+// are returned.
+func (sources Sources) addPackagesInline(packages []rpmmd.PackageSpec, rpmRepos []rpmmd.RepoConfig) ([]rpmmd.PackageSpec, error) {
+	localRepos := make(map[string]bool)
+	for _, repo := range rpmRepos {
+		if repo.HasLocalPackages() {
+			localRepos[repo.Id] = true
+		}
+	}
+	if len(localRepos) == 0 {
+		return packages, nil
+	}
+
+	var remote []rpmmd.PackageSpec
+	for _, pkg := range packages {
+		if !localRepos[pkg.RepoID] {
+			remote = append(remote, pkg)
+			continue
+		}
+		if err := sources.inlineSource().AddPackage(pkg); err != nil {
+			return nil, err
+		}
+	}
+	return remote, nil
+}
+
 // GenSources generates the Sources from the given inputs. Note that
 // the packages and rpmRepos need to come from the *resolved* set.
 func GenSources(inputs SourceInputs, rpmDownloader RpmDownloader) (Sources, error) {
 	sources := Sources{}
 
-	// collect rpm package sources
-	if len(inputs.Depsolved.Packages) > 0 {
-		var err error
+	// collect rpm package sources, local packages are embedded
+	packages, err := sources.addPackagesInline(inputs.Depsolved.Packages, inputs.Depsolved.Repos)
+	if err != nil {
+		return nil, err
+	}
+	if len(packages) > 0 {
 		switch rpmDownloader {
 		case RpmDownloaderCurl:
-			err = sources.addPackagesCurl(inputs.Depsolved.Packages)
+			err = sources.addPackagesCurl(packages)
 		case RpmDownloaderLibrepo:
-			err = sources.addPackagesLibrepo(inputs.Depsolved.Packages, inputs.Depsolved.Repos)
+			err = sources.addPackagesLibrepo(packages, inputs.Depsolved.Repos)
 		default:
 			err = fmt.Errorf("unknown rpm downloader %v", rpmDownloader)
 		}
@@ -135,12 +175,10 @@ func GenSources(inputs SourceInputs, rpmDownloader RpmDownloader) (Sources, erro
 
 	// collect inline data sources
 	if len(inputs.InlineData) > 0 {
-		ils := NewInlineSource()
+		ils := sources.inlineSource()
 		for _, data := range inputs.InlineData {
 			ils.AddItem(data)
 		}
-
-		sources[SourceNameInline] = ils
 	}
 
 	// collect skopeo and local container sources
@@ -3,10 +3,14 @@ package osbuild
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/dnfjson"
@@ -338,3 +342,49 @@ func TestGenSourcesRpmBad(t *testing.T) {
 	_, err := GenSources(inputs, 99)
 	assert.EqualError(t, err, "unknown rpm downloader 99")
 }
+
+func TestGenSourcesRpmLocalPackages(t *testing.T) {
+	pkgsDir := t.TempDir()
+	pkgPath := filepath.Join(pkgsDir, "hello-2.12-1.x86_64.rpm")
+	require.NoError(t, os.WriteFile(pkgPath, []byte("hello"), 0644))
+
+	localPkg := rpmmd.PackageSpec{
+		Name:           "hello",
+		Version:        "2.12",
+		Release:        "1",
+		Arch:           "x86_64",
+		RemoteLocation: "file://" + pkgPath,
+		Checksum:       "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		RepoID:         "repo_id_local",
+	}
+	inputs := SourceInputs{
+		Depsolved: dnfjson.DepsolveResult{
+			Packages: []rpmmd.PackageSpec{opensslPkg, localPkg},
+			Repos:    append(fakeRepos, rpmmd.RepoConfig{Id: "repo_id_local", PackagesDir: pkgsDir}),
+		},
+		InlineData: []string{"some data"},
+	}
+	sources, err := GenSources(inputs, RpmDownloaderCurl)
+	require.NoError(t, err)
+
+	// the local package is embedded, only the other package is downloaded
+	curl := sources[SourceNameCurl].(*CurlSource)
+	assert.Len(t, curl.Items, 1)
+	assert.Contains(t, curl.Items, opensslPkg.Checksum)
+	inline := sources[SourceNameInline].(*InlineSource)
+	assert.Len(t, inline.Items, 2)
+	assert.Equal(t, InlineSourceItem{Encoding: "base64", Data: "aGVsbG8="}, inline.Items[localPkg.Checksum])
+
+	// only local packages do not need any other source
+	inputs.Depsolved.Packages = []rpmmd.PackageSpec{localPkg}
+	inputs.InlineData = nil
+	sources, err = GenSources(inputs, RpmDownloaderLibrepo)
+	require.NoError(t, err)
+	assert.Len(t, sources, 1)
+	assert.Contains(t, sources, SourceNameInline)
+
+	// the package has changed since depsolving
+	require.NoError(t, os.WriteFile(pkgPath, []byte("hello world"), 0644))
+	_, err = GenSources(inputs, RpmDownloaderCurl)
+	assert.ErrorContains(t, err, fmt.Sprintf("package %s has changed since it was depsolved", pkgPath))
+}
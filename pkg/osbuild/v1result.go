@@ -106,20 +106,9 @@ func convertStageResult(sr1 *v1StageResult) (*StageResult, StageMetadata, error)
 		Error:   "",
 	}
 
-	var metadata StageMetadata
-	switch sr1.Name {
-	case "org.osbuild.rpm":
-		metadata = new(RPMStageMetadata)
-		if err := json.Unmarshal(sr1.Metadata, metadata); err != nil {
-			return nil, nil, err
-		}
-	case "org.osbuild.ostree.commit":
-		metadata = new(OSTreeCommitStageMetadata)
-		if err := json.Unmarshal(sr1.Metadata, metadata); err != nil {
-			return nil, nil, err
-		}
-	default:
-		metadata = RawStageMetadata(sr1.Metadata)
+	metadata, err := decodeStageMetadata(sr1.Name, sr1.Metadata)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	return sr, metadata, nil
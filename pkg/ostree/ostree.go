@@ -1,6 +1,7 @@
 package ostree
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
@@ -206,7 +207,7 @@ func httpClientForRef(scheme string, ss SourceSpec) (*http.Client, error) {
 // resolveRef resolves the URL path specified by the location and ref
 // (location+"refs/heads/"+ref) and returns the commit ID for the named ref. If
 // there is an error, it will be of type ResolveRefError.
-func resolveRef(ss SourceSpec) (string, error) {
+func resolveRef(ctx context.Context, ss SourceSpec) (string, error) {
 	u, err := url.Parse(ss.URL)
 	if err != nil {
 		return "", NewResolveRefError("error parsing ostree repository location: %v", err)
@@ -218,7 +219,7 @@ func resolveRef(ss SourceSpec) (string, error) {
 		return "", err
 	}
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return "", NewResolveRefError("error preparing ostree resolve request: %s", err)
 	}
@@ -227,6 +228,7 @@ func resolveRef(ss SourceSpec) (string, error) {
 	if err != nil {
 		return "", NewResolveRefError("error sending request to ostree repository %q: %v", u.String(), err)
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		return "", NewResolveRefError("ostree repository %q returned status: %s", u.String(), resp.Status)
 	}
@@ -253,7 +255,9 @@ func resolveRef(ss SourceSpec) (string, error) {
 // resolved or checked against the repository.
 //
 // If the ref is malformed, the function returns with a RefError.
-func Resolve(source SourceSpec) (CommitSpec, error) {
+//
+// Cancelling the context aborts the request to the repository.
+func Resolve(ctx context.Context, source SourceSpec) (CommitSpec, error) {
 	commit := CommitSpec{
 		Ref: source.Ref,
 		URL: source.URL,
@@ -300,7 +304,7 @@ func Resolve(source SourceSpec) (CommitSpec, error) {
 	// URL set: Resolve checksum
 	if source.URL != "" {
 		// If a URL is specified, we need to fetch the commit at the URL.
-		checksum, err := resolveRef(source)
+		checksum, err := resolveRef(ctx, source)
 		if err != nil {
 			return CommitSpec{}, err // ResolveRefError
 		}
@@ -1,6 +1,7 @@
 package ostree
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
@@ -79,7 +80,7 @@ func TestOstreeResolveRef(t *testing.T) {
 			{srvConf.Srv.URL, "valid/ostree/ref"}: goodRef,
 		}
 		for in, expOut := range validCases {
-			out, err := resolveRef(SourceSpec{
+			out, err := resolveRef(context.Background(), SourceSpec{
 				in.location,
 				in.ref,
 				srvConf.RHSM,
@@ -98,7 +99,7 @@ func TestOstreeResolveRef(t *testing.T) {
 			{srvConf.Srv.URL, "get_bad_ref"}:        fmt.Sprintf("ostree repository \"%s/refs/heads/get_bad_ref\" returned invalid reference", srvConf.Srv.URL),
 		}
 		for in, expMsg := range errCases {
-			_, err := resolveRef(SourceSpec{
+			_, err := resolveRef(context.Background(), SourceSpec{
 				in.location,
 				in.ref,
 				srvConf.RHSM,
@@ -280,3 +281,15 @@ func TestClientForRefCA(t *testing.T) {
 	// no certificates got added
 	assert.Equal(t, 0, len(tlsConf.Certificates))
 }
+
+func TestResolveRefCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Resolve(ctx, SourceSpec{URL: srv.URL, Ref: "test/ref"})
+	assert.ErrorContains(t, err, "context canceled")
+}
@@ -0,0 +1,392 @@
+package repomd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+const (
+	xmlnsCommon    = "http://linux.duke.edu/metadata/common"
+	xmlnsRPM       = "http://linux.duke.edu/metadata/rpm"
+	xmlnsFilelists = "http://linux.duke.edu/metadata/filelists"
+	xmlnsRepo      = "http://linux.duke.edu/metadata/repo"
+)
+
+// primaryFilesRegexp selects the files of a package that are listed in the
+// primary metadata, the same as createrepo
+var primaryFilesRegexp = regexp.MustCompile(`^/etc/|bin/|^/usr/lib/sendmail$`)
+
+type xmlOutDependency struct {
+	Name    string `xml:"name,attr"`
+	Flags   string `xml:"flags,attr,omitempty"`
+	Epoch   string `xml:"epoch,attr,omitempty"`
+	Version string `xml:"ver,attr,omitempty"`
+	Release string `xml:"rel,attr,omitempty"`
+	Pre     string `xml:"pre,attr,omitempty"`
+}
+
+type xmlOutDependencies struct {
+	Entries []xmlOutDependency `xml:"rpm:entry"`
+}
+
+type xmlOutFile struct {
+	Type string `xml:"type,attr,omitempty"`
+	Path string `xml:",chardata"`
+}
+
+type xmlOutPackage struct {
+	Type     string     `xml:"type,attr"`
+	Name     string     `xml:"name"`
+	Arch     string     `xml:"arch"`
+	Version  xmlVersion `xml:"version"`
+	Checksum struct {
+		Type  string `xml:"type,attr"`
+		PkgID string `xml:"pkgid,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"checksum"`
+	Summary     string `xml:"summary"`
+	Description string `xml:"description"`
+	Packager    string `xml:"packager"`
+	URL         string `xml:"url"`
+	Time        struct {
+		File  int64 `xml:"file,attr"`
+		Build int64 `xml:"build,attr"`
+	} `xml:"time"`
+	Size struct {
+		Package   int64  `xml:"package,attr"`
+		Installed uint32 `xml:"installed,attr"`
+		Archive   uint32 `xml:"archive,attr"`
+	} `xml:"size"`
+	Location struct {
+		Base string `xml:"xml:base,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+	Format struct {
+		License     string `xml:"rpm:license"`
+		SourceRPM   string `xml:"rpm:sourcerpm"`
+		HeaderRange struct {
+			Start int64 `xml:"start,attr"`
+			End   int64 `xml:"end,attr"`
+		} `xml:"rpm:header-range"`
+		Provides    *xmlOutDependencies `xml:"rpm:provides,omitempty"`
+		Requires    *xmlOutDependencies `xml:"rpm:requires,omitempty"`
+		Conflicts   *xmlOutDependencies `xml:"rpm:conflicts,omitempty"`
+		Obsoletes   *xmlOutDependencies `xml:"rpm:obsoletes,omitempty"`
+		Recommends  *xmlOutDependencies `xml:"rpm:recommends,omitempty"`
+		Suggests    *xmlOutDependencies `xml:"rpm:suggests,omitempty"`
+		Supplements *xmlOutDependencies `xml:"rpm:supplements,omitempty"`
+		Enhances    *xmlOutDependencies `xml:"rpm:enhances,omitempty"`
+		Files       []xmlOutFile        `xml:"file"`
+	} `xml:"format"`
+}
+
+type xmlOutPrimary struct {
+	XMLName  xml.Name        `xml:"metadata"`
+	Xmlns    string          `xml:"xmlns,attr"`
+	XmlnsRPM string          `xml:"xmlns:rpm,attr"`
+	Count    int             `xml:"packages,attr"`
+	Packages []xmlOutPackage `xml:"package"`
+}
+
+type xmlOutFilelistsPackage struct {
+	PkgID   string       `xml:"pkgid,attr"`
+	Name    string       `xml:"name,attr"`
+	Arch    string       `xml:"arch,attr"`
+	Version xmlVersion   `xml:"version"`
+	Files   []xmlOutFile `xml:"file"`
+}
+
+type xmlOutFilelists struct {
+	XMLName  xml.Name                 `xml:"filelists"`
+	Xmlns    string                   `xml:"xmlns,attr"`
+	Count    int                      `xml:"packages,attr"`
+	Packages []xmlOutFilelistsPackage `xml:"package"`
+}
+
+type xmlOutChecksum struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlOutRepoMDData struct {
+	Type         string         `xml:"type,attr"`
+	Checksum     xmlOutChecksum `xml:"checksum"`
+	OpenChecksum xmlOutChecksum `xml:"open-checksum"`
+	Location     struct {
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+	Timestamp int64 `xml:"timestamp"`
+	Size      int   `xml:"size"`
+	OpenSize  int   `xml:"open-size"`
+}
+
+type xmlOutRepoMD struct {
+	XMLName  xml.Name           `xml:"repomd"`
+	Xmlns    string             `xml:"xmlns,attr"`
+	XmlnsRPM string             `xml:"xmlns:rpm,attr"`
+	Revision string             `xml:"revision"`
+	Data     []xmlOutRepoMDData `xml:"data"`
+}
+
+func toXMLDependencies(deps []localDependency) *xmlOutDependencies {
+	if len(deps) == 0 {
+		return nil
+	}
+	out := &xmlOutDependencies{}
+	for _, dep := range deps {
+		entry := xmlOutDependency{
+			Name:    dep.Name,
+			Flags:   dep.Flags,
+			Epoch:   dep.Epoch,
+			Version: dep.Version,
+			Release: dep.Release,
+		}
+		if dep.Pre {
+			entry.Pre = "1"
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return out
+}
+
+func toXMLFiles(files []localFile, filter func(string) bool) []xmlOutFile {
+	var out []xmlOutFile
+	for _, file := range files {
+		if filter != nil && !filter(file.Path) {
+			continue
+		}
+		entry := xmlOutFile{Path: file.Path}
+		if file.Dir {
+			entry.Type = "dir"
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func (pkg *localPackage) version() xmlVersion {
+	return xmlVersion{
+		Epoch:   strconv.FormatUint(uint64(pkg.Epoch), 10),
+		Version: pkg.Version,
+		Release: pkg.Release,
+	}
+}
+
+func (pkg *localPackage) toXMLPrimary() xmlOutPackage {
+	checksumType, checksum, _ := strings.Cut(pkg.Checksum, ":")
+	out := xmlOutPackage{
+		Type:        "rpm",
+		Name:        pkg.Name,
+		Arch:        pkg.Arch,
+		Version:     pkg.version(),
+		Summary:     pkg.Summary,
+		Description: pkg.Description,
+		URL:         pkg.URL,
+	}
+	out.Checksum.Type = checksumType
+	out.Checksum.PkgID = "YES"
+	out.Checksum.Value = checksum
+	out.Time.File = pkg.FileTime
+	out.Time.Build = pkg.BuildTime.Unix()
+	out.Size.Package = pkg.PackageSize
+	out.Size.Installed = pkg.InstalledSize
+	out.Size.Archive = pkg.ArchiveSize
+	// the packages are not copied, they are referenced by their location
+	// on the host
+	out.Location.Base = "file://" + filepath.Dir(pkg.Filename) + "/"
+	out.Location.Href = pkg.Location
+	out.Format.License = pkg.License
+	out.Format.SourceRPM = pkg.SourceRPM
+	out.Format.HeaderRange.Start = pkg.HeaderStart
+	out.Format.HeaderRange.End = pkg.HeaderEnd
+	out.Format.Provides = toXMLDependencies(pkg.ProvidesDeps)
+	out.Format.Requires = toXMLDependencies(pkg.RequiresDeps)
+	out.Format.Conflicts = toXMLDependencies(pkg.ConflictsDeps)
+	out.Format.Obsoletes = toXMLDependencies(pkg.ObsoletesDeps)
+	out.Format.Recommends = toXMLDependencies(pkg.RecommendsDeps)
+	out.Format.Suggests = toXMLDependencies(pkg.SuggestsDeps)
+	out.Format.Supplements = toXMLDependencies(pkg.SupplementsDeps)
+	out.Format.Enhances = toXMLDependencies(pkg.EnhancesDeps)
+	out.Format.Files = toXMLFiles(pkg.FileEntries, primaryFilesRegexp.MatchString)
+	return out
+}
+
+func (pkg *localPackage) toXMLFilelists() xmlOutFilelistsPackage {
+	_, checksum, _ := strings.Cut(pkg.Checksum, ":")
+	return xmlOutFilelistsPackage{
+		PkgID:   checksum,
+		Name:    pkg.Name,
+		Arch:    pkg.Arch,
+		Version: pkg.version(),
+		Files:   toXMLFiles(pkg.FileEntries, nil),
+	}
+}
+
+// localPackageFiles returns the paths of the package files of a repository
+// with local packages: the .rpm files in its PackagesDir (source packages are
+// skipped) and its PackageFiles.
+func localPackageFiles(repo rpmmd.RepoConfig) ([]string, error) {
+	var files []string
+	if repo.PackagesDir != "" {
+		entries, err := os.ReadDir(repo.PackagesDir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read packages directory: %w", err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".rpm") || strings.HasSuffix(name, ".src.rpm") {
+				continue
+			}
+			files = append(files, filepath.Join(repo.PackagesDir, name))
+		}
+	}
+	files = append(files, repo.PackageFiles...)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("repository %s has no packages", repoName(&repo))
+	}
+	return files, nil
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// writeFileAtomic writes a file through a temporary file so that concurrent
+// readers, e.g. another depsolver using the same repository, never see a
+// partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// nolint:gosec
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeDataFile writes the gzip compressed metadata file of the given type
+// to the repodata directory, named by its checksum like createrepo does.
+func writeDataFile(repodata, dataType string, content []byte) (*xmlOutRepoMDData, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	openSum := sha256.Sum256(content)
+	sum := sha256.Sum256(buf.Bytes())
+	checksum := hex.EncodeToString(sum[:])
+
+	filename := fmt.Sprintf("%s-%s.xml.gz", checksum, dataType)
+	if err := writeFileAtomic(filepath.Join(repodata, filename), buf.Bytes()); err != nil {
+		return nil, err
+	}
+	data := &xmlOutRepoMDData{
+		Type:         dataType,
+		Checksum:     xmlOutChecksum{Type: "sha256", Value: checksum},
+		OpenChecksum: xmlOutChecksum{Type: "sha256", Value: hex.EncodeToString(openSum[:])},
+		Size:         buf.Len(),
+		OpenSize:     len(content),
+	}
+	data.Location.Href = "repodata/" + filename
+	return data, nil
+}
+
+// CreateRepo generates the primary and filelists metadata of a repository
+// with local packages (see rpmmd.RepoConfig.PackagesDir and PackageFiles) in
+// dest, so that the depsolver can use it with a file:// baseurl. The package
+// files are not copied, the metadata references them by their absolute path.
+// The metadata of the same package files is always the same.
+func CreateRepo(dest string, repo rpmmd.RepoConfig) error {
+	files, err := localPackageFiles(repo)
+	if err != nil {
+		return err
+	}
+
+	var pkgs []*localPackage
+	for _, file := range files {
+		pkg, err := readLocalPackage(file)
+		if err != nil {
+			return fmt.Errorf("cannot read package of repository %s: %w", repoName(&repo), err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Filename < pkgs[j].Filename
+	})
+
+	primary := xmlOutPrimary{Xmlns: xmlnsCommon, XmlnsRPM: xmlnsRPM, Count: len(pkgs)}
+	filelists := xmlOutFilelists{Xmlns: xmlnsFilelists, Count: len(pkgs)}
+	var revision int64
+	for _, pkg := range pkgs {
+		primary.Packages = append(primary.Packages, pkg.toXMLPrimary())
+		filelists.Packages = append(filelists.Packages, pkg.toXMLFilelists())
+		if pkg.FileTime > revision {
+			revision = pkg.FileTime
+		}
+	}
+
+	repodata := filepath.Join(dest, "repodata")
+	if err := os.MkdirAll(repodata, 0755); err != nil {
+		return err
+	}
+	md := xmlOutRepoMD{
+		Xmlns:    xmlnsRepo,
+		XmlnsRPM: xmlnsRPM,
+		Revision: strconv.FormatInt(revision, 10),
+	}
+	for _, content := range []struct {
+		dataType string
+		v        interface{}
+	}{
+		{"primary", primary},
+		{"filelists", filelists},
+	} {
+		data, err := marshalXML(content.v)
+		if err != nil {
+			return err
+		}
+		repomdData, err := writeDataFile(repodata, content.dataType, data)
+		if err != nil {
+			return fmt.Errorf("cannot write %s metadata: %w", content.dataType, err)
+		}
+		repomdData.Timestamp = revision
+		md.Data = append(md.Data, *repomdData)
+	}
+
+	data, err := marshalXML(md)
+	if err != nil {
+		return err
+	}
+	// the data files of the previous metadata are kept for readers that
+	// still use it
+	return writeFileAtomic(filepath.Join(dest, repomdPath), data)
+}
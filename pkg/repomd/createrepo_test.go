@@ -0,0 +1,196 @@
+package repomd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// encodeTestRPMHeader encodes a header structure with string, string array
+// and int32 tags.
+func encodeTestRPMHeader(t *testing.T, tags map[int32]interface{}) []byte {
+	var ids []int32
+	for tag := range tags {
+		ids = append(ids, tag)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var index, data bytes.Buffer
+	for _, tag := range ids {
+		entry := rpmIndexEntry{Tag: tag}
+		switch value := tags[tag].(type) {
+		case string:
+			entry.Type, entry.Count, entry.Offset = rpmTypeString, 1, int32(data.Len())
+			data.WriteString(value + "\x00")
+		case []string:
+			entry.Type, entry.Count, entry.Offset = rpmTypeStringArray, uint32(len(value)), int32(data.Len())
+			for _, s := range value {
+				data.WriteString(s + "\x00")
+			}
+		case []uint32:
+			for data.Len()%4 != 0 {
+				data.WriteByte(0)
+			}
+			entry.Type, entry.Count, entry.Offset = rpmTypeInt32, uint32(len(value)), int32(data.Len())
+			require.NoError(t, binary.Write(&data, binary.BigEndian, value))
+		default:
+			t.Fatalf("unsupported value of tag %d", tag)
+		}
+		require.NoError(t, binary.Write(&index, binary.BigEndian, entry))
+	}
+
+	var hdr bytes.Buffer
+	hdr.Write(rpmHeaderMagic)
+	hdr.Write([]byte{0, 0, 0, 0})
+	require.NoError(t, binary.Write(&hdr, binary.BigEndian, []uint32{uint32(len(ids)), uint32(data.Len())}))
+	hdr.Write(index.Bytes())
+	hdr.Write(data.Bytes())
+	return hdr.Bytes()
+}
+
+// makeTestRPM writes an RPM file with an empty signature header, the given
+// header tags and a fake payload.
+func makeTestRPM(t *testing.T, filename string, tags map[int32]interface{}) {
+	var rpm bytes.Buffer
+	lead := make([]byte, rpmLeadSize)
+	copy(lead, rpmLeadMagic)
+	rpm.Write(lead)
+	// the empty signature header is already aligned to 8 bytes
+	rpm.Write(encodeTestRPMHeader(t, nil))
+	rpm.Write(encodeTestRPMHeader(t, tags))
+	rpm.WriteString("payload")
+	require.NoError(t, os.WriteFile(filename, rpm.Bytes(), 0644))
+}
+
+func testRPMTags(name, version, release string, extra map[int32]interface{}) map[int32]interface{} {
+	tags := map[int32]interface{}{
+		rpmTagName:        name,
+		rpmTagVersion:     version,
+		rpmTagRelease:     release,
+		rpmTagArch:        "x86_64",
+		rpmTagSummary:     "The " + name + " package",
+		rpmTagDescription: "A test package",
+		rpmTagLicense:     "MIT",
+		rpmTagBuildTime:   []uint32{1700000000},
+		rpmTagSourceRPM:   fmt.Sprintf("%s-%s-%s.src.rpm", name, version, release),
+		rpmTagProvideName: []string{name},
+		rpmTagProvideVersion: []string{
+			fmt.Sprintf("%s-%s", version, release),
+		},
+		rpmTagProvideFlags: []uint32{rpmSenseEqual},
+	}
+	for tag, value := range extra {
+		tags[tag] = value
+	}
+	return tags
+}
+
+func fileChecksum(t *testing.T, filename string) string {
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+func TestCreateRepo(t *testing.T) {
+	pkgsDir := t.TempDir()
+	hello := filepath.Join(pkgsDir, "hello-2.12-1.x86_64.rpm")
+	makeTestRPM(t, hello, testRPMTags("hello", "2.12", "1", map[int32]interface{}{
+		rpmTagRequireName:    []string{"libgreet", "rpmlib(PayloadIsZstd)", "/bin/sh"},
+		rpmTagRequireVersion: []string{"1:1.0", "5.4.18-1", ""},
+		rpmTagRequireFlags:   []uint32{rpmSenseGreater | rpmSenseEqual, rpmSenseLess | rpmSenseEqual | rpmSenseRpmlib, rpmSensePre},
+		rpmTagBaseNames:      []string{"hello", "hello"},
+		rpmTagDirNames:       []string{"/usr/bin/", "/usr/share/doc/"},
+		rpmTagDirIndexes:     []uint32{0, 1},
+		rpmTagFileModes:      []uint32{0100755, 040755},
+	}))
+	// source packages are skipped
+	makeTestRPM(t, filepath.Join(pkgsDir, "hello-2.12-1.src.rpm"), testRPMTags("hello", "2.12", "1", nil))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgsDir, "README"), []byte("not a package"), 0644))
+
+	otherDir := t.TempDir()
+	libgreet := filepath.Join(otherDir, "libgreet.rpm")
+	makeTestRPM(t, libgreet, testRPMTags("libgreet", "1.1", "3", map[int32]interface{}{
+		rpmTagEpoch: []uint32{1},
+	}))
+
+	repo := rpmmd.RepoConfig{
+		Id:           "local",
+		PackagesDir:  pkgsDir,
+		PackageFiles: []string{libgreet},
+	}
+	dest := t.TempDir()
+	require.NoError(t, CreateRepo(dest, repo))
+
+	result, err := NewReader("").ReadRepository(rpmmd.RepoConfig{BaseURLs: []string{"file://" + dest}})
+	require.NoError(t, err)
+	require.Len(t, result.Packages, 2)
+
+	pkg := result.Packages[0]
+	assert.Equal(t, "hello-2.12-1.x86_64", pkg.NEVRA())
+	assert.Equal(t, "The hello package", pkg.Summary)
+	assert.Equal(t, "hello-2.12-1.src.rpm", pkg.SourceRPM)
+	assert.Equal(t, "hello-2.12-1.x86_64.rpm", pkg.Location)
+	assert.Equal(t, fileChecksum(t, hello), pkg.Checksum)
+	assert.Equal(t, []Dependency{{Name: "hello", Flags: "EQ", Epoch: "0", Version: "2.12", Release: "1"}}, pkg.Provides)
+	assert.Equal(t, []Dependency{
+		{Name: "libgreet", Flags: "GE", Epoch: "1", Version: "1.0"},
+		{Name: "/bin/sh"},
+	}, pkg.Requires)
+	assert.Equal(t, []string{"/usr/bin/hello", "/usr/share/doc/hello"}, pkg.Files)
+
+	pkg = result.Packages[1]
+	assert.Equal(t, "libgreet-1:1.1-3.x86_64", pkg.NEVRA())
+	assert.Equal(t, fileChecksum(t, libgreet), pkg.Checksum)
+
+	// the packages are referenced by their absolute location
+	md, err := os.ReadFile(filepath.Join(dest, repomdPath))
+	require.NoError(t, err)
+	primary, err := parseRepoMD(bytes.NewReader(md))
+	require.NoError(t, err)
+	err = readData(&localSource{root: dest}, primary.find("primary"), func(r io.Reader) error {
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), fmt.Sprintf(`<location xml:base="file://%s/" href="libgreet.rpm">`, otherDir))
+		assert.Contains(t, string(data), `<rpm:entry name="/bin/sh" pre="1"></rpm:entry>`)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the metadata of the same packages is the same
+	require.NoError(t, CreateRepo(dest, repo))
+	regenerated, err := os.ReadFile(filepath.Join(dest, repomdPath))
+	require.NoError(t, err)
+	assert.Equal(t, md, regenerated)
+}
+
+func TestCreateRepoErrors(t *testing.T) {
+	emptyDir := t.TempDir()
+	err := CreateRepo(t.TempDir(), rpmmd.RepoConfig{Id: "empty", PackagesDir: emptyDir})
+	assert.EqualError(t, err, "repository empty has no packages")
+
+	err = CreateRepo(t.TempDir(), rpmmd.RepoConfig{Id: "missing", PackagesDir: filepath.Join(emptyDir, "missing")})
+	assert.ErrorContains(t, err, "cannot read packages directory")
+
+	srpm := filepath.Join(emptyDir, "hello.src.rpm")
+	tags := testRPMTags("hello", "2.12", "1", nil)
+	delete(tags, rpmTagSourceRPM)
+	makeTestRPM(t, srpm, tags)
+	err = CreateRepo(t.TempDir(), rpmmd.RepoConfig{Id: "srpm", PackageFiles: []string{srpm}})
+	assert.EqualError(t, err, fmt.Sprintf("cannot read package of repository srpm: %s is a source package", srpm))
+
+	notRPM := filepath.Join(emptyDir, "README")
+	require.NoError(t, os.WriteFile(notRPM, bytes.Repeat([]byte("x"), rpmLeadSize), 0644))
+	err = CreateRepo(t.TempDir(), rpmmd.RepoConfig{Id: "invalid", PackageFiles: []string{notRPM}})
+	assert.EqualError(t, err, fmt.Sprintf("cannot read package of repository invalid: %s is not an RPM file", notRPM))
+}
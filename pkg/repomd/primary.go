@@ -0,0 +1,226 @@
+package repomd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// Package is a package of a repository with its dependency information.
+type Package struct {
+	Name        string    `json:"name"`
+	Epoch       uint      `json:"epoch"`
+	Version     string    `json:"version"`
+	Release     string    `json:"release"`
+	Arch        string    `json:"arch"`
+	Summary     string    `json:"summary"`
+	Description string    `json:"description"`
+	URL         string    `json:"url"`
+	License     string    `json:"license"`
+	BuildTime   time.Time `json:"build_time"`
+	SourceRPM   string    `json:"source_rpm"`
+
+	// Checksum of the package file, e.g. "sha256:<hex>"
+	Checksum string `json:"checksum"`
+
+	// Path of the package file relative to the root of the repository
+	Location string `json:"location"`
+
+	Provides []Dependency `json:"provides,omitempty"`
+	Requires []Dependency `json:"requires,omitempty"`
+
+	// Files of the package. Only the files listed in the primary metadata
+	// (e.g. /usr/bin/*) are known if the repository has no filelists.
+	Files []string `json:"files,omitempty"`
+}
+
+// Dependency is a provided or required capability of a package.
+type Dependency struct {
+	Name string `json:"name"`
+
+	// Comparison of the version, one of EQ, LT, LE, GT and GE (optional)
+	Flags   string `json:"flags,omitempty"`
+	Epoch   string `json:"epoch,omitempty"`
+	Version string `json:"version,omitempty"`
+	Release string `json:"release,omitempty"`
+}
+
+var depFlagOperators = map[string]string{
+	"EQ": "=",
+	"LT": "<",
+	"LE": "<=",
+	"GT": ">",
+	"GE": ">=",
+}
+
+// String returns the dependency in the format of rpm, e.g. "bash >= 5.2-1".
+func (d Dependency) String() string {
+	op, ok := depFlagOperators[d.Flags]
+	if !ok || d.Version == "" {
+		return d.Name
+	}
+	evr := d.Version
+	if d.Epoch != "" && d.Epoch != "0" {
+		evr = d.Epoch + ":" + evr
+	}
+	if d.Release != "" {
+		evr += "-" + d.Release
+	}
+	return fmt.Sprintf("%s %s %s", d.Name, op, evr)
+}
+
+// NEVRA returns the Name-[Epoch:]Version-Release.Arch string of the package.
+func (p *Package) NEVRA() string {
+	if p.Epoch == 0 {
+		return fmt.Sprintf("%s-%s-%s.%s", p.Name, p.Version, p.Release, p.Arch)
+	}
+	return fmt.Sprintf("%s-%d:%s-%s.%s", p.Name, p.Epoch, p.Version, p.Release, p.Arch)
+}
+
+// ToRPMMD returns the package as an rpmmd.Package, as returned by
+// dnfjson.Solver.FetchMetadata.
+func (p *Package) ToRPMMD() rpmmd.Package {
+	return rpmmd.Package{
+		Name:        p.Name,
+		Summary:     p.Summary,
+		Description: p.Description,
+		URL:         p.URL,
+		Epoch:       p.Epoch,
+		Version:     p.Version,
+		Release:     p.Release,
+		Arch:        p.Arch,
+		BuildTime:   p.BuildTime,
+		License:     p.License,
+	}
+}
+
+type xmlDependency struct {
+	Name    string `xml:"name,attr"`
+	Flags   string `xml:"flags,attr"`
+	Epoch   string `xml:"epoch,attr"`
+	Version string `xml:"ver,attr"`
+	Release string `xml:"rel,attr"`
+}
+
+type xmlVersion struct {
+	Epoch   string `xml:"epoch,attr"`
+	Version string `xml:"ver,attr"`
+	Release string `xml:"rel,attr"`
+}
+
+type xmlPrimaryPackage struct {
+	Type     string     `xml:"type,attr"`
+	Name     string     `xml:"name"`
+	Arch     string     `xml:"arch"`
+	Version  xmlVersion `xml:"version"`
+	Checksum struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"checksum"`
+	Summary     string `xml:"summary"`
+	Description string `xml:"description"`
+	URL         string `xml:"url"`
+	Time        struct {
+		Build int64 `xml:"build,attr"`
+	} `xml:"time"`
+	Location struct {
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+	Format struct {
+		License   string          `xml:"license"`
+		SourceRPM string          `xml:"sourcerpm"`
+		Provides  []xmlDependency `xml:"provides>entry"`
+		Requires  []xmlDependency `xml:"requires>entry"`
+		Files     []string        `xml:"file"`
+	} `xml:"format"`
+}
+
+func parseEpoch(epoch string) (uint, error) {
+	if epoch == "" {
+		return 0, nil
+	}
+	e, err := strconv.ParseUint(epoch, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid epoch %q: %w", epoch, err)
+	}
+	return uint(e), nil
+}
+
+func toDependencies(entries []xmlDependency) []Dependency {
+	if len(entries) == 0 {
+		return nil
+	}
+	deps := make([]Dependency, len(entries))
+	for idx, e := range entries {
+		deps[idx] = Dependency(e)
+	}
+	return deps
+}
+
+// parsePrimary parses the primary metadata of a repository. The packages are
+// keyed by their pkgid (checksum) for merging the filelists.
+func parsePrimary(r io.Reader) ([]Package, map[string]int, error) {
+	var pkgs []Package
+	byID := make(map[string]int)
+	err := decodeElements(r, "package", func(dec *xml.Decoder, start *xml.StartElement) error {
+		var p xmlPrimaryPackage
+		if err := dec.DecodeElement(&p, start); err != nil {
+			return err
+		}
+		if p.Type != "" && p.Type != "rpm" {
+			return nil
+		}
+		epoch, err := parseEpoch(p.Version.Epoch)
+		if err != nil {
+			return fmt.Errorf("package %s: %w", p.Name, err)
+		}
+		pkgs = append(pkgs, Package{
+			Name:        p.Name,
+			Epoch:       epoch,
+			Version:     p.Version.Version,
+			Release:     p.Version.Release,
+			Arch:        p.Arch,
+			Summary:     p.Summary,
+			Description: p.Description,
+			URL:         p.URL,
+			License:     p.Format.License,
+			BuildTime:   time.Unix(p.Time.Build, 0).UTC(),
+			SourceRPM:   p.Format.SourceRPM,
+			Checksum:    p.Checksum.Type + ":" + p.Checksum.Value,
+			Location:    p.Location.Href,
+			Provides:    toDependencies(p.Format.Provides),
+			Requires:    toDependencies(p.Format.Requires),
+			Files:       p.Format.Files,
+		})
+		byID[p.Checksum.Value] = len(pkgs) - 1
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pkgs, byID, nil
+}
+
+type xmlFilelistsPackage struct {
+	ID    string   `xml:"pkgid,attr"`
+	Files []string `xml:"file"`
+}
+
+// parseFilelists adds the full file lists to the packages of the primary
+// metadata.
+func parseFilelists(r io.Reader, pkgs []Package, byID map[string]int) error {
+	return decodeElements(r, "package", func(dec *xml.Decoder, start *xml.StartElement) error {
+		var p xmlFilelistsPackage
+		if err := dec.DecodeElement(&p, start); err != nil {
+			return err
+		}
+		if idx, ok := byID[p.ID]; ok {
+			pkgs[idx].Files = p.Files
+		}
+		return nil
+	})
+}
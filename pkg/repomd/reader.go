@@ -0,0 +1,300 @@
+package repomd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// Repository is the parsed metadata of a repository.
+type Repository struct {
+	// ID of the repository configuration, see rpmmd.RepoConfig.Hash()
+	ID string `json:"id"`
+
+	// Revision of the metadata from repomd.xml
+	Revision string `json:"revision"`
+
+	Packages   []Package  `json:"packages"`
+	Advisories []Advisory `json:"advisories,omitempty"`
+}
+
+// cachedRepository is the on-disk cache of a parsed repository. It is valid
+// as long as the repomd.xml of the repository has the same checksum.
+type cachedRepository struct {
+	RepoMDChecksum string     `json:"repomd_checksum"`
+	Repository     Repository `json:"repository"`
+}
+
+// Reader reads the metadata of repositories.
+type Reader struct {
+	// Directory of the parsed metadata, no caching if empty
+	cacheDir string
+}
+
+// NewReader creates a new Reader that caches the parsed metadata in cacheDir,
+// one file per repository keyed by rpmmd.RepoConfig.Hash(). The cache is
+// disabled if cacheDir is empty.
+func NewReader(cacheDir string) *Reader {
+	return &Reader{cacheDir: cacheDir}
+}
+
+func repoName(repo *rpmmd.RepoConfig) string {
+	if repo.Id != "" {
+		return repo.Id
+	}
+	return repo.Hash()
+}
+
+func (r *Reader) cachePath(id string) string {
+	return filepath.Join(r.cacheDir, id+".json")
+}
+
+// readCache returns the cached repository if it is still valid.
+func (r *Reader) readCache(id, repomdChecksum string) *Repository {
+	if r.cacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.cachePath(id))
+	if err != nil {
+		return nil
+	}
+	var cached cachedRepository
+	if err := json.Unmarshal(data, &cached); err != nil || cached.RepoMDChecksum != repomdChecksum {
+		return nil
+	}
+	return &cached.Repository
+}
+
+func (r *Reader) writeCache(repo *Repository, repomdChecksum string) error {
+	if r.cacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(r.cacheDir, 0700); err != nil {
+		return fmt.Errorf("cannot create metadata cache: %w", err)
+	}
+	data, err := json.Marshal(cachedRepository{
+		RepoMDChecksum: repomdChecksum,
+		Repository:     *repo,
+	})
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so that a concurrent reader never
+	// sees a partial cache
+	tmp, err := os.CreateTemp(r.cacheDir, repo.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cannot write metadata cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write metadata cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write metadata cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.cachePath(repo.ID)); err != nil {
+		return fmt.Errorf("cannot write metadata cache: %w", err)
+	}
+	return nil
+}
+
+func readAll(src repoSource, href string) ([]byte, error) {
+	f, err := src.open(href)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// ReadRepository reads the primary, filelists and updateinfo metadata of a
+// repository. The filelists and updateinfo are optional. Repositories with a
+// local path, file://, http:// or https:// baseurl are supported, the metalink
+// or mirrorlist of a repository without a baseurl is resolved to the first
+// mirror. The repomd.xml is read on every call, the other metadata only if it
+// is not cached yet.
+func (r *Reader) ReadRepository(repo rpmmd.RepoConfig) (*Repository, error) {
+	src, err := newRepoSource(&repo)
+	if err != nil {
+		return nil, err
+	}
+	id := repo.Hash()
+
+	repomdData, err := readAll(src, repomdPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read metadata of repository %s: %w", repoName(&repo), err)
+	}
+	repomdSum := sha256.Sum256(repomdData)
+	repomdChecksum := hex.EncodeToString(repomdSum[:])
+	if cached := r.readCache(id, repomdChecksum); cached != nil {
+		return cached, nil
+	}
+
+	md, err := parseRepoMD(bytes.NewReader(repomdData))
+	if err != nil {
+		return nil, fmt.Errorf("repository %s: %w", repoName(&repo), err)
+	}
+	primary := md.find("primary")
+	if primary == nil {
+		return nil, fmt.Errorf("repository %s has no primary metadata", repoName(&repo))
+	}
+
+	result := &Repository{
+		ID:       id,
+		Revision: strings.TrimSpace(md.Revision),
+	}
+	var byID map[string]int
+	err = readData(src, primary, func(r io.Reader) (err error) {
+		result.Packages, byID, err = parsePrimary(r)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("repository %s: %w", repoName(&repo), err)
+	}
+	if filelists := md.find("filelists"); filelists != nil {
+		err = readData(src, filelists, func(r io.Reader) error {
+			return parseFilelists(r, result.Packages, byID)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: %w", repoName(&repo), err)
+		}
+	}
+	if updateinfo := md.find("updateinfo"); updateinfo != nil {
+		err = readData(src, updateinfo, func(r io.Reader) (err error) {
+			result.Advisories, err = parseUpdateinfo(r)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: %w", repoName(&repo), err)
+		}
+	}
+
+	if err := r.writeCache(result, repomdChecksum); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Read reads the metadata of all repositories.
+func (r *Reader) Read(repos []rpmmd.RepoConfig) (*Metadata, error) {
+	md := &Metadata{}
+	for _, repo := range repos {
+		result, err := r.ReadRepository(repo)
+		if err != nil {
+			return nil, err
+		}
+		md.Repositories = append(md.Repositories, result)
+	}
+	return md, nil
+}
+
+// Metadata is the metadata of a set of repositories.
+type Metadata struct {
+	Repositories []*Repository
+}
+
+func (md *Metadata) packages() []*Package {
+	var pkgs []*Package
+	for _, repo := range md.Repositories {
+		for idx := range repo.Packages {
+			pkgs = append(pkgs, &repo.Packages[idx])
+		}
+	}
+	return pkgs
+}
+
+// toPackageList converts the packages to an rpmmd.PackageList, sorted in the
+// same way as the results of the depsolver.
+func toPackageList(pkgs []*Package) rpmmd.PackageList {
+	list := make(rpmmd.PackageList, len(pkgs))
+	for idx, pkg := range pkgs {
+		list[idx] = pkg.ToRPMMD()
+	}
+	sortID := func(pkg rpmmd.Package) string {
+		return fmt.Sprintf("%s-%s-%s", pkg.Name, pkg.Version, pkg.Release)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return sortID(list[i]) < sortID(list[j])
+	})
+	return list
+}
+
+// PackageList returns all the packages of the repositories, like
+// dnfjson.Solver.FetchMetadata.
+func (md *Metadata) PackageList() rpmmd.PackageList {
+	return toPackageList(md.packages())
+}
+
+// Search returns the packages with a name that matches one of the glob
+// patterns, like dnfjson.Solver.SearchMetadata.
+func (md *Metadata) Search(patterns ...string) (rpmmd.PackageList, error) {
+	return md.PackageList().Search(patterns...)
+}
+
+// WhatProvides returns the packages that provide a capability, e.g.
+// "webserver", "libc.so.6()(64bit)" or a file path like "/usr/bin/sh".
+// Versions of the capability are not compared.
+func (md *Metadata) WhatProvides(capability string) []*Package {
+	var found []*Package
+	for _, pkg := range md.packages() {
+		if provides(pkg, capability) {
+			found = append(found, pkg)
+		}
+	}
+	return found
+}
+
+func provides(pkg *Package, capability string) bool {
+	for _, dep := range pkg.Provides {
+		if dep.Name == capability {
+			return true
+		}
+	}
+	if strings.HasPrefix(capability, "/") {
+		for _, file := range pkg.Files {
+			if file == capability {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WhatRequires returns the packages that require a capability. Versions of
+// the capability are not compared.
+func (md *Metadata) WhatRequires(capability string) []*Package {
+	var found []*Package
+	for _, pkg := range md.packages() {
+		for _, dep := range pkg.Requires {
+			if dep.Name == capability {
+				found = append(found, pkg)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Advisories returns the advisories of all repositories, sorted by ID. An
+// advisory that is part of multiple repositories is returned once for each
+// repository.
+func (md *Metadata) Advisories() []Advisory {
+	var advisories []Advisory
+	for _, repo := range md.Repositories {
+		advisories = append(advisories, repo.Advisories...)
+	}
+	sort.SliceStable(advisories, func(i, j int) bool {
+		return advisories[i].ID < advisories[j].ID
+	})
+	return advisories
+}
@@ -0,0 +1,439 @@
+package repomd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+const testPrimary = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm" packages="3">
+<package type="rpm">
+  <name>httpd</name>
+  <arch>x86_64</arch>
+  <version epoch="0" ver="2.4.62" rel="1.el9"/>
+  <checksum type="sha256" pkgid="YES">1111</checksum>
+  <summary>Apache HTTP Server</summary>
+  <description>The Apache HTTP Server is a powerful web server.</description>
+  <url>https://httpd.apache.org/</url>
+  <time file="1720000000" build="1719000000"/>
+  <location href="Packages/httpd-2.4.62-1.el9.x86_64.rpm"/>
+  <format>
+    <rpm:license>ASL 2.0</rpm:license>
+    <rpm:sourcerpm>httpd-2.4.62-1.el9.src.rpm</rpm:sourcerpm>
+    <rpm:provides>
+      <rpm:entry name="httpd" flags="EQ" epoch="0" ver="2.4.62" rel="1.el9"/>
+      <rpm:entry name="webserver"/>
+    </rpm:provides>
+    <rpm:requires>
+      <rpm:entry name="/bin/sh" pre="1"/>
+      <rpm:entry name="libc.so.6()(64bit)"/>
+    </rpm:requires>
+    <file>/usr/sbin/httpd</file>
+  </format>
+</package>
+<package type="rpm">
+  <name>glibc</name>
+  <arch>x86_64</arch>
+  <version epoch="0" ver="2.34" rel="100.el9"/>
+  <checksum type="sha256" pkgid="YES">2222</checksum>
+  <summary>The GNU libc libraries</summary>
+  <description>The glibc package contains standard libraries.</description>
+  <url>http://www.gnu.org/software/glibc/</url>
+  <time file="1720000000" build="1718000000"/>
+  <location href="Packages/glibc-2.34-100.el9.x86_64.rpm"/>
+  <format>
+    <rpm:license>LGPLv2+</rpm:license>
+    <rpm:sourcerpm>glibc-2.34-100.el9.src.rpm</rpm:sourcerpm>
+    <rpm:provides>
+      <rpm:entry name="libc.so.6()(64bit)"/>
+    </rpm:provides>
+  </format>
+</package>
+<package type="rpm">
+  <name>bash</name>
+  <arch>x86_64</arch>
+  <version epoch="1" ver="5.1.8" rel="9.el9"/>
+  <checksum type="sha256" pkgid="YES">3333</checksum>
+  <summary>The GNU Bourne Again shell</summary>
+  <description>The GNU Bourne Again shell (Bash).</description>
+  <url>https://www.gnu.org/software/bash</url>
+  <time file="1720000000" build="1717000000"/>
+  <location href="Packages/bash-5.1.8-9.el9.x86_64.rpm"/>
+  <format>
+    <rpm:license>GPLv3+</rpm:license>
+    <rpm:provides>
+      <rpm:entry name="bash" flags="EQ" epoch="1" ver="5.1.8" rel="9.el9"/>
+    </rpm:provides>
+    <rpm:requires>
+      <rpm:entry name="libc.so.6()(64bit)"/>
+    </rpm:requires>
+    <file>/usr/bin/sh</file>
+  </format>
+</package>
+</metadata>
+`
+
+const testFilelists = `<?xml version="1.0" encoding="UTF-8"?>
+<filelists xmlns="http://linux.duke.edu/metadata/filelists" packages="3">
+<package pkgid="1111" name="httpd" arch="x86_64">
+  <version epoch="0" ver="2.4.62" rel="1.el9"/>
+  <file>/etc/httpd/conf/httpd.conf</file>
+  <file>/usr/sbin/httpd</file>
+</package>
+<package pkgid="3333" name="bash" arch="x86_64">
+  <version epoch="1" ver="5.1.8" rel="9.el9"/>
+  <file>/usr/bin/bash</file>
+  <file>/usr/bin/sh</file>
+</package>
+</filelists>
+`
+
+const testUpdateinfo = `<?xml version="1.0" encoding="UTF-8"?>
+<updates>
+  <update from="security@example.com" status="final" type="security" version="2">
+    <id>RHSA-2024:0002</id>
+    <title>Important: httpd security update</title>
+    <severity>Important</severity>
+    <issued date="2024-07-01 12:00:00"/>
+    <description>An update for httpd is now available.</description>
+    <references>
+      <reference href="https://access.redhat.com/security/cve/CVE-2024-0001" id="CVE-2024-0001" type="cve" title="CVE-2024-0001"/>
+    </references>
+    <pkglist>
+      <collection short="rhel-9">
+        <name>rhel-9</name>
+        <package name="httpd" version="2.4.62" release="1.el9" epoch="0" arch="x86_64" src="httpd-2.4.62-1.el9.src.rpm">
+          <filename>httpd-2.4.62-1.el9.x86_64.rpm</filename>
+        </package>
+      </collection>
+    </pkglist>
+  </update>
+  <update type="bugfix">
+    <id>RHBA-2024:0001</id>
+    <title>bash bug fix update</title>
+    <issued date="1717000000"/>
+    <pkglist>
+      <collection>
+        <package name="bash" version="5.1.8" release="9.el9" epoch="1" arch="x86_64"/>
+      </collection>
+    </pkglist>
+  </update>
+</updates>
+`
+
+func compressGzip(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func compressXz(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w, err := xz.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func compressZstd(t *testing.T, data []byte) []byte {
+	w, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer w.Close()
+	return w.EncodeAll(data, nil)
+}
+
+type testDataFile struct {
+	dataType string
+	filename string
+	content  []byte
+}
+
+// makeTestRepo writes a repository with the given metadata files and returns
+// its path.
+func makeTestRepo(t *testing.T, files []testDataFile) string {
+	root := t.TempDir()
+	repodata := filepath.Join(root, "repodata")
+	require.NoError(t, os.Mkdir(repodata, 0755))
+
+	var repomd strings.Builder
+	repomd.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<repomd xmlns="http://linux.duke.edu/metadata/repo">
+  <revision>1720000000</revision>
+`)
+	for _, f := range files {
+		sum := sha256.Sum256(f.content)
+		checksum := hex.EncodeToString(sum[:])
+		require.NoError(t, os.WriteFile(filepath.Join(repodata, f.filename), f.content, 0644))
+		fmt.Fprintf(&repomd, `  <data type="%s">
+    <checksum type="sha256">%s</checksum>
+    <location href="repodata/%s"/>
+  </data>
+`, f.dataType, checksum, f.filename)
+	}
+	repomd.WriteString("</repomd>\n")
+	require.NoError(t, os.WriteFile(filepath.Join(repodata, "repomd.xml"), []byte(repomd.String()), 0644))
+	return root
+}
+
+func makeCompressedTestRepo(t *testing.T) string {
+	return makeTestRepo(t, []testDataFile{
+		{"primary", "primary.xml.zst", compressZstd(t, []byte(testPrimary))},
+		{"filelists", "filelists.xml.xz", compressXz(t, []byte(testFilelists))},
+		{"updateinfo", "updateinfo.xml.gz", compressGzip(t, []byte(testUpdateinfo))},
+	})
+}
+
+func TestReadRepository(t *testing.T) {
+	root := makeCompressedTestRepo(t)
+	repo := rpmmd.RepoConfig{Id: "test", BaseURLs: []string{"file://" + root}}
+
+	result, err := NewReader("").ReadRepository(repo)
+	require.NoError(t, err)
+	assert.Equal(t, repo.Hash(), result.ID)
+	assert.Equal(t, "1720000000", result.Revision)
+	require.Len(t, result.Packages, 3)
+
+	assert.Equal(t, Package{
+		Name:        "httpd",
+		Version:     "2.4.62",
+		Release:     "1.el9",
+		Arch:        "x86_64",
+		Summary:     "Apache HTTP Server",
+		Description: "The Apache HTTP Server is a powerful web server.",
+		URL:         "https://httpd.apache.org/",
+		License:     "ASL 2.0",
+		BuildTime:   time.Unix(1719000000, 0).UTC(),
+		SourceRPM:   "httpd-2.4.62-1.el9.src.rpm",
+		Checksum:    "sha256:1111",
+		Location:    "Packages/httpd-2.4.62-1.el9.x86_64.rpm",
+		Provides: []Dependency{
+			{Name: "httpd", Flags: "EQ", Epoch: "0", Version: "2.4.62", Release: "1.el9"},
+			{Name: "webserver"},
+		},
+		Requires: []Dependency{
+			{Name: "/bin/sh"},
+			{Name: "libc.so.6()(64bit)"},
+		},
+		Files: []string{"/etc/httpd/conf/httpd.conf", "/usr/sbin/httpd"},
+	}, result.Packages[0])
+	assert.Equal(t, "bash-1:5.1.8-9.el9.x86_64", result.Packages[2].NEVRA())
+	assert.Equal(t, "bash = 1:5.1.8-9.el9", result.Packages[2].Provides[0].String())
+	// glibc has no filelists entry
+	assert.Nil(t, result.Packages[1].Files)
+
+	require.Len(t, result.Advisories, 2)
+	assert.Equal(t, Advisory{
+		ID:          "RHSA-2024:0002",
+		Type:        "security",
+		Title:       "Important: httpd security update",
+		Severity:    "Important",
+		Issued:      time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC),
+		Description: "An update for httpd is now available.",
+		References: []Reference{
+			{ID: "CVE-2024-0001", Type: "cve", Href: "https://access.redhat.com/security/cve/CVE-2024-0001"},
+		},
+		Packages: []AdvisoryPackage{
+			{Name: "httpd", Version: "2.4.62", Release: "1.el9", Arch: "x86_64", Filename: "httpd-2.4.62-1.el9.x86_64.rpm"},
+		},
+	}, result.Advisories[0])
+	assert.Equal(t, time.Unix(1717000000, 0).UTC(), result.Advisories[1].Issued)
+	assert.Equal(t, "bash-1:5.1.8-9.el9.x86_64", result.Advisories[1].Packages[0].NEVRA())
+}
+
+func TestReadRepositoryPlainPath(t *testing.T) {
+	root := makeTestRepo(t, []testDataFile{
+		{"primary", "primary.xml", []byte(testPrimary)},
+	})
+	result, err := NewReader("").ReadRepository(rpmmd.RepoConfig{BaseURLs: []string{root}})
+	require.NoError(t, err)
+	assert.Len(t, result.Packages, 3)
+	// only the files of the primary metadata are known without filelists
+	assert.Equal(t, []string{"/usr/sbin/httpd"}, result.Packages[0].Files)
+	assert.Nil(t, result.Advisories)
+}
+
+func TestReadRepositoryErrors(t *testing.T) {
+	noPrimary := makeTestRepo(t, []testDataFile{
+		{"filelists", "filelists.xml", []byte(testFilelists)},
+	})
+	badChecksum := makeTestRepo(t, []testDataFile{
+		{"primary", "primary.xml", []byte(testPrimary)},
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(badChecksum, "repodata", "primary.xml"), []byte(testPrimary+"\n"), 0644))
+
+	testCases := map[string]struct {
+		repo     rpmmd.RepoConfig
+		expected string
+	}{
+		"scheme": {
+			repo:     rpmmd.RepoConfig{Id: "scheme", BaseURLs: []string{"ftp://example.com/repo"}},
+			expected: `repository scheme: baseurl "ftp://example.com/repo" has an unsupported scheme`,
+		},
+		"no-baseurl": {
+			repo:     rpmmd.RepoConfig{Id: "no-baseurl"},
+			expected: "repository no-baseurl has no baseurl, metalink or mirrorlist",
+		},
+		"no-primary": {
+			repo:     rpmmd.RepoConfig{Id: "no-primary", BaseURLs: []string{noPrimary}},
+			expected: "repository no-primary has no primary metadata",
+		},
+		"bad-checksum": {
+			repo:     rpmmd.RepoConfig{Id: "bad-checksum", BaseURLs: []string{badChecksum}},
+			expected: "repository bad-checksum: checksum mismatch for primary metadata",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewReader("").ReadRepository(tc.repo)
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestReadRepositoryHTTP(t *testing.T) {
+	root := makeCompressedTestRepo(t)
+	mux := http.NewServeMux()
+	mux.Handle("/repo/", http.StripPrefix("/repo/", http.FileServer(http.Dir(root))))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/metalink", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>
+<metalink version="3.0" xmlns="http://www.metalinker.org/">
+ <files>
+  <file name="repomd.xml">
+   <resources maxconnections="1">
+    <url protocol="rsync" type="rsync">rsync://example.com/repo/repodata/repomd.xml</url>
+    <url protocol="http" type="http">%s/repo/repodata/repomd.xml</url>
+   </resources>
+  </file>
+ </files>
+</metalink>`, srv.URL)
+	})
+	mux.HandleFunc("/mirrorlist", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "# mirrors\n\n%s/repo/\n", srv.URL)
+	})
+
+	testCases := map[string]rpmmd.RepoConfig{
+		"baseurl":    {Id: "baseurl", BaseURLs: []string{srv.URL + "/repo"}},
+		"metalink":   {Id: "metalink", Metalink: srv.URL + "/metalink"},
+		"mirrorlist": {Id: "mirrorlist", MirrorList: srv.URL + "/mirrorlist"},
+	}
+	for name, repo := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := NewReader("").ReadRepository(repo)
+			require.NoError(t, err)
+			assert.Equal(t, "1720000000", result.Revision)
+			assert.Len(t, result.Packages, 3)
+			assert.Len(t, result.Advisories, 2)
+		})
+	}
+
+	_, err := NewReader("").ReadRepository(rpmmd.RepoConfig{Id: "missing", BaseURLs: []string{srv.URL + "/missing"}})
+	assert.ErrorContains(t, err, "cannot read metadata of repository missing")
+	_, err = NewReader("").ReadRepository(rpmmd.RepoConfig{Id: "no-mirrors", MirrorList: srv.URL + "/metalink"})
+	assert.ErrorContains(t, err, "repository no-mirrors has no mirrors")
+}
+
+func TestReadRepositoryCache(t *testing.T) {
+	root := makeCompressedTestRepo(t)
+	cacheDir := t.TempDir()
+	repo := rpmmd.RepoConfig{BaseURLs: []string{root}}
+	reader := NewReader(cacheDir)
+
+	expected, err := reader.ReadRepository(repo)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(cacheDir, repo.Hash()+".json"))
+
+	// the metadata files are not read again while repomd.xml is unchanged
+	require.NoError(t, os.Remove(filepath.Join(root, "repodata", "primary.xml.zst")))
+	cached, err := reader.ReadRepository(repo)
+	require.NoError(t, err)
+	assert.Equal(t, expected, cached)
+
+	// a new revision invalidates the cache
+	repomd := filepath.Join(root, "repodata", "repomd.xml")
+	data, err := os.ReadFile(repomd)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(repomd, bytes.Replace(data, []byte("1720000000"), []byte("1720000001"), 1), 0644))
+	_, err = reader.ReadRepository(repo)
+	assert.ErrorContains(t, err, "cannot open primary metadata")
+}
+
+func TestMetadataQueries(t *testing.T) {
+	md, err := NewReader("").Read([]rpmmd.RepoConfig{
+		{BaseURLs: []string{makeCompressedTestRepo(t)}},
+	})
+	require.NoError(t, err)
+
+	names := func(pkgs []*Package) []string {
+		var names []string
+		for _, pkg := range pkgs {
+			names = append(names, pkg.Name)
+		}
+		return names
+	}
+
+	list := md.PackageList()
+	require.Len(t, list, 3)
+	assert.Equal(t, "bash", list[0].Name)
+	assert.Equal(t, uint(1), list[0].Epoch)
+
+	found, err := md.Search("http*", "glibc")
+	require.NoError(t, err)
+	assert.Equal(t, rpmmd.PackageList{list[1], list[2]}, found)
+
+	assert.Equal(t, []string{"httpd"}, names(md.WhatProvides("webserver")))
+	assert.Equal(t, []string{"glibc"}, names(md.WhatProvides("libc.so.6()(64bit)")))
+	assert.Equal(t, []string{"bash"}, names(md.WhatProvides("/usr/bin/bash")))
+	assert.Empty(t, md.WhatProvides("nginx"))
+	assert.Equal(t, []string{"httpd", "bash"}, names(md.WhatRequires("libc.so.6()(64bit)")))
+
+	var ids []string
+	for _, advisory := range md.Advisories() {
+		ids = append(ids, advisory.ID)
+	}
+	assert.Equal(t, []string{"RHBA-2024:0001", "RHSA-2024:0002"}, ids)
+}
+
+func TestReadTestRepo(t *testing.T) {
+	md, err := NewReader("").Read([]rpmmd.RepoConfig{
+		{Id: "baseos", BaseURLs: []string{"../../test/data/testrepo"}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, md.PackageList(), 1125)
+
+	found, err := md.Search("ModemManager")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "1.18.2", found[0].Version)
+
+	// files that are only listed in the filelists
+	var names []string
+	for _, pkg := range md.WhatProvides("/usr/share/ModemManager/mm-foxconn-t77w968-carrier-mapping.conf") {
+		names = append(names, pkg.Name)
+	}
+	assert.Equal(t, []string{"ModemManager"}, names)
+}
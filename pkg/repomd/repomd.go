@@ -0,0 +1,159 @@
+// Package repomd reads the metadata of rpm-md repositories (as created by
+// createrepo) without the depsolver, for read-only queries like package
+// searches, provides lookups and advisory listings.
+package repomd
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/sha1" // #nosec G505 -- old repositories still use sha1 checksums
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// repomdPath is the path of the repomd.xml file relative to the root of the
+// repository.
+const repomdPath = "repodata/repomd.xml"
+
+// repoMD is the index of the metadata files of a repository.
+type repoMD struct {
+	Revision string       `xml:"revision"`
+	Data     []repoMDData `xml:"data"`
+}
+
+type repoMDData struct {
+	Type     string `xml:"type,attr"`
+	Checksum struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"checksum"`
+	Location struct {
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+}
+
+// find returns the metadata file of the given type or nil if the repository
+// does not have one.
+func (md *repoMD) find(dataType string) *repoMDData {
+	for idx := range md.Data {
+		if md.Data[idx].Type == dataType {
+			return &md.Data[idx]
+		}
+	}
+	return nil
+}
+
+func parseRepoMD(r io.Reader) (*repoMD, error) {
+	var md repoMD
+	if err := xml.NewDecoder(r).Decode(&md); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", repomdPath, err)
+	}
+	return &md, nil
+}
+
+func newHash(checksumType string) (hash.Hash, error) {
+	switch checksumType {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "sha", "sha1":
+		return sha1.New(), nil // #nosec G401
+	default:
+		return nil, fmt.Errorf("unsupported checksum type %q", checksumType)
+	}
+}
+
+// decompress returns a reader for the uncompressed content of a metadata
+// file. The compression is detected by the file extension.
+func decompress(r io.Reader, filename string) (io.ReadCloser, error) {
+	switch filepath.Ext(filename) {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".xz":
+		xzr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xzr), nil
+	case ".zst":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case ".bz2":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+// readData verifies the checksum of a metadata file of the repository and
+// passes its uncompressed content to parse.
+func readData(src repoSource, data *repoMDData, parse func(io.Reader) error) error {
+	if _, err := cleanHref(data.Location.Href); err != nil {
+		return fmt.Errorf("%s metadata %w", data.Type, err)
+	}
+
+	h, err := newHash(data.Checksum.Type)
+	if err != nil {
+		return fmt.Errorf("cannot verify %s metadata: %w", data.Type, err)
+	}
+
+	f, err := src.open(data.Location.Href)
+	if err != nil {
+		return fmt.Errorf("cannot open %s metadata: %w", data.Type, err)
+	}
+	defer f.Close()
+
+	tee := io.TeeReader(f, h)
+	r, err := decompress(tee, data.Location.Href)
+	if err != nil {
+		return fmt.Errorf("cannot decompress %s metadata: %w", data.Type, err)
+	}
+	defer r.Close()
+	if err := parse(r); err != nil {
+		return fmt.Errorf("cannot parse %s metadata: %w", data.Type, err)
+	}
+
+	// the parser might stop before the end of the file
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return fmt.Errorf("cannot read %s metadata: %w", data.Type, err)
+	}
+	if checksum := hex.EncodeToString(h.Sum(nil)); checksum != strings.TrimSpace(data.Checksum.Value) {
+		return fmt.Errorf("checksum mismatch for %s metadata: expected %s, got %s", data.Type, strings.TrimSpace(data.Checksum.Value), checksum)
+	}
+	return nil
+}
+
+// decodeElements calls fn for every element with the given local name in the
+// XML document of r, so that large documents are not read into memory at
+// once.
+func decodeElements(r io.Reader, name string, fn func(*xml.Decoder, *xml.StartElement) error) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == name {
+			if err := fn(dec, &start); err != nil {
+				return err
+			}
+		}
+	}
+}
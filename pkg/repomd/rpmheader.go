@@ -0,0 +1,515 @@
+package repomd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The RPM file format: a 96 byte lead, followed by the signature header,
+// padded to 8 bytes, and the main header, followed by the payload. Both
+// headers consist of an index of tags and a data store.
+const (
+	rpmLeadSize        = 96
+	rpmHeaderIntroSize = 16
+	rpmIndexEntrySize  = 16
+
+	// limits of the header size to reject garbage early
+	rpmMaxIndexEntries = 1 << 16
+	rpmMaxDataSize     = 256 << 20
+)
+
+var (
+	rpmLeadMagic   = []byte{0xed, 0xab, 0xee, 0xdb}
+	rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+)
+
+// tag types of the header index
+const (
+	rpmTypeInt16       = 3
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+)
+
+// header tags, see rpmtag.h
+const (
+	rpmTagName           = 1000
+	rpmTagVersion        = 1001
+	rpmTagRelease        = 1002
+	rpmTagEpoch          = 1003
+	rpmTagSummary        = 1004
+	rpmTagDescription    = 1005
+	rpmTagBuildTime      = 1006
+	rpmTagSize           = 1009
+	rpmTagLicense        = 1014
+	rpmTagURL            = 1020
+	rpmTagArch           = 1022
+	rpmTagFileModes      = 1030
+	rpmTagSourceRPM      = 1044
+	rpmTagArchiveSize    = 1046
+	rpmTagProvideName    = 1047
+	rpmTagRequireFlags   = 1048
+	rpmTagRequireName    = 1049
+	rpmTagRequireVersion = 1050
+	rpmTagConflictFlags  = 1053
+	rpmTagConflictName   = 1054
+	rpmTagConflictVer    = 1055
+	rpmTagObsoleteName   = 1090
+	rpmTagProvideFlags   = 1112
+	rpmTagProvideVersion = 1113
+	rpmTagObsoleteFlags  = 1114
+	rpmTagObsoleteVer    = 1115
+	rpmTagDirIndexes     = 1116
+	rpmTagBaseNames      = 1117
+	rpmTagDirNames       = 1118
+	rpmTagRecommendName  = 5046
+	rpmTagRecommendVer   = 5047
+	rpmTagRecommendFlags = 5048
+	rpmTagSuggestName    = 5049
+	rpmTagSuggestVer     = 5050
+	rpmTagSuggestFlags   = 5051
+	rpmTagSupplementName = 5052
+	rpmTagSupplementVer  = 5053
+	rpmTagSupplementFlag = 5054
+	rpmTagEnhanceName    = 5055
+	rpmTagEnhanceVer     = 5056
+	rpmTagEnhanceFlags   = 5057
+)
+
+// dependency flags, see rpmds.h
+const (
+	rpmSenseLess    = 1 << 1
+	rpmSenseGreater = 1 << 2
+	rpmSenseEqual   = 1 << 3
+	rpmSensePrereq  = 1 << 6
+	rpmSenseInterp  = 1 << 8
+	rpmSensePre     = 1 << 9
+	rpmSensePost    = 1 << 10
+	rpmSensePreun   = 1 << 11
+	rpmSensePostun  = 1 << 12
+	rpmSenseRpmlib  = 1 << 24
+
+	rpmSenseScripts = rpmSensePrereq | rpmSenseInterp | rpmSensePre | rpmSensePost | rpmSensePreun | rpmSensePostun
+)
+
+const fileModeTypeMask = 0170000
+const fileModeDir = 0040000
+
+type rpmIndexEntry struct {
+	Tag    int32
+	Type   uint32
+	Offset int32
+	Count  uint32
+}
+
+// rpmHeader is a parsed header of an RPM file.
+type rpmHeader struct {
+	entries map[int32]rpmIndexEntry
+	data    []byte
+}
+
+// readRPMHeader reads a header structure and returns it with its size in
+// bytes.
+func readRPMHeader(r io.Reader) (*rpmHeader, int64, error) {
+	intro := make([]byte, rpmHeaderIntroSize)
+	if _, err := io.ReadFull(r, intro); err != nil {
+		return nil, 0, err
+	}
+	if !bytes.Equal(intro[:4], rpmHeaderMagic) {
+		return nil, 0, fmt.Errorf("invalid header magic")
+	}
+	nIndex := binary.BigEndian.Uint32(intro[8:12])
+	dataSize := binary.BigEndian.Uint32(intro[12:16])
+	if nIndex > rpmMaxIndexEntries || dataSize > rpmMaxDataSize {
+		return nil, 0, fmt.Errorf("header too large")
+	}
+
+	index := make([]rpmIndexEntry, nIndex)
+	if err := binary.Read(r, binary.BigEndian, index); err != nil {
+		return nil, 0, err
+	}
+	hdr := &rpmHeader{
+		entries: make(map[int32]rpmIndexEntry, nIndex),
+		data:    make([]byte, dataSize),
+	}
+	if _, err := io.ReadFull(r, hdr.data); err != nil {
+		return nil, 0, err
+	}
+	for _, entry := range index {
+		if entry.Offset < 0 || int64(entry.Offset) > int64(dataSize) {
+			return nil, 0, fmt.Errorf("invalid offset of tag %d", entry.Tag)
+		}
+		hdr.entries[entry.Tag] = entry
+	}
+	size := int64(rpmHeaderIntroSize) + int64(nIndex)*rpmIndexEntrySize + int64(dataSize)
+	return hdr, size, nil
+}
+
+// strings returns the values of a string, string array or i18n string tag.
+// Only the first (untranslated) value of i18n strings is returned.
+func (h *rpmHeader) strings(tag int32) ([]string, error) {
+	entry, ok := h.entries[tag]
+	if !ok {
+		return nil, nil
+	}
+	switch entry.Type {
+	case rpmTypeString, rpmTypeStringArray, rpmTypeI18NString:
+	default:
+		return nil, fmt.Errorf("tag %d is not a string", tag)
+	}
+	count := entry.Count
+	if entry.Type != rpmTypeStringArray {
+		count = 1
+	}
+	values := make([]string, 0, count)
+	data := h.data[entry.Offset:]
+	for i := uint32(0); i < count; i++ {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string in tag %d", tag)
+		}
+		values = append(values, string(data[:end]))
+		data = data[end+1:]
+	}
+	return values, nil
+}
+
+func (h *rpmHeader) string(tag int32) (string, error) {
+	values, err := h.strings(tag)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[0], nil
+}
+
+// ints returns the values of an int16 or int32 tag.
+func (h *rpmHeader) ints(tag int32) ([]uint32, error) {
+	entry, ok := h.entries[tag]
+	if !ok {
+		return nil, nil
+	}
+	var size uint32
+	switch entry.Type {
+	case rpmTypeInt16:
+		size = 2
+	case rpmTypeInt32:
+		size = 4
+	default:
+		return nil, fmt.Errorf("tag %d is not an integer", tag)
+	}
+	if uint64(entry.Offset)+uint64(entry.Count)*uint64(size) > uint64(len(h.data)) {
+		return nil, fmt.Errorf("tag %d exceeds the header", tag)
+	}
+	values := make([]uint32, entry.Count)
+	data := h.data[entry.Offset:]
+	for idx := range values {
+		if size == 2 {
+			values[idx] = uint32(binary.BigEndian.Uint16(data[idx*2:]))
+		} else {
+			values[idx] = binary.BigEndian.Uint32(data[idx*4:])
+		}
+	}
+	return values, nil
+}
+
+func (h *rpmHeader) int(tag int32) (uint32, error) {
+	values, err := h.ints(tag)
+	if err != nil || len(values) == 0 {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+// splitEVR splits a dependency version [epoch:]version[-release].
+func splitEVR(evr string) (epoch, version, release string) {
+	if idx := strings.Index(evr, ":"); idx >= 0 {
+		epoch, evr = evr[:idx], evr[idx+1:]
+	} else if evr != "" {
+		epoch = "0"
+	}
+	if idx := strings.LastIndex(evr, "-"); idx >= 0 {
+		evr, release = evr[:idx], evr[idx+1:]
+	}
+	return epoch, evr, release
+}
+
+func depFlags(flags uint32) string {
+	switch flags & (rpmSenseLess | rpmSenseGreater | rpmSenseEqual) {
+	case rpmSenseEqual:
+		return "EQ"
+	case rpmSenseLess:
+		return "LT"
+	case rpmSenseLess | rpmSenseEqual:
+		return "LE"
+	case rpmSenseGreater:
+		return "GT"
+	case rpmSenseGreater | rpmSenseEqual:
+		return "GE"
+	default:
+		return ""
+	}
+}
+
+// dependencies returns the dependencies of the name, version and flags
+// tags. rpmlib() dependencies are internal to rpm and are skipped, like
+// createrepo does.
+func (h *rpmHeader) dependencies(nameTag, versionTag, flagsTag int32) ([]localDependency, error) {
+	names, err := h.strings(nameTag)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := h.strings(versionTag)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := h.ints(flagsTag)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) != len(names) || len(flags) != len(names) {
+		return nil, fmt.Errorf("inconsistent dependency tags %d", nameTag)
+	}
+
+	var deps []localDependency
+	for idx, name := range names {
+		if flags[idx]&rpmSenseRpmlib != 0 || strings.HasPrefix(name, "rpmlib(") {
+			continue
+		}
+		dep := localDependency{
+			Dependency: Dependency{Name: name},
+			Pre:        flags[idx]&rpmSenseScripts != 0,
+		}
+		if versions[idx] != "" {
+			dep.Flags = depFlags(flags[idx])
+			dep.Epoch, dep.Version, dep.Release = splitEVR(versions[idx])
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// localDependency is a dependency of a local package, with the pre flag of
+// requirements that are needed by scriptlets.
+type localDependency struct {
+	Dependency
+	Pre bool
+}
+
+// localFile is a file of a local package.
+type localFile struct {
+	Path string
+	Dir  bool
+}
+
+// localPackage is a package file that the metadata is generated for.
+type localPackage struct {
+	Package
+
+	// Absolute path of the package file
+	Filename string
+
+	FileTime      int64
+	PackageSize   int64
+	InstalledSize uint32
+	ArchiveSize   uint32
+	HeaderStart   int64
+	HeaderEnd     int64
+
+	ProvidesDeps    []localDependency
+	RequiresDeps    []localDependency
+	ConflictsDeps   []localDependency
+	ObsoletesDeps   []localDependency
+	RecommendsDeps  []localDependency
+	SuggestsDeps    []localDependency
+	SupplementsDeps []localDependency
+	EnhancesDeps    []localDependency
+	FileEntries     []localFile
+}
+
+// isSourceRPM returns true if the header is the header of a source package,
+// which does not have a source rpm.
+func (h *rpmHeader) isSourceRPM() bool {
+	_, ok := h.entries[rpmTagSourceRPM]
+	return !ok
+}
+
+// readLocalPackage reads the header of an RPM file.
+func readLocalPackage(filename string) (*localPackage, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// the package checksum covers the whole file
+	h := sha256.New()
+	r := io.TeeReader(f, h)
+
+	lead := make([]byte, rpmLeadSize)
+	if _, err := io.ReadFull(r, lead); err != nil {
+		return nil, fmt.Errorf("%s: cannot read lead: %w", filename, err)
+	}
+	if !bytes.Equal(lead[:4], rpmLeadMagic) {
+		return nil, fmt.Errorf("%s is not an RPM file", filename)
+	}
+	_, sigSize, err := readRPMHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: cannot read signature header: %w", filename, err)
+	}
+	// the signature header is padded to a multiple of 8 bytes
+	if pad := (8 - sigSize%8) % 8; pad > 0 {
+		if _, err := io.CopyN(io.Discard, r, pad); err != nil {
+			return nil, fmt.Errorf("%s: cannot read signature header: %w", filename, err)
+		}
+		sigSize += pad
+	}
+	hdr, hdrSize, err := readRPMHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: cannot read header: %w", filename, err)
+	}
+	if hdr.isSourceRPM() {
+		return nil, fmt.Errorf("%s is a source package", filename)
+	}
+	fileSize, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, err := hdr.toLocalPackage()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	pkg.Checksum = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	if pkg.Filename, err = filepath.Abs(filename); err != nil {
+		return nil, err
+	}
+	pkg.Location = filepath.Base(filename)
+	pkg.FileTime = stat.ModTime().Unix()
+	pkg.HeaderStart = rpmLeadSize + sigSize
+	pkg.HeaderEnd = pkg.HeaderStart + hdrSize
+	pkg.PackageSize = pkg.HeaderEnd + fileSize
+	return pkg, nil
+}
+
+func (h *rpmHeader) toLocalPackage() (*localPackage, error) {
+	var pkg localPackage
+	var err error
+	stringTags := map[int32]*string{
+		rpmTagName:        &pkg.Name,
+		rpmTagVersion:     &pkg.Version,
+		rpmTagRelease:     &pkg.Release,
+		rpmTagArch:        &pkg.Arch,
+		rpmTagSummary:     &pkg.Summary,
+		rpmTagDescription: &pkg.Description,
+		rpmTagURL:         &pkg.URL,
+		rpmTagLicense:     &pkg.License,
+		rpmTagSourceRPM:   &pkg.SourceRPM,
+	}
+	for tag, value := range stringTags {
+		if *value, err = h.string(tag); err != nil {
+			return nil, err
+		}
+	}
+	if pkg.Name == "" || pkg.Version == "" || pkg.Release == "" || pkg.Arch == "" {
+		return nil, fmt.Errorf("package header has no name, version, release or architecture")
+	}
+
+	epoch, err := h.int(rpmTagEpoch)
+	if err != nil {
+		return nil, err
+	}
+	pkg.Epoch = uint(epoch)
+	buildTime, err := h.int(rpmTagBuildTime)
+	if err != nil {
+		return nil, err
+	}
+	pkg.BuildTime = time.Unix(int64(buildTime), 0).UTC()
+	if pkg.InstalledSize, err = h.int(rpmTagSize); err != nil {
+		return nil, err
+	}
+	if pkg.ArchiveSize, err = h.int(rpmTagArchiveSize); err != nil {
+		return nil, err
+	}
+
+	depTags := []struct {
+		deps                     *[]localDependency
+		nameTag, verTag, flagTag int32
+	}{
+		{&pkg.ProvidesDeps, rpmTagProvideName, rpmTagProvideVersion, rpmTagProvideFlags},
+		{&pkg.RequiresDeps, rpmTagRequireName, rpmTagRequireVersion, rpmTagRequireFlags},
+		{&pkg.ConflictsDeps, rpmTagConflictName, rpmTagConflictVer, rpmTagConflictFlags},
+		{&pkg.ObsoletesDeps, rpmTagObsoleteName, rpmTagObsoleteVer, rpmTagObsoleteFlags},
+		{&pkg.RecommendsDeps, rpmTagRecommendName, rpmTagRecommendVer, rpmTagRecommendFlags},
+		{&pkg.SuggestsDeps, rpmTagSuggestName, rpmTagSuggestVer, rpmTagSuggestFlags},
+		{&pkg.SupplementsDeps, rpmTagSupplementName, rpmTagSupplementVer, rpmTagSupplementFlag},
+		{&pkg.EnhancesDeps, rpmTagEnhanceName, rpmTagEnhanceVer, rpmTagEnhanceFlags},
+	}
+	for _, dt := range depTags {
+		if *dt.deps, err = h.dependencies(dt.nameTag, dt.verTag, dt.flagTag); err != nil {
+			return nil, err
+		}
+	}
+	for _, dep := range pkg.ProvidesDeps {
+		pkg.Provides = append(pkg.Provides, dep.Dependency)
+	}
+	for _, dep := range pkg.RequiresDeps {
+		pkg.Requires = append(pkg.Requires, dep.Dependency)
+	}
+
+	if pkg.FileEntries, err = h.files(); err != nil {
+		return nil, err
+	}
+	for _, file := range pkg.FileEntries {
+		pkg.Files = append(pkg.Files, file.Path)
+	}
+	return &pkg, nil
+}
+
+// files returns the files of the package from the compressed file list.
+func (h *rpmHeader) files() ([]localFile, error) {
+	baseNames, err := h.strings(rpmTagBaseNames)
+	if err != nil {
+		return nil, err
+	}
+	dirNames, err := h.strings(rpmTagDirNames)
+	if err != nil {
+		return nil, err
+	}
+	dirIndexes, err := h.ints(rpmTagDirIndexes)
+	if err != nil {
+		return nil, err
+	}
+	modes, err := h.ints(rpmTagFileModes)
+	if err != nil {
+		return nil, err
+	}
+	if len(dirIndexes) != len(baseNames) {
+		return nil, fmt.Errorf("inconsistent file list")
+	}
+
+	files := make([]localFile, len(baseNames))
+	for idx, name := range baseNames {
+		dirIdx := dirIndexes[idx]
+		if int(dirIdx) >= len(dirNames) {
+			return nil, fmt.Errorf("invalid directory index of file %s", name)
+		}
+		files[idx].Path = dirNames[dirIdx] + name
+		if idx < len(modes) {
+			files[idx].Dir = modes[idx]&fileModeTypeMask == fileModeDir
+		}
+	}
+	return files, nil
+}
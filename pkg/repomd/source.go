@@ -0,0 +1,226 @@
+package repomd
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// repoSource opens the files of a repository by their location relative to
+// the root of the repository.
+type repoSource interface {
+	open(href string) (io.ReadCloser, error)
+}
+
+// cleanHref returns the cleaned location of a file of the repository, or an
+// error if the location is outside of the repository.
+func cleanHref(href string) (string, error) {
+	cleaned := path.Clean(href)
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("location %q is outside of the repository", href)
+	}
+	return cleaned, nil
+}
+
+// localSource is a repository with a local path or file:// baseurl.
+type localSource struct {
+	root string
+}
+
+func (s *localSource) open(href string) (io.ReadCloser, error) {
+	cleaned, err := cleanHref(href)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filepath.Join(s.root, filepath.FromSlash(cleaned)))
+}
+
+// httpSource is a repository with a http:// or https:// baseurl.
+type httpSource struct {
+	client  *http.Client
+	baseURL *url.URL
+}
+
+func (s *httpSource) open(href string) (io.ReadCloser, error) {
+	cleaned, err := cleanHref(href)
+	if err != nil {
+		return nil, err
+	}
+	u := *s.baseURL
+	u.Path = path.Join(u.Path, cleaned)
+	return httpGet(s.client, u.String())
+}
+
+func httpGet(client *http.Client, u string) (io.ReadCloser, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned status: %s", u, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// httpClient returns a client with the TLS configuration of the repository.
+func httpClient(repo *rpmmd.RepoConfig) (*http.Client, error) {
+	tlsConf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if repo.IgnoreSSL != nil && *repo.IgnoreSSL {
+		// #nosec G402 -- the repository configuration disables the verification
+		tlsConf.InsecureSkipVerify = true
+	}
+	if repo.SSLCACert != "" {
+		caCertPEM, err := os.ReadFile(repo.SSLCACert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca certificate: %w", err)
+		}
+		tlsConf.RootCAs = x509.NewCertPool()
+		if ok := tlsConf.RootCAs.AppendCertsFromPEM(caCertPEM); !ok {
+			return nil, fmt.Errorf("cannot add ca certificate %s", repo.SSLCACert)
+		}
+	}
+	if repo.SSLClientCert != "" && repo.SSLClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(repo.SSLClientCert, repo.SSLClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConf
+	return &http.Client{
+		Transport: transport,
+		Timeout:   300 * time.Second,
+	}, nil
+}
+
+// metalink is the subset of a metalink document that lists the mirrors of
+// the repomd.xml file.
+type metalink struct {
+	Files []struct {
+		Name string `xml:"name,attr"`
+		URLs []struct {
+			Protocol string `xml:"protocol,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"resources>url"`
+	} `xml:"files>file"`
+}
+
+// parseMetalink returns the baseurls of the mirrors of a metalink.
+func parseMetalink(r io.Reader) ([]string, error) {
+	var ml metalink
+	if err := xml.NewDecoder(r).Decode(&ml); err != nil {
+		return nil, fmt.Errorf("cannot parse metalink: %w", err)
+	}
+	var baseURLs []string
+	for _, file := range ml.Files {
+		if file.Name != path.Base(repomdPath) {
+			continue
+		}
+		for _, u := range file.URLs {
+			value := strings.TrimSpace(u.Value)
+			if (u.Protocol == "http" || u.Protocol == "https") && strings.HasSuffix(value, "/"+repomdPath) {
+				baseURLs = append(baseURLs, strings.TrimSuffix(value, repomdPath))
+			}
+		}
+	}
+	return baseURLs, nil
+}
+
+// parseMirrorlist returns the baseurls of a mirrorlist, one per line. Lines
+// that are not http://, https:// or file:// URLs are ignored.
+func parseMirrorlist(r io.Reader) ([]string, error) {
+	var baseURLs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		u, err := url.Parse(line)
+		if err != nil || u.Host == "" && u.Scheme != "file" {
+			continue
+		}
+		switch u.Scheme {
+		case "http", "https", "file":
+			baseURLs = append(baseURLs, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read mirrorlist: %w", err)
+	}
+	return baseURLs, nil
+}
+
+// baseURLs returns the baseurls of the repository, resolving the metalink or
+// mirrorlist if the repository has no baseurl.
+func baseURLs(client *http.Client, repo *rpmmd.RepoConfig) ([]string, error) {
+	if len(repo.BaseURLs) > 0 {
+		return repo.BaseURLs, nil
+	}
+
+	var list string
+	var parse func(io.Reader) ([]string, error)
+	switch {
+	case repo.Metalink != "":
+		list, parse = repo.Metalink, parseMetalink
+	case repo.MirrorList != "":
+		list, parse = repo.MirrorList, parseMirrorlist
+	default:
+		return nil, fmt.Errorf("repository %s has no baseurl, metalink or mirrorlist", repoName(repo))
+	}
+	body, err := httpGet(client, list)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve mirrors of repository %s: %w", repoName(repo), err)
+	}
+	defer body.Close()
+	urls, err := parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve mirrors of repository %s: %w", repoName(repo), err)
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("repository %s has no mirrors in %s", repoName(repo), list)
+	}
+	return urls, nil
+}
+
+// newRepoSource returns the source of the first baseurl (or mirror) of the
+// repository. Local paths, file://, http:// and https:// URLs are supported.
+func newRepoSource(repo *rpmmd.RepoConfig) (repoSource, error) {
+	client, err := httpClient(repo)
+	if err != nil {
+		return nil, fmt.Errorf("repository %s: %w", repoName(repo), err)
+	}
+	urls, err := baseURLs(client, repo)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := urls[0]
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("repository %s has an invalid baseurl: %w", repoName(repo), err)
+	}
+	switch u.Scheme {
+	case "":
+		return &localSource{root: baseURL}, nil
+	case "file":
+		return &localSource{root: u.Path}, nil
+	case "http", "https":
+		return &httpSource{client: client, baseURL: u}, nil
+	default:
+		return nil, fmt.Errorf("repository %s: baseurl %q has an unsupported scheme", repoName(repo), baseURL)
+	}
+}
@@ -0,0 +1,144 @@
+package repomd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Advisory is an update advisory (erratum) of the updateinfo metadata of a
+// repository, e.g. RHSA-2024:1234 or FEDORA-2024-0123456789.
+type Advisory struct {
+	ID string `json:"id"`
+
+	// Type of the advisory: security, bugfix, enhancement or newpackage
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Severity string `json:"severity,omitempty"`
+
+	// Time the advisory was issued, zero if the date cannot be parsed
+	Issued time.Time `json:"issued"`
+
+	Description string `json:"description,omitempty"`
+
+	// CVEs, bugs and other references of the advisory
+	References []Reference `json:"references,omitempty"`
+
+	// The package builds that fix the advisory
+	Packages []AdvisoryPackage `json:"packages"`
+}
+
+// Reference is a reference of an advisory to an issue, e.g. a CVE.
+type Reference struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Href string `json:"href,omitempty"`
+}
+
+// AdvisoryPackage is a package build that fixes an advisory.
+type AdvisoryPackage struct {
+	Name     string `json:"name"`
+	Epoch    uint   `json:"epoch"`
+	Version  string `json:"version"`
+	Release  string `json:"release"`
+	Arch     string `json:"arch"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// NEVRA returns the Name-[Epoch:]Version-Release.Arch string of the package.
+func (p *AdvisoryPackage) NEVRA() string {
+	if p.Epoch == 0 {
+		return fmt.Sprintf("%s-%s-%s.%s", p.Name, p.Version, p.Release, p.Arch)
+	}
+	return fmt.Sprintf("%s-%d:%s-%s.%s", p.Name, p.Epoch, p.Version, p.Release, p.Arch)
+}
+
+type xmlUpdate struct {
+	Type     string `xml:"type,attr"`
+	ID       string `xml:"id"`
+	Title    string `xml:"title"`
+	Severity string `xml:"severity"`
+	Issued   struct {
+		Date string `xml:"date,attr"`
+	} `xml:"issued"`
+	Description string `xml:"description"`
+	References  []struct {
+		ID   string `xml:"id,attr"`
+		Type string `xml:"type,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"references>reference"`
+	Packages []struct {
+		Name     string `xml:"name,attr"`
+		Epoch    string `xml:"epoch,attr"`
+		Version  string `xml:"version,attr"`
+		Release  string `xml:"release,attr"`
+		Arch     string `xml:"arch,attr"`
+		Filename string `xml:"filename"`
+	} `xml:"pkglist>collection>package"`
+}
+
+// updateinfo dates are written in different formats by different tools
+var issuedDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05 UTC",
+	"2006-01-02",
+}
+
+func parseIssuedDate(date string) time.Time {
+	date = strings.TrimSpace(date)
+	for _, layout := range issuedDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t
+		}
+	}
+	if secs, err := strconv.ParseInt(date, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC()
+	}
+	return time.Time{}
+}
+
+// parseUpdateinfo parses the updateinfo metadata of a repository.
+func parseUpdateinfo(r io.Reader) ([]Advisory, error) {
+	var advisories []Advisory
+	err := decodeElements(r, "update", func(dec *xml.Decoder, start *xml.StartElement) error {
+		var u xmlUpdate
+		if err := dec.DecodeElement(&u, start); err != nil {
+			return err
+		}
+		advisory := Advisory{
+			ID:          u.ID,
+			Type:        u.Type,
+			Title:       u.Title,
+			Severity:    u.Severity,
+			Issued:      parseIssuedDate(u.Issued.Date),
+			Description: u.Description,
+			Packages:    make([]AdvisoryPackage, 0, len(u.Packages)),
+		}
+		for _, ref := range u.References {
+			advisory.References = append(advisory.References, Reference(ref))
+		}
+		for _, p := range u.Packages {
+			epoch, err := parseEpoch(p.Epoch)
+			if err != nil {
+				return fmt.Errorf("advisory %s: package %s: %w", u.ID, p.Name, err)
+			}
+			advisory.Packages = append(advisory.Packages, AdvisoryPackage{
+				Name:     p.Name,
+				Epoch:    epoch,
+				Version:  p.Version,
+				Release:  p.Release,
+				Arch:     p.Arch,
+				Filename: p.Filename,
+			})
+		}
+		advisories = append(advisories, advisory)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return advisories, nil
+}
@@ -27,6 +27,8 @@ type repository struct {
 	MetadataExpire string   `json:"metadata_expire,omitempty"`
 	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
 	PackageSets    []string `json:"package_sets,omitempty"`
+	PackagesDir    string   `json:"packages_dir,omitempty"`
+	PackageFiles   []string `json:"package_files,omitempty"`
 }
 
 type RepoConfig struct {
@@ -50,6 +52,14 @@ type RepoConfig struct {
 	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
 	PackageSets    []string `json:"package_sets,omitempty"`
 
+	// Local packages without repository metadata, instead of a baseurl,
+	// metalink or mirrorlist. PackagesDir is a directory of .rpm files and
+	// PackageFiles are paths of .rpm files. The repository metadata is
+	// generated for depsolving and the packages are embedded in the
+	// manifest instead of being downloaded.
+	PackagesDir  string   `json:"packages_dir,omitempty"`
+	PackageFiles []string `json:"package_files,omitempty"`
+
 	// These fields are only filled out by the worker during the
 	// depsolve job for certain baseurls.
 	SSLCACert     string `json:"sslcacert,omitempty"`
@@ -85,7 +95,15 @@ func (r *RepoConfig) Hash() string {
 		bpts(r.ModuleHotfixes)+
 		r.SSLCACert+
 		r.SSLClientKey+
-		r.SSLClientCert)))
+		r.SSLClientCert+
+		r.PackagesDir+
+		ats(r.PackageFiles))))
+}
+
+// HasLocalPackages returns true if the repository is a set of local packages
+// without repository metadata, see PackagesDir and PackageFiles.
+func (r *RepoConfig) HasLocalPackages() bool {
+	return r.PackagesDir != "" || len(r.PackageFiles) > 0
 }
 
 type DistrosRepoConfigs map[string]map[string][]RepoConfig
@@ -274,6 +292,8 @@ func LoadRepositoriesFromReader(r io.Reader) (map[string][]RepoConfig, error) {
 				ModuleHotfixes: repo.ModuleHotfixes,
 				ImageTypeTags:  repo.ImageTypeTags,
 				PackageSets:    repo.PackageSets,
+				PackagesDir:    repo.PackagesDir,
+				PackageFiles:   repo.PackageFiles,
 			}
 
 			repoConfigs[arch] = append(repoConfigs[arch], config)
@@ -2,6 +2,7 @@ package rpmmd
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -58,6 +59,25 @@ func TestRepoConfigMarshalEmpty(t *testing.T) {
 	assert.Equal(t, string(js), `{}`)
 }
 
+func TestRepoConfigLocalPackages(t *testing.T) {
+	repos, err := LoadRepositoriesFromReader(strings.NewReader(`{
+		"x86_64": [
+			{"name": "baseos", "baseurl": "https://example.com/baseos"},
+			{"name": "local", "packages_dir": "/srv/rpms", "package_files": ["/tmp/hello.rpm"]}
+		]
+	}`))
+	assert.NoError(t, err)
+	baseos, local := repos["x86_64"][0], repos["x86_64"][1]
+	assert.False(t, baseos.HasLocalPackages())
+	assert.True(t, local.HasLocalPackages())
+	assert.Equal(t, "/srv/rpms", local.PackagesDir)
+	assert.Equal(t, []string{"/tmp/hello.rpm"}, local.PackageFiles)
+
+	other := local
+	other.PackageFiles = nil
+	assert.NotEqual(t, local.Hash(), other.Hash())
+}
+
 func TestPackageSpecEmptyJson(t *testing.T) {
 	pkg := &PackageSpec{Name: "pkg1"}
 	js, err := json.Marshal(pkg)
@@ -0,0 +1,130 @@
+package rpmmd
+
+import (
+	"cmp"
+	"strings"
+)
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// CompareVersions compares two version or release strings in the same way
+// as rpm (rpmvercmp) and returns -1, 0 or 1 if a is older than, equal to or
+// newer than b. A tilde sorts before anything, even the end of the string
+// (1.0~rc1 < 1.0), and a caret sorts after the end of the string but before
+// anything else (1.0 < 1.0^git1 < 1.0.1).
+func CompareVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	for len(a) > 0 || len(b) > 0 {
+		a, b = trimSeparators(a), trimSeparators(b)
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if len(a) == 0 {
+				return -1
+			}
+			if len(b) == 0 {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if len(a) == 0 || len(b) == 0 {
+			break
+		}
+
+		// compare the next segment of digits or letters
+		isNum := isDigit(a[0])
+		segment := isAlpha
+		if isNum {
+			segment = isDigit
+		}
+		segA, segB := leading(a, segment), leading(b, segment)
+		a, b = a[len(segA):], b[len(segB):]
+
+		// numeric segments are newer than alpha segments
+		if len(segB) == 0 {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return cmp.Compare(len(segA), len(segB))
+			}
+		}
+		if res := strings.Compare(segA, segB); res != 0 {
+			return res
+		}
+	}
+
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) > 0:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// trimSeparators removes the leading characters of s that are not part of a
+// segment.
+func trimSeparators(s string) string {
+	idx := 0
+	for idx < len(s) && !isDigit(s[idx]) && !isAlpha(s[idx]) && s[idx] != '~' && s[idx] != '^' {
+		idx++
+	}
+	return s[idx:]
+}
+
+// leading returns the prefix of s of the characters that match fn.
+func leading(s string, fn func(byte) bool) string {
+	idx := 0
+	for idx < len(s) && fn(s[idx]) {
+		idx++
+	}
+	return s[:idx]
+}
+
+// CompareEVR compares the epoch, version and release of two packages in the
+// same way as rpm and returns -1, 0 or 1 if the first is older than, equal to
+// or newer than the second.
+func CompareEVR(epochA uint, versionA, releaseA string, epochB uint, versionB, releaseB string) int {
+	if res := cmp.Compare(epochA, epochB); res != 0 {
+		return res
+	}
+	if res := CompareVersions(versionA, versionB); res != 0 {
+		return res
+	}
+	return CompareVersions(releaseA, releaseB)
+}
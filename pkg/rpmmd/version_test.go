@@ -0,0 +1,72 @@
+package rpmmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	// test cases of the rpmvercmp tests of rpm
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "2.0", -1},
+		{"2.0", "1.0", 1},
+		{"2.0.1", "2.0.1", 0},
+		{"2.0", "2.0.1", -1},
+		{"2.0.1a", "2.0.1", 1},
+		{"5.5p1", "5.5p2", -1},
+		{"5.5p10", "5.5p1", 1},
+		{"10xyz", "10.1xyz", -1},
+		{"xyz10", "xyz10.1", -1},
+		{"xyz.4", "8", -1},
+		{"8", "xyz.4", 1},
+		{"5.5p1", "5.5.p1", 0},
+		{"5.6p1", "5.5p1", 1},
+		{"6.0.rc1", "6.0", 1},
+		{"10b2", "10a1", 1},
+		{"1.0aa", "1.0a", 1},
+		{"10.0001", "10.1", 0},
+		{"10.0001", "10.0039", -1},
+		{"4.999.9", "5.0", -1},
+		{"20101121", "20101122", -1},
+		{"2_0", "2_0", 0},
+		{"2.0", "2_0", 0},
+		{"a", "a", 0},
+		{"a+", "a+", 0},
+		{"a+", "a_", 0},
+		{"+a", "_a", 0},
+		{"+_", "_+", 0},
+		{"1.0~rc1", "1.0~rc1", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~rc1~git123", "1.0~rc1", -1},
+		{"1.0^", "1.0", 1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0^git1", "1.0^git2", -1},
+		{"1.0^git1", "1.01", -1},
+		{"1.0^20160101", "1.0.1", -1},
+		{"1.0^20160101^git1", "1.0^20160101", 1},
+		{"1.0~rc1^git1", "1.0~rc1", 1},
+		{"1.0^git1~pre", "1.0^git1", -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s/%s", tc.a, tc.b), func(t *testing.T) {
+			assert.Equal(t, tc.expected, CompareVersions(tc.a, tc.b))
+			assert.Equal(t, -tc.expected, CompareVersions(tc.b, tc.a))
+		})
+	}
+}
+
+func TestCompareEVR(t *testing.T) {
+	assert.Equal(t, 0, CompareEVR(0, "1.0", "1.el9", 0, "1.0", "1.el9"))
+	assert.Equal(t, 1, CompareEVR(1, "1.0", "1.el9", 0, "2.0", "1.el9"))
+	assert.Equal(t, -1, CompareEVR(0, "1.0", "1.el9", 0, "1.0", "2.el9"))
+	assert.Equal(t, 1, CompareEVR(0, "1.0.1", "1.el9", 0, "1.0", "2.el9"))
+	assert.Equal(t, -1, CompareEVR(0, "1.0", "1.el9", 0, "1.0", "1.el9_1"))
+}
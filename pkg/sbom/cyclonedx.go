@@ -0,0 +1,223 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+const (
+	cycloneDXFormat      = "CycloneDX"
+	cycloneDXSpecVersion = "1.5"
+	cycloneDXToolName    = "osbuild-images"
+)
+
+// cycloneDXSerialUUID is used to derive stable serial numbers from the
+// package set when no explicit serial number is given
+var cycloneDXSerialUUID = uuid.MustParse("6f0d3b5e-2c4a-4e8b-a7f1-3d9c5b2e8a41")
+
+// CycloneDXOptions contains optional settings for GenerateCycloneDX
+type CycloneDXOptions struct {
+	// SerialNumber is the serialNumber of the generated document, if
+	// unset a "urn:uuid:" serial number is derived from the document
+	// name and the package checksums.
+	SerialNumber string
+
+	// Created is the timestamp recorded in the document metadata, if
+	// unset the current time is used.
+	Created time.Time
+
+	// PurlNamespace is the vendor part of the generated purl
+	// identifiers, e.g. "fedora" or "redhat". It is omitted if unset.
+	PurlNamespace string
+
+	// Licenses maps package names to their declared license
+	// expression. Packages without an entry have no license.
+	Licenses map[string]string
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string              `json:"timestamp"`
+	Tools     cycloneDXTools      `json:"tools"`
+	Component *cycloneDXComponent `json:"component,omitempty"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type               string                       `json:"type"`
+	BOMRef             string                       `json:"bom-ref,omitempty"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version,omitempty"`
+	PURL               string                       `json:"purl,omitempty"`
+	Hashes             []cycloneDXHash              `json:"hashes,omitempty"`
+	Licenses           []cycloneDXLicense           `json:"licenses,omitempty"`
+	ExternalReferences []cycloneDXExternalReference `json:"externalReferences,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type cycloneDXLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cycloneDXExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// GenerateCycloneDX creates a CycloneDX 1.5 document for the given
+// depsolved packages without running the depsolver. The repos are used
+// to resolve the download locations. If name is set it is recorded as
+// the component the document describes.
+func GenerateCycloneDX(name string, pkgs []rpmmd.PackageSpec, repos []rpmmd.RepoConfig, opts *CycloneDXOptions) (*Document, error) {
+	if opts == nil {
+		opts = &CycloneDXOptions{}
+	}
+
+	repoMap := make(map[string]rpmmd.RepoConfig, len(repos))
+	for _, repo := range repos {
+		repoMap[repo.Id] = repo
+	}
+
+	doc := cycloneDXDocument{
+		BOMFormat:    cycloneDXFormat,
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: opts.SerialNumber,
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Tools: cycloneDXTools{
+				Components: []cycloneDXComponent{
+					{Type: "application", Name: cycloneDXToolName},
+				},
+			},
+		},
+		Components: make([]cycloneDXComponent, 0, len(pkgs)),
+	}
+	if name != "" {
+		doc.Metadata.Component = &cycloneDXComponent{
+			Type: "operating-system",
+			Name: name,
+		}
+	}
+
+	created := opts.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	doc.Metadata.Timestamp = created.UTC().Format(time.RFC3339)
+
+	usedRefs := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		component, err := cycloneDXComponentFor(pkg, repoMap, opts)
+		if err != nil {
+			return nil, err
+		}
+		// the same package may be part of the set more than once,
+		// ensure that every component has a unique reference
+		ref := component.BOMRef
+		for i := 1; usedRefs[ref]; i++ {
+			ref = fmt.Sprintf("%s#%d", component.BOMRef, i)
+		}
+		component.BOMRef = ref
+		usedRefs[ref] = true
+
+		doc.Components = append(doc.Components, component)
+	}
+
+	if doc.SerialNumber == "" {
+		doc.SerialNumber = cycloneDXSerialNumberFor(name, pkgs)
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDocument(StandardTypeCycloneDX, raw)
+}
+
+func cycloneDXComponentFor(pkg rpmmd.PackageSpec, repoMap map[string]rpmmd.RepoConfig, opts *CycloneDXOptions) (cycloneDXComponent, error) {
+	if pkg.Name == "" {
+		return cycloneDXComponent{}, fmt.Errorf("cannot generate SBOM entry for package without a name")
+	}
+
+	purl := purlFor(pkg, opts.PurlNamespace)
+	component := cycloneDXComponent{
+		Type:    "library",
+		BOMRef:  purl,
+		Name:    pkg.Name,
+		Version: evr(pkg),
+		PURL:    purl,
+	}
+
+	if license, ok := opts.Licenses[pkg.Name]; ok && license != "" {
+		component.Licenses = []cycloneDXLicense{{Expression: license}}
+	}
+
+	location, err := downloadLocation(pkg, repoMap)
+	if err != nil {
+		return cycloneDXComponent{}, err
+	}
+	if location != "" {
+		component.ExternalReferences = []cycloneDXExternalReference{
+			{Type: "distribution", URL: location},
+		}
+	}
+
+	if pkg.Checksum != "" {
+		hash, err := cycloneDXHashFor(pkg.Checksum)
+		if err != nil {
+			return cycloneDXComponent{}, fmt.Errorf("invalid checksum for package %q: %w", pkg.Name, err)
+		}
+		component.Hashes = []cycloneDXHash{hash}
+	}
+
+	return component, nil
+}
+
+// cycloneDXHashFor converts a "<algo>:<value>" checksum as used by
+// rpmmd into its CycloneDX representation
+func cycloneDXHashFor(checksum string) (cycloneDXHash, error) {
+	algo, value, ok := strings.Cut(checksum, ":")
+	if !ok || value == "" {
+		return cycloneDXHash{}, fmt.Errorf("checksum %q is not of the form <algorithm>:<value>", checksum)
+	}
+	switch algo {
+	case "md5":
+		return cycloneDXHash{Algorithm: "MD5", Content: value}, nil
+	case "sha1", "sha256", "sha384", "sha512":
+		return cycloneDXHash{Algorithm: "SHA-" + strings.TrimPrefix(algo, "sha"), Content: value}, nil
+	default:
+		return cycloneDXHash{}, fmt.Errorf("unsupported checksum algorithm %q", algo)
+	}
+}
+
+func cycloneDXSerialNumberFor(name string, pkgs []rpmmd.PackageSpec) string {
+	ids := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		ids = append(ids, nevra(pkg)+"@"+pkg.Checksum)
+	}
+	sort.Strings(ids)
+	return "urn:uuid:" + uuid.NewSHA1(cycloneDXSerialUUID, []byte(name+"\n"+strings.Join(ids, "\n"))).String()
+}
@@ -0,0 +1,120 @@
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+var testPackages = []rpmmd.PackageSpec{
+	{
+		Name:           "bash",
+		Version:        "5.2.26",
+		Release:        "3.fc40",
+		Arch:           "x86_64",
+		Checksum:       "sha256:bbbb",
+		RemoteLocation: "https://example.com/repo/Packages/bash-5.2.26-3.fc40.x86_64.rpm",
+		RepoID:         "fedora",
+	},
+	{
+		Name:     "glibc",
+		Epoch:    1,
+		Version:  "2.39",
+		Release:  "5.fc40",
+		Arch:     "x86_64",
+		Checksum: "sha512:gggg",
+		Path:     "Packages/glibc-2.39-5.fc40.x86_64.rpm",
+		RepoID:   "fedora",
+	},
+}
+
+var testRepos = []rpmmd.RepoConfig{
+	{
+		Id:       "fedora",
+		BaseURLs: []string{"https://example.com/repo"},
+	},
+}
+
+func TestGenerateCycloneDX(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	doc, err := GenerateCycloneDX("image-os", testPackages, testRepos, &CycloneDXOptions{
+		Created:       created,
+		PurlNamespace: "fedora",
+		Licenses: map[string]string{
+			"bash": "GPL-3.0-or-later",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StandardTypeCycloneDX, doc.DocType)
+
+	var cdx cycloneDXDocument
+	require.NoError(t, json.Unmarshal(doc.Document, &cdx))
+	assert.Equal(t, "CycloneDX", cdx.BOMFormat)
+	assert.Equal(t, "1.5", cdx.SpecVersion)
+	assert.Regexp(t, `^urn:uuid:[0-9a-f-]{36}$`, cdx.SerialNumber)
+	assert.Equal(t, "2024-05-01T12:00:00Z", cdx.Metadata.Timestamp)
+	require.NotNil(t, cdx.Metadata.Component)
+	assert.Equal(t, "image-os", cdx.Metadata.Component.Name)
+
+	assert.Equal(t, []cycloneDXComponent{
+		{
+			Type:     "library",
+			BOMRef:   "pkg:rpm/fedora/bash@5.2.26-3.fc40?arch=x86_64&repository_id=fedora",
+			Name:     "bash",
+			Version:  "5.2.26-3.fc40",
+			PURL:     "pkg:rpm/fedora/bash@5.2.26-3.fc40?arch=x86_64&repository_id=fedora",
+			Hashes:   []cycloneDXHash{{Algorithm: "SHA-256", Content: "bbbb"}},
+			Licenses: []cycloneDXLicense{{Expression: "GPL-3.0-or-later"}},
+			ExternalReferences: []cycloneDXExternalReference{
+				{Type: "distribution", URL: "https://example.com/repo/Packages/bash-5.2.26-3.fc40.x86_64.rpm"},
+			},
+		},
+		{
+			Type:    "library",
+			BOMRef:  "pkg:rpm/fedora/glibc@2.39-5.fc40?arch=x86_64&epoch=1&repository_id=fedora",
+			Name:    "glibc",
+			Version: "1:2.39-5.fc40",
+			PURL:    "pkg:rpm/fedora/glibc@2.39-5.fc40?arch=x86_64&epoch=1&repository_id=fedora",
+			Hashes:  []cycloneDXHash{{Algorithm: "SHA-512", Content: "gggg"}},
+			ExternalReferences: []cycloneDXExternalReference{
+				{Type: "distribution", URL: "https://example.com/repo/Packages/glibc-2.39-5.fc40.x86_64.rpm"},
+			},
+		},
+	}, cdx.Components)
+
+	// the serial number is stable for the same package set
+	doc2, err := GenerateCycloneDX("image-os", testPackages, testRepos, &CycloneDXOptions{Created: created})
+	require.NoError(t, err)
+	var cdx2 cycloneDXDocument
+	require.NoError(t, json.Unmarshal(doc2.Document, &cdx2))
+	assert.Equal(t, cdx.SerialNumber, cdx2.SerialNumber)
+}
+
+func TestGenerateCycloneDXDuplicateRefs(t *testing.T) {
+	pkgs := []rpmmd.PackageSpec{
+		{Name: "foo", Version: "1", Release: "1", Arch: "x86_64"},
+		{Name: "foo", Version: "1", Release: "1", Arch: "x86_64"},
+	}
+	doc, err := GenerateCycloneDX("", pkgs, nil, nil)
+	require.NoError(t, err)
+
+	var cdx cycloneDXDocument
+	require.NoError(t, json.Unmarshal(doc.Document, &cdx))
+	assert.Nil(t, cdx.Metadata.Component)
+	require.Len(t, cdx.Components, 2)
+	assert.Equal(t, "pkg:rpm/foo@1-1?arch=x86_64", cdx.Components[0].BOMRef)
+	assert.Equal(t, "pkg:rpm/foo@1-1?arch=x86_64#1", cdx.Components[1].BOMRef)
+}
+
+func TestGenerateCycloneDXErrors(t *testing.T) {
+	_, err := GenerateCycloneDX("err", []rpmmd.PackageSpec{{Version: "1"}}, nil, nil)
+	assert.EqualError(t, err, "cannot generate SBOM entry for package without a name")
+
+	_, err = GenerateCycloneDX("err", []rpmmd.PackageSpec{{Name: "foo", Checksum: "sha224:abcd"}}, nil, nil)
+	assert.EqualError(t, err, `invalid checksum for package "foo": unsupported checksum algorithm "sha224"`)
+}
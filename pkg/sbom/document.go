@@ -10,6 +10,7 @@ type StandardType uint64
 const (
 	StandardTypeNone StandardType = iota
 	StandardTypeSpdx
+	StandardTypeCycloneDX
 )
 
 func (t StandardType) String() string {
@@ -18,6 +19,8 @@ func (t StandardType) String() string {
 		return "none"
 	case StandardTypeSpdx:
 		return "spdx"
+	case StandardTypeCycloneDX:
+		return "cyclonedx"
 	default:
 		panic("invalid standard type")
 	}
@@ -41,6 +44,8 @@ func (t *StandardType) UnmarshalJSON(data []byte) error {
 		*t = StandardTypeNone
 	case `"spdx"`:
 		*t = StandardTypeSpdx
+	case `"cyclonedx"`:
+		*t = StandardTypeCycloneDX
 	default:
 		return fmt.Errorf("invalid SBOM standard type: %s", data)
 	}
@@ -59,6 +64,10 @@ func NewDocument(docType StandardType, doc json.RawMessage) (*Document, error) {
 	switch docType {
 	case StandardTypeSpdx:
 		docType = StandardTypeSpdx
+	case StandardTypeCycloneDX:
+		if err := validateCycloneDX(doc); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unsupported SBOM document type: %s", docType)
 	}
@@ -68,3 +77,24 @@ func NewDocument(docType StandardType, doc json.RawMessage) (*Document, error) {
 		Document: doc,
 	}, nil
 }
+
+// cycloneDXHeader contains the fields that every CycloneDX JSON
+// document must carry, see https://cyclonedx.org/docs/1.5/json/
+type cycloneDXHeader struct {
+	BOMFormat   string `json:"bomFormat"`
+	SpecVersion string `json:"specVersion"`
+}
+
+func validateCycloneDX(doc json.RawMessage) error {
+	var hdr cycloneDXHeader
+	if err := json.Unmarshal(doc, &hdr); err != nil {
+		return fmt.Errorf("invalid CycloneDX document: %w", err)
+	}
+	if hdr.BOMFormat != "CycloneDX" {
+		return fmt.Errorf("invalid CycloneDX document: unexpected bomFormat %q", hdr.BOMFormat)
+	}
+	if hdr.SpecVersion == "" {
+		return fmt.Errorf("invalid CycloneDX document: missing specVersion")
+	}
+	return nil
+}
@@ -34,6 +34,14 @@ func TestStandardTypeJSONUnmarhsall(t *testing.T) {
 				TypeOmit: StandardTypeSpdx,
 			},
 		},
+		{
+			name: "StandardTypeCycloneDX",
+			data: []byte(`{"type":"cyclonedx","type_omit":"cyclonedx"}`),
+			want: testStruct{
+				Type:     StandardTypeCycloneDX,
+				TypeOmit: StandardTypeCycloneDX,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -73,6 +81,14 @@ func TestStandardTypeJSONMarhsall(t *testing.T) {
 				TypeOmit: StandardTypeSpdx,
 			},
 		},
+		{
+			name: "StandardTypeCycloneDX",
+			want: []byte(`{"type":"cyclonedx","type_omit":"cyclonedx"}`),
+			data: TestStruct{
+				Type:     StandardTypeCycloneDX,
+				TypeOmit: StandardTypeCycloneDX,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -84,3 +100,44 @@ func TestStandardTypeJSONMarhsall(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDocumentCycloneDX(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		errStr string
+	}{
+		{
+			name: "happy",
+			doc:  `{"bomFormat":"CycloneDX","specVersion":"1.5","components":[]}`,
+		},
+		{
+			name:   "wrong-format",
+			doc:    `{"bomFormat":"SPDX","specVersion":"1.5"}`,
+			errStr: `invalid CycloneDX document: unexpected bomFormat "SPDX"`,
+		},
+		{
+			name:   "no-spec-version",
+			doc:    `{"bomFormat":"CycloneDX"}`,
+			errStr: "invalid CycloneDX document: missing specVersion",
+		},
+		{
+			name:   "not-json-object",
+			doc:    `[]`,
+			errStr: "invalid CycloneDX document: json: cannot unmarshal array into Go value of type sbom.cycloneDXHeader",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewDocument(StandardTypeCycloneDX, json.RawMessage(tt.doc))
+			if tt.errStr != "" {
+				assert.EqualError(t, err, tt.errStr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, StandardTypeCycloneDX, doc.DocType)
+			assert.Equal(t, json.RawMessage(tt.doc), doc.Document)
+		})
+	}
+}
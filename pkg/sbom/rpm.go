@@ -0,0 +1,89 @@
+package sbom
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// downloadLocation returns the URL the package was downloaded from, or an
+// empty string if it is not known
+func downloadLocation(pkg rpmmd.PackageSpec, repoMap map[string]rpmmd.RepoConfig) (string, error) {
+	repo, hasRepo := repoMap[pkg.RepoID]
+	switch {
+	case pkg.RemoteLocation != "":
+		return pkg.RemoteLocation, nil
+	case hasRepo && len(repo.BaseURLs) > 0 && pkg.Path != "":
+		loc, err := url.JoinPath(repo.BaseURLs[0], pkg.Path)
+		if err != nil {
+			return "", fmt.Errorf("cannot determine download location for %q: %w", pkg.Name, err)
+		}
+		return loc, nil
+	default:
+		return "", nil
+	}
+}
+
+// purlFor returns the package URL for the given rpm, see
+// https://github.com/package-url/purl-spec/blob/master/PURL-TYPES.rst#rpm
+func purlFor(pkg rpmmd.PackageSpec, namespace string) string {
+	var b strings.Builder
+	b.WriteString("pkg:rpm/")
+	if namespace != "" {
+		b.WriteString(url.PathEscape(namespace))
+		b.WriteString("/")
+	}
+	b.WriteString(url.PathEscape(pkg.Name))
+	if pkg.Version != "" {
+		b.WriteString("@")
+		b.WriteString(url.PathEscape(pkg.Version))
+		if pkg.Release != "" {
+			b.WriteString("-")
+			b.WriteString(url.PathEscape(pkg.Release))
+		}
+	}
+
+	// qualifiers must be sorted lexicographically by key
+	var qualifiers []string
+	if pkg.Arch != "" {
+		qualifiers = append(qualifiers, "arch="+url.QueryEscape(pkg.Arch))
+	}
+	if pkg.Epoch != 0 {
+		qualifiers = append(qualifiers, fmt.Sprintf("epoch=%d", pkg.Epoch))
+	}
+	if pkg.RepoID != "" {
+		qualifiers = append(qualifiers, "repository_id="+url.QueryEscape(pkg.RepoID))
+	}
+	if len(qualifiers) > 0 {
+		b.WriteString("?")
+		b.WriteString(strings.Join(qualifiers, "&"))
+	}
+	return b.String()
+}
+
+func evr(pkg rpmmd.PackageSpec) string {
+	if pkg.Version == "" {
+		return ""
+	}
+	v := pkg.Version
+	if pkg.Release != "" {
+		v += "-" + pkg.Release
+	}
+	if pkg.Epoch != 0 {
+		v = fmt.Sprintf("%d:%s", pkg.Epoch, v)
+	}
+	return v
+}
+
+func nevra(pkg rpmmd.PackageSpec) string {
+	s := pkg.Name
+	if v := evr(pkg); v != "" {
+		s += "-" + v
+	}
+	if pkg.Arch != "" {
+		s += "." + pkg.Arch
+	}
+	return s
+}
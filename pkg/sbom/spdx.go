@@ -0,0 +1,280 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+const (
+	spdxVersion     = "SPDX-2.3"
+	spdxDataLicense = "CC0-1.0"
+	spdxDocumentID  = "SPDXRef-DOCUMENT"
+	spdxNoAssertion = "NOASSERTION"
+	spdxCreator     = "Tool: osbuild-images"
+
+	spdxNamespaceBase = "https://osbuild.org/spdxdocs"
+)
+
+// spdxNamespaceUUID is used to derive stable document namespaces
+// from the package set when no explicit namespace is given
+var spdxNamespaceUUID = uuid.MustParse("2b1b6a3a-8f0e-4f7e-9b9e-6e1f3c3f4a55")
+
+var spdxIDInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9.-]`)
+
+// SPDXOptions contains optional settings for GenerateSPDX
+type SPDXOptions struct {
+	// Namespace is the documentNamespace of the generated document,
+	// if unset a namespace is derived from the document name and
+	// the package checksums.
+	Namespace string
+
+	// Created is the creation time recorded in the document, if
+	// unset the current time is used.
+	Created time.Time
+
+	// PurlNamespace is the vendor part of the generated purl
+	// identifiers, e.g. "fedora" or "redhat". It is omitted if unset.
+	PurlNamespace string
+
+	// Licenses maps package names to their declared license
+	// expression. Packages without an entry get NOASSERTION.
+	Licenses map[string]string
+
+	// Requires maps the NEVRA of a package, as returned by
+	// rpmmd.PackageSpec.GetNEVRA(), to the NEVRAs of the packages it
+	// depends on and is used to generate DEPENDS_ON relationships.
+	// NEVRAs are used so that packages that are installed for more
+	// than one architecture are separate elements.
+	Requires map[string][]string
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	CopyrightText    string            `json:"copyrightText"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// GenerateSPDX creates an SPDX 2.3 document for the given depsolved
+// packages without running the depsolver. The repos are used to
+// resolve download locations and the repository qualifier of the
+// purl identifiers.
+func GenerateSPDX(name string, pkgs []rpmmd.PackageSpec, repos []rpmmd.RepoConfig, opts *SPDXOptions) (*Document, error) {
+	if opts == nil {
+		opts = &SPDXOptions{}
+	}
+
+	repoMap := make(map[string]rpmmd.RepoConfig, len(repos))
+	for _, repo := range repos {
+		repoMap[repo.Id] = repo
+	}
+
+	doc := spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       spdxDataLicense,
+		SPDXID:            spdxDocumentID,
+		Name:              name,
+		DocumentNamespace: opts.Namespace,
+		CreationInfo: spdxCreationInfo{
+			Creators: []string{spdxCreator},
+		},
+		Packages:      make([]spdxPackage, 0, len(pkgs)),
+		Relationships: make([]spdxRelationship, 0, len(pkgs)),
+	}
+
+	created := opts.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	doc.CreationInfo.Created = created.UTC().Format(time.RFC3339)
+
+	pkgIDs := make(map[string]string, len(pkgs))
+	usedIDs := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		spdxPkg, err := spdxPackageFor(pkg, repoMap, opts)
+		if err != nil {
+			return nil, err
+		}
+		// packages may appear with multiple arches or versions,
+		// ensure that every element has a unique id
+		id := spdxPkg.SPDXID
+		for i := 1; usedIDs[id]; i++ {
+			id = fmt.Sprintf("%s-%d", spdxPkg.SPDXID, i)
+		}
+		spdxPkg.SPDXID = id
+		usedIDs[id] = true
+		if _, ok := pkgIDs[pkg.GetNEVRA()]; !ok {
+			pkgIDs[pkg.GetNEVRA()] = id
+		}
+
+		doc.Packages = append(doc.Packages, spdxPkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      spdxDocumentID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: id,
+		})
+	}
+
+	// iterate in a stable order so that the document is
+	// reproducible
+	reqNEVRAs := make([]string, 0, len(opts.Requires))
+	for pkgNEVRA := range opts.Requires {
+		reqNEVRAs = append(reqNEVRAs, pkgNEVRA)
+	}
+	sort.Strings(reqNEVRAs)
+	for _, pkgNEVRA := range reqNEVRAs {
+		fromID, ok := pkgIDs[pkgNEVRA]
+		if !ok {
+			continue
+		}
+		deps := append([]string(nil), opts.Requires[pkgNEVRA]...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			toID, ok := pkgIDs[dep]
+			if !ok {
+				return nil, fmt.Errorf("package %q requires %q which is not part of the package set", pkgNEVRA, dep)
+			}
+			if toID == fromID {
+				continue
+			}
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID:      fromID,
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: toID,
+			})
+		}
+	}
+
+	if doc.DocumentNamespace == "" {
+		doc.DocumentNamespace = spdxNamespaceFor(name, pkgs)
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDocument(StandardTypeSpdx, raw)
+}
+
+func spdxPackageFor(pkg rpmmd.PackageSpec, repoMap map[string]rpmmd.RepoConfig, opts *SPDXOptions) (spdxPackage, error) {
+	if pkg.Name == "" {
+		return spdxPackage{}, fmt.Errorf("cannot generate SBOM entry for package without a name")
+	}
+
+	spdxPkg := spdxPackage{
+		SPDXID:           "SPDXRef-RPM-" + spdxIDInvalidChars.ReplaceAllString(nevra(pkg), "-"),
+		Name:             pkg.Name,
+		VersionInfo:      evr(pkg),
+		DownloadLocation: spdxNoAssertion,
+		LicenseDeclared:  spdxNoAssertion,
+		LicenseConcluded: spdxNoAssertion,
+		CopyrightText:    spdxNoAssertion,
+	}
+
+	if license, ok := opts.Licenses[pkg.Name]; ok && license != "" {
+		spdxPkg.LicenseDeclared = license
+	}
+
+	location, err := downloadLocation(pkg, repoMap)
+	if err != nil {
+		return spdxPackage{}, err
+	}
+	if location != "" {
+		spdxPkg.DownloadLocation = location
+	}
+
+	if pkg.Checksum != "" {
+		checksum, err := spdxChecksumFor(pkg.Checksum)
+		if err != nil {
+			return spdxPackage{}, fmt.Errorf("invalid checksum for package %q: %w", pkg.Name, err)
+		}
+		spdxPkg.Checksums = []spdxChecksum{checksum}
+	}
+
+	spdxPkg.ExternalRefs = []spdxExternalRef{
+		{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  purlFor(pkg, opts.PurlNamespace),
+		},
+	}
+
+	return spdxPkg, nil
+}
+
+// spdxChecksumFor converts a "<algo>:<value>" checksum as used by
+// rpmmd into its SPDX representation
+func spdxChecksumFor(checksum string) (spdxChecksum, error) {
+	algo, value, ok := strings.Cut(checksum, ":")
+	if !ok || value == "" {
+		return spdxChecksum{}, fmt.Errorf("checksum %q is not of the form <algorithm>:<value>", checksum)
+	}
+	switch algo {
+	case "md5", "sha1", "sha224", "sha256", "sha384", "sha512":
+		return spdxChecksum{
+			Algorithm:     strings.ToUpper(algo),
+			ChecksumValue: value,
+		}, nil
+	default:
+		return spdxChecksum{}, fmt.Errorf("unsupported checksum algorithm %q", algo)
+	}
+}
+
+func spdxNamespaceFor(name string, pkgs []rpmmd.PackageSpec) string {
+	ids := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		ids = append(ids, nevra(pkg)+"@"+pkg.Checksum)
+	}
+	sort.Strings(ids)
+	id := uuid.NewSHA1(spdxNamespaceUUID, []byte(name+"\n"+strings.Join(ids, "\n")))
+	return fmt.Sprintf("%s/%s-%s", spdxNamespaceBase, url.PathEscape(name), id)
+}
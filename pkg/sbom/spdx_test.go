@@ -0,0 +1,180 @@
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func TestGenerateSPDX(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	doc, err := GenerateSPDX("image-os", testPackages, testRepos, &SPDXOptions{
+		Namespace:     "https://example.com/spdx/image-os",
+		Created:       created,
+		PurlNamespace: "fedora",
+		Licenses: map[string]string{
+			"bash": "GPL-3.0-or-later",
+		},
+		Requires: map[string][]string{
+			"bash-5.2.26-3.fc40.x86_64": {"glibc-1:2.39-5.fc40.x86_64"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StandardTypeSpdx, doc.DocType)
+
+	var spdx spdxDocument
+	require.NoError(t, json.Unmarshal(doc.Document, &spdx))
+
+	assert.Equal(t, "SPDX-2.3", spdx.SPDXVersion)
+	assert.Equal(t, "SPDXRef-DOCUMENT", spdx.SPDXID)
+	assert.Equal(t, "image-os", spdx.Name)
+	assert.Equal(t, "https://example.com/spdx/image-os", spdx.DocumentNamespace)
+	assert.Equal(t, "2024-05-01T12:00:00Z", spdx.CreationInfo.Created)
+
+	expectedPkgs := []spdxPackage{
+		{
+			SPDXID:           "SPDXRef-RPM-bash-5.2.26-3.fc40.x86-64",
+			Name:             "bash",
+			VersionInfo:      "5.2.26-3.fc40",
+			DownloadLocation: "https://example.com/repo/Packages/bash-5.2.26-3.fc40.x86_64.rpm",
+			LicenseDeclared:  "GPL-3.0-or-later",
+			LicenseConcluded: "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+			Checksums:        []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: "bbbb"}},
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  "pkg:rpm/fedora/bash@5.2.26-3.fc40?arch=x86_64&repository_id=fedora",
+			}},
+		},
+		{
+			SPDXID:           "SPDXRef-RPM-glibc-1-2.39-5.fc40.x86-64",
+			Name:             "glibc",
+			VersionInfo:      "1:2.39-5.fc40",
+			DownloadLocation: "https://example.com/repo/Packages/glibc-2.39-5.fc40.x86_64.rpm",
+			LicenseDeclared:  "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+			Checksums:        []spdxChecksum{{Algorithm: "SHA512", ChecksumValue: "gggg"}},
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  "pkg:rpm/fedora/glibc@2.39-5.fc40?arch=x86_64&epoch=1&repository_id=fedora",
+			}},
+		},
+	}
+	assert.Equal(t, expectedPkgs, spdx.Packages)
+
+	expectedRels := []spdxRelationship{
+		{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-RPM-bash-5.2.26-3.fc40.x86-64"},
+		{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-RPM-glibc-1-2.39-5.fc40.x86-64"},
+		{SPDXElementID: "SPDXRef-RPM-bash-5.2.26-3.fc40.x86-64", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-RPM-glibc-1-2.39-5.fc40.x86-64"},
+	}
+	assert.Equal(t, expectedRels, spdx.Relationships)
+}
+
+func TestGenerateSPDXStableNamespace(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	opts := &SPDXOptions{Created: created}
+
+	doc1, err := GenerateSPDX("image-os", testPackages, testRepos, opts)
+	require.NoError(t, err)
+	doc2, err := GenerateSPDX("image-os", testPackages, testRepos, opts)
+	require.NoError(t, err)
+	assert.Equal(t, doc1, doc2)
+
+	doc3, err := GenerateSPDX("image-os", testPackages[:1], testRepos, opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, doc1, doc3)
+}
+
+func TestGenerateSPDXDuplicateIDs(t *testing.T) {
+	pkgs := []rpmmd.PackageSpec{
+		{Name: "foo", Version: "1", Release: "1", Arch: "x86_64"},
+		{Name: "foo", Version: "1", Release: "1", Arch: "x86_64"},
+	}
+	doc, err := GenerateSPDX("dup", pkgs, nil, nil)
+	require.NoError(t, err)
+
+	var spdx spdxDocument
+	require.NoError(t, json.Unmarshal(doc.Document, &spdx))
+	require.Len(t, spdx.Packages, 2)
+	assert.Equal(t, "SPDXRef-RPM-foo-1-1.x86-64", spdx.Packages[0].SPDXID)
+	assert.Equal(t, "SPDXRef-RPM-foo-1-1.x86-64-1", spdx.Packages[1].SPDXID)
+}
+
+func TestGenerateSPDXMultilib(t *testing.T) {
+	pkgs := []rpmmd.PackageSpec{
+		{Name: "glibc", Version: "2.39", Release: "5.fc40", Arch: "x86_64"},
+		{Name: "glibc", Version: "2.39", Release: "5.fc40", Arch: "i686"},
+		{Name: "libgcc", Version: "14.1.1", Release: "1.fc40", Arch: "i686"},
+	}
+	doc, err := GenerateSPDX("multilib", pkgs, nil, &SPDXOptions{
+		Requires: map[string][]string{
+			"glibc-2.39-5.fc40.i686": {"libgcc-14.1.1-1.fc40.i686"},
+		},
+	})
+	require.NoError(t, err)
+
+	var spdx spdxDocument
+	require.NoError(t, json.Unmarshal(doc.Document, &spdx))
+	require.Len(t, spdx.Packages, 3)
+	assert.Equal(t, "SPDXRef-RPM-glibc-2.39-5.fc40.x86-64", spdx.Packages[0].SPDXID)
+	assert.Equal(t, "SPDXRef-RPM-glibc-2.39-5.fc40.i686", spdx.Packages[1].SPDXID)
+	// the dependency of the i686 package is not attributed to the x86_64 one
+	assert.Contains(t, spdx.Relationships, spdxRelationship{
+		SPDXElementID:      "SPDXRef-RPM-glibc-2.39-5.fc40.i686",
+		RelationshipType:   "DEPENDS_ON",
+		RelatedSPDXElement: "SPDXRef-RPM-libgcc-14.1.1-1.fc40.i686",
+	})
+	assert.NotContains(t, spdx.Relationships, spdxRelationship{
+		SPDXElementID:      "SPDXRef-RPM-glibc-2.39-5.fc40.x86-64",
+		RelationshipType:   "DEPENDS_ON",
+		RelatedSPDXElement: "SPDXRef-RPM-libgcc-14.1.1-1.fc40.i686",
+	})
+}
+
+func TestGenerateSPDXErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		pkgs   []rpmmd.PackageSpec
+		opts   *SPDXOptions
+		errStr string
+	}{
+		{
+			name:   "no-name",
+			pkgs:   []rpmmd.PackageSpec{{Version: "1"}},
+			errStr: "cannot generate SBOM entry for package without a name",
+		},
+		{
+			name:   "bad-checksum",
+			pkgs:   []rpmmd.PackageSpec{{Name: "foo", Checksum: "abcd"}},
+			errStr: `invalid checksum for package "foo": checksum "abcd" is not of the form <algorithm>:<value>`,
+		},
+		{
+			name:   "unknown-checksum-algo",
+			pkgs:   []rpmmd.PackageSpec{{Name: "foo", Checksum: "crc32:abcd"}},
+			errStr: `invalid checksum for package "foo": unsupported checksum algorithm "crc32"`,
+		},
+		{
+			name: "unknown-requires",
+			pkgs: []rpmmd.PackageSpec{{Name: "foo", Version: "1", Release: "1", Arch: "noarch"}},
+			opts: &SPDXOptions{
+				Requires: map[string][]string{"foo-1-1.noarch": {"bar-1-1.noarch"}},
+			},
+			errStr: `package "foo-1-1.noarch" requires "bar-1-1.noarch" which is not part of the package set`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GenerateSPDX("err", tt.pkgs, nil, tt.opts)
+			assert.EqualError(t, err, tt.errStr)
+		})
+	}
+}